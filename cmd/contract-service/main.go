@@ -1,8 +1,19 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
 
 	"github.com/nurpe/snowops-contract/internal/auth"
 	"github.com/nurpe/snowops-contract/internal/config"
@@ -10,11 +21,20 @@ import (
 	httphandler "github.com/nurpe/snowops-contract/internal/http"
 	"github.com/nurpe/snowops-contract/internal/http/middleware"
 	"github.com/nurpe/snowops-contract/internal/logger"
+	"github.com/nurpe/snowops-contract/internal/metrics"
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/notifier"
 	"github.com/nurpe/snowops-contract/internal/repository"
 	"github.com/nurpe/snowops-contract/internal/service"
+	"github.com/nurpe/snowops-contract/internal/tracing"
+	"github.com/nurpe/snowops-contract/internal/version"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "verify-usage" {
+		os.Exit(runVerifyUsage(os.Args[2:]))
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
@@ -23,6 +43,16 @@ func main() {
 
 	appLogger := logger.New(cfg.Environment)
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("failed to set up tracing")
+	}
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = shutdownTracing(ctx)
+	}()
+
 	database, err := db.New(cfg, appLogger)
 	if err != nil {
 		appLogger.Fatal().Err(err).Msg("failed to connect database")
@@ -30,20 +60,205 @@ func main() {
 
 	contractRepo := repository.NewContractRepository(database)
 
-	contractService := service.NewContractService(contractRepo)
+	workTypes := make([]model.WorkType, 0, len(cfg.Contracts.WorkTypes))
+	for _, workType := range cfg.Contracts.WorkTypes {
+		workTypes = append(workTypes, model.WorkType(strings.ToLower(workType)))
+	}
+
+	var listPresets service.ListPresets
+	if cfg.Contracts.ListPresetsFile != "" {
+		listPresets, err = service.LoadListPresets(cfg.Contracts.ListPresetsFile)
+		if err != nil {
+			appLogger.Fatal().Err(err).Msg("failed to load list presets")
+		}
+	}
+
+	calendar, err := service.NewBusinessCalendar(cfg.Calendar.WeekendDays, cfg.Calendar.Holidays, cfg.Calendar.Timezone)
+	if err != nil {
+		appLogger.Fatal().Err(err).Msg("invalid business calendar")
+	}
+
+	orgCacheTTL := cfg.OrgCache.TTL
+	if cfg.OrgCache.Disabled {
+		orgCacheTTL = 0
+	}
+
+	var sinks notifier.Multi
+	if cfg.Webhook.URL != "" {
+		sinks = append(sinks, notifier.NewAsync(notifier.NewWebhook(cfg.Webhook.URL, cfg.Webhook.Timeout, cfg.Webhook.Secret), cfg.Webhook.Timeout, appLogger))
+	}
+	if cfg.NATS.URL != "" {
+		publisher, err := notifier.NewNATS(cfg.NATS.URL, cfg.NATS.SubjectPrefix, version.ServiceName)
+		if err != nil {
+			appLogger.Fatal().Err(err).Msg("failed to connect to NATS")
+		}
+		defer publisher.Close()
+		sinks = append(sinks, notifier.NewAsync(publisher, cfg.NATS.Timeout, appLogger))
+	}
+	var events notifier.Notifier = notifier.Noop{}
+	if len(sinks) == 1 {
+		events = sinks[0]
+	} else if len(sinks) > 1 {
+		events = sinks
+	}
+
+	contractService := service.NewContractService(contractRepo, events, service.Config{
+		StrictUsage:               cfg.Contracts.StrictUsage,
+		FlagUsageLoadErrors:       cfg.Contracts.UsageLoadErrorMode == config.UsageLoadErrorFlag,
+		Calendar:                  calendar,
+		SnapshotMaxItems:          cfg.Contracts.SnapshotMaxItems,
+		WorkTypes:                 workTypes,
+		MaxActivePerOrg:           cfg.Contracts.MaxActivePerOrg,
+		AutoDeactivateGrace:       cfg.Jobs.AutoDeactivateGrace,
+		ListPresets:               listPresets,
+		ResultTolerance:           cfg.Contracts.ResultTolerance,
+		MinimalVolumeBudgetFactor: cfg.Contracts.MinimalVolumeBudgetFactor,
+		IdempotencyKeyTTL:         cfg.Contracts.IdempotencyKeyTTL,
+		RejectOverBudget:          cfg.Contracts.RejectOverBudget,
+		OrgCacheTTL:               orgCacheTTL,
+		OrgCacheSize:              cfg.OrgCache.Size,
+	}, appLogger)
+
+	if cfg.ReadOnlyMode {
+		if err := contractService.EnableReadOnly(context.Background()); err != nil {
+			appLogger.Fatal().Err(err).Msg("failed to enable read-only mode")
+		}
+	}
+
+	metrics.Register(prometheus.DefaultRegisterer)
+	prometheus.MustRegister(metrics.NewBudgetExceededCollector(contractService.CountBudgetExceeded))
+
+	// Фоновые задачи работают на контексте, который отменяется по SIGINT/SIGTERM;
+	// при остановке сервер дожидается их, чтобы не оборвать транзакцию на середине.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var jobs sync.WaitGroup
+	startJob := func(run func(context.Context)) {
+		jobs.Add(1)
+		go func() {
+			defer jobs.Done()
+			run(ctx)
+		}()
+	}
+	if cfg.Jobs.UsageConsistencyInterval > 0 {
+		startJob(func(ctx context.Context) {
+			runUsageConsistencyCheck(ctx, contractService, cfg.Jobs.UsageConsistencyInterval, appLogger)
+		})
+	}
+	if cfg.Jobs.AutoDeactivateInterval > 0 {
+		startJob(func(ctx context.Context) {
+			runAutoDeactivation(ctx, contractService, cfg.Jobs.AutoDeactivateInterval, appLogger)
+		})
+	}
+	startJob(func(ctx context.Context) {
+		runIdempotencyCleanup(ctx, contractService, cfg.Jobs.IdempotencyCleanupInterval, appLogger)
+	})
 
 	tokenParser := auth.NewParser(cfg.Auth.AccessSecret)
 
-	handler := httphandler.NewHandler(contractService, appLogger)
-	authMiddleware := middleware.Auth(tokenParser)
+	handler := httphandler.NewHandler(contractService, httphandler.Config{
+		StrictQueryParams:    cfg.HTTP.StrictQueryParams,
+		AtomicTripUsageBatch: cfg.Contracts.AtomicTripUsageBatch,
+	}, appLogger)
+	authMiddleware := middleware.Auth(tokenParser, middleware.AuthConfig{
+		DisableTooAdmin: cfg.Auth.DisableTooAdmin,
+	}, appLogger)
 	router := httphandler.NewRouter(handler, authMiddleware, cfg.Environment)
 
 	addr := fmt.Sprintf("%s:%d", cfg.HTTP.Host, cfg.HTTP.Port)
-	appLogger.Info().Str("addr", addr).Msg("starting contract service")
+	servers := []*http.Server{{Addr: addr, Handler: router}}
+	if cfg.HTTP.MetricsAddr != "" {
+		servers = append(servers, &http.Server{Addr: cfg.HTTP.MetricsAddr, Handler: httphandler.NewMetricsHandler()})
+	}
+
+	serveErr := make(chan error, len(servers))
+	for _, server := range servers {
+		appLogger.Info().Str("addr", server.Addr).Msg("starting contract service")
+		go func(server *http.Server) {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				serveErr <- err
+			}
+		}(server)
+	}
 
-	if err := router.Run(addr); err != nil {
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		appLogger.Info().Msg("shutting down contract service")
+	case err := <-serveErr:
 		appLogger.Error().Err(err).Msg("failed to start server")
-		os.Exit(1)
+		exitCode = 1
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			appLogger.Error().Err(err).Str("addr", server.Addr).Msg("graceful shutdown failed")
+		}
+	}
+	jobs.Wait()
+
+	if exitCode != 0 {
+		os.Exit(exitCode)
 	}
 }
 
+func runUsageConsistencyCheck(ctx context.Context, contracts *service.ContractService, interval time.Duration, log zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report, err := contracts.RefreshUsageConsistency(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("usage consistency check failed")
+		} else if report.InconsistentCount > 0 {
+			log.Warn().Int("contracts", report.InconsistentCount).Msg("contract_usage differs from trip_usage_log")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runAutoDeactivation(ctx context.Context, contracts *service.ContractService, interval time.Duration, log zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		deactivated, err := contracts.DeactivateExpired(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("auto deactivation failed")
+		} else if deactivated > 0 {
+			log.Info().Int("contracts", deactivated).Msg("expired contracts deactivated")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func runIdempotencyCleanup(ctx context.Context, contracts *service.ContractService, interval time.Duration, log zerolog.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		purged, err := contracts.PurgeExpiredIdempotencyKeys(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("idempotency key cleanup failed")
+		} else if purged > 0 {
+			log.Debug().Int64("keys", purged).Msg("expired idempotency keys purged")
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
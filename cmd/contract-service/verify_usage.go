@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/nurpe/snowops-contract/internal/config"
+	"github.com/nurpe/snowops-contract/internal/db"
+	"github.com/nurpe/snowops-contract/internal/logger"
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+	"github.com/nurpe/snowops-contract/internal/service"
+)
+
+// Коды выхода verify-usage: cron может реагировать на расхождения без разбора CSV.
+const (
+	verifyExitOK           = 0
+	verifyExitError        = 1
+	verifyExitInconsistent = 2
+)
+
+// runVerifyUsage сверяет contract_usage с trip_usage_log и корректировками по
+// всем контрактам и пишет CSV расхождений. Ничего не исправляет и не применяет миграции.
+func runVerifyUsage(args []string) int {
+	flags := flag.NewFlagSet("verify-usage", flag.ContinueOnError)
+	output := flags.String("output", "-", "путь к CSV-файлу (- — stdout)")
+	if err := flags.Parse(args); err != nil {
+		return verifyExitError
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to load config: %v\n", err)
+		return verifyExitError
+	}
+	appLogger := logger.New(cfg.Environment)
+
+	database, err := db.Open(cfg, appLogger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to connect database: %v\n", err)
+		return verifyExitError
+	}
+
+	contracts := service.NewContractService(repository.NewContractRepository(database), nil, service.Config{}, appLogger)
+	report, err := contracts.RefreshUsageConsistency(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "usage verification failed: %v\n", err)
+		return verifyExitError
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		file, err := os.Create(*output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "failed to create output: %v\n", err)
+			return verifyExitError
+		}
+		defer file.Close()
+		out = file
+	}
+
+	if err := writeDiscrepanciesCSV(out, report.Contracts); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to write csv: %v\n", err)
+		return verifyExitError
+	}
+
+	if report.InconsistentCount > 0 {
+		fmt.Fprintf(os.Stderr, "%d contracts with inconsistent usage\n", report.InconsistentCount)
+		return verifyExitInconsistent
+	}
+	return verifyExitOK
+}
+
+func writeDiscrepanciesCSV(out io.Writer, items []model.UsageDiscrepancy) error {
+	writer := csv.NewWriter(out)
+	if err := writer.Write([]string{
+		"contract_id",
+		"usage_volume_m3",
+		"logged_volume_m3",
+		"volume_diff_m3",
+		"usage_cost",
+		"logged_cost",
+		"cost_diff",
+	}); err != nil {
+		return err
+	}
+	for _, item := range items {
+		if err := writer.Write([]string{
+			item.ContractID.String(),
+			formatCSVFloat(item.UsageVolumeM3),
+			formatCSVFloat(item.LoggedVolumeM3),
+			formatCSVFloat(item.UsageVolumeM3 - item.LoggedVolumeM3),
+			formatCSVFloat(item.UsageCost),
+			formatCSVFloat(item.LoggedCost),
+			formatCSVFloat(item.UsageCost - item.LoggedCost),
+		}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatCSVFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', 2, 64)
+}
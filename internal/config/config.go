@@ -2,25 +2,106 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
 )
 
 type HTTPConfig struct {
-	Host string
-	Port int
+	Host              string
+	Port              int
+	StrictQueryParams bool
+	// MetricsAddr — адрес внутреннего listener для /metrics; пусто — метрики не отдаются
+	MetricsAddr string
 }
 
 type DBConfig struct {
-	DSN             string
-	MaxOpenConns    int
-	MaxIdleConns    int
-	ConnMaxLifetime time.Duration
+	DSN                string
+	MaxOpenConns       int
+	MaxIdleConns       int
+	ConnMaxLifetime    time.Duration
+	SlowQueryThreshold time.Duration
 }
 
 type AuthConfig struct {
 	AccessSecret string
+	// DisableTooAdmin — выключить устаревшую роль TOO_ADMIN (403)
+	DisableTooAdmin bool
+}
+
+// Режимы USAGE_LOAD_ERROR_MODE.
+const (
+	UsageLoadErrorFail = "fail"
+	UsageLoadErrorFlag = "flag"
+)
+
+type ContractsConfig struct {
+	StrictUsage bool
+	// UsageLoadErrorMode — fail: ошибка чтения usage даёт 500; flag: контракт
+	// отдаётся с usage_load_error
+	UsageLoadErrorMode string
+	WorkTypes          []string
+	MaxActivePerOrg    int
+	// ListPresetsFile — JSON с фильтрами списка по умолчанию для ролей
+	ListPresetsFile string
+	// ResultTolerance — допустимый недобор минимального объёма (доля, 0.02 = 2%)
+	ResultTolerance float64
+	// MinimalVolumeBudgetFactor — во сколько раз стоимость минимального объёма
+	// может превышать budget_total, прежде чем контракт отклоняется
+	MinimalVolumeBudgetFactor float64
+	// IdempotencyKeyTTL — сколько хранится Idempotency-Key создания контракта
+	IdempotencyKeyTTL time.Duration
+	// SnapshotMaxItems — лимит тикетов, рейсов и строк журнала в снимке контракта
+	SnapshotMaxItems int
+	// AtomicTripUsageBatch — пакетная запись рейсов всё-или-ничего вместо best-effort
+	AtomicTripUsageBatch bool
+	// RejectOverBudget — отклонять рейсы, с которыми total_cost превысит budget_total
+	RejectOverBudget bool
+}
+
+// BusinessCalendarConfig — рабочие дни для прогнозов; пусто — календарные дни.
+type BusinessCalendarConfig struct {
+	WeekendDays []string
+	Holidays    []string
+	Timezone    string
+}
+
+type OrgCacheConfig struct {
+	Disabled bool
+	TTL      time.Duration
+	Size     int
+}
+
+type WebhookConfig struct {
+	URL     string
+	Timeout time.Duration
+	// Secret — общий секрет подписи X-Signature; пусто — без подписи
+	Secret string
+}
+
+// NATSConfig — публикация событий в NATS JetStream; пустой URL — выключено.
+type NATSConfig struct {
+	URL string
+	// SubjectPrefix — префикс subject: <prefix>.contract.created
+	SubjectPrefix string
+	// Timeout — таймаут публикации одного события
+	Timeout time.Duration
+}
+
+// TracingConfig — экспорт трасс OpenTelemetry по OTLP/HTTP; пустой эндпоинт — выключено.
+type TracingConfig struct {
+	OTLPEndpoint string
+	Insecure     bool
+	SampleRatio  float64
+}
+
+type JobsConfig struct {
+	UsageConsistencyInterval time.Duration
+	AutoDeactivateInterval   time.Duration
+	AutoDeactivateGrace      time.Duration
+	// IdempotencyCleanupInterval — период удаления истёкших Idempotency-Key
+	IdempotencyCleanupInterval time.Duration
 }
 
 type Config struct {
@@ -28,6 +109,15 @@ type Config struct {
 	HTTP        HTTPConfig
 	DB          DBConfig
 	Auth        AuthConfig
+	Contracts   ContractsConfig
+	Jobs        JobsConfig
+	Webhook     WebhookConfig
+	NATS        NATSConfig
+	OrgCache    OrgCacheConfig
+	Tracing     TracingConfig
+	Calendar    BusinessCalendarConfig
+	// ReadOnlyMode — включить режим обслуживания при старте (выключается через API)
+	ReadOnlyMode bool
 }
 
 func Load() (*Config, error) {
@@ -46,18 +136,121 @@ func Load() (*Config, error) {
 	cfg := &Config{
 		Environment: v.GetString("APP_ENV"),
 		HTTP: HTTPConfig{
-			Host: v.GetString("HTTP_HOST"),
-			Port: v.GetInt("HTTP_PORT"),
+			Host:              v.GetString("HTTP_HOST"),
+			Port:              v.GetInt("HTTP_PORT"),
+			StrictQueryParams: v.GetBool("HTTP_STRICT_QUERY_PARAMS"),
+			MetricsAddr:       v.GetString("HTTP_METRICS_ADDR"),
 		},
 		DB: DBConfig{
-			DSN:             v.GetString("DB_DSN"),
-			MaxOpenConns:    v.GetInt("DB_MAX_OPEN_CONNS"),
-			MaxIdleConns:    v.GetInt("DB_MAX_IDLE_CONNS"),
-			ConnMaxLifetime: v.GetDuration("DB_CONN_MAX_LIFETIME"),
+			DSN:                v.GetString("DB_DSN"),
+			MaxOpenConns:       v.GetInt("DB_MAX_OPEN_CONNS"),
+			MaxIdleConns:       v.GetInt("DB_MAX_IDLE_CONNS"),
+			ConnMaxLifetime:    v.GetDuration("DB_CONN_MAX_LIFETIME"),
+			SlowQueryThreshold: v.GetDuration("DB_SLOW_QUERY_THRESHOLD"),
 		},
 		Auth: AuthConfig{
-			AccessSecret: v.GetString("JWT_ACCESS_SECRET"),
+			AccessSecret:    v.GetString("JWT_ACCESS_SECRET"),
+			DisableTooAdmin: v.GetBool("AUTH_TOO_ADMIN_DISABLED"),
+		},
+		Contracts: ContractsConfig{
+			StrictUsage:               v.GetBool("USAGE_STRICT_MODE"),
+			UsageLoadErrorMode:        strings.ToLower(strings.TrimSpace(v.GetString("USAGE_LOAD_ERROR_MODE"))),
+			WorkTypes:                 splitList(v.GetString("WORK_TYPES")),
+			MaxActivePerOrg:           v.GetInt("CONTRACTS_MAX_ACTIVE_PER_ORG"),
+			ListPresetsFile:           v.GetString("LIST_PRESETS_FILE"),
+			ResultTolerance:           v.GetFloat64("RESULT_TOLERANCE"),
+			MinimalVolumeBudgetFactor: v.GetFloat64("MINIMAL_VOLUME_BUDGET_FACTOR"),
+			IdempotencyKeyTTL:         v.GetDuration("IDEMPOTENCY_KEY_TTL"),
+			AtomicTripUsageBatch:      v.GetBool("TRIP_USAGE_BATCH_ATOMIC"),
+			RejectOverBudget:          v.GetBool("USAGE_REJECT_OVER_BUDGET"),
+			SnapshotMaxItems:          v.GetInt("SNAPSHOT_MAX_ITEMS"),
+		},
+		Jobs: JobsConfig{
+			UsageConsistencyInterval:   v.GetDuration("USAGE_CONSISTENCY_CHECK_INTERVAL"),
+			AutoDeactivateInterval:     v.GetDuration("AUTO_DEACTIVATE_INTERVAL"),
+			AutoDeactivateGrace:        v.GetDuration("AUTO_DEACTIVATE_GRACE_PERIOD"),
+			IdempotencyCleanupInterval: v.GetDuration("IDEMPOTENCY_CLEANUP_INTERVAL"),
+		},
+		OrgCache: OrgCacheConfig{
+			Disabled: v.GetBool("ORG_CACHE_DISABLED"),
+			TTL:      v.GetDuration("ORG_CACHE_TTL"),
+			Size:     v.GetInt("ORG_CACHE_SIZE"),
+		},
+		Webhook: WebhookConfig{
+			URL:     v.GetString("WEBHOOK_URL"),
+			Timeout: v.GetDuration("WEBHOOK_TIMEOUT"),
+			Secret:  v.GetString("WEBHOOK_SECRET"),
+		},
+		NATS: NATSConfig{
+			URL:           v.GetString("NATS_URL"),
+			SubjectPrefix: v.GetString("NATS_SUBJECT_PREFIX"),
+			Timeout:       v.GetDuration("NATS_PUBLISH_TIMEOUT"),
 		},
+		Tracing: TracingConfig{
+			OTLPEndpoint: v.GetString("TRACING_OTLP_ENDPOINT"),
+			Insecure:     v.GetBool("TRACING_OTLP_INSECURE"),
+		},
+		Calendar: BusinessCalendarConfig{
+			WeekendDays: splitList(v.GetString("BUSINESS_WEEKEND_DAYS")),
+			Holidays:    splitList(v.GetString("BUSINESS_HOLIDAYS")),
+			Timezone:    v.GetString("BUSINESS_CALENDAR_TZ"),
+		},
+		ReadOnlyMode: v.GetBool("READ_ONLY_MODE"),
+	}
+
+	cfg.Tracing.SampleRatio = 1
+	if v.IsSet("TRACING_SAMPLE_RATIO") {
+		cfg.Tracing.SampleRatio = v.GetFloat64("TRACING_SAMPLE_RATIO")
+	}
+
+	if cfg.DB.SlowQueryThreshold <= 0 {
+		cfg.DB.SlowQueryThreshold = 200 * time.Millisecond
+		if cfg.Environment == "production" {
+			cfg.DB.SlowQueryThreshold = time.Second
+		}
+	}
+
+	// Защита от массового создания контрактов (например, зациклившимся импортом)
+	if cfg.Contracts.MaxActivePerOrg <= 0 {
+		cfg.Contracts.MaxActivePerOrg = 1000
+	}
+
+	if cfg.Contracts.MinimalVolumeBudgetFactor <= 0 {
+		cfg.Contracts.MinimalVolumeBudgetFactor = 1.5
+	}
+
+	if cfg.Contracts.SnapshotMaxItems <= 0 {
+		cfg.Contracts.SnapshotMaxItems = 10000
+	}
+	if cfg.Contracts.UsageLoadErrorMode == "" {
+		cfg.Contracts.UsageLoadErrorMode = UsageLoadErrorFail
+	}
+	if cfg.Contracts.IdempotencyKeyTTL <= 0 {
+		cfg.Contracts.IdempotencyKeyTTL = 24 * time.Hour
+	}
+	if cfg.Jobs.IdempotencyCleanupInterval <= 0 {
+		cfg.Jobs.IdempotencyCleanupInterval = time.Hour
+	}
+
+	if cfg.OrgCache.TTL <= 0 {
+		cfg.OrgCache.TTL = time.Minute
+	}
+	if cfg.OrgCache.Size <= 0 {
+		cfg.OrgCache.Size = 1000
+	}
+
+	if cfg.Webhook.Timeout <= 0 {
+		cfg.Webhook.Timeout = 5 * time.Second
+	}
+	if !v.IsSet("NATS_SUBJECT_PREFIX") {
+		cfg.NATS.SubjectPrefix = "snowops"
+	}
+	if cfg.NATS.Timeout <= 0 {
+		cfg.NATS.Timeout = 5 * time.Second
+	}
+
+	if cfg.Jobs.AutoDeactivateGrace < 0 {
+		cfg.Jobs.AutoDeactivateGrace = 0
 	}
 
 	if err := validate(cfg); err != nil {
@@ -67,6 +260,17 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// splitList разбирает список через запятую, пропуская пустые элементы.
+func splitList(raw string) []string {
+	var items []string
+	for _, part := range strings.Split(raw, ",") {
+		if item := strings.TrimSpace(part); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func validate(cfg *Config) error {
 	if cfg.DB.DSN == "" {
 		return fmt.Errorf("DB_DSN is required")
@@ -80,6 +284,17 @@ func validate(cfg *Config) error {
 	if cfg.HTTP.Port == 0 {
 		return fmt.Errorf("HTTP_PORT is required")
 	}
+	if cfg.Contracts.ResultTolerance < 0 || cfg.Contracts.ResultTolerance >= 1 {
+		return fmt.Errorf("RESULT_TOLERANCE must be in [0, 1)")
+	}
+	if cfg.Contracts.MinimalVolumeBudgetFactor < 1 {
+		return fmt.Errorf("MINIMAL_VOLUME_BUDGET_FACTOR must be >= 1")
+	}
+	if cfg.Contracts.UsageLoadErrorMode != UsageLoadErrorFail && cfg.Contracts.UsageLoadErrorMode != UsageLoadErrorFlag {
+		return fmt.Errorf("USAGE_LOAD_ERROR_MODE must be %q or %q", UsageLoadErrorFail, UsageLoadErrorFlag)
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		return fmt.Errorf("TRACING_SAMPLE_RATIO must be in [0, 1]")
+	}
 	return nil
 }
-
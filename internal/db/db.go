@@ -3,7 +3,6 @@ package db
 import (
 	"context"
 	"fmt"
-	"time"
 
 	"github.com/rs/zerolog"
 	"gorm.io/driver/postgres"
@@ -13,17 +12,24 @@ import (
 	"github.com/nurpe/snowops-contract/internal/config"
 )
 
+// New подключается к БД и применяет миграции.
 func New(cfg *config.Config, log zerolog.Logger) (*gorm.DB, error) {
+	database, err := Open(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := Migrate(database); err != nil {
+		return nil, fmt.Errorf("run migrations: %w", err)
+	}
+
+	return database, nil
+}
+
+// Open подключается к БД без миграций — для утилит, которые только читают.
+func Open(cfg *config.Config, log zerolog.Logger) (*gorm.DB, error) {
 	dbCfg := cfg.DB
-	gormLog := gormlogger.New(
-		zerologWriter{logger: log},
-		gormlogger.Config{
-			SlowThreshold:             time.Second,
-			Colorful:                  false,
-			IgnoreRecordNotFoundError: true,
-			LogLevel:                  selectLogLevel(cfg.Environment),
-		},
-	)
+	gormLog := newQueryLogger(log, selectLogLevel(cfg.Environment), dbCfg.SlowQueryThreshold)
 
 	database, err := gorm.Open(postgres.Open(dbCfg.DSN), &gorm.Config{
 		Logger: gormLog,
@@ -31,6 +37,9 @@ func New(cfg *config.Config, log zerolog.Logger) (*gorm.DB, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := registerTracing(database); err != nil {
+		return nil, err
+	}
 
 	sqlDB, err := database.DB()
 	if err != nil {
@@ -47,10 +56,6 @@ func New(cfg *config.Config, log zerolog.Logger) (*gorm.DB, error) {
 		sqlDB.SetConnMaxLifetime(dbCfg.ConnMaxLifetime)
 	}
 
-	if err := runMigrations(database); err != nil {
-		return nil, fmt.Errorf("run migrations: %w", err)
-	}
-
 	return database, nil
 }
 
@@ -64,12 +69,3 @@ func selectLogLevel(env string) gormlogger.LogLevel {
 	}
 	return gormlogger.Warn
 }
-
-type zerologWriter struct {
-	logger zerolog.Logger
-}
-
-func (w zerologWriter) Printf(msg string, args ...interface{}) {
-	w.logger.Info().Msgf(msg, args...)
-}
-
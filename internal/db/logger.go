@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/nurpe/snowops-contract/internal/logger"
+)
+
+// queryLogger реализует gormlogger.Interface поверх zerolog: медленные запросы и
+// ошибки пишутся структурированно (sql, duration, rows, request_id).
+type queryLogger struct {
+	log           zerolog.Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+}
+
+func newQueryLogger(log zerolog.Logger, level gormlogger.LogLevel, slowThreshold time.Duration) *queryLogger {
+	return &queryLogger{
+		log:           log,
+		level:         level,
+		slowThreshold: slowThreshold,
+	}
+}
+
+func (l *queryLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	clone := *l
+	clone.level = level
+	return &clone
+}
+
+func (l *queryLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.event(ctx, l.log.Info()).Msgf(msg, args...)
+	}
+}
+
+func (l *queryLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.event(ctx, l.log.Warn()).Msgf(msg, args...)
+	}
+}
+
+func (l *queryLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.event(ctx, l.log.Error()).Msgf(msg, args...)
+	}
+}
+
+func (l *queryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.event(ctx, l.log.Error()).
+			Err(err).
+			Str("sql", sql).
+			Dur("duration", elapsed).
+			Int64("rows", rows).
+			Msg("query failed")
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		sql, rows := fc()
+		l.event(ctx, l.log.Warn()).
+			Str("sql", sql).
+			Dur("duration", elapsed).
+			Dur("threshold", l.slowThreshold).
+			Int64("rows", rows).
+			Msg("slow query")
+	case l.level >= gormlogger.Info:
+		sql, rows := fc()
+		l.event(ctx, l.log.Debug()).
+			Str("sql", sql).
+			Dur("duration", elapsed).
+			Int64("rows", rows).
+			Msg("query")
+	}
+}
+
+func (l *queryLogger) event(ctx context.Context, event *zerolog.Event) *zerolog.Event {
+	if requestID := logger.RequestIDFromContext(ctx); requestID != "" {
+		event = event.Str("request_id", requestID)
+	}
+	return event
+}
@@ -45,6 +45,16 @@ var migrationStatements = []string{
 		END IF;
 	END
 	$$;`,
+	// Материализованные снимки вычисляемого статуса для отчётов (источник истины — расчёт при чтении)
+	`ALTER TABLE contracts ADD COLUMN IF NOT EXISTS ui_status VARCHAR(20);`,
+	`ALTER TABLE contracts ADD COLUMN IF NOT EXISTS result VARCHAR(10);`,
+	`ALTER TABLE contracts ADD COLUMN IF NOT EXISTS status_computed_at TIMESTAMPTZ;`,
+	// Естественный ключ клиента для идемпотентных импортов
+	`ALTER TABLE contracts ADD COLUMN IF NOT EXISTS client_reference VARCHAR(255);`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS ux_contracts_client_reference ON contracts (client_reference);`,
+	`ALTER TABLE contracts ADD COLUMN IF NOT EXISTS is_locked BOOLEAN NOT NULL DEFAULT FALSE;`,
+	`ALTER TABLE contracts ADD COLUMN IF NOT EXISTS locked_by UUID;`,
+	`ALTER TABLE contracts ADD COLUMN IF NOT EXISTS locked_at TIMESTAMPTZ;`,
 	`CREATE INDEX IF NOT EXISTS idx_contracts_contractor_id ON contracts (contractor_id) WHERE contractor_id IS NOT NULL;`,
 	`CREATE INDEX IF NOT EXISTS idx_contracts_landfill_id ON contracts (landfill_id) WHERE landfill_id IS NOT NULL;`,
 	`CREATE INDEX IF NOT EXISTS idx_contracts_created_by_org ON contracts (created_by_org);`,
@@ -53,11 +63,23 @@ var migrationStatements = []string{
 	`CREATE INDEX IF NOT EXISTS idx_contracts_is_active ON contracts (is_active);`,
 	`CREATE INDEX IF NOT EXISTS idx_contracts_start_at ON contracts (start_at);`,
 	`CREATE INDEX IF NOT EXISTS idx_contracts_end_at ON contracts (end_at);`,
+	// Составные индексы под горячие запросы списка (фильтр + ORDER BY c.created_at DESC).
+	// Проверка: EXPLAIN SELECT ... FROM contracts c WHERE c.contractor_id = $1 ORDER BY c.created_at DESC
+	// должен показывать Index Scan using idx_contracts_contractor_created без отдельного Sort.
+	`CREATE INDEX IF NOT EXISTS idx_contracts_created_at ON contracts (created_at DESC);`,
+	`CREATE INDEX IF NOT EXISTS idx_contracts_org_active_created ON contracts (created_by_org, is_active, created_at DESC);`,
+	`CREATE INDEX IF NOT EXISTS idx_contracts_contractor_created ON contracts (contractor_id, created_at DESC) WHERE contractor_id IS NOT NULL;`,
+	`CREATE INDEX IF NOT EXISTS idx_contracts_landfill_created ON contracts (landfill_id, created_at DESC) WHERE landfill_id IS NOT NULL;`,
+	// Горячий запрос «мои активные контракты» подрядчика (ListActiveForContractor)
+	`CREATE INDEX IF NOT EXISTS idx_contracts_contractor_active_created ON contracts (contractor_id, created_at DESC) WHERE is_active = TRUE;`,
 	`CREATE TABLE IF NOT EXISTS contract_polygons (
 		contract_id UUID NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
 		polygon_id UUID NOT NULL,
 		PRIMARY KEY (contract_id, polygon_id)
 	);`,
+	`ALTER TABLE contract_polygons ADD COLUMN IF NOT EXISTS budget NUMERIC(14,2) CHECK (budget IS NULL OR budget > 0);`,
+	`ALTER TABLE contract_polygons ADD COLUMN IF NOT EXISTS total_volume_m3 NUMERIC(14,2) NOT NULL DEFAULT 0;`,
+	`ALTER TABLE contract_polygons ADD COLUMN IF NOT EXISTS total_cost NUMERIC(14,2) NOT NULL DEFAULT 0;`,
 	`CREATE INDEX IF NOT EXISTS idx_contract_polygons_contract_id ON contract_polygons (contract_id);`,
 	`CREATE INDEX IF NOT EXISTS idx_contract_polygons_polygon_id ON contract_polygons (polygon_id);`,
 	`CREATE TABLE IF NOT EXISTS contract_usage (
@@ -77,6 +99,33 @@ var migrationStatements = []string{
 		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 	);`,
 	`CREATE INDEX IF NOT EXISTS idx_trip_usage_log_contract_id ON trip_usage_log (contract_id);`,
+	`ALTER TABLE trip_usage_log ADD COLUMN IF NOT EXISTS reported_volume NUMERIC(14,3);`,
+	`ALTER TABLE trip_usage_log ADD COLUMN IF NOT EXISTS reported_unit VARCHAR(10);`,
+	// Ручные корректировки usage, не привязанные к рейсу (урегулирование споров)
+	`CREATE TABLE IF NOT EXISTS contract_usage_adjustments (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		contract_id UUID NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+		volume_delta_m3 NUMERIC(14,2) NOT NULL DEFAULT 0,
+		cost_delta NUMERIC(14,2) NOT NULL DEFAULT 0,
+		reason TEXT NOT NULL,
+		actor_user_id UUID NOT NULL,
+		actor_org_id UUID NOT NULL,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		CHECK (volume_delta_m3 <> 0 OR cost_delta <> 0)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_contract_usage_adjustments_contract_id ON contract_usage_adjustments (contract_id);`,
+	// Журнал событий контракта (ручные и автоматические действия)
+	`CREATE TABLE IF NOT EXISTS contract_audit_log (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		contract_id UUID NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+		action VARCHAR(50) NOT NULL,
+		actor_user_id UUID,
+		actor_org_id UUID,
+		details JSONB NOT NULL DEFAULT '{}'::jsonb,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_contract_audit_log_contract_created ON contract_audit_log (contract_id, created_at DESC);`,
+	`CREATE INDEX IF NOT EXISTS idx_contracts_active_end_at ON contracts (end_at) WHERE is_active = TRUE;`,
 	`CREATE OR REPLACE FUNCTION set_updated_at()
 	RETURNS TRIGGER AS $$
 	BEGIN
@@ -94,9 +143,53 @@ var migrationStatements = []string{
 		END IF;
 	END
 	$$;`,
+	// Пересчёт usage на дату (as_of) суммирует журнал по контракту до created_at
+	`CREATE INDEX IF NOT EXISTS idx_trip_usage_log_contract_created ON trip_usage_log (contract_id, created_at);`,
+	`CREATE INDEX IF NOT EXISTS idx_contract_usage_adjustments_contract_created ON contract_usage_adjustments (contract_id, created_at);`,
+	// Ключи Idempotency-Key для POST /contracts; живут IDEMPOTENCY_KEY_TTL
+	`CREATE TABLE IF NOT EXISTS contract_idempotency_keys (
+		org_id UUID NOT NULL,
+		idempotency_key VARCHAR(255) NOT NULL,
+		contract_id UUID NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+		created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		PRIMARY KEY (org_id, idempotency_key)
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_contract_idempotency_keys_created_at ON contract_idempotency_keys (created_at);`,
+	// Автор контракта для аудита; у созданных раньше — NULL
+	`ALTER TABLE contracts ADD COLUMN IF NOT EXISTS created_by_user UUID;`,
+	`CREATE INDEX IF NOT EXISTS idx_contracts_created_by_user ON contracts (created_by_user, created_at DESC) WHERE created_by_user IS NOT NULL;`,
+	// Момент последнего изменения условий (PUT /contracts/:id); NULL — не менялся
+	`ALTER TABLE contracts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;`,
+	// Keyset-пагинация GET /contracts в порядке по умолчанию
+	`CREATE INDEX IF NOT EXISTS idx_contracts_created_at_id ON contracts (created_at DESC, id DESC);`,
+	// Мягкое удаление (DELETE /contracts/:id без purge); NULL — контракт не удалён
+	`ALTER TABLE contracts ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;`,
+	// История изменений условий контракта: строка на каждое изменённое поле
+	`CREATE TABLE IF NOT EXISTS contract_amendments (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		contract_id UUID NOT NULL REFERENCES contracts(id) ON DELETE CASCADE,
+		field VARCHAR(50) NOT NULL,
+		old_value TEXT NOT NULL,
+		new_value TEXT NOT NULL,
+		changed_by UUID NOT NULL,
+		changed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		reason TEXT NOT NULL
+	);`,
+	`CREATE INDEX IF NOT EXISTS idx_contract_amendments_contract_changed ON contract_amendments (contract_id, changed_at);`,
+	// Отпечаток тела запроса для Idempotency-Key; NULL у ключей, сохранённых раньше
+	`ALTER TABLE contract_idempotency_keys ADD COLUMN IF NOT EXISTS request_hash VARCHAR(64);`,
+	// Режим обслуживания (только чтение), общий для всех реплик; одна строка
+	`CREATE TABLE IF NOT EXISTS maintenance_mode (
+		id BOOLEAN PRIMARY KEY DEFAULT TRUE CHECK (id),
+		read_only BOOLEAN NOT NULL DEFAULT FALSE,
+		updated_by UUID,
+		updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+	);`,
+	`INSERT INTO maintenance_mode (id) VALUES (TRUE) ON CONFLICT (id) DO NOTHING;`,
 }
 
-func runMigrations(db *gorm.DB) error {
+// Migrate применяет миграции схемы; повторный запуск безопасен.
+func Migrate(db *gorm.DB) error {
 	for i, stmt := range migrationStatements {
 		if err := db.Exec(stmt).Error; err != nil {
 			return fmt.Errorf("migration %d failed: %w", i+1, err)
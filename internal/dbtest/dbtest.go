@@ -0,0 +1,168 @@
+// Package dbtest даёт тестам отдельную базу Postgres со схемой сервиса.
+// Сервер задаётся TEST_DATABASE_URL; без неё тесты с БД пропускаются.
+package dbtest
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"github.com/nurpe/snowops-contract/internal/db"
+)
+
+// EnvDatabaseURL — DSN сервера Postgres для тестов. Пользователю нужно право
+// CREATEDB: каждый тест получает свою базу и удаляет её после себя.
+const EnvDatabaseURL = "TEST_DATABASE_URL"
+
+// externalTables — таблицы соседних сервисов, которые читает репозиторий,
+// в объёме, нужном запросам контрактов.
+var externalTables = []string{
+	`CREATE TABLE organizations (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		name VARCHAR(255) NOT NULL DEFAULT ''
+	);`,
+	`CREATE TABLE cleaning_areas (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		name VARCHAR(255) NOT NULL DEFAULT ''
+	);`,
+	`CREATE TABLE tickets (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		contract_id UUID,
+		cleaning_area_id UUID,
+		planned_start_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		planned_end_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		status VARCHAR(30) NOT NULL DEFAULT 'PLANNED'
+	);`,
+	`CREATE TABLE ticket_assignments (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		ticket_id UUID NOT NULL REFERENCES tickets(id) ON DELETE CASCADE,
+		is_active BOOLEAN NOT NULL DEFAULT TRUE
+	);`,
+	`CREATE TABLE trips (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		ticket_id UUID REFERENCES tickets(id) ON DELETE SET NULL,
+		ticket_assignment_id UUID,
+		driver_id UUID,
+		vehicle_id UUID,
+		camera_id UUID,
+		polygon_id UUID,
+		vehicle_plate_number VARCHAR(20),
+		detected_plate_number VARCHAR(20),
+		entry_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+		exit_at TIMESTAMPTZ,
+		status VARCHAR(30) NOT NULL DEFAULT 'COMPLETED',
+		detected_volume_entry NUMERIC(10,2),
+		detected_volume_exit NUMERIC(10,2)
+	);`,
+	`CREATE TABLE appeals (
+		id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+		ticket_id UUID REFERENCES tickets(id) ON DELETE CASCADE
+	);`,
+}
+
+// Open создаёт пустую базу, таблицы соседних сервисов и применяет миграции.
+// База удаляется по завершении теста.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(EnvDatabaseURL)
+	if dsn == "" {
+		t.Skip(EnvDatabaseURL + " is not set")
+	}
+
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("connect to test server: %v", err)
+	}
+	adminDB, err := admin.DB()
+	if err != nil {
+		t.Fatalf("connect to test server: %v", err)
+	}
+	name := "contract_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+	if err := admin.Exec(`CREATE DATABASE ` + name).Error; err != nil {
+		adminDB.Close()
+		t.Fatalf("create test database: %v", err)
+	}
+
+	database, err := gorm.Open(postgres.Open(withDatabase(dsn, name)), &gorm.Config{Logger: gormlogger.Discard})
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(func() {
+		sqlDB.Close()
+		if err := admin.Exec(`DROP DATABASE IF EXISTS ` + name + ` WITH (FORCE)`).Error; err != nil {
+			t.Logf("drop test database %s: %v", name, err)
+		}
+		adminDB.Close()
+	})
+
+	for _, stmt := range externalTables {
+		if err := database.Exec(stmt).Error; err != nil {
+			t.Fatalf("create external table: %v", err)
+		}
+	}
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return database
+}
+
+// withDatabase подставляет имя базы в DSN в форме URL или key=value.
+func withDatabase(dsn, name string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		if u, err := url.Parse(dsn); err == nil {
+			u.Path = "/" + name
+			return u.String()
+		}
+	}
+	return dsn + " dbname=" + name
+}
+
+// Exec выполняет подготовку данных и валит тест при ошибке.
+func Exec(t testing.TB, database *gorm.DB, sql string, args ...interface{}) {
+	t.Helper()
+	if err := database.Exec(sql, args...).Error; err != nil {
+		t.Fatalf("exec %q: %v", sql, err)
+	}
+}
+
+// Organization создаёт организацию и возвращает её id.
+func Organization(t testing.TB, database *gorm.DB, name string) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	Exec(t, database, `INSERT INTO organizations (id, name) VALUES (?, ?)`, id, name)
+	return id
+}
+
+// Ticket создаёт тикет, привязанный к контракту (uuid.Nil — без контракта).
+func Ticket(t testing.TB, database *gorm.DB, contractID uuid.UUID) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	var contract *uuid.UUID
+	if contractID != uuid.Nil {
+		contract = &contractID
+	}
+	Exec(t, database, `INSERT INTO tickets (id, contract_id) VALUES (?, ?)`, id, contract)
+	return id
+}
+
+// Trip создаёт рейс тикета с выгрузкой на полигон (uuid.Nil — без полигона).
+func Trip(t testing.TB, database *gorm.DB, ticketID, polygonID uuid.UUID) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	var polygon *uuid.UUID
+	if polygonID != uuid.Nil {
+		polygon = &polygonID
+	}
+	Exec(t, database, `INSERT INTO trips (id, ticket_id, polygon_id) VALUES (?, ?, ?)`, id, ticketID, polygon)
+	return id
+}
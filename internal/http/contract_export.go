@@ -0,0 +1,161 @@
+package http
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nurpe/snowops-contract/internal/http/middleware"
+	"github.com/nurpe/snowops-contract/internal/http/response"
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/service"
+)
+
+// contractExportColumns — колонки выгрузки GET /contracts/export.
+var contractExportColumns = []string{
+	"id",
+	"name",
+	"contract_type",
+	"work_type",
+	"price_per_m3",
+	"budget_total",
+	"total_volume_m3",
+	"total_cost",
+	"payable_amount",
+	"ui_status",
+	"result",
+	"start_at",
+	"end_at",
+}
+
+// csvFlushEvery — через сколько строк CSV буфер сбрасывается клиенту.
+const csvFlushEvery = 100
+
+// exportContracts выгружает контракты файлом для бухгалтерии. Фильтры и
+// права — те же, что у GET /contracts (контрагент выгружает только свои
+// контракты); строки читаются потоком и не собираются в памяти.
+func (h *Handler) exportContracts(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	input, err := h.parseListContractsInput(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	locale, err := parseExportLocale(c.Query("locale"))
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid locale")
+		return
+	}
+
+	switch format := strings.ToLower(strings.TrimSpace(c.DefaultQuery("format", "csv"))); format {
+	case "csv":
+		h.exportContractsCSV(c, principal, input, locale)
+	case "xlsx":
+		h.exportContractsXLSX(c, principal, input)
+	default:
+		response.Error(c, http.StatusBadRequest, "invalid format")
+	}
+}
+
+func (h *Handler) exportContractsCSV(c *gin.Context, principal model.Principal, input service.ListContractsInput, locale exportLocale) {
+	writer := csv.NewWriter(c.Writer)
+	writer.Comma = locale.CSVDelimiter
+	started := false
+	rows := 0
+
+	// Заголовки ответа — перед первой строкой: до неё ошибку ещё можно отдать JSON
+	start := func() error {
+		started = true
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Header("Content-Disposition", exportAttachment("csv"))
+		c.Status(http.StatusOK)
+		if locale.Name != exportLocaleISO.Name {
+			// BOM, чтобы Excel с русской локалью открыл кириллицу в UTF-8
+			if _, err := c.Writer.WriteString("\ufeff"); err != nil {
+				return err
+			}
+		}
+		return writer.Write(contractExportColumns)
+	}
+
+	err := h.contracts.StreamList(c.Request.Context(), principal, input, func(contract model.Contract) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := writer.Write(contractExportRecord(contract, locale)); err != nil {
+			return err
+		}
+		rows++
+		if rows%csvFlushEvery == 0 {
+			writer.Flush()
+			c.Writer.Flush()
+		}
+		return writer.Error()
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		writer.Flush()
+		err = writer.Error()
+	}
+
+	switch {
+	case err != nil && !started:
+		h.handleError(c, err)
+	case err != nil:
+		h.log.Error().Err(err).Int("rows", rows).Msg("contract export aborted")
+	}
+}
+
+// contractExportRecord — строка CSV по contractExportColumns; суммы — из
+// декорированного контракта, как в GET /contracts. Текстовые ячейки
+// экранируются escapeFormula, числа и даты — нет.
+func contractExportRecord(contract model.Contract, locale exportLocale) []string {
+	volume, cost := contractExportTotals(contract)
+	return []string{
+		contract.ID.String(),
+		escapeFormula(contract.Name),
+		escapeFormula(string(contract.ContractType)),
+		escapeFormula(string(contract.WorkType)),
+		locale.formatNumber(contract.PricePerM3),
+		locale.formatNumber(contract.BudgetTotal),
+		locale.formatOptionalNumber(volume),
+		locale.formatOptionalNumber(cost),
+		locale.formatOptionalNumber(contract.PayableAmount),
+		escapeFormula(string(contract.UIStatus)),
+		escapeFormula(string(contract.Result)),
+		locale.formatTime(contract.StartAt),
+		locale.formatTime(contract.EndAt),
+	}
+}
+
+// contractExportTotals — объём и стоимость из usage контракта: без строки
+// usage — нули, непрочитанный usage — nil (пустая ячейка).
+func contractExportTotals(contract model.Contract) (volume, cost *float64) {
+	if !contract.UsageLoaded() {
+		return nil, nil
+	}
+	var totalVolume, totalCost float64
+	if contract.Usage != nil {
+		totalVolume, totalCost = contract.Usage.TotalVolumeM3, contract.Usage.TotalCost
+	}
+	return &totalVolume, &totalCost
+}
+
+// exportAttachment — Content-Disposition с именем файла вида
+// contracts-20240301-150405.csv (время выгрузки, UTC).
+func exportAttachment(extension string) string {
+	return fmt.Sprintf(`attachment; filename="contracts-%s.%s"`, time.Now().UTC().Format("20060102-150405"), extension)
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+func TestEscapeFormula(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{value: "", want: ""},
+		{value: "Уборка дорог", want: "Уборка дорог"},
+		{value: "=HYPERLINK(\"http://evil\")", want: "'=HYPERLINK(\"http://evil\")"},
+		{value: "+7 701 000 00 00", want: "'+7 701 000 00 00"},
+		{value: "-1+1", want: "'-1+1"},
+		{value: "@SUM(A1:A2)", want: "'@SUM(A1:A2)"},
+		{value: "\t=1", want: "'\t=1"},
+		{value: "\r=1", want: "'\r=1"},
+		{value: "Договор =1", want: "Договор =1"},
+	}
+	for _, tt := range tests {
+		if got := escapeFormula(tt.value); got != tt.want {
+			t.Errorf("escapeFormula(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestContractExportRecordEscapesTextOnly(t *testing.T) {
+	contract := model.Contract{
+		ID:           uuid.New(),
+		Name:         "=1+2",
+		ContractType: model.ContractTypeContractorService,
+		WorkType:     model.WorkTypeRoad,
+		PricePerM3:   -1.5,
+		BudgetTotal:  1000,
+		StartAt:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndAt:        time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+	}
+	record := contractExportRecord(contract, exportLocaleISO)
+	if record[1] != "'=1+2" {
+		t.Fatalf("name cell = %q, want escaped", record[1])
+	}
+	// отрицательные числа остаются числами
+	if record[4] != "-1.50" {
+		t.Fatalf("price_per_m3 cell = %q, want -1.50", record[4])
+	}
+}
@@ -0,0 +1,180 @@
+package http
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/service"
+)
+
+const (
+	xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	xlsxSheet       = "Contracts"
+
+	// форматы ячеек: суммы в тенге, объём с двумя знаками, дата и время
+	xlsxCurrencyFormat = `#,##0.00 "₸"`
+	xlsxVolumeFormat   = `#,##0.00`
+	xlsxDateTimeFormat = `yyyy-mm-dd hh:mm`
+)
+
+// xlsxStyles — id стилей ячеек книги выгрузки.
+type xlsxStyles struct {
+	header, currency, volume, dateTime int
+}
+
+// exportContractsXLSX выгружает контракты в книгу Excel: лист Contracts с
+// закреплённой жирной шапкой, автофильтром и числовыми (а не текстовыми)
+// колонками. Строки пишутся потоковым writer'ом excelize, который при большом
+// объёме сбрасывает лист во временный файл, поэтому память ограничена. Ответ
+// начинается только после записи всех строк — до этого любая ошибка
+// отдаётся обычным JSON.
+func (h *Handler) exportContractsXLSX(c *gin.Context, principal model.Principal, input service.ListContractsInput) {
+	book := excelize.NewFile()
+	defer func() {
+		if err := book.Close(); err != nil {
+			h.log.Warn().Err(err).Msg("failed to clean up contract export workbook")
+		}
+	}()
+
+	writer, styles, err := newContractExportSheet(book)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	row := 1
+	err = h.contracts.StreamList(c.Request.Context(), principal, input, func(contract model.Contract) error {
+		row++
+		cell, err := excelize.CoordinatesToCellName(1, row)
+		if err != nil {
+			return err
+		}
+		return writer.SetRow(cell, contractExportCells(contract, styles))
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	// таблица на весь диапазон даёт автофильтр по шапке; пустой выгрузке
+	// excelize сам добавляет пустую строку, которой требует таблица
+	lastCell, err := excelize.CoordinatesToCellName(len(contractExportColumns), row)
+	if err == nil {
+		showStripes := false
+		err = writer.AddTable(&excelize.Table{
+			Range:          "A1:" + lastCell,
+			Name:           xlsxSheet,
+			StyleName:      "TableStyleLight1",
+			ShowRowStripes: &showStripes,
+		})
+	}
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", xlsxContentType)
+	c.Header("Content-Disposition", exportAttachment("xlsx"))
+	c.Status(http.StatusOK)
+	if _, err := book.WriteTo(c.Writer); err != nil {
+		h.log.Error().Err(err).Int("rows", row-1).Msg("contract export aborted")
+	}
+}
+
+// newContractExportSheet готовит лист: стили, ширину колонок, закреплённую
+// шапку и саму шапку. Всё это потоковый writer принимает только до строк данных.
+func newContractExportSheet(book *excelize.File) (*excelize.StreamWriter, xlsxStyles, error) {
+	var styles xlsxStyles
+	if err := book.SetSheetName(book.GetSheetName(0), xlsxSheet); err != nil {
+		return nil, styles, err
+	}
+
+	var err error
+	if styles.header, err = book.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}}); err != nil {
+		return nil, styles, err
+	}
+	currencyFormat, volumeFormat, dateTimeFormat := xlsxCurrencyFormat, xlsxVolumeFormat, xlsxDateTimeFormat
+	if styles.currency, err = book.NewStyle(&excelize.Style{CustomNumFmt: &currencyFormat}); err != nil {
+		return nil, styles, err
+	}
+	if styles.volume, err = book.NewStyle(&excelize.Style{CustomNumFmt: &volumeFormat}); err != nil {
+		return nil, styles, err
+	}
+	if styles.dateTime, err = book.NewStyle(&excelize.Style{CustomNumFmt: &dateTimeFormat}); err != nil {
+		return nil, styles, err
+	}
+
+	writer, err := book.NewStreamWriter(xlsxSheet)
+	if err != nil {
+		return nil, styles, err
+	}
+	if err := writer.SetColWidth(1, 1, 38); err != nil {
+		return nil, styles, err
+	}
+	if err := writer.SetColWidth(2, 2, 40); err != nil {
+		return nil, styles, err
+	}
+	if err := writer.SetColWidth(3, len(contractExportColumns), 18); err != nil {
+		return nil, styles, err
+	}
+	if err := writer.SetPanes(&excelize.Panes{
+		Freeze:      true,
+		YSplit:      1,
+		TopLeftCell: "A2",
+		ActivePane:  "bottomLeft",
+	}); err != nil {
+		return nil, styles, err
+	}
+
+	header := make([]interface{}, len(contractExportColumns))
+	for i, name := range contractExportColumns {
+		header[i] = excelize.Cell{StyleID: styles.header, Value: name}
+	}
+	if err := writer.SetRow("A1", header); err != nil {
+		return nil, styles, err
+	}
+	return writer, styles, nil
+}
+
+// contractExportCells — строка книги по contractExportColumns: суммы и объём —
+// числа из декорированного usage, даты — даты Excel (UTC). Строки
+// экранируются так же, как в CSV: файл могут пересохранить в CSV.
+func contractExportCells(contract model.Contract, styles xlsxStyles) []interface{} {
+	volume, cost := contractExportTotals(contract)
+	return []interface{}{
+		contract.ID.String(),
+		escapeFormula(contract.Name),
+		escapeFormula(string(contract.ContractType)),
+		escapeFormula(string(contract.WorkType)),
+		excelize.Cell{StyleID: styles.currency, Value: contract.PricePerM3},
+		excelize.Cell{StyleID: styles.currency, Value: contract.BudgetTotal},
+		excelize.Cell{StyleID: styles.volume, Value: xlsxNumber(volume)},
+		excelize.Cell{StyleID: styles.currency, Value: xlsxNumber(cost)},
+		excelize.Cell{StyleID: styles.currency, Value: xlsxNumber(contract.PayableAmount)},
+		escapeFormula(string(contract.UIStatus)),
+		escapeFormula(string(contract.Result)),
+		excelize.Cell{StyleID: styles.dateTime, Value: xlsxTime(contract.StartAt)},
+		excelize.Cell{StyleID: styles.dateTime, Value: xlsxTime(contract.EndAt)},
+	}
+}
+
+// xlsxNumber — значение числовой ячейки; nil — пустая ячейка.
+func xlsxNumber(value *float64) interface{} {
+	if value == nil {
+		return nil
+	}
+	return *value
+}
+
+// xlsxTime — время без зоны: Excel не хранит часовой пояс, выгрузка — в UTC.
+func xlsxTime(value time.Time) time.Time {
+	utc := value.UTC()
+	return time.Date(utc.Year(), utc.Month(), utc.Day(), utc.Hour(), utc.Minute(), utc.Second(), utc.Nanosecond(), time.UTC)
+}
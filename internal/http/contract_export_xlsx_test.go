@@ -0,0 +1,68 @@
+package http
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/xuri/excelize/v2"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+func TestContractExportCellsEscapeFormulas(t *testing.T) {
+	book := excelize.NewFile()
+	defer book.Close()
+	writer, styles, err := newContractExportSheet(book)
+	if err != nil {
+		t.Fatalf("new sheet: %v", err)
+	}
+	contract := model.Contract{
+		ID:           uuid.New(),
+		Name:         "=HYPERLINK(\"http://evil\",\"open\")",
+		ContractType: model.ContractTypeContractorService,
+		WorkType:     model.WorkTypeRoad,
+		PricePerM3:   100,
+		BudgetTotal:  1000,
+		StartAt:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndAt:        time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC),
+	}
+	if err := writer.SetRow("A2", contractExportCells(contract, styles)); err != nil {
+		t.Fatalf("set row: %v", err)
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("flush: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := book.WriteTo(&buf); err != nil {
+		t.Fatalf("write book: %v", err)
+	}
+
+	saved, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatalf("open book: %v", err)
+	}
+	defer saved.Close()
+	name, err := saved.GetCellValue(xlsxSheet, "B2")
+	if err != nil {
+		t.Fatalf("get name: %v", err)
+	}
+	if name != "'"+contract.Name {
+		t.Fatalf("name cell = %q, want escaped", name)
+	}
+	formula, err := saved.GetCellFormula(xlsxSheet, "B2")
+	if err != nil {
+		t.Fatalf("get formula: %v", err)
+	}
+	if formula != "" {
+		t.Fatalf("name cell has formula %q", formula)
+	}
+	cellType, err := saved.GetCellType(xlsxSheet, "E2")
+	if err != nil {
+		t.Fatalf("get price type: %v", err)
+	}
+	if cellType != excelize.CellTypeNumber && cellType != excelize.CellTypeUnset {
+		t.Fatalf("price cell type = %v, want number", cellType)
+	}
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nurpe/snowops-contract/internal/http/middleware"
+	"github.com/nurpe/snowops-contract/internal/http/response"
+	"github.com/nurpe/snowops-contract/internal/service"
+)
+
+// exportContractorData отдаёт zip-архив с данными подрядчика. Архив пишется
+// прямо в ответ по мере чтения; ошибки после начала записи можно только
+// залогировать — клиент получит оборванный архив.
+func (h *Handler) exportContractorData(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractorID, err := parseUUIDParam(c, "id", "contractor_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := service.EnsureContractorExportAccess(principal, contractorID); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="contractor-%s.zip"`, contractorID))
+	c.Status(http.StatusOK)
+
+	archive := zip.NewWriter(c.Writer)
+	err = h.contracts.ExportContractorData(c.Request.Context(), principal, contractorID, func(entry service.ExportEntry) error {
+		w, err := archive.Create(entry.Name)
+		if err != nil {
+			return err
+		}
+		if err := json.NewEncoder(w).Encode(entry.Data); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		h.log.Error().Err(err).Str("contractor_id", contractorID.String()).Msg("contractor data export aborted")
+		return
+	}
+	if err := archive.Close(); err != nil {
+		h.log.Error().Err(err).Str("contractor_id", contractorID.String()).Msg("contractor data export aborted")
+	}
+}
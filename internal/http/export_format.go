@@ -0,0 +1,81 @@
+package http
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportLocale задаёт форматирование чисел и дат в выгрузках (CSV).
+// По умолчанию — нейтральный формат: точка и ISO-даты. Для ru/kk — запятая,
+// даты дд.мм.гггг и разделитель колонок ";", как ожидает Excel с русской локалью.
+type exportLocale struct {
+	Name             string
+	DecimalSeparator string
+	DateTimeLayout   string
+	CSVDelimiter     rune
+}
+
+var (
+	exportLocaleISO = exportLocale{
+		Name:             "iso",
+		DecimalSeparator: ".",
+		DateTimeLayout:   time.RFC3339,
+		CSVDelimiter:     ',',
+	}
+	exportLocaleRU = exportLocale{
+		Name:             "ru",
+		DecimalSeparator: ",",
+		DateTimeLayout:   "02.01.2006 15:04:05",
+		CSVDelimiter:     ';',
+	}
+)
+
+func parseExportLocale(raw string) (exportLocale, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "iso", "en":
+		return exportLocaleISO, nil
+	case "ru", "kk":
+		return exportLocaleRU, nil
+	default:
+		return exportLocale{}, fmt.Errorf("unsupported locale %q", raw)
+	}
+}
+
+func (l exportLocale) formatNumber(value float64) string {
+	formatted := strconv.FormatFloat(value, 'f', 2, 64)
+	if l.DecimalSeparator != "." {
+		formatted = strings.Replace(formatted, ".", l.DecimalSeparator, 1)
+	}
+	return formatted
+}
+
+// formatOptionalNumber — formatNumber; nil — пустая строка.
+func (l exportLocale) formatOptionalNumber(value *float64) string {
+	if value == nil {
+		return ""
+	}
+	return l.formatNumber(*value)
+}
+
+func (l exportLocale) formatTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+	return value.Format(l.DateTimeLayout)
+}
+
+// escapeFormula защищает текстовую ячейку выгрузки от CSV/формульной инъекции:
+// значение, которое табличный редактор принял бы за формулу (начинается с
+// =, +, -, @, табуляции или перевода каретки), получает префикс "'".
+func escapeFormula(value string) string {
+	if value == "" {
+		return value
+	}
+	switch value[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,84 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// contractFields содержит допустимые имена полей верхнего уровня для ?fields=
+var contractFields = jsonFieldNames(reflect.TypeOf(model.Contract{}))
+
+func jsonFieldNames(t reflect.Type) map[string]struct{} {
+	names := make(map[string]struct{}, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		tag := t.Field(i).Tag.Get("json")
+		name := strings.Split(tag, ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		names[name] = struct{}{}
+	}
+	return names
+}
+
+// parseFieldsQuery разбирает список полей через запятую и проверяет их по модели.
+// Пустой параметр означает полный объект (nil).
+func parseFieldsQuery(raw string, allowed map[string]struct{}) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	var fields []string
+	seen := make(map[string]struct{})
+	for _, part := range strings.Split(raw, ",") {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		if _, ok := allowed[name]; !ok {
+			return nil, fmt.Errorf("unknown field %q", name)
+		}
+		if _, dup := seen[name]; dup {
+			continue
+		}
+		seen[name] = struct{}{}
+		fields = append(fields, name)
+	}
+	return fields, nil
+}
+
+// selectFields оставляет в каждом элементе только запрошенные поля верхнего уровня.
+func selectFields[T any](items []T, fields []string) ([]map[string]json.RawMessage, error) {
+	result := make([]map[string]json.RawMessage, 0, len(items))
+	for i := range items {
+		trimmed, err := selectItemFields(items[i], fields)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, trimmed)
+	}
+	return result, nil
+}
+
+func selectItemFields(item interface{}, fields []string) (map[string]json.RawMessage, error) {
+	encoded, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var full map[string]json.RawMessage
+	if err := json.Unmarshal(encoded, &full); err != nil {
+		return nil, err
+	}
+	trimmed := make(map[string]json.RawMessage, len(fields))
+	for _, name := range fields {
+		if value, ok := full[name]; ok {
+			trimmed[name] = value
+		}
+	}
+	return trimmed, nil
+}
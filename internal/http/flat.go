@@ -0,0 +1,145 @@
+package http
+
+import (
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// flatContract — контракт без вложенных объектов для BI-инструментов (?flat=true).
+// Связанные объекты и usage разворачиваются в скалярные поля с префиксом.
+type flatContract struct {
+	ID                    uuid.UUID              `json:"id"`
+	ContractType          model.ContractType     `json:"contract_type"`
+	Name                  string                 `json:"name"`
+	WorkType              model.WorkType         `json:"work_type"`
+	ContractorID          *uuid.UUID             `json:"contractor_id"`
+	ContractorName        *string                `json:"contractor_name"`
+	LandfillID            *uuid.UUID             `json:"landfill_id"`
+	LandfillName          *string                `json:"landfill_name"`
+	CreatedByOrgID        uuid.UUID              `json:"created_by_org_id"`
+	CreatedByOrgName      *string                `json:"created_by_org_name"`
+	CreatedByUserID       *uuid.UUID             `json:"created_by_user_id"`
+	PolygonIDs            string                 `json:"polygon_ids"` // через запятую
+	PricePerM3            float64                `json:"price_per_m3"`
+	BudgetTotal           float64                `json:"budget_total"`
+	MinimalVolumeM3       float64                `json:"minimal_volume_m3"`
+	StartAt               time.Time              `json:"start_at"`
+	EndAt                 time.Time              `json:"end_at"`
+	IsActive              bool                   `json:"is_active"`
+	ClientReference       *string                `json:"client_reference"`
+	Locked                bool                   `json:"locked"`
+	CreatedAt             time.Time              `json:"created_at"`
+	UpdatedAt             *time.Time             `json:"updated_at"`
+	DeletedAt             *time.Time             `json:"deleted_at"`
+	UsageTotalVolumeM3    *float64               `json:"usage_total_volume_m3"`
+	UsageTotalCost        *float64               `json:"usage_total_cost"`
+	UsageUpdatedAt        *time.Time             `json:"usage_updated_at"`
+	UsageMissing          bool                   `json:"usage_missing"`
+	UsageLoadError        bool                   `json:"usage_load_error"`
+	UIStatus              model.ContractUIStatus `json:"ui_status"`
+	Result                *model.ContractResult  `json:"result"`
+	ResultTolerance       float64                `json:"result_tolerance"`
+	PayableAmount         *float64               `json:"payable_amount"`
+	BudgetExceeded        *bool                  `json:"budget_exceeded"`
+	VolumeProgress        *float64               `json:"volume_progress"`
+	HealthScore           *int                   `json:"health_score"`
+	BudgetExhaustedAt     *time.Time             `json:"budget_exhausted_at"`
+	EffectiveEndAt        *time.Time             `json:"effective_end_at"`
+	HealthTimeElapsed     *float64               `json:"health_time_elapsed"`
+	HealthMinimumProgress *float64               `json:"health_minimum_progress"`
+	HealthBudget          *float64               `json:"health_budget"`
+	HealthPacing          *float64               `json:"health_pacing"`
+}
+
+// flatContractFields — допустимые имена для ?fields= вместе с ?flat=true
+var flatContractFields = jsonFieldNames(reflect.TypeOf(flatContract{}))
+
+func flattenContract(contract model.Contract) flatContract {
+	flat := flatContract{
+		ID:                contract.ID,
+		ContractType:      contract.ContractType,
+		Name:              contract.Name,
+		WorkType:          contract.WorkType,
+		ContractorID:      contract.ContractorID,
+		LandfillID:        contract.LandfillID,
+		CreatedByOrgID:    contract.CreatedByOrgID,
+		CreatedByUserID:   contract.CreatedByUserID,
+		PricePerM3:        contract.PricePerM3,
+		BudgetTotal:       contract.BudgetTotal,
+		MinimalVolumeM3:   contract.MinimalVolumeM3,
+		StartAt:           contract.StartAt,
+		EndAt:             contract.EndAt,
+		IsActive:          contract.IsActive,
+		ClientReference:   contract.ClientReference,
+		Locked:            contract.IsLocked,
+		CreatedAt:         contract.CreatedAt,
+		UpdatedAt:         contract.UpdatedAt,
+		DeletedAt:         contract.DeletedAt,
+		UsageMissing:      contract.UsageMissing,
+		UsageLoadError:    contract.UsageLoadError,
+		UIStatus:          contract.UIStatus,
+		ResultTolerance:   contract.ResultTolerance,
+		PayableAmount:     contract.PayableAmount,
+		BudgetExceeded:    contract.BudgetExceeded,
+		VolumeProgress:    contract.VolumeProgress,
+		HealthScore:       contract.HealthScore,
+		BudgetExhaustedAt: contract.BudgetExhaustedAt,
+		EffectiveEndAt:    contract.EffectiveEndAt,
+	}
+
+	flat.ContractorName = organizationName(contract.ContractorOrg)
+	flat.LandfillName = organizationName(contract.LandfillOrg)
+	flat.CreatedByOrgName = organizationName(contract.CreatedByOrg)
+
+	polygonIDs := contract.PolygonIDs
+	if len(polygonIDs) == 0 {
+		for _, polygon := range contract.Polygons {
+			polygonIDs = append(polygonIDs, polygon.PolygonID)
+		}
+	}
+	ids := make([]string, 0, len(polygonIDs))
+	for _, id := range polygonIDs {
+		ids = append(ids, id.String())
+	}
+	flat.PolygonIDs = strings.Join(ids, ",")
+
+	if contract.Usage != nil {
+		flat.UsageTotalVolumeM3 = &contract.Usage.TotalVolumeM3
+		flat.UsageTotalCost = &contract.Usage.TotalCost
+		flat.UsageUpdatedAt = &contract.Usage.UpdatedAt
+	}
+
+	if contract.Result != "" {
+		result := contract.Result
+		flat.Result = &result
+	}
+
+	if contract.Health != nil {
+		flat.HealthTimeElapsed = &contract.Health.TimeElapsed
+		flat.HealthMinimumProgress = &contract.Health.MinimumProgress
+		flat.HealthBudget = &contract.Health.Budget
+		flat.HealthPacing = &contract.Health.Pacing
+	}
+
+	return flat
+}
+
+func flattenContracts(contracts []model.Contract) []flatContract {
+	items := make([]flatContract, 0, len(contracts))
+	for _, contract := range contracts {
+		items = append(items, flattenContract(contract))
+	}
+	return items
+}
+
+func organizationName(org *model.OrganizationLookup) *string {
+	if org == nil {
+		return nil
+	}
+	return &org.Name
+}
@@ -1,83 +1,173 @@
 package http
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/rs/zerolog"
 
 	"github.com/nurpe/snowops-contract/internal/http/middleware"
+	"github.com/nurpe/snowops-contract/internal/http/response"
 	"github.com/nurpe/snowops-contract/internal/model"
 	"github.com/nurpe/snowops-contract/internal/service"
 )
 
+type Config struct {
+	// StrictQueryParams — отвечать 400 на неизвестные query-параметры.
+	StrictQueryParams bool
+	// AtomicTripUsageBatch — POST /trips/usage/batch пишет всё или ничего
+	AtomicTripUsageBatch bool
+}
+
 type Handler struct {
 	contracts *service.ContractService
+	cfg       Config
 	log       zerolog.Logger
 }
 
 func NewHandler(
 	contracts *service.ContractService,
+	cfg Config,
 	log zerolog.Logger,
 ) *Handler {
 	return &Handler{
 		contracts: contracts,
+		cfg:       cfg,
 		log:       log,
 	}
 }
 
 func (h *Handler) Register(r *gin.Engine, authMiddleware gin.HandlerFunc) {
+	r.GET("/reference-data", h.getReferenceData)
+
 	protected := r.Group("/")
-	protected.Use(authMiddleware)
+	protected.Use(authMiddleware, h.rejectWritesInReadOnly, h.rejectUnknownQueryParams)
 
 	protected.GET("/contracts", h.listContracts)
 	protected.POST("/contracts", h.createContract)
+	protected.POST("/contracts/batch-get", h.batchGetContracts)
+	protected.POST("/contracts/recompute-statuses", h.recomputeContractStatuses)
+	protected.POST("/contracts/bulk-deactivate", h.bulkDeactivateContracts)
+	protected.POST("/contracts/restore", h.restoreContractSnapshot)
+	protected.GET("/contracts/filter-options", h.getContractFilterOptions)
+	protected.GET("/contracts/accessible", h.listAccessibleContracts)
+	protected.GET("/contracts/export", h.exportContracts)
 	protected.GET("/contracts/:id", h.getContract)
 	protected.GET("/contracts/:id/deletion-info", h.getContractDeletionInfo)
+	protected.GET("/contracts/:id/snapshot", h.getContractSnapshot)
+	protected.GET("/contracts/:id/cost-preview", h.previewContractCost)
+	protected.GET("/contracts/:id/budget-change-preview", h.previewBudgetChange)
+	protected.GET("/contracts/:id/payable-breakdown", h.getPayableBreakdown)
+	protected.GET("/contracts/:id/audit", h.listContractAudit)
+	protected.GET("/contracts/:id/amendments", h.listContractAmendments)
+	protected.GET("/contracts/:id/plate-mismatches/summary", h.getPlateMismatchSummary)
+	protected.GET("/contracts/:id/capacity-estimate", h.getCapacityEstimate)
+	protected.GET("/contracts/:id/usage/stream", h.streamContractUsage)
+	protected.POST("/contracts/:id/lock", h.lockContract)
+	protected.POST("/contracts/:id/unlock", h.unlockContract)
+	protected.POST("/contracts/:id/usage-adjustments", h.recordUsageAdjustment)
+	protected.PUT("/contracts/:id", h.updateContract)
 	protected.DELETE("/contracts/:id", h.deleteContract)
+	protected.POST("/contracts/:id/restore", h.restoreContract)
 	protected.GET("/contracts/:id/tickets", h.listContractTickets)
 	protected.GET("/contracts/:id/trips", h.listContractTrips)
+	protected.GET("/contracts/:id/usage-log", h.listContractUsageLog)
+	protected.GET("/cleaning-areas/:id/contracts", h.listCleaningAreaContracts)
+	protected.GET("/contractors/:id/data-export", h.exportContractorData)
+	protected.GET("/contractors/:id/monthly-spend", h.getContractorMonthlySpend)
+	protected.GET("/landfills/:id/polygons", h.listLandfillPolygons)
+	protected.POST("/polygons/check-conflicts", h.checkPolygonConflicts)
 	protected.PUT("/tickets/:ticket_id/contract", h.assignTicketContract)
+	protected.POST("/tickets/:ticket_id/reconcile-contract", h.reconcileTicketContract)
 	protected.POST("/trips/usage", h.recordTripUsage)
+	protected.POST("/trips/usage/batch", h.recordTripUsageBatch)
+	protected.GET("/reports/usage-consistency", h.usageConsistencyReport)
+	protected.GET("/reports/utilization-distribution", h.utilizationDistributionReport)
+	protected.GET("/reports/contractor-performance", h.contractorPerformanceReport)
+	protected.GET(readOnlyRoutePath, h.getReadOnlyMode)
+	protected.PUT(readOnlyRoutePath, h.setReadOnlyMode)
 }
 
 func (h *Handler) listContracts(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		response.Error(c, http.StatusUnauthorized, "missing principal")
 		return
 	}
 
-	var contractorID *uuid.UUID
-	if raw := c.Query("contractor_id"); raw != "" {
-		parsed, err := uuid.Parse(strings.TrimSpace(raw))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, errorResponse("invalid contractor_id"))
-			return
-		}
-		contractorID = &parsed
+	input, err := h.parseListContractsInput(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
-	var landfillID *uuid.UUID
-	if raw := c.Query("landfill_id"); raw != "" {
-		parsed, err := uuid.Parse(strings.TrimSpace(raw))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, errorResponse("invalid landfill_id"))
+	flat := parseBoolQuery(c.Query("flat"))
+	allowedFields := contractFields
+	if flat {
+		allowedFields = flatContractFields
+	}
+	fields, err := parseFieldsQuery(c.Query("fields"), allowedFields)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if acceptsNDJSON(c) {
+		h.streamContracts(c, principal, input, fields, flat)
+		return
+	}
+
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			response.Error(c, http.StatusBadRequest, "invalid limit")
 			return
 		}
-		landfillID = &parsed
+		input.Limit = limit
+	}
+	input.Cursor = strings.TrimSpace(c.Query("cursor"))
+
+	contracts, nextCursor, err := h.contracts.List(c.Request.Context(), principal, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	if flat {
+		writeItemsPage(h, c, flattenContracts(contracts), fields, nextCursor)
+		return
+	}
+	writeItemsPage(h, c, contracts, fields, nextCursor)
+}
+
+// parseListContractsInput разбирает фильтры списка контрактов из query; общая
+// часть GET /contracts и GET /contracts/export.
+func (h *Handler) parseListContractsInput(c *gin.Context) (service.ListContractsInput, error) {
+	contractorID, err := parseUUIDQuery(c, "contractor_id")
+	if err != nil {
+		return service.ListContractsInput{}, err
+	}
+	landfillID, err := parseUUIDQuery(c, "landfill_id")
+	if err != nil {
+		return service.ListContractsInput{}, err
+	}
+	createdByUser, err := parseUUIDQuery(c, "created_by_user")
+	if err != nil {
+		return service.ListContractsInput{}, err
 	}
 
 	var contractType *model.ContractType
 	if raw := c.Query("contract_type"); raw != "" {
 		value := model.ContractType(strings.ToUpper(strings.TrimSpace(raw)))
 		if value != model.ContractTypeContractorService && value != model.ContractTypeLandfillService {
-			c.JSON(http.StatusBadRequest, errorResponse("invalid contract_type"))
-			return
+			return service.ListContractsInput{}, errors.New("invalid contract_type")
 		}
 		contractType = &value
 	}
@@ -85,11 +175,8 @@ func (h *Handler) listContracts(c *gin.Context) {
 	var workType *model.WorkType
 	if raw := c.Query("work_type"); raw != "" {
 		value := model.WorkType(strings.ToLower(strings.TrimSpace(raw)))
-		if value != model.WorkTypeRoad &&
-			value != model.WorkTypeSidewalk &&
-			value != model.WorkTypeYard {
-			c.JSON(http.StatusBadRequest, errorResponse("invalid work_type"))
-			return
+		if !h.contracts.IsAllowedWorkType(value) {
+			return service.ListContractsInput{}, errors.New("invalid work_type")
 		}
 		workType = &value
 	}
@@ -101,8 +188,7 @@ func (h *Handler) listContracts(c *gin.Context) {
 			value != model.ContractUIStatusActive &&
 			value != model.ContractUIStatusExpired &&
 			value != model.ContractUIStatusArchived {
-			c.JSON(http.StatusBadRequest, errorResponse("invalid status"))
-			return
+			return service.ListContractsInput{}, errors.New("invalid status")
 		}
 		status = &value
 	}
@@ -120,110 +206,205 @@ func (h *Handler) listContracts(c *gin.Context) {
 
 	startFrom, err := parseTimeQuery("start_from")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid start_from"))
-		return
+		return service.ListContractsInput{}, errors.New("invalid start_from")
 	}
 	startTo, err := parseTimeQuery("start_to")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid start_to"))
-		return
+		return service.ListContractsInput{}, errors.New("invalid start_to")
 	}
 	endFrom, err := parseTimeQuery("end_from")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid end_from"))
-		return
+		return service.ListContractsInput{}, errors.New("invalid end_from")
 	}
 	endTo, err := parseTimeQuery("end_to")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid end_to"))
-		return
+		return service.ListContractsInput{}, errors.New("invalid end_to")
+	}
+	asOf, err := parseTimeQuery("as_of")
+	if err != nil {
+		return service.ListContractsInput{}, errors.New("invalid as_of")
+	}
+
+	var perspective model.ContractPerspective
+	if raw := c.Query("perspective"); raw != "" {
+		value, ok := model.ParseContractPerspective(raw)
+		if !ok {
+			return service.ListContractsInput{}, errors.New("invalid perspective")
+		}
+		perspective = value
 	}
 
 	onlyActive := parseBoolQuery(c.Query("only_active"))
+	writableOnly := parseBoolQuery(c.Query("writable_only"))
+	includeDeleted := parseBoolQuery(c.Query("include_deleted"))
 
-	contracts, err := h.contracts.List(
-		c.Request.Context(),
-		principal,
-		service.ListContractsInput{
-			ContractorID: contractorID,
-			LandfillID:   landfillID,
-			ContractType: contractType,
-			WorkType:     workType,
-			OnlyActive:   onlyActive,
-			Status:       status,
-			StartFrom:    startFrom,
-			StartTo:      startTo,
-			EndFrom:      endFrom,
-			EndTo:        endTo,
-		},
-	)
+	var budgetExceeded *bool
+	if raw := strings.TrimSpace(c.Query("budget_exceeded")); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return service.ListContractsInput{}, errors.New("invalid budget_exceeded")
+		}
+		budgetExceeded = &value
+	}
+
+	var sortBy model.ContractSortField
+	if raw := c.Query("sort_by"); raw != "" {
+		value, ok := model.ParseContractSortField(raw)
+		if !ok {
+			return service.ListContractsInput{}, errors.New("invalid sort_by")
+		}
+		sortBy = value
+	}
+	var sortDir model.SortDirection
+	if raw := c.Query("sort_dir"); raw != "" {
+		value, ok := model.ParseSortDirection(raw)
+		if !ok {
+			return service.ListContractsInput{}, errors.New("invalid sort_dir")
+		}
+		sortDir = value
+	}
+
+	minUtilization, err := parsePercentQuery(c, "utilization_gte")
 	if err != nil {
-		h.handleError(c, err)
+		return service.ListContractsInput{}, err
+	}
+	maxUtilization, err := parsePercentQuery(c, "utilization_lte")
+	if err != nil {
+		return service.ListContractsInput{}, err
+	}
+
+	includeUsage := true
+	if raw, ok := c.GetQuery("include_usage"); ok {
+		includeUsage = parseBoolQuery(raw)
+	}
+
+	return service.ListContractsInput{
+		ContractorID:   contractorID,
+		LandfillID:     landfillID,
+		ContractType:   contractType,
+		WorkType:       workType,
+		CreatedByUser:  createdByUser,
+		OnlyActive:     onlyActive,
+		IncludeUsage:   includeUsage,
+		Status:         status,
+		StartFrom:      startFrom,
+		StartTo:        startTo,
+		EndFrom:        endFrom,
+		EndTo:          endTo,
+		WritableOnly:   writableOnly,
+		Perspective:    perspective,
+		BudgetExceeded: budgetExceeded,
+		MinUtilization: minUtilization,
+		MaxUtilization: maxUtilization,
+		SortBy:         sortBy,
+		SortDir:        sortDir,
+		AsOf:           asOf,
+		IncludeDeleted: includeDeleted,
+		UsePreset:      !hasAnyQueryParam(c, contractListFilterParams),
+	}, nil
+}
+
+// writeItems отдаёт список, оставляя только запрошенные ?fields=.
+func writeItems[T any](h *Handler, c *gin.Context, items []T, fields []string) {
+	writeItemsPage(h, c, items, fields, "")
+}
+
+// writeItemsPage — writeItems для страницы с курсором следующей.
+func writeItemsPage[T any](h *Handler, c *gin.Context, items []T, fields []string, nextCursor string) {
+	if fields == nil {
+		response.CursorPaginated(c, http.StatusOK, items, nextCursor)
 		return
 	}
 
-	c.JSON(http.StatusOK, successResponse(contracts))
+	trimmed, err := selectFields(items, fields)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.CursorPaginated(c, http.StatusOK, trimmed, nextCursor)
 }
 
+// idempotencyKeyHeader — непрозрачный ключ запроса create; повтор возвращает
+// уже созданный контракт.
+const idempotencyKeyHeader = "Idempotency-Key"
+
 type createContractRequest struct {
-	ContractType    string      `json:"contract_type" binding:"required"`
-	ContractorID    *string     `json:"contractor_id"` // Опционально для LANDFILL_SERVICE
-	LandfillID      *string     `json:"landfill_id"`   // Опционально для CONTRACTOR_SERVICE
-	PolygonIDs      []uuid.UUID `json:"polygon_ids"`   // Обязательно для LANDFILL_SERVICE
-	Name            string      `json:"name" binding:"required"`
-	WorkType        *string     `json:"work_type"` // Опционально для LANDFILL_SERVICE
-	PricePerM3      float64     `json:"price_per_m3" binding:"required,gt=0"`
-	BudgetTotal     float64     `json:"budget_total" binding:"required,gt=0"`
-	MinimalVolumeM3 float64     `json:"minimal_volume_m3" binding:"required,gt=0"`
-	StartAt         string      `json:"start_at" binding:"required"`
-	EndAt           string      `json:"end_at" binding:"required"`
-	IsActive        *bool       `json:"is_active"`
+	ContractType     string             `json:"contract_type" binding:"required"`
+	ContractorID     *string            `json:"contractor_id"`      // Опционально для LANDFILL_SERVICE
+	LandfillID       *string            `json:"landfill_id"`        // Опционально для CONTRACTOR_SERVICE
+	PolygonIDs       []string           `json:"polygon_ids"`        // Обязательно для LANDFILL_SERVICE
+	PerPolygonBudget map[string]float64 `json:"per_polygon_budget"` // Опционально для LANDFILL_SERVICE
+	Name             string             `json:"name" binding:"required"`
+	WorkType         *string            `json:"work_type"` // Опционально для LANDFILL_SERVICE
+	PricePerM3       float64            `json:"price_per_m3" binding:"required,gt=0"`
+	BudgetTotal      float64            `json:"budget_total" binding:"required,gt=0"`
+	MinimalVolumeM3  float64            `json:"minimal_volume_m3" binding:"required,gt=0"`
+	StartAt          string             `json:"start_at" binding:"required"`
+	EndAt            string             `json:"end_at" binding:"required"`
+	IsActive         *bool              `json:"is_active"`
+	ClientReference  *string            `json:"client_reference"` // Ключ идемпотентности импорта
 }
 
 func (h *Handler) createContract(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		response.Error(c, http.StatusUnauthorized, "missing principal")
 		return
 	}
 
 	var req createContractRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
 	contractType := model.ContractType(strings.ToUpper(strings.TrimSpace(req.ContractType)))
 	if contractType != model.ContractTypeContractorService && contractType != model.ContractTypeLandfillService {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid contract_type"))
+		response.Error(c, http.StatusBadRequest, "invalid contract_type")
 		return
 	}
 
-	var contractorID *uuid.UUID
-	if req.ContractorID != nil {
-		parsed, err := uuid.Parse(strings.TrimSpace(*req.ContractorID))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, errorResponse("invalid contractor_id"))
-			return
-		}
-		contractorID = &parsed
+	req.ContractorID = normalizeOptional(req.ContractorID)
+	req.LandfillID = normalizeOptional(req.LandfillID)
+	req.WorkType = normalizeOptional(req.WorkType)
+	req.ClientReference = normalizeOptional(req.ClientReference)
+	if req.ClientReference != nil && len(*req.ClientReference) > 255 {
+		response.Error(c, http.StatusBadRequest, "client_reference is too long")
+		return
+	}
+	rawIdempotencyKey := c.GetHeader(idempotencyKeyHeader)
+	idempotencyKey := normalizeOptional(&rawIdempotencyKey)
+	if idempotencyKey != nil && len(*idempotencyKey) > 255 {
+		response.Error(c, http.StatusBadRequest, "Idempotency-Key is too long")
+		return
 	}
 
-	var landfillID *uuid.UUID
-	if req.LandfillID != nil {
-		parsed, err := uuid.Parse(strings.TrimSpace(*req.LandfillID))
-		if err != nil {
-			c.JSON(http.StatusBadRequest, errorResponse("invalid landfill_id"))
-			return
-		}
-		landfillID = &parsed
+	contractorID, err := parseOptionalUUIDField("contractor_id", req.ContractorID)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	landfillID, err := parseOptionalUUIDField("landfill_id", req.LandfillID)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	polygonIDs, err := parseUUIDListField("polygon_ids", req.PolygonIDs)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	polygonBudgets, err := parseUUIDKeyedField("per_polygon_budget", req.PerPolygonBudget)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
 	}
 
 	var workType model.WorkType
 	if req.WorkType != nil {
-		wt := model.WorkType(strings.ToLower(strings.TrimSpace(*req.WorkType)))
-		if wt != model.WorkTypeRoad && wt != model.WorkTypeSidewalk && wt != model.WorkTypeYard {
-			c.JSON(http.StatusBadRequest, errorResponse("invalid work_type"))
+		wt := model.WorkType(strings.ToLower(*req.WorkType))
+		if !h.contracts.IsAllowedWorkType(wt) {
+			response.Error(c, http.StatusBadRequest, "invalid work_type")
 			return
 		}
 		workType = wt
@@ -231,24 +412,25 @@ func (h *Handler) createContract(c *gin.Context) {
 
 	startAt, err := parseTime(req.StartAt)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid start_at format"))
+		response.Error(c, http.StatusBadRequest, "invalid start_at format")
 		return
 	}
 
 	endAt, err := parseTime(req.EndAt)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid end_at format"))
+		response.Error(c, http.StatusBadRequest, "invalid end_at format")
 		return
 	}
 
-	contract, err := h.contracts.Create(
+	contract, created, err := h.contracts.Create(
 		c.Request.Context(),
 		principal,
 		service.CreateContractInput{
 			ContractType:    contractType,
 			ContractorID:    contractorID,
 			LandfillID:      landfillID,
-			PolygonIDs:      req.PolygonIDs,
+			PolygonIDs:      polygonIDs,
+			PolygonBudgets:  polygonBudgets,
 			Name:            req.Name,
 			WorkType:        workType,
 			PricePerM3:      req.PricePerM3,
@@ -257,6 +439,8 @@ func (h *Handler) createContract(c *gin.Context) {
 			StartAt:         startAt,
 			EndAt:           endAt,
 			IsActive:        req.IsActive,
+			ClientReference: req.ClientReference,
+			IdempotencyKey:  idempotencyKey,
 		},
 	)
 	if err != nil {
@@ -264,283 +448,1364 @@ func (h *Handler) createContract(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, successResponse(contract))
+	if !created {
+		response.Success(c, http.StatusOK, contract)
+		return
+	}
+	for i, warning := range contract.Warnings {
+		contract.Warnings[i] = response.Localize(c, warning)
+	}
+	response.Success(c, http.StatusCreated, contract)
 }
 
 func (h *Handler) getContract(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		response.Error(c, http.StatusUnauthorized, "missing principal")
 		return
 	}
 
-	contractID, err := parseUUIDParam(c, "id")
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid contract id"))
+		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	contract, err := h.contracts.Get(c.Request.Context(), principal, contractID)
+	var contract *model.Contract
+	if raw := c.Query("as_of"); raw != "" {
+		asOf, parseErr := parseTime(raw)
+		if parseErr != nil {
+			response.Error(c, http.StatusBadRequest, "invalid as_of")
+			return
+		}
+		contract, err = h.contracts.GetAsOf(c.Request.Context(), principal, contractID, asOf)
+	} else {
+		contract, err = h.contracts.Get(c.Request.Context(), principal, contractID)
+	}
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, successResponse(contract))
+	if parseBoolQuery(c.Query("flat")) {
+		response.Success(c, http.StatusOK, flattenContract(*contract))
+		return
+	}
+	response.Success(c, http.StatusOK, contract)
 }
 
-func (h *Handler) listContractTickets(c *gin.Context) {
+type batchGetContractsRequest struct {
+	IDs []string `json:"ids" binding:"required"`
+}
+
+func (h *Handler) batchGetContracts(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		response.Error(c, http.StatusUnauthorized, "missing principal")
 		return
 	}
 
-	contractID, err := parseUUIDParam(c, "id")
+	var req batchGetContractsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.IDs) == 0 || len(req.IDs) > service.MaxBatchGetIDs {
+		response.Error(c, http.StatusBadRequest, fmt.Sprintf("ids must contain 1 to %d items", service.MaxBatchGetIDs))
+		return
+	}
+	ids, err := parseUUIDListField("ids", req.IDs)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid contract id"))
+		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	items, err := h.contracts.ListContractTickets(c.Request.Context(), principal, contractID)
+	result, err := h.contracts.BatchGet(c.Request.Context(), principal, ids)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, successResponse(items))
+	response.Success(c, http.StatusOK, result)
 }
 
-func (h *Handler) listContractTrips(c *gin.Context) {
+type recomputeStatusesRequest struct {
+	ContractorID *string `json:"contractor_id"`
+	LandfillID   *string `json:"landfill_id"`
+	ContractType *string `json:"contract_type"`
+}
+
+func (h *Handler) recomputeContractStatuses(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		response.Error(c, http.StatusUnauthorized, "missing principal")
 		return
 	}
 
-	contractID, err := parseUUIDParam(c, "id")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid contract id"))
+	var req recomputeStatusesRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var input service.RecomputeStatusesInput
+	var err error
+	if input.ContractorID, err = parseOptionalUUIDField("contractor_id", req.ContractorID); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.LandfillID, err = parseOptionalUUIDField("landfill_id", req.LandfillID); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if raw := normalizeOptional(req.ContractType); raw != nil {
+		value := model.ContractType(strings.ToUpper(*raw))
+		if value != model.ContractTypeContractorService && value != model.ContractTypeLandfillService {
+			response.Error(c, http.StatusBadRequest, "invalid contract_type")
+			return
+		}
+		input.ContractType = &value
+	}
 
-	items, err := h.contracts.ListContractTrips(c.Request.Context(), principal, contractID)
+	result, err := h.contracts.RecomputeStatuses(c.Request.Context(), principal, input)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, successResponse(items))
+	response.Success(c, http.StatusOK, result)
 }
 
-type assignTicketContractRequest struct {
-	ContractID string `json:"contract_id" binding:"required"`
+type bulkDeactivateRequest struct {
+	ContractorID *string `json:"contractor_id"`
+	Status       *string `json:"status"`
+	EndFrom      *string `json:"end_from"`
+	EndTo        *string `json:"end_to"`
 }
 
-func (h *Handler) assignTicketContract(c *gin.Context) {
+func (h *Handler) bulkDeactivateContracts(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	var req bulkDeactivateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var input service.BulkDeactivateInput
+	var err error
+	if input.ContractorID, err = parseOptionalUUIDField("contractor_id", req.ContractorID); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
+	if raw := normalizeOptional(req.Status); raw != nil {
+		value := model.ContractUIStatus(strings.ToUpper(*raw))
+		if value != model.ContractUIStatusPlanned &&
+			value != model.ContractUIStatusActive &&
+			value != model.ContractUIStatusExpired {
+			response.Error(c, http.StatusBadRequest, "invalid status")
+			return
+		}
+		input.Status = &value
+	}
+	if raw := normalizeOptional(req.EndFrom); raw != nil {
+		value, err := parseTime(*raw)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid end_from")
+			return
+		}
+		input.EndFrom = &value
+	}
+	if raw := normalizeOptional(req.EndTo); raw != nil {
+		value, err := parseTime(*raw)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid end_to")
+			return
+		}
+		input.EndTo = &value
+	}
 
-	ticketID, err := parseUUIDParam(c, "ticket_id")
+	result, err := h.contracts.BulkDeactivate(c.Request.Context(), principal, input)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket id"))
+		h.handleError(c, err)
 		return
 	}
 
-	var req assignTicketContractRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+	response.Success(c, http.StatusOK, result)
+}
+
+func (h *Handler) listLandfillPolygons(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
 		return
 	}
 
-	contractID, err := uuid.Parse(strings.TrimSpace(req.ContractID))
+	landfillID, err := parseUUIDParam(c, "id", "landfill_id")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid contract_id"))
+		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	err = h.contracts.AssignTicketContract(c.Request.Context(), principal, service.AssignTicketContractInput{
-		TicketID:   ticketID,
-		ContractID: contractID,
-	})
+	polygons, err := h.contracts.ListLandfillPolygons(c.Request.Context(), principal, landfillID)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, successResponse(gin.H{"status": "linked"}))
-}
-
-type recordTripUsageRequest struct {
-	TripID           string  `json:"trip_id" binding:"required"`
-	TicketID         string  `json:"ticket_id" binding:"required"`
-	DetectedVolumeM3 float64 `json:"detected_volume_m3" binding:"required,gt=0"`
+	response.Success(c, http.StatusOK, polygons)
 }
 
-func (h *Handler) recordTripUsage(c *gin.Context) {
+func (h *Handler) listAccessibleContracts(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
-		return
-	}
-
-	var req recordTripUsageRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
+		response.Error(c, http.StatusUnauthorized, "missing principal")
 		return
 	}
 
-	tripID, err := uuid.Parse(strings.TrimSpace(req.TripID))
+	items, err := h.contracts.ListAccessible(c.Request.Context(), principal)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid trip_id"))
+		h.handleError(c, err)
 		return
 	}
-	ticketID, err := uuid.Parse(strings.TrimSpace(req.TicketID))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid ticket_id"))
+
+	response.Success(c, http.StatusOK, items)
+}
+
+func (h *Handler) getContractFilterOptions(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
 		return
 	}
 
-	err = h.contracts.RecordTripUsage(c.Request.Context(), principal, service.RecordTripUsageInput{
-		TripID:   tripID,
-		TicketID: ticketID,
-		VolumeM3: req.DetectedVolumeM3,
-	})
+	options, err := h.contracts.GetFilterOptions(c.Request.Context(), principal)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, successResponse(gin.H{"status": "recorded"}))
+	response.Success(c, http.StatusOK, options)
 }
 
-func (h *Handler) getContractDeletionInfo(c *gin.Context) {
+func (h *Handler) getPayableBreakdown(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		response.Error(c, http.StatusUnauthorized, "missing principal")
 		return
 	}
 
-	contractID, err := parseUUIDParam(c, "id")
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid contract id"))
+		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	info, err := h.contracts.GetDeletionInfo(c.Request.Context(), principal, contractID)
+	breakdown, err := h.contracts.GetPayableBreakdown(c.Request.Context(), principal, contractID)
 	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, successResponse(gin.H{
-		"contract": gin.H{
-			"id":   info.Contract.ID,
-			"name": info.Contract.Name,
-		},
-		"dependencies": gin.H{
-			"tickets_count":     info.Dependencies.TicketsCount,
-			"trips_count":       info.Dependencies.TripsCount,
-			"assignments_count": info.Dependencies.AssignmentsCount,
-			"appeals_count":     info.Dependencies.AppealsCount,
-			"usage_log_count":   info.Dependencies.UsageLogCount,
-			"polygons_count":    info.Dependencies.PolygonsCount,
-		},
-		"will_be_deleted": gin.H{
-			"tickets":     info.Dependencies.TicketsCount > 0,
-			"trips":       info.Dependencies.TripsCount > 0,
-			"assignments": info.Dependencies.AssignmentsCount > 0,
-			"appeals":     info.Dependencies.AppealsCount > 0,
-			"usage_log":   info.Dependencies.UsageLogCount > 0,
-			"polygons":    info.Dependencies.PolygonsCount > 0,
-		},
-	}))
+	response.Success(c, http.StatusOK, breakdown)
 }
 
-func (h *Handler) deleteContract(c *gin.Context) {
+type usageAdjustmentRequest struct {
+	VolumeDeltaM3 float64 `json:"volume_delta_m3"`
+	CostDelta     float64 `json:"cost_delta"`
+	Reason        string  `json:"reason" binding:"required"`
+}
+
+func (h *Handler) recordUsageAdjustment(c *gin.Context) {
 	principal, ok := middleware.MustPrincipal(c)
 	if !ok {
-		c.JSON(http.StatusUnauthorized, errorResponse("missing principal"))
+		response.Error(c, http.StatusUnauthorized, "missing principal")
 		return
 	}
 
-	contractID, err := parseUUIDParam(c, "id")
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, errorResponse("invalid contract id"))
+		response.Error(c, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Check force parameter for cascade deletion
-	force := parseBoolQuery(c.Query("force"))
+	var req usageAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
-	if err := h.contracts.Delete(c.Request.Context(), principal, contractID, force); err != nil {
+	adjustment, err := h.contracts.RecordUsageAdjustment(c.Request.Context(), principal, contractID, service.RecordUsageAdjustmentInput{
+		VolumeDeltaM3: req.VolumeDeltaM3,
+		CostDelta:     req.CostDelta,
+		Reason:        req.Reason,
+	})
+	if err != nil {
 		h.handleError(c, err)
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	response.Success(c, http.StatusCreated, adjustment)
 }
 
-func (h *Handler) handleError(c *gin.Context, err error) {
-	switch {
-	case errors.Is(err, service.ErrPermissionDenied):
-		c.JSON(http.StatusForbidden, errorResponse(err.Error()))
-	case errors.Is(err, service.ErrNotFound):
-		c.JSON(http.StatusNotFound, errorResponse(err.Error()))
-	case errors.Is(err, service.ErrInvalidInput):
-		c.JSON(http.StatusBadRequest, errorResponse(err.Error()))
-	case errors.Is(err, service.ErrConflict):
-		c.JSON(http.StatusConflict, errorResponse(err.Error()))
-	default:
-		h.log.Error().Err(err).Msg("handler error")
-		c.JSON(http.StatusInternalServerError, errorResponse("internal error"))
-	}
+type updateContractRequest struct {
+	Name            *string  `json:"name"`
+	PricePerM3      *float64 `json:"price_per_m3" binding:"omitempty,gt=0"`
+	BudgetTotal     *float64 `json:"budget_total" binding:"omitempty,gt=0"`
+	MinimalVolumeM3 *float64 `json:"minimal_volume_m3" binding:"omitempty,gt=0"`
+	StartAt         *string  `json:"start_at"`
+	EndAt           *string  `json:"end_at"`
+	IsActive        *bool    `json:"is_active"`
+	Reason          string   `json:"reason"`
 }
 
-func parseBoolQuery(raw string) bool {
-	switch strings.ToLower(strings.TrimSpace(raw)) {
-	case "1", "true", "yes", "on":
-		return true
-	default:
-		return false
+// updateContract меняет условия контракта; отсутствующие в теле поля не меняются.
+func (h *Handler) updateContract(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
 	}
-}
 
-func parseUUIDParam(c *gin.Context, param string) (uuid.UUID, error) {
-	raw := strings.TrimSpace(c.Param(param))
-	return uuid.Parse(raw)
-}
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
 
-func parseTime(raw string) (time.Time, error) {
-	// Try RFC3339 first
-	if t, err := time.Parse(time.RFC3339, raw); err == nil {
-		return t, nil
+	var req updateContractRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
 	}
-	// Try common formats
-	formats := []string{
-		"2006-01-02T15:04:05Z07:00",
-		"2006-01-02 15:04:05",
-		"2006-01-02",
+
+	input := service.UpdateContractInput{
+		Name:            req.Name,
+		PricePerM3:      req.PricePerM3,
+		BudgetTotal:     req.BudgetTotal,
+		MinimalVolumeM3: req.MinimalVolumeM3,
+		IsActive:        req.IsActive,
+		Reason:          req.Reason,
 	}
-	for _, format := range formats {
-		if t, err := time.Parse(format, raw); err == nil {
-			return t, nil
+	if req.StartAt != nil {
+		startAt, err := parseTime(*req.StartAt)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid start_at format")
+			return
 		}
+		input.StartAt = &startAt
 	}
-	return time.Time{}, errors.New("invalid time format")
-}
-
-func successResponse(data interface{}) gin.H {
-	return gin.H{
-		"data": data,
+	if req.EndAt != nil {
+		endAt, err := parseTime(*req.EndAt)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid end_at format")
+			return
+		}
+		input.EndAt = &endAt
 	}
+
+	contract, err := h.contracts.Update(c.Request.Context(), principal, contractID, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	for i, warning := range contract.Warnings {
+		contract.Warnings[i] = response.Localize(c, warning)
+	}
+	response.Success(c, http.StatusOK, contract)
+}
+
+func (h *Handler) previewContractCost(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	volume, err := strconv.ParseFloat(strings.TrimSpace(c.Query("volume")), 64)
+	if err != nil || volume <= 0 {
+		response.Error(c, http.StatusBadRequest, "invalid volume")
+		return
+	}
+
+	preview, err := h.contracts.PreviewCost(c.Request.Context(), principal, contractID, volume)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, preview)
+}
+
+func (h *Handler) previewBudgetChange(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	newBudget, err := strconv.ParseFloat(strings.TrimSpace(c.Query("new_budget")), 64)
+	if err != nil || newBudget <= 0 {
+		response.Error(c, http.StatusBadRequest, "invalid new_budget")
+		return
+	}
+
+	preview, err := h.contracts.PreviewBudgetChange(c.Request.Context(), principal, contractID, newBudget)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	if preview.Reason != "" {
+		preview.Reason = response.Localize(c, preview.Reason)
+	}
+	for i, warning := range preview.Warnings {
+		preview.Warnings[i] = response.Localize(c, warning)
+	}
+	response.Success(c, http.StatusOK, preview)
+}
+
+func (h *Handler) listContractTickets(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input := service.ListContractTicketsInput{Limit: limit, Offset: offset}
+	if raw := c.Query("status"); raw != "" {
+		status, ok := model.ParseTicketStatus(raw)
+		if !ok {
+			response.Error(c, http.StatusBadRequest, "invalid status")
+			return
+		}
+		input.Status = &status
+	}
+	for name, target := range map[string]**time.Time{
+		"from": &input.From,
+		"to":   &input.To,
+	} {
+		if raw := strings.TrimSpace(c.Query(name)); raw != "" {
+			t, err := parseTime(raw)
+			if err != nil {
+				response.Error(c, http.StatusBadRequest, "invalid "+name)
+				return
+			}
+			*target = &t
+		}
+	}
+
+	page, err := h.contracts.ListContractTickets(c.Request.Context(), principal, contractID, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, http.StatusOK, page.Items, response.Pagination{
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	})
+}
+
+func (h *Handler) listContractTrips(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var completed *bool
+	if raw := strings.TrimSpace(c.Query("completed")); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid completed")
+			return
+		}
+		completed = &value
+	}
+
+	items, err := h.contracts.ListContractTrips(c.Request.Context(), principal, contractID, service.ListContractTripsInput{
+		Completed:     completed,
+		PlateMismatch: parseBoolQuery(c.Query("plate_mismatch")),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, items)
+}
+
+func (h *Handler) listContractUsageLog(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	items, err := h.contracts.ListUsageLog(c.Request.Context(), principal, contractID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, items)
+}
+
+func (h *Handler) lockContract(c *gin.Context) {
+	h.setContractLock(c, true)
+}
+
+func (h *Handler) unlockContract(c *gin.Context) {
+	h.setContractLock(c, false)
+}
+
+func (h *Handler) setContractLock(c *gin.Context, locked bool) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	contract, err := h.contracts.SetContractLock(c.Request.Context(), principal, contractID, locked)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, contract)
+}
+
+func (h *Handler) getCapacityEstimate(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	estimate, err := h.contracts.EstimateCapacity(c.Request.Context(), principal, contractID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, estimate)
+}
+
+func (h *Handler) getPlateMismatchSummary(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	summary, err := h.contracts.GetPlateMismatchSummary(c.Request.Context(), principal, contractID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, summary)
+}
+
+func (h *Handler) listContractAudit(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	limit, offset, err := parsePagination(c)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input := service.ListAuditInput{Limit: limit, Offset: offset}
+	if raw := strings.TrimSpace(c.Query("action")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				input.Actions = append(input.Actions, model.AuditAction(part))
+			}
+		}
+	}
+	if input.ActorUserID, err = parseUUIDQuery(c, "actor_user_id"); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if input.ActorOrgID, err = parseUUIDQuery(c, "actor_org_id"); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	for name, target := range map[string]**time.Time{
+		"from": &input.From,
+		"to":   &input.To,
+	} {
+		if raw := strings.TrimSpace(c.Query(name)); raw != "" {
+			t, err := parseTime(raw)
+			if err != nil {
+				response.Error(c, http.StatusBadRequest, "invalid "+name)
+				return
+			}
+			*target = &t
+		}
+	}
+
+	page, err := h.contracts.ListContractAudit(c.Request.Context(), principal, contractID, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, http.StatusOK, page.Items, response.Pagination{
+		Total:  page.Total,
+		Limit:  page.Limit,
+		Offset: page.Offset,
+	})
+}
+
+// listContractAmendments — история изменений условий контракта.
+func (h *Handler) listContractAmendments(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	amendments, err := h.contracts.ListContractAmendments(c.Request.Context(), principal, contractID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	response.Success(c, http.StatusOK, amendments)
+}
+
+func (h *Handler) listCleaningAreaContracts(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	cleaningAreaID, err := parseUUIDParam(c, "id", "cleaning_area_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	contracts, err := h.contracts.ListByCleaningArea(c.Request.Context(), principal, cleaningAreaID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, contracts)
+}
+
+type assignTicketContractRequest struct {
+	ContractID string `json:"contract_id" binding:"required"`
+}
+
+func (h *Handler) assignTicketContract(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	ticketID, err := parseUUIDParam(c, "ticket_id", "ticket_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req assignTicketContractRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	contractID, err := parseUUIDField("contract_id", req.ContractID)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	err = h.contracts.AssignTicketContract(c.Request.Context(), principal, service.AssignTicketContractInput{
+		TicketID:   ticketID,
+		ContractID: contractID,
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{"status": "linked"})
+}
+
+func (h *Handler) reconcileTicketContract(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	ticketID, err := parseUUIDParam(c, "ticket_id", "ticket_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apply := parseBoolQuery(c.Query("apply"))
+
+	result, err := h.contracts.ReconcileTicketContract(c.Request.Context(), principal, ticketID, apply)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, result)
+}
+
+type recordTripUsageRequest struct {
+	TripID           string  `json:"trip_id" binding:"required"`
+	TicketID         string  `json:"ticket_id" binding:"required"`
+	DetectedVolumeM3 float64 `json:"detected_volume_m3" binding:"required,gt=0"`
+	Unit             *string `json:"unit"` // m3 (по умолчанию) или liters
+	RecordedAt       *string `json:"recorded_at"`
+}
+
+func (h *Handler) recordTripUsage(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	var req recordTripUsageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	input, err := parseTripUsageRequest(req)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	input.ForceOverBudget = parseBoolQuery(c.Query("force_over_budget"))
+
+	err = h.contracts.RecordTripUsage(c.Request.Context(), principal, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, gin.H{"status": "recorded"})
+}
+
+type polygonAssignmentRequest struct {
+	PolygonID string `json:"polygon_id"`
+	StartAt   string `json:"start_at"`
+	EndAt     string `json:"end_at"`
+}
+
+type checkPolygonConflictsRequest struct {
+	Items []polygonAssignmentRequest `json:"items" binding:"required"`
+}
+
+// checkPolygonConflicts проверяет план назначений полигонов на пересечения
+// с действующими контрактами приёма и между собой; ничего не записывает.
+func (h *Handler) checkPolygonConflicts(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	var req checkPolygonConflictsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Items) == 0 || len(req.Items) > service.MaxPolygonConflictItems {
+		response.Error(c, http.StatusBadRequest, fmt.Sprintf("items must contain 1 to %d entries", service.MaxPolygonConflictItems))
+		return
+	}
+
+	assignments := make([]service.PolygonAssignment, 0, len(req.Items))
+	for i, item := range req.Items {
+		polygonID, err := parseUUIDField(fmt.Sprintf("items[%d].polygon_id", i), item.PolygonID)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		startAt, err := parseTime(item.StartAt)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, fmt.Sprintf("invalid items[%d].start_at", i))
+			return
+		}
+		endAt, err := parseTime(item.EndAt)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, fmt.Sprintf("invalid items[%d].end_at", i))
+			return
+		}
+		assignments = append(assignments, service.PolygonAssignment{PolygonID: polygonID, StartAt: startAt, EndAt: endAt})
+	}
+
+	report, err := h.contracts.CheckPolygonConflicts(c.Request.Context(), principal, assignments)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, report)
+}
+
+type recordTripUsageBatchRequest struct {
+	Items []recordTripUsageRequest `json:"items" binding:"required"`
+}
+
+// recordTripUsageBatch отвечает 207 с результатом по каждому рейсу. Атомарность
+// задаётся конфигом: всё или ничего либо независимая запись каждого рейса.
+func (h *Handler) recordTripUsageBatch(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	var req recordTripUsageBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if len(req.Items) == 0 || len(req.Items) > service.MaxTripUsageBatch {
+		response.Error(c, http.StatusBadRequest, fmt.Sprintf("items must contain 1 to %d entries", service.MaxTripUsageBatch))
+		return
+	}
+
+	forceOverBudget := parseBoolQuery(c.Query("force_over_budget"))
+	results := make([]response.ItemResult, len(req.Items))
+	inputs := make([]service.RecordTripUsageInput, 0, len(req.Items))
+	positions := make([]int, 0, len(req.Items))
+	for i, item := range req.Items {
+		results[i] = response.ItemResult{Index: i, ID: item.TripID}
+		input, err := parseTripUsageRequest(item)
+		if err != nil {
+			results[i].Status = http.StatusBadRequest
+			results[i].Error = err.Error()
+			continue
+		}
+		input.ForceOverBudget = forceOverBudget
+		inputs = append(inputs, input)
+		positions = append(positions, i)
+	}
+
+	atomic := h.cfg.AtomicTripUsageBatch
+	if atomic && len(inputs) < len(req.Items) {
+		// Невалидный элемент отменяет весь атомарный пакет
+		for _, i := range positions {
+			results[i].Status, results[i].Error = h.errorStatus(service.ErrBatchAborted)
+		}
+		response.MultiStatus(c, results)
+		return
+	}
+
+	if len(inputs) > 0 {
+		errs, err := h.contracts.RecordTripUsageBatch(c.Request.Context(), principal, inputs, atomic)
+		if err != nil {
+			h.handleError(c, err)
+			return
+		}
+		for k, itemErr := range errs {
+			i := positions[k]
+			if itemErr == nil {
+				results[i].Status = http.StatusCreated
+				continue
+			}
+			results[i].Status, results[i].Error = h.errorStatus(itemErr)
+		}
+	}
+
+	response.MultiStatus(c, results)
+}
+
+func parseTripUsageRequest(req recordTripUsageRequest) (service.RecordTripUsageInput, error) {
+	var input service.RecordTripUsageInput
+
+	tripID, err := parseUUIDField("trip_id", req.TripID)
+	if err != nil {
+		return input, err
+	}
+	ticketID, err := parseUUIDField("ticket_id", req.TicketID)
+	if err != nil {
+		return input, err
+	}
+	if req.DetectedVolumeM3 <= 0 {
+		return input, errors.New("detected_volume_m3 must be greater than 0")
+	}
+
+	unit := model.VolumeUnitM3
+	if raw := normalizeOptional(req.Unit); raw != nil {
+		unit = model.VolumeUnit(strings.ToLower(*raw))
+		if unit != model.VolumeUnitM3 && unit != model.VolumeUnitLiters {
+			return input, errors.New("invalid unit")
+		}
+	}
+
+	var recordedAt *time.Time
+	if raw := normalizeOptional(req.RecordedAt); raw != nil {
+		parsed, err := parseTime(*raw)
+		if err != nil {
+			return input, errors.New("invalid recorded_at")
+		}
+		recordedAt = &parsed
+	}
+
+	return service.RecordTripUsageInput{
+		TripID:     tripID,
+		TicketID:   ticketID,
+		VolumeM3:   req.DetectedVolumeM3,
+		Unit:       unit,
+		RecordedAt: recordedAt,
+	}, nil
+}
+
+func (h *Handler) usageConsistencyReport(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	report, err := h.contracts.CheckUsageConsistency(c.Request.Context(), principal)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, report)
+}
+
+func (h *Handler) utilizationDistributionReport(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	var bounds []float64
+	if raw := strings.TrimSpace(c.Query("buckets")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				response.Error(c, http.StatusBadRequest, "invalid buckets")
+				return
+			}
+			bounds = append(bounds, value)
+		}
+	}
+
+	report, err := h.contracts.GetUtilizationDistribution(c.Request.Context(), principal, bounds)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, report)
+}
+
+func (h *Handler) contractorPerformanceReport(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	input := service.ContractorPerformanceInput{
+		SortBy: strings.ToLower(strings.TrimSpace(c.Query("sort_by"))),
+	}
+	for _, field := range []struct {
+		name   string
+		target **time.Time
+	}{
+		{"end_from", &input.EndFrom},
+		{"end_to", &input.EndTo},
+	} {
+		raw := strings.TrimSpace(c.Query(field.name))
+		if raw == "" {
+			continue
+		}
+		parsed, err := parseTime(raw)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid "+field.name)
+			return
+		}
+		*field.target = &parsed
+	}
+
+	report, err := h.contracts.GetContractorPerformance(c.Request.Context(), principal, input)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, report)
+}
+
+func (h *Handler) getContractorMonthlySpend(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractorID, err := parseUUIDParam(c, "id", "contractor_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	year := time.Now().UTC().Year()
+	if raw := strings.TrimSpace(c.Query("year")); raw != "" {
+		year, err = strconv.Atoi(raw)
+		if err != nil {
+			response.Error(c, http.StatusBadRequest, "invalid year")
+			return
+		}
+	}
+
+	report, err := h.contracts.GetContractorMonthlySpend(c.Request.Context(), principal, contractorID, year)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, report)
+}
+
+// getContractSnapshot отдаёт полный снимок контракта файлом для архива.
+func (h *Handler) getContractSnapshot(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	snapshot, err := h.contracts.GetSnapshot(c.Request.Context(), principal, contractID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="contract-%s-snapshot.json"`, contractID))
+	response.Success(c, http.StatusOK, snapshot)
+}
+
+// restoreContractSnapshot принимает снимок GET /contracts/:id/snapshot как
+// скачан (в конверте {"data": ...}) или без конверта.
+func (h *Handler) restoreContractSnapshot(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	var envelope struct {
+		Data json.RawMessage `json:"data"`
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid snapshot")
+		return
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid snapshot: "+err.Error())
+		return
+	}
+	if len(envelope.Data) > 0 {
+		body = envelope.Data
+	}
+
+	var snapshot service.ContractSnapshot
+	if err := json.Unmarshal(body, &snapshot); err != nil {
+		response.Error(c, http.StatusBadRequest, "invalid snapshot: "+err.Error())
+		return
+	}
+
+	contract, err := h.contracts.RestoreSnapshot(c.Request.Context(), principal, service.RestoreSnapshotInput{
+		Snapshot:      snapshot,
+		Overwrite:     parseBoolQuery(c.Query("overwrite")),
+		RestoreLedger: parseBoolQuery(c.Query("restore_ledger")),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusCreated, contract)
+}
+
+func (h *Handler) getContractDeletionInfo(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	info, err := h.contracts.GetDeletionInfo(c.Request.Context(), principal, contractID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, gin.H{
+		"contract": gin.H{
+			"id":   info.Contract.ID,
+			"name": info.Contract.Name,
+		},
+		"dependencies": gin.H{
+			"tickets_count":     info.Dependencies.TicketsCount,
+			"trips_count":       info.Dependencies.TripsCount,
+			"assignments_count": info.Dependencies.AssignmentsCount,
+			"appeals_count":     info.Dependencies.AppealsCount,
+			"usage_log_count":   info.Dependencies.UsageLogCount,
+			"polygons_count":    info.Dependencies.PolygonsCount,
+		},
+		"will_be_deleted": gin.H{
+			"tickets":     info.Dependencies.TicketsCount > 0,
+			"trips":       info.Dependencies.TripsCount > 0,
+			"assignments": info.Dependencies.AssignmentsCount > 0,
+			"appeals":     info.Dependencies.AppealsCount > 0,
+			"usage_log":   info.Dependencies.UsageLogCount > 0,
+			"polygons":    info.Dependencies.PolygonsCount > 0,
+		},
+	})
+}
+
+func (h *Handler) deleteContract(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// force — удалить и при связанных тикетах; purge (только с force) — удалить физически
+	force := parseBoolQuery(c.Query("force"))
+	purge := parseBoolQuery(c.Query("purge"))
+
+	if err := h.contracts.Delete(c.Request.Context(), principal, contractID, force, purge); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) restoreContract(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	contract, err := h.contracts.Restore(c.Request.Context(), principal, contractID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, contract)
+}
+
+func (h *Handler) handleError(c *gin.Context, err error) {
+	var validation *service.ValidationError
+	if errors.As(err, &validation) {
+		fields := make([]response.FieldError, 0, len(validation.Fields))
+		for _, field := range validation.Fields {
+			fields = append(fields, response.FieldError{Field: field.Field, Message: field.Message})
+		}
+		response.ErrorWithFields(c, http.StatusBadRequest, err.Error(), fields)
+		return
+	}
+	status, message := h.errorStatus(err)
+	response.Error(c, status, message)
+}
+
+// errorStatus сопоставляет ошибку сервиса HTTP-статусу и тексту для клиента;
+// внутренние ошибки логируются и не раскрываются.
+func (h *Handler) errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, service.ErrPermissionDenied):
+		return http.StatusForbidden, err.Error()
+	case errors.Is(err, service.ErrNotFound):
+		return http.StatusNotFound, err.Error()
+	case errors.Is(err, service.ErrInvalidInput):
+		return http.StatusBadRequest, err.Error()
+	case errors.Is(err, service.ErrConflict):
+		return http.StatusConflict, err.Error()
+	case errors.Is(err, service.ErrIdempotencyKeyMismatch):
+		return http.StatusUnprocessableEntity, err.Error()
+	case errors.Is(err, service.ErrBatchAborted):
+		return http.StatusFailedDependency, err.Error()
+	default:
+		h.log.Error().Err(err).Msg("handler error")
+		return http.StatusInternalServerError, "internal error"
+	}
+}
+
+func parseBoolQuery(raw string) bool {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "1", "true", "yes", "on":
+		return true
+	default:
+		return false
+	}
+}
+
+// parsePercentQuery читает процент 0–100 и возвращает долю (100 → 1);
+// пустой параметр — nil.
+func parsePercentQuery(c *gin.Context, name string) (*float64, error) {
+	raw := strings.TrimSpace(c.Query(name))
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil || !(value >= 0 && value <= 100) {
+		return nil, errors.New("invalid " + name)
+	}
+	fraction := value / 100
+	return &fraction, nil
+}
+
+// normalizeOptional treats empty or whitespace-only strings as "not provided".
+func normalizeOptional(raw *string) *string {
+	if raw == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*raw)
+	if trimmed == "" {
+		return nil
+	}
+	return &trimmed
 }
 
-func errorResponse(message string) gin.H {
-	return gin.H{
-		"error": message,
+func parseTime(raw string) (time.Time, error) {
+	// Try RFC3339 first
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	// Try common formats
+	formats := []string{
+		"2006-01-02T15:04:05Z07:00",
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+	}
+	for _, format := range formats {
+		if t, err := time.Parse(format, raw); err == nil {
+			return t, nil
+		}
 	}
+	return time.Time{}, errors.New("invalid time format")
 }
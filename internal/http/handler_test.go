@@ -0,0 +1,231 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/nurpe/snowops-contract/internal/dbtest"
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+	"github.com/nurpe/snowops-contract/internal/service"
+)
+
+func TestHandleErrorRendersAllFieldErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/contracts", nil)
+
+	h := NewHandler(nil, Config{}, zerolog.Nop())
+	h.handleError(c, &service.ValidationError{Fields: []service.FieldError{
+		{Field: "name", Message: "name is required"},
+		{Field: "end_at", Message: "end_at must be after start_at"},
+	}})
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", recorder.Code)
+	}
+	var body struct {
+		Error  string `json:"error"`
+		Fields []struct {
+			Field   string `json:"field"`
+			Message string `json:"message"`
+		} `json:"fields"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode body %s: %v", recorder.Body.String(), err)
+	}
+	if len(body.Fields) != 2 || body.Fields[0].Field != "name" || body.Fields[1].Field != "end_at" {
+		t.Fatalf("fields = %+v, want name and end_at", body.Fields)
+	}
+	if body.Error == "" {
+		t.Fatalf("error message is empty: %s", recorder.Body.String())
+	}
+}
+
+func TestParsePercentQuery(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    *float64
+		wantErr bool
+	}{
+		{raw: ""},
+		{raw: "0", want: ptrFloat(0)},
+		{raw: "80", want: ptrFloat(0.8)},
+		{raw: "100", want: ptrFloat(1)},
+		{raw: "100.5", wantErr: true},
+		{raw: "-1", wantErr: true},
+		{raw: "NaN", wantErr: true},
+		{raw: "abc", wantErr: true},
+	}
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/contracts?utilization_gte="+url.QueryEscape(tt.raw), nil)
+		got, err := parsePercentQuery(c, "utilization_gte")
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%q: err = %v, wantErr %v", tt.raw, err, tt.wantErr)
+		}
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Fatalf("%q: got %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func ptrFloat(v float64) *float64 {
+	return &v
+}
+
+func TestParseListContractsInputSort(t *testing.T) {
+	tests := []struct {
+		query   string
+		sortBy  model.ContractSortField
+		sortDir model.SortDirection
+		wantErr bool
+	}{
+		{query: ""},
+		{query: "sort_by=name&sort_dir=asc", sortBy: model.ContractSortName, sortDir: model.SortAsc},
+		{query: "sort_by=BUDGET_TOTAL&sort_dir=DESC", sortBy: model.ContractSortBudgetTotal, sortDir: model.SortDesc},
+		{query: "sort_by=end_at", sortBy: model.ContractSortEndAt},
+		{query: "sort_by=price_per_m3", wantErr: true},
+		{query: "sort_by=name%3BDROP%20TABLE%20contracts", wantErr: true},
+		{query: "sort_dir=up", wantErr: true},
+	}
+	gin.SetMode(gin.TestMode)
+	h := &Handler{}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/contracts", nil)
+		c.Request.URL.RawQuery = tt.query
+		input, err := h.parseListContractsInput(c)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%q: err = %v, wantErr %v", tt.query, err, tt.wantErr)
+		}
+		if err == nil && (input.SortBy != tt.sortBy || input.SortDir != tt.sortDir) {
+			t.Fatalf("%q: sort = %s %s, want %s %s", tt.query, input.SortBy, input.SortDir, tt.sortBy, tt.sortDir)
+		}
+	}
+}
+
+func TestCreateContractTreatsBlankOptionalFieldsAsMissing(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := dbtest.Open(t)
+	h := NewHandler(service.NewContractService(repository.NewContractRepository(database), nil, service.Config{}, zerolog.Nop()), Config{}, zerolog.Nop())
+	principal := model.Principal{
+		UserID:         uuid.New(),
+		OrganizationID: dbtest.Organization(t, database, "КГУ ЖКХ"),
+		Role:           model.UserRoleKguZkhAdmin,
+	}
+	contractorID := dbtest.Organization(t, database, "ТОО Подрядчик").String()
+	landfillID := dbtest.Organization(t, database, "ТОО Полигон").String()
+
+	contractorBody := func(field, value string) map[string]any {
+		body := map[string]any{
+			"contract_type":     "CONTRACTOR_SERVICE",
+			"contractor_id":     contractorID,
+			"work_type":         "road",
+			"name":              "Уборка дорог",
+			"price_per_m3":      100,
+			"budget_total":      100000,
+			"minimal_volume_m3": 500,
+			"start_at":          "2024-01-01T00:00:00Z",
+			"end_at":            "2024-03-31T00:00:00Z",
+		}
+		body[field] = value
+		return body
+	}
+	landfillBody := func(field, value string) map[string]any {
+		body := contractorBody("landfill_id", landfillID)
+		body["contract_type"] = "LANDFILL_SERVICE"
+		body["polygon_ids"] = []string{uuid.NewString()}
+		delete(body, "contractor_id")
+		delete(body, "work_type")
+		body[field] = value
+		return body
+	}
+
+	tests := []struct {
+		name       string
+		body       map[string]any
+		wantStatus int
+		// wantField — поле ошибки валидации при 400
+		wantField string
+		// check — проверка созданного контракта при 201
+		check func(contract model.Contract) bool
+	}{
+		// contractor_id: обязателен для CONTRACTOR_SERVICE, для LANDFILL_SERVICE пустое значение — nil
+		{name: "contractor_id empty", body: contractorBody("contractor_id", ""), wantStatus: http.StatusBadRequest, wantField: "contractor_id"},
+		{name: "contractor_id blank", body: contractorBody("contractor_id", "   "), wantStatus: http.StatusBadRequest, wantField: "contractor_id"},
+		{name: "contractor_id empty on landfill", body: landfillBody("contractor_id", ""), wantStatus: http.StatusCreated,
+			check: func(c model.Contract) bool { return c.ContractorID == nil }},
+		{name: "contractor_id blank on landfill", body: landfillBody("contractor_id", "   "), wantStatus: http.StatusCreated,
+			check: func(c model.Contract) bool { return c.ContractorID == nil }},
+		{name: "contractor_id valid", body: contractorBody("contractor_id", " "+contractorID+" "), wantStatus: http.StatusCreated,
+			check: func(c model.Contract) bool { return c.ContractorID != nil && c.ContractorID.String() == contractorID }},
+
+		// landfill_id: обязателен для LANDFILL_SERVICE, для CONTRACTOR_SERVICE пустое значение — nil
+		{name: "landfill_id empty", body: landfillBody("landfill_id", ""), wantStatus: http.StatusBadRequest, wantField: "landfill_id"},
+		{name: "landfill_id blank", body: landfillBody("landfill_id", "   "), wantStatus: http.StatusBadRequest, wantField: "landfill_id"},
+		{name: "landfill_id empty on contractor", body: contractorBody("landfill_id", ""), wantStatus: http.StatusCreated,
+			check: func(c model.Contract) bool { return c.LandfillID == nil }},
+		{name: "landfill_id blank on contractor", body: contractorBody("landfill_id", "   "), wantStatus: http.StatusCreated,
+			check: func(c model.Contract) bool { return c.LandfillID == nil }},
+		{name: "landfill_id valid", body: landfillBody("landfill_id", landfillID), wantStatus: http.StatusCreated,
+			check: func(c model.Contract) bool { return c.LandfillID != nil && c.LandfillID.String() == landfillID }},
+
+		// work_type: обязателен для CONTRACTOR_SERVICE, для LANDFILL_SERVICE пустое значение — не задан
+		{name: "work_type empty", body: contractorBody("work_type", ""), wantStatus: http.StatusBadRequest, wantField: "work_type"},
+		{name: "work_type blank", body: contractorBody("work_type", "   "), wantStatus: http.StatusBadRequest, wantField: "work_type"},
+		{name: "work_type empty on landfill", body: landfillBody("work_type", ""), wantStatus: http.StatusCreated,
+			check: func(c model.Contract) bool { return c.WorkType == "" }},
+		{name: "work_type blank on landfill", body: landfillBody("work_type", "   "), wantStatus: http.StatusCreated,
+			check: func(c model.Contract) bool { return c.WorkType == "" }},
+		{name: "work_type valid", body: contractorBody("work_type", "ROAD"), wantStatus: http.StatusCreated,
+			check: func(c model.Contract) bool { return c.WorkType == model.WorkTypeRoad }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatalf("encode body: %v", err)
+			}
+			recorder := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(recorder)
+			c.Request = httptest.NewRequest(http.MethodPost, "/contracts", bytes.NewReader(raw))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Set("principal", principal)
+
+			h.createContract(c)
+
+			if recorder.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.wantStatus, recorder.Body.String())
+			}
+			var body struct {
+				Data   model.Contract `json:"data"`
+				Fields []struct {
+					Field string `json:"field"`
+				} `json:"fields"`
+			}
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("decode body %s: %v", recorder.Body.String(), err)
+			}
+			if tt.wantField != "" {
+				// пустая строка не доходит до разбора UUID: ошибка — «поле обязательно»
+				if len(body.Fields) == 0 || body.Fields[0].Field != tt.wantField {
+					t.Fatalf("fields = %+v, want %s: %s", body.Fields, tt.wantField, recorder.Body.String())
+				}
+			}
+			if tt.check != nil && !tt.check(body.Data) {
+				t.Fatalf("unexpected contract: %s", recorder.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package http
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/nurpe/snowops-contract/internal/http/response"
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+	"github.com/nurpe/snowops-contract/internal/service"
+)
+
+var snakeCaseName = regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)*$`)
+
+// responseTypes — типы, которые отдаются в ответах API (вложенные проверяются
+// рекурсивно). Новый тип ответа нужно добавить сюда.
+var responseTypes = []reflect.Type{
+	reflect.TypeOf(model.Contract{}),
+	reflect.TypeOf(model.AccessibleContract{}),
+	reflect.TypeOf(model.LandfillPolygonCoverage{}),
+	reflect.TypeOf(model.ContractAuditEntry{}),
+	reflect.TypeOf(model.ContractAmendment{}),
+	reflect.TypeOf(model.UsageAdjustment{}),
+	reflect.TypeOf(model.UsageLedgerEntry{}),
+	reflect.TypeOf(model.TripUsageLogEntry{}),
+	reflect.TypeOf(model.ContractTicket{}),
+	reflect.TypeOf(model.ContractTrip{}),
+	reflect.TypeOf(model.ContractFilterOptions{}),
+	reflect.TypeOf(flatContract{}),
+	reflect.TypeOf(repository.ContractDependencies{}),
+	reflect.TypeOf(response.ItemResult{}),
+	reflect.TypeOf(service.BatchGetResult{}),
+	reflect.TypeOf(service.BudgetChangePreview{}),
+	reflect.TypeOf(service.BulkDeactivateResult{}),
+	reflect.TypeOf(service.CapacityEstimate{}),
+	reflect.TypeOf(service.ContractorMonthlySpend{}),
+	reflect.TypeOf(service.ContractorPerformanceReport{}),
+	reflect.TypeOf(service.ContractSnapshot{}),
+	reflect.TypeOf(service.CostPreview{}),
+	reflect.TypeOf(service.PayableBreakdown{}),
+	reflect.TypeOf(service.PlateMismatchSummary{}),
+	reflect.TypeOf(service.PolygonConflictReport{}),
+	reflect.TypeOf(service.ReconcileTicketContractResult{}),
+	reflect.TypeOf(service.RecomputeStatusesResult{}),
+	reflect.TypeOf(service.UsageConsistencyReport{}),
+	reflect.TypeOf(service.UtilizationDistribution{}),
+}
+
+// Имена полей — часть контракта API: camelCase или поле без json-тега
+// (Go-имя в ответе) ломают клиентов.
+func TestResponseJSONNamesAreSnakeCase(t *testing.T) {
+	for _, typ := range responseTypes {
+		if err := checkSnakeCaseJSON(typ, map[reflect.Type]bool{}); err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestCheckSnakeCaseJSONRejectsBadNames(t *testing.T) {
+	type nested struct {
+		TotalCost float64 `json:"totalCost"`
+	}
+	tests := []struct {
+		name string
+		typ  reflect.Type
+	}{
+		{name: "camelCase tag", typ: reflect.TypeOf(struct {
+			BudgetTotal float64 `json:"budgetTotal"`
+		}{})},
+		{name: "missing tag", typ: reflect.TypeOf(struct {
+			BudgetTotal float64
+		}{})},
+		{name: "nested slice element", typ: reflect.TypeOf(struct {
+			Items []nested `json:"items"`
+		}{})},
+	}
+	for _, tt := range tests {
+		if err := checkSnakeCaseJSON(tt.typ, map[reflect.Type]bool{}); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+// checkSnakeCaseJSON проверяет, что у всех экспортируемых полей структуры (и
+// вложенных структур) есть json-тег в snake_case.
+func checkSnakeCaseJSON(t reflect.Type, seen map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || seen[t] || t.PkgPath() == "time" {
+		return nil
+	}
+	seen[t] = true
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			if err := checkSnakeCaseJSON(field.Type, seen); err != nil {
+				return err
+			}
+			continue
+		}
+		if !snakeCaseName.MatchString(name) {
+			return fmt.Errorf("%s.%s: json name %q is not snake_case", t.Name(), field.Name, name)
+		}
+		if err := checkSnakeCaseJSON(field.Type, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
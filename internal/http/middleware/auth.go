@@ -5,8 +5,11 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog"
 
 	"github.com/nurpe/snowops-contract/internal/auth"
+	"github.com/nurpe/snowops-contract/internal/http/response"
+	"github.com/nurpe/snowops-contract/internal/metrics"
 	"github.com/nurpe/snowops-contract/internal/model"
 )
 
@@ -17,23 +20,30 @@ const (
 	bearerPrefix        = "Bearer"
 )
 
-func Auth(parser *auth.Parser) gin.HandlerFunc {
+type AuthConfig struct {
+	// DisableTooAdmin — отклонять (403) токены устаревшей роли TOO_ADMIN;
+	// по умолчанию она работает как LANDFILL_ADMIN.
+	DisableTooAdmin bool
+}
+
+func Auth(parser *auth.Parser, cfg AuthConfig, log zerolog.Logger) gin.HandlerFunc {
+	deprecated := newDeprecatedRoleLogger(log)
 	return func(c *gin.Context) {
 		rawHeader := c.GetHeader(authorizationHeader)
 		if rawHeader == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "authorization header missing"})
+			response.Abort(c, http.StatusUnauthorized, "authorization header missing")
 			return
 		}
 
 		parts := strings.SplitN(rawHeader, " ", 2)
 		if len(parts) != 2 || !strings.EqualFold(parts[0], bearerPrefix) {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
+			response.Abort(c, http.StatusUnauthorized, "invalid authorization header")
 			return
 		}
 
 		claims, err := parser.Parse(parts[1])
 		if err != nil {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			response.Abort(c, http.StatusUnauthorized, "invalid token")
 			return
 		}
 
@@ -43,6 +53,15 @@ func Auth(parser *auth.Parser) gin.HandlerFunc {
 			Role:           claims.Role,
 		}
 
+		if principal.IsToo() {
+			metrics.DeprecatedRoleRequests.WithLabelValues(string(principal.Role)).Inc()
+			deprecated.warn(principal, cfg.DisableTooAdmin)
+			if cfg.DisableTooAdmin {
+				response.Abort(c, http.StatusForbidden, "role TOO_ADMIN is disabled, use LANDFILL_ADMIN")
+				return
+			}
+		}
+
 		c.Set(claimsContextKey, claims)
 		c.Set(principalContextKey, principal)
 		c.Next()
@@ -62,4 +81,3 @@ func MustPrincipal(c *gin.Context) (model.Principal, bool) {
 
 	return principal, true
 }
-
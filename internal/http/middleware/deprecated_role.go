@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+const (
+	// deprecatedRoleLogInterval — не чаще одного предупреждения на пользователя за интервал
+	deprecatedRoleLogInterval = time.Hour
+	// deprecatedRoleLogMaxUsers — порог, после которого из памяти вычищаются устаревшие записи
+	deprecatedRoleLogMaxUsers = 10000
+)
+
+// deprecatedRoleLogger пишет предупреждение об устаревшей роли один раз на
+// пользователя за deprecatedRoleLogInterval, чтобы отследить оставшихся
+// пользователей TOO_ADMIN, не засоряя лог.
+type deprecatedRoleLogger struct {
+	log  zerolog.Logger
+	mu   sync.Mutex
+	seen map[uuid.UUID]time.Time
+	now  func() time.Time
+}
+
+func newDeprecatedRoleLogger(log zerolog.Logger) *deprecatedRoleLogger {
+	return &deprecatedRoleLogger{
+		log:  log,
+		seen: make(map[uuid.UUID]time.Time),
+		now:  time.Now,
+	}
+}
+
+func (l *deprecatedRoleLogger) warn(principal model.Principal, disabled bool) {
+	now := l.now()
+
+	l.mu.Lock()
+	if last, ok := l.seen[principal.UserID]; ok && now.Sub(last) < deprecatedRoleLogInterval {
+		l.mu.Unlock()
+		return
+	}
+	if len(l.seen) >= deprecatedRoleLogMaxUsers {
+		for userID, last := range l.seen {
+			if now.Sub(last) >= deprecatedRoleLogInterval {
+				delete(l.seen, userID)
+			}
+		}
+	}
+	l.seen[principal.UserID] = now
+	l.mu.Unlock()
+
+	l.log.Warn().
+		Str("role", string(principal.Role)).
+		Str("user_id", principal.UserID.String()).
+		Str("org_id", principal.OrganizationID.String()).
+		Str("replacement", string(model.UserRoleLandfillAdmin)).
+		Bool("disabled", disabled).
+		Msg("deprecated role used")
+}
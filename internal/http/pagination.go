@@ -0,0 +1,28 @@
+package http
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+var errInvalidPagination = errors.New("invalid limit or offset")
+
+// parsePagination читает limit/offset; ноль означает значение по умолчанию сервиса.
+func parsePagination(c *gin.Context) (limit, offset int, err error) {
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, err = strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return 0, 0, errInvalidPagination
+		}
+	}
+	if raw := strings.TrimSpace(c.Query("offset")); raw != "" {
+		offset, err = strconv.Atoi(raw)
+		if err != nil || offset < 0 {
+			return 0, 0, errInvalidPagination
+		}
+	}
+	return limit, offset, nil
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nurpe/snowops-contract/internal/http/response"
+)
+
+// strictQueryHeader включает строгую проверку query-параметров для одного
+// запроса, даже если она выключена в конфигурации.
+const strictQueryHeader = "X-Strict-Query-Params"
+
+// contractListFilterParams — фильтры и сортировка списка; если ни один не
+// передан, сервис применяет пресет роли.
+var contractListFilterParams = []string{
+	"contractor_id",
+	"landfill_id",
+	"contract_type",
+	"work_type",
+	"created_by_user",
+	"status",
+	"only_active",
+	"writable_only",
+	"perspective",
+	"budget_exceeded",
+	"utilization_gte",
+	"utilization_lte",
+	"start_from",
+	"start_to",
+	"end_from",
+	"end_to",
+	"include_deleted",
+	"as_of",
+	"sort_by",
+	"sort_dir",
+}
+
+var contractListQueryParams = append([]string{
+	"include_usage",
+	"fields",
+	"flat",
+	"limit",
+	"cursor",
+}, contractListFilterParams...)
+
+var contractExportQueryParams = append([]string{
+	"format",
+	"locale",
+}, contractListFilterParams...)
+
+// hasAnyQueryParam — передан ли хотя бы один из параметров.
+func hasAnyQueryParam(c *gin.Context, names []string) bool {
+	query := c.Request.URL.Query()
+	for _, name := range names {
+		if _, ok := query[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// knownQueryParams — допустимые query-параметры по маршрутам ("METHOD FullPath").
+// Маршрута нет в списке — параметров у него нет. При добавлении параметра в
+// обработчик его нужно добавить и сюда.
+var knownQueryParams = map[string][]string{
+	http.MethodGet + " /contracts":                              contractListQueryParams,
+	http.MethodGet + " /contracts/export":                       contractExportQueryParams,
+	http.MethodGet + " /contracts/:id":                          {"flat", "as_of"},
+	http.MethodGet + " /contracts/:id/cost-preview":             {"volume"},
+	http.MethodGet + " /contracts/:id/budget-change-preview":    {"new_budget"},
+	http.MethodGet + " /contracts/:id/tickets":                  {"status", "from", "to", "limit", "offset"},
+	http.MethodGet + " /contracts/:id/trips":                    {"completed", "plate_mismatch"},
+	http.MethodGet + " /contracts/:id/audit":                    {"limit", "offset", "action", "actor_user_id", "actor_org_id", "from", "to"},
+	http.MethodDelete + " /contracts/:id":                       {"force", "purge"},
+	http.MethodPost + " /contracts/restore":                     {"overwrite", "restore_ledger"},
+	http.MethodPost + " /tickets/:ticket_id/reconcile-contract": {"apply"},
+	http.MethodPost + " /trips/usage":                           {"force_over_budget"},
+	http.MethodPost + " /trips/usage/batch":                     {"force_over_budget"},
+	http.MethodGet + " /reports/utilization-distribution":       {"buckets"},
+	http.MethodGet + " /reports/contractor-performance":         {"end_from", "end_to", "sort_by"},
+	http.MethodGet + " /contractors/:id/monthly-spend":          {"year"},
+}
+
+// rejectUnknownQueryParams отвечает 400 на неизвестные query-параметры (например,
+// опечатку contracter_id), если строгий режим включён конфигом или заголовком.
+func (h *Handler) rejectUnknownQueryParams(c *gin.Context) {
+	if !h.cfg.StrictQueryParams && !parseBoolQuery(c.GetHeader(strictQueryHeader)) {
+		c.Next()
+		return
+	}
+
+	allowed := make(map[string]struct{})
+	for _, name := range knownQueryParams[c.Request.Method+" "+c.FullPath()] {
+		allowed[name] = struct{}{}
+	}
+
+	var unknown []string
+	for name := range c.Request.URL.Query() {
+		if _, ok := allowed[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		response.Abort(c, http.StatusBadRequest, "unknown query parameter: "+unknown[0])
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nurpe/snowops-contract/internal/http/middleware"
+	"github.com/nurpe/snowops-contract/internal/http/response"
+)
+
+const readOnlyRoutePath = "/maintenance/read-only"
+
+// readOnlyExemptRoutes — не-GET маршруты, которые ничего не меняют в данных
+// или нужны для выхода из режима обслуживания.
+var readOnlyExemptRoutes = map[string]struct{}{
+	http.MethodPost + " /contracts/batch-get":      {},
+	http.MethodPost + " /polygons/check-conflicts": {},
+	http.MethodPut + " " + readOnlyRoutePath:       {},
+}
+
+// rejectWritesInReadOnly отклоняет изменяющие запросы с 503, пока включён
+// режим обслуживания.
+func (h *Handler) rejectWritesInReadOnly(c *gin.Context) {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		c.Next()
+		return
+	}
+	if _, ok := readOnlyExemptRoutes[c.Request.Method+" "+c.FullPath()]; ok {
+		c.Next()
+		return
+	}
+	if h.contracts.ReadOnly(c.Request.Context()) {
+		response.Abort(c, http.StatusServiceUnavailable, "service is in read-only maintenance mode, writes are temporarily disabled")
+		return
+	}
+	c.Next()
+}
+
+type readOnlyState struct {
+	Enabled bool `json:"enabled"`
+}
+
+func (h *Handler) getReadOnlyMode(c *gin.Context) {
+	response.Success(c, http.StatusOK, readOnlyState{Enabled: h.contracts.ReadOnly(c.Request.Context())})
+}
+
+type setReadOnlyRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+func (h *Handler) setReadOnlyMode(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	var req setReadOnlyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	if err := h.contracts.SetReadOnly(c.Request.Context(), principal, *req.Enabled); err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	response.Success(c, http.StatusOK, readOnlyState{Enabled: *req.Enabled})
+}
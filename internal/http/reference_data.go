@@ -0,0 +1,36 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nurpe/snowops-contract/internal/http/response"
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// referenceData — значения перечислений, которые принимает и возвращает API.
+// Собирается из констант model, чтобы фронтенд не хардкодил списки.
+type referenceData struct {
+	ContractTypes        []model.ContractType        `json:"contract_types"`
+	WorkTypes            []model.WorkType            `json:"work_types"`
+	ContractStatuses     []model.ContractUIStatus    `json:"contract_statuses"`
+	ContractResults      []model.ContractResult      `json:"contract_results"`
+	ContractPerspectives []model.ContractPerspective `json:"contract_perspectives"`
+	TicketStatuses       []model.TicketStatus        `json:"ticket_statuses"`
+	VolumeUnits          []model.VolumeUnit          `json:"volume_units"`
+	Roles                []model.UserRole            `json:"roles"`
+}
+
+func (h *Handler) getReferenceData(c *gin.Context) {
+	response.Success(c, http.StatusOK, referenceData{
+		ContractTypes:        model.ContractTypes(),
+		WorkTypes:            h.contracts.WorkTypes(),
+		ContractStatuses:     model.ContractUIStatuses(),
+		ContractResults:      model.ContractResults(),
+		ContractPerspectives: model.ContractPerspectives(),
+		TicketStatuses:       model.TicketStatuses(),
+		VolumeUnits:          model.VolumeUnits(),
+		Roles:                model.UserRoles(),
+	})
+}
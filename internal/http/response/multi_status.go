@@ -0,0 +1,26 @@
+package response
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ItemResult — результат одного элемента пакетного запроса.
+type ItemResult struct {
+	Index  int    `json:"index"`
+	Status int    `json:"status"`
+	ID     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// MultiStatus отвечает 207 с массивом результатов по элементам пакета — клиент
+// разбирает частичные сбои по status каждого элемента, а не по статусу ответа.
+func MultiStatus(c *gin.Context, items []ItemResult) {
+	for i := range items {
+		if items[i].Error != "" {
+			items[i].Error = Localize(c, items[i].Error)
+		}
+	}
+	Success(c, http.StatusMultiStatus, items)
+}
@@ -0,0 +1,182 @@
+// Package response формирует JSON-конверты ответов для всех версий API.
+//
+// v1 (по умолчанию): {"data": ...} и {"error": "message"}.
+// v2 (Accept: application/vnd.snowops.v2+json): {"data": ..., "meta": {...}} и
+// {"error": {"code", "message"}, "meta": {...}}; пагинация и next_cursor
+// переезжают в meta. Ошибки проверки полей добавляют массив fields: в v1 —
+// рядом с error, в v2 — внутри error.
+//
+// Сообщения ошибок переводятся по Accept-Language (см. пакет i18n); code v2
+// от языка не зависит.
+package response
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nurpe/snowops-contract/internal/i18n"
+	"github.com/nurpe/snowops-contract/internal/logger"
+)
+
+// MediaTypeV2 — значение Accept, которым клиент выбирает конверт v2.
+const MediaTypeV2 = "application/vnd.snowops.v2+json"
+
+// Pagination — метаданные страницы списков с пагинацией.
+type Pagination struct {
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+}
+
+type meta struct {
+	APIVersion string      `json:"api_version"`
+	RequestID  string      `json:"request_id,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+}
+
+// FieldError — ошибка одного поля запроса в массиве fields.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type errorBody struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// IsV2 — клиент запросил конверт v2.
+func IsV2(c *gin.Context) bool {
+	return strings.Contains(c.GetHeader("Accept"), MediaTypeV2)
+}
+
+// Success отвечает данными в конверте версии запроса.
+func Success(c *gin.Context, status int, data interface{}) {
+	write(c, status, data, nil, "")
+}
+
+// Paginated — Success со страницей: в v1 пагинация лежит рядом с data, в v2 — в meta.
+func Paginated(c *gin.Context, status int, data interface{}, page Pagination) {
+	write(c, status, data, &page, "")
+}
+
+// CursorPaginated — Success со страницей keyset-пагинации: next_cursor в v1
+// лежит рядом с data, в v2 — в meta; на последней странице его нет.
+func CursorPaginated(c *gin.Context, status int, data interface{}, nextCursor string) {
+	write(c, status, data, nil, nextCursor)
+}
+
+// Error отвечает ошибкой в конверте версии запроса; сообщение — на языке клиента.
+func Error(c *gin.Context, status int, message string) {
+	setV2ContentType(c)
+	c.JSON(status, errorEnvelope(c, status, Localize(c, message), nil))
+}
+
+// ErrorWithFields — Error с ошибками отдельных полей; сообщения полей тоже
+// переводятся.
+func ErrorWithFields(c *gin.Context, status int, message string, fields []FieldError) {
+	setV2ContentType(c)
+	localized := make([]FieldError, len(fields))
+	for i, field := range fields {
+		localized[i] = FieldError{Field: field.Field, Message: Localize(c, field.Message)}
+	}
+	c.JSON(status, errorEnvelope(c, status, Localize(c, message), localized))
+}
+
+// Localize переводит сообщение (ошибку, предупреждение) на язык из
+// Accept-Language запроса и выставляет Content-Language ответа.
+func Localize(c *gin.Context, message string) string {
+	lang := Language(c)
+	if !c.Writer.Written() {
+		c.Header("Content-Language", string(lang))
+	}
+	return i18n.Translate(lang, message)
+}
+
+// Language — язык сообщений, выбранный клиентом заголовком Accept-Language.
+func Language(c *gin.Context) i18n.Lang {
+	return i18n.FromAcceptLanguage(c.GetHeader("Accept-Language"))
+}
+
+// Abort — Error с прерыванием цепочки middleware.
+func Abort(c *gin.Context, status int, message string) {
+	c.Abort()
+	Error(c, status, message)
+}
+
+func write(c *gin.Context, status int, data interface{}, page *Pagination, nextCursor string) {
+	if !IsV2(c) {
+		body := gin.H{"data": data}
+		if page != nil {
+			body["pagination"] = page
+		}
+		if nextCursor != "" {
+			body["next_cursor"] = nextCursor
+		}
+		c.JSON(status, body)
+		return
+	}
+
+	setV2ContentType(c)
+	pageMeta := newMeta(c, page)
+	pageMeta.NextCursor = nextCursor
+	c.JSON(status, gin.H{
+		"data": data,
+		"meta": pageMeta,
+	})
+}
+
+func errorEnvelope(c *gin.Context, status int, message string, fields []FieldError) gin.H {
+	if !IsV2(c) {
+		body := gin.H{"error": message}
+		if len(fields) > 0 {
+			body["fields"] = fields
+		}
+		return body
+	}
+	return gin.H{
+		"error": errorBody{Code: errorCode(status), Message: message, Fields: fields},
+		"meta":  newMeta(c, nil),
+	}
+}
+
+func newMeta(c *gin.Context, page *Pagination) meta {
+	return meta{
+		APIVersion: "v2",
+		RequestID:  logger.RequestIDFromContext(c.Request.Context()),
+		Pagination: page,
+	}
+}
+
+// setV2ContentType выставляется до записи тела: gin не перезаписывает Content-Type.
+func setV2ContentType(c *gin.Context) {
+	if IsV2(c) && !c.Writer.Written() {
+		c.Header("Content-Type", MediaTypeV2+"; charset=utf-8")
+	}
+}
+
+// errorCode — машиночитаемый код ошибки v2 по HTTP-статусу.
+func errorCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return "invalid_input"
+	case http.StatusUnauthorized:
+		return "unauthorized"
+	case http.StatusForbidden:
+		return "permission_denied"
+	case http.StatusNotFound:
+		return "not_found"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusServiceUnavailable:
+		return "unavailable"
+	case http.StatusInternalServerError:
+		return "internal"
+	default:
+		return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+	}
+}
@@ -6,6 +6,12 @@ import (
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/nurpe/snowops-contract/internal/http/middleware"
+	"github.com/nurpe/snowops-contract/internal/http/response"
+	"github.com/nurpe/snowops-contract/internal/version"
 )
 
 func NewRouter(handler *Handler, authMiddleware gin.HandlerFunc, env string) *gin.Engine {
@@ -15,12 +21,15 @@ func NewRouter(handler *Handler, authMiddleware gin.HandlerFunc, env string) *gi
 
 	router := gin.New()
 	router.Use(gin.Recovery())
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
 	router.Use(cors.New(cors.Config{
 		AllowAllOrigins: true,
 		AllowMethods:    []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowHeaders:    []string{"*"},
 		ExposeHeaders: []string{
 			"Content-Type",
+			"X-Request-ID",
 		},
 		MaxAge: 12 * time.Hour,
 	}))
@@ -29,7 +38,38 @@ func NewRouter(handler *Handler, authMiddleware gin.HandlerFunc, env string) *gi
 		c.JSON(http.StatusOK, gin.H{"status": "ok"})
 	})
 
+	router.GET("/", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"service": version.ServiceName,
+			"version": version.Version,
+		})
+	})
+
+	router.GET("/version", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"version": version.Version,
+			"commit":  version.Commit,
+		})
+	})
+
+	router.NoRoute(func(c *gin.Context) {
+		response.Error(c, http.StatusNotFound, "route not found")
+	})
+
 	handler.Register(router, authMiddleware)
 
 	return router
 }
+
+// NewMetricsHandler отдаёт метрики Prometheus. Обработчик не входит в публичный
+// роутер: его обслуживает отдельный внутренний listener (HTTP_METRICS_ADDR).
+func NewMetricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	// Ошибка одного коллектора (например, недоступна база для
+	// contract_budget_exceeded_total) не должна скрывать остальные метрики.
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError}),
+	))
+	return mux
+}
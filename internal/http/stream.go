@@ -0,0 +1,140 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/nurpe/snowops-contract/internal/http/middleware"
+	"github.com/nurpe/snowops-contract/internal/http/response"
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/service"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+func acceptsNDJSON(c *gin.Context) bool {
+	return strings.Contains(strings.ToLower(c.GetHeader("Accept")), ndjsonContentType)
+}
+
+// streamContracts пишет по одному контракту в строке (NDJSON), сбрасывая буфер
+// после каждой записи. Ошибки до первой строки возвращаются обычным JSON-ответом;
+// после начала потока запрос можно только оборвать.
+func (h *Handler) streamContracts(c *gin.Context, principal model.Principal, input service.ListContractsInput, fields []string, flat bool) {
+	started := false
+	encoder := json.NewEncoder(c.Writer)
+
+	err := h.contracts.StreamList(c.Request.Context(), principal, input, func(contract model.Contract) error {
+		if !started {
+			c.Header("Content-Type", ndjsonContentType)
+			c.Status(http.StatusOK)
+			started = true
+		}
+
+		var item interface{} = contract
+		if flat {
+			item = flattenContract(contract)
+		}
+		if fields != nil {
+			trimmed, err := selectItemFields(item, fields)
+			if err != nil {
+				return err
+			}
+			item = trimmed
+		}
+
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+
+	switch {
+	case err != nil && !started:
+		h.handleError(c, err)
+	case err != nil:
+		h.log.Error().Err(err).Msg("contract stream aborted")
+	case !started:
+		c.Header("Content-Type", ndjsonContentType)
+		c.Status(http.StatusOK)
+		c.Writer.WriteHeaderNow()
+	}
+}
+
+// usageStreamHeartbeat — период комментариев-пингов SSE, чтобы прокси не
+// закрывали простаивающее соединение.
+const usageStreamHeartbeat = 15 * time.Second
+
+// streamContractUsage — SSE: событие usage сразу после подключения и затем
+// при каждом изменении usage контракта.
+func (h *Handler) streamContractUsage(c *gin.Context) {
+	principal, ok := middleware.MustPrincipal(c)
+	if !ok {
+		response.Error(c, http.StatusUnauthorized, "missing principal")
+		return
+	}
+
+	contractID, err := parseUUIDParam(c, "id", "contract_id")
+	if err != nil {
+		response.Error(c, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	subscription, err := h.contracts.SubscribeUsage(c.Request.Context(), principal, contractID)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	defer subscription.Cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	if subscription.Current != nil {
+		if err := writeSSE(c, "usage", subscription.Current); err != nil {
+			return
+		}
+	} else {
+		c.Writer.WriteHeaderNow()
+		c.Writer.Flush()
+	}
+
+	heartbeat := time.NewTicker(usageStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case usage := <-subscription.Updates:
+			if err := writeSSE(c, "usage", usage); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(c.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func writeSSE(c *gin.Context, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// uuidFieldError — единый текст 400 для некорректного UUID в пути, query или теле.
+type uuidFieldError struct {
+	field string
+}
+
+func (e uuidFieldError) Error() string {
+	return fmt.Sprintf("invalid %s: must be a UUID", e.field)
+}
+
+func parseUUIDField(field, raw string) (uuid.UUID, error) {
+	id, err := uuid.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return uuid.Nil, uuidFieldError{field: field}
+	}
+	return id, nil
+}
+
+// parseOptionalUUIDField — пустое значение означает «не задано».
+func parseOptionalUUIDField(field string, raw *string) (*uuid.UUID, error) {
+	raw = normalizeOptional(raw)
+	if raw == nil {
+		return nil, nil
+	}
+	id, err := parseUUIDField(field, *raw)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
+
+// parseUUIDListField разбирает массив UUID; ошибка называет индекс элемента.
+func parseUUIDListField(field string, raws []string) ([]uuid.UUID, error) {
+	if raws == nil {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, 0, len(raws))
+	for i, raw := range raws {
+		id, err := parseUUIDField(fmt.Sprintf("%s[%d]", field, i), raw)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseUUIDKeyedField разбирает объект с UUID-ключами; ошибка называет ключ.
+func parseUUIDKeyedField[T any](field string, raws map[string]T) (map[uuid.UUID]T, error) {
+	if raws == nil {
+		return nil, nil
+	}
+	result := make(map[uuid.UUID]T, len(raws))
+	for key, value := range raws {
+		id, err := parseUUIDField(fmt.Sprintf("%s[%q]", field, key), key)
+		if err != nil {
+			return nil, err
+		}
+		result[id] = value
+	}
+	return result, nil
+}
+
+// parseUUIDParam разбирает параметр пути; field — имя в тексте ошибки.
+func parseUUIDParam(c *gin.Context, param, field string) (uuid.UUID, error) {
+	return parseUUIDField(field, c.Param(param))
+}
+
+// parseUUIDQuery разбирает необязательный query-параметр.
+func parseUUIDQuery(c *gin.Context, name string) (*uuid.UUID, error) {
+	raw := c.Query(name)
+	return parseOptionalUUIDField(name, &raw)
+}
@@ -0,0 +1,222 @@
+package i18n
+
+import "regexp"
+
+type translations map[Lang]string
+
+// messages — точные переводы частей сообщений. Ключ — английский текст,
+// как его формируют handler, middleware и сервис.
+var messages = map[string]translations{
+	// общие ошибки сервиса
+	"not found":         {Russian: "не найдено", Kazakh: "табылмады"},
+	"permission denied": {Russian: "доступ запрещён", Kazakh: "рұқсат жоқ"},
+	"invalid input":     {Russian: "некорректные данные", Kazakh: "деректер қате"},
+	"conflict":          {Russian: "конфликт", Kazakh: "қайшылық"},
+	"batch aborted":     {Russian: "пакет отменён", Kazakh: "пакет тоқтатылды"},
+	"internal error":    {Russian: "внутренняя ошибка", Kazakh: "ішкі қате"},
+	"route not found":   {Russian: "маршрут не найден", Kazakh: "маршрут табылмады"},
+
+	// аутентификация и режимы
+	"missing principal":            {Russian: "пользователь не определён", Kazakh: "пайдаланушы анықталмады"},
+	"authorization header missing": {Russian: "отсутствует заголовок Authorization", Kazakh: "Authorization тақырыбы жоқ"},
+	"invalid authorization header": {Russian: "некорректный заголовок Authorization", Kazakh: "Authorization тақырыбы қате"},
+	"invalid token":                {Russian: "недействительный токен", Kazakh: "токен жарамсыз"},
+	"role TOO_ADMIN is disabled, use LANDFILL_ADMIN": {
+		Russian: "роль TOO_ADMIN отключена, используйте LANDFILL_ADMIN",
+		Kazakh:  "TOO_ADMIN рөлі өшірілген, LANDFILL_ADMIN қолданыңыз",
+	},
+	"service is in read-only maintenance mode, writes are temporarily disabled": {
+		Russian: "сервис в режиме обслуживания только для чтения, запись временно недоступна",
+		Kazakh:  "сервис тек оқуға арналған қызмет көрсету режимінде, жазу уақытша қолжетімсіз",
+	},
+
+	// параметры запроса
+	"unknown query parameter":       {Russian: "неизвестный параметр запроса", Kazakh: "белгісіз сұрау параметрі"},
+	"must be a UUID":                {Russian: "должен быть UUID", Kazakh: "UUID болуы керек"},
+	"invalid start_at format":       {Russian: "некорректный формат start_at", Kazakh: "start_at пішімі қате"},
+	"invalid end_at format":         {Russian: "некорректный формат end_at", Kazakh: "end_at пішімі қате"},
+	"invalid time format":           {Russian: "некорректный формат времени", Kazakh: "уақыт пішімі қате"},
+	"invalid limit or offset":       {Russian: "некорректные limit или offset", Kazakh: "limit немесе offset қате"},
+	"client_reference is too long":  {Russian: "client_reference слишком длинный", Kazakh: "client_reference тым ұзын"},
+	"Idempotency-Key is too long":   {Russian: "Idempotency-Key слишком длинный", Kazakh: "Idempotency-Key тым ұзын"},
+	"idempotency key already used":  {Russian: "ключ идемпотентности уже использован", Kazakh: "идемпотенттілік кілті бұрын қолданылған"},
+	"from must not be after to":     {Russian: "from не может быть позже to", Kazakh: "from мәні to мәнінен кейін болмауы керек"},
+	"polygon_ids contains nil uuid": {Russian: "polygon_ids содержит нулевой UUID", Kazakh: "polygon_ids ішінде нөлдік UUID бар"},
+	"cursor does not match sort order": {
+		Russian: "курсор не соответствует порядку сортировки",
+		Kazakh:  "курсор сұрыптау ретіне сәйкес келмейді",
+	},
+	"utilization_gte must not exceed utilization_lte": {
+		Russian: "utilization_gte не может быть больше utilization_lte",
+		Kazakh:  "utilization_gte мәні utilization_lte мәнінен артық болмауы керек",
+	},
+	"idempotency key already used with a different request body": {
+		Russian: "ключ идемпотентности уже использован с другим телом запроса",
+		Kazakh:  "идемпотенттілік кілті басқа сұрау денесімен қолданылған",
+	},
+
+	// контракты и usage
+	"contract is locked":                             {Russian: "контракт заблокирован", Kazakh: "келісімшарт бұғатталған"},
+	"contract already exists":                        {Russian: "контракт уже существует", Kazakh: "келісімшарт бұрыннан бар"},
+	"contract with client reference already exists":  {Russian: "контракт с таким client_reference уже существует", Kazakh: "мұндай client_reference бар келісімшарт бұрыннан бар"},
+	"active contract limit reached for organization": {Russian: "достигнут лимит активных контрактов организации", Kazakh: "ұйымның белсенді келісімшарттар шегіне жетті"},
+	"trip usage already recorded":                    {Russian: "рейс уже учтён", Kazakh: "рейс бұрын есепке алынған"},
+	"usage totals would become negative":             {Russian: "итоги usage стали бы отрицательными", Kazakh: "usage қорытындысы теріс болып кетеді"},
+	"detected_volume_m3 must be greater than 0":      {Russian: "detected_volume_m3 должен быть больше 0", Kazakh: "detected_volume_m3 0-ден үлкен болуы керек"},
+	"detected_volume_m3 must be at least 0.01 m3":    {Russian: "detected_volume_m3 должен быть не меньше 0.01 м3", Kazakh: "detected_volume_m3 кемінде 0.01 м3 болуы керек"},
+	"ticket not found":                               {Russian: "тикет не найден", Kazakh: "тикет табылмады"},
+	"ticket is not linked to any contract":           {Russian: "тикет не привязан к контракту", Kazakh: "тикет ешбір келісімшартқа байланбаған"},
+	"ticket already linked to a different contract":  {Russian: "тикет уже привязан к другому контракту", Kazakh: "тикет басқа келісімшартқа байланған"},
+	"ticket has recorded trip usage":                 {Russian: "по тикету уже учтены рейсы", Kazakh: "тикет бойынша рейстер есепке алынған"},
+	"ticket was relinked to another contract":        {Russian: "тикет перепривязан к другому контракту", Kazakh: "тикет басқа келісімшартқа қайта байланған"},
+	"contract is not deleted":                        {Russian: "контракт не удалён", Kazakh: "келісімшарт жойылмаған"},
+	"purge requires force":                           {Russian: "purge требует force", Kazakh: "purge үшін force керек"},
+
+	"budget_total must be positive":      {Russian: "budget_total должен быть положительным", Kazakh: "budget_total оң болуы керек"},
+	"no fields to update":                {Russian: "нет полей для изменения", Kazakh: "өзгертілетін өріс жоқ"},
+	"name is required":                   {Russian: "не указано название", Kazakh: "атауы көрсетілмеген"},
+	"reason is required":                 {Russian: "не указана причина изменения", Kazakh: "өзгерту себебі көрсетілмеген"},
+	"price_per_m3 must be positive":      {Russian: "price_per_m3 должен быть положительным", Kazakh: "price_per_m3 оң болуы керек"},
+	"minimal_volume_m3 must be positive": {Russian: "minimal_volume_m3 должен быть положительным", Kazakh: "minimal_volume_m3 оң болуы керек"},
+	"end_at must be after start_at":      {Russian: "end_at должен быть позже start_at", Kazakh: "end_at мәні start_at мәнінен кейін болуы керек"},
+	"contractor_id is required for CONTRACTOR_SERVICE": {
+		Russian: "для CONTRACTOR_SERVICE нужен contractor_id",
+		Kazakh:  "CONTRACTOR_SERVICE үшін contractor_id керек",
+	},
+	"landfill_id is required for LANDFILL_SERVICE": {
+		Russian: "для LANDFILL_SERVICE нужен landfill_id",
+		Kazakh:  "LANDFILL_SERVICE үшін landfill_id керек",
+	},
+	"polygon_ids is required for LANDFILL_SERVICE": {
+		Russian: "для LANDFILL_SERVICE нужны polygon_ids",
+		Kazakh:  "LANDFILL_SERVICE үшін polygon_ids керек",
+	},
+
+	// снимки
+	"invalid snapshot":                   {Russian: "некорректный снимок", Kazakh: "снимок қате"},
+	"snapshot has no contract":           {Russian: "в снимке нет контракта", Kazakh: "снимокта келісімшарт жоқ"},
+	"snapshot usage ledger is truncated": {Russian: "журнал usage в снимке обрезан", Kazakh: "снимоктағы usage журналы қысқартылған"},
+	"contract name is required":          {Russian: "не указано название контракта", Kazakh: "келісімшарт атауы көрсетілмеген"},
+	"contract created_by_org_id is required": {
+		Russian: "не указан created_by_org_id контракта",
+		Kazakh:  "келісімшарттың created_by_org_id көрсетілмеген",
+	},
+	"contract work_type is required": {Russian: "не указан work_type контракта", Kazakh: "келісімшарттың work_type көрсетілмеген"},
+	"contract price, budget and minimal volume must be positive": {
+		Russian: "цена, бюджет и минимальный объём контракта должны быть положительными",
+		Kazakh:  "келісімшарттың бағасы, бюджеті және ең аз көлемі оң болуы керек",
+	},
+	"contract end_at must be after start_at": {Russian: "end_at контракта должен быть позже start_at", Kazakh: "келісімшарттың end_at мәні start_at мәнінен кейін болуы керек"},
+	"CONTRACTOR_SERVICE requires contractor_id and no polygons": {
+		Russian: "CONTRACTOR_SERVICE требует contractor_id и не допускает полигонов",
+		Kazakh:  "CONTRACTOR_SERVICE үшін contractor_id керек, полигондарға жол берілмейді",
+	},
+	"LANDFILL_SERVICE requires landfill_id and polygons": {
+		Russian: "LANDFILL_SERVICE требует landfill_id и полигоны",
+		Kazakh:  "LANDFILL_SERVICE үшін landfill_id және полигондар керек",
+	},
+
+	// предупреждения create
+	"minimal_volume_m3 is unreachable within budget_total": {
+		Russian: "minimal_volume_m3 недостижим в пределах budget_total",
+		Kazakh:  "budget_total шегінде minimal_volume_m3 көлеміне жету мүмкін емес",
+	},
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	formats translations
+}
+
+// patterns — переводы частей с переменными значениями; группы подставляются
+// в формат по порядку.
+var patterns = []pattern{
+	{
+		re:      regexp.MustCompile(`^invalid ([\w.\[\]"-]+)$`),
+		formats: translations{Russian: "некорректный параметр %s", Kazakh: "%s параметрі қате"},
+	},
+	{
+		re:      regexp.MustCompile(`^contract (\S+)$`),
+		formats: translations{Russian: "контракт %s", Kazakh: "келісімшарт %s"},
+	},
+	{
+		re:      regexp.MustCompile(`^duplicate polygon_id (\S+)$`),
+		formats: translations{Russian: "повторяющийся polygon_id %s", Kazakh: "polygon_id %s қайталанады"},
+	},
+	{
+		re:      regexp.MustCompile(`^unknown audit action (".*")$`),
+		formats: translations{Russian: "неизвестное действие журнала %s", Kazakh: "журналдың белгісіз әрекеті %s"},
+	},
+	{
+		re: regexp.MustCompile(`^limit must be at most (\d+) and offset non-negative$`),
+		formats: translations{
+			Russian: "limit должен быть не больше %s, offset — неотрицательным",
+			Kazakh:  "limit %s мәнінен аспауы, offset теріс болмауы керек",
+		},
+	},
+	{
+		re:      regexp.MustCompile(`^limit must be between 1 and (\d+)$`),
+		formats: translations{Russian: "limit должен быть от 1 до %s", Kazakh: "limit 1 мен %s аралығында болуы керек"},
+	},
+	{
+		re: regexp.MustCompile(`^minimal_volume_m3 \* price_per_m3 \((\S+)\) exceeds budget_total \((\S+)\) more than (\S+)x$`),
+		formats: translations{
+			Russian: "minimal_volume_m3 * price_per_m3 (%s) превышает budget_total (%s) более чем в %s раза",
+			Kazakh:  "minimal_volume_m3 * price_per_m3 (%s) budget_total (%s) мәнінен %s еседен артық",
+		},
+	},
+	{
+		re: regexp.MustCompile(`^budget_total is below accrued cost \((\S+) < (\S+)\)$`),
+		formats: translations{
+			Russian: "budget_total ниже уже начисленной стоимости (%s < %s)",
+			Kazakh:  "budget_total есептелген құннан төмен (%s < %s)",
+		},
+	},
+	{
+		re: regexp.MustCompile(`^trip usage would exceed budget_total \((\S+) > (\S+)\)$`),
+		formats: translations{
+			Russian: "рейс превысит budget_total (%s > %s)",
+			Kazakh:  "рейс budget_total мәнінен асады (%s > %s)",
+		},
+	},
+	{
+		re: regexp.MustCompile(`^polygon budgets exceed budget_total \((\S+) > (\S+)\)$`),
+		formats: translations{
+			Russian: "бюджеты полигонов превышают budget_total (%s > %s)",
+			Kazakh:  "полигондар бюджеті budget_total мәнінен асады (%s > %s)",
+		},
+	},
+	{
+		re: regexp.MustCompile(`^per_polygon_budget has polygon (\S+) outside polygon_ids$`),
+		formats: translations{
+			Russian: "в per_polygon_budget полигон %s не из polygon_ids",
+			Kazakh:  "per_polygon_budget ішіндегі %s полигоны polygon_ids тізімінде жоқ",
+		},
+	},
+	{
+		re: regexp.MustCompile(`^per_polygon_budget for polygon (\S+) must be positive$`),
+		formats: translations{
+			Russian: "per_polygon_budget полигона %s должен быть положительным",
+			Kazakh:  "%s полигонының per_polygon_budget мәні оң болуы керек",
+		},
+	},
+	{
+		re:      regexp.MustCompile(`^usage_ledger\[(\d+)\] is incomplete$`),
+		formats: translations{Russian: "запись usage_ledger[%s] неполная", Kazakh: "usage_ledger[%s] жазбасы толық емес"},
+	},
+	{
+		re:      regexp.MustCompile(`^usage_ledger\[(\d+)\] has invalid kind$`),
+		formats: translations{Russian: "у записи usage_ledger[%s] некорректный kind", Kazakh: "usage_ledger[%s] жазбасының kind мәні қате"},
+	},
+	{
+		re:      regexp.MustCompile(`^usage_ledger\[(\d+)\] adjustment is empty$`),
+		formats: translations{Russian: "корректировка usage_ledger[%s] пустая", Kazakh: "usage_ledger[%s] түзетуі бос"},
+	},
+	{
+		re: regexp.MustCompile(`^usage_ledger\[(\d+)\] trip needs ticket_id and positive volume$`),
+		formats: translations{
+			Russian: "рейсу usage_ledger[%s] нужны ticket_id и положительный объём",
+			Kazakh:  "usage_ledger[%s] рейсіне ticket_id және оң көлем керек",
+		},
+	},
+}
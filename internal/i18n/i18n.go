@@ -0,0 +1,120 @@
+// Package i18n переводит сообщения ошибок и предупреждений API на язык клиента.
+//
+// Сервис формирует сообщения по-английски; перевод делается на границе HTTP
+// по заголовку Accept-Language. Сообщение вида "conflict: contract is locked"
+// переводится по частям, разделённым ": ", — части без перевода (имена полей,
+// значения) остаются как есть. Машиночитаемый code ответа v2 не переводится.
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lang — поддерживаемый язык сообщений.
+type Lang string
+
+const (
+	English Lang = "en"
+	Russian Lang = "ru"
+	Kazakh  Lang = "kk"
+)
+
+// Default — язык без Accept-Language или без поддерживаемого языка в нём.
+const Default = English
+
+const segmentSeparator = ": "
+
+// listSeparator разделяет сообщения нескольких полей в одной части.
+const listSeparator = "; "
+
+// FromAcceptLanguage выбирает язык по заголовку Accept-Language (RFC 9110):
+// поддерживаемый язык с наибольшим q, при равенстве — первый по порядку.
+// Регион игнорируется (ru-RU → ru).
+func FromAcceptLanguage(header string) Lang {
+	type candidate struct {
+		lang Lang
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		for _, param := range strings.Split(params, ";") {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if !ok || !strings.EqualFold(name, "q") {
+				continue
+			}
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				parsed = 0
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if lang, ok := supported(base); ok {
+			candidates = append(candidates, candidate{lang: lang, q: q})
+		}
+	}
+	if len(candidates) == 0 {
+		return Default
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+	return candidates[0].lang
+}
+
+func supported(tag string) (Lang, bool) {
+	switch Lang(tag) {
+	case English, Russian, Kazakh:
+		return Lang(tag), true
+	default:
+		return "", false
+	}
+}
+
+// Translate переводит сообщение на lang; для English и неизвестных частей
+// возвращает исходный текст.
+func Translate(lang Lang, message string) string {
+	if lang == English || message == "" {
+		return message
+	}
+	segments := strings.Split(message, segmentSeparator)
+	for i, segment := range segments {
+		items := strings.Split(segment, listSeparator)
+		for j, item := range items {
+			items[j] = translateSegment(lang, item)
+		}
+		segments[i] = strings.Join(items, listSeparator)
+	}
+	return strings.Join(segments, segmentSeparator)
+}
+
+func translateSegment(lang Lang, segment string) string {
+	if entry, ok := messages[segment]; ok {
+		if translated := entry[lang]; translated != "" {
+			return translated
+		}
+		return segment
+	}
+	for _, pattern := range patterns {
+		match := pattern.re.FindStringSubmatch(segment)
+		if match == nil {
+			continue
+		}
+		format := pattern.formats[lang]
+		if format == "" {
+			return segment
+		}
+		args := make([]any, 0, len(match)-1)
+		for _, group := range match[1:] {
+			args = append(args, group)
+		}
+		return fmt.Sprintf(format, args...)
+	}
+	return segment
+}
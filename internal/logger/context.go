@@ -0,0 +1,19 @@
+package logger
+
+import "context"
+
+type requestIDKey struct{}
+
+// WithRequestID сохраняет идентификатор запроса в контексте для логирования.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFromContext возвращает идентификатор запроса или пустую строку.
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
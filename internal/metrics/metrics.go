@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "contract"
+
+// UsageInconsistentContracts — число контрактов, у которых contract_usage
+// расходится с суммой trip_usage_log.
+var UsageInconsistentContracts = prometheus.NewGauge(prometheus.GaugeOpts{
+	Namespace: namespace,
+	Name:      "usage_inconsistent_contracts",
+	Help:      "Number of contracts whose contract_usage totals differ from the trip_usage_log sum.",
+})
+
+// UsageRowsMissing считает контракты, прочитанные без строки contract_usage.
+// Create всегда создаёт эту строку, поэтому любой рост — признак порчи данных.
+var UsageRowsMissing = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "usage_rows_missing_total",
+	Help:      "Number of times a contract was read without its contract_usage row.",
+})
+
+// UsageLoadErrors считает контракты, usage или полигоны которых не удалось прочитать.
+var UsageLoadErrors = prometheus.NewCounter(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "usage_load_errors_total",
+	Help:      "Number of times contract usage or polygons failed to load while reading a contract.",
+})
+
+// DeprecatedRoleRequests считает запросы с токенами устаревших ролей (TOO_ADMIN),
+// включая отклонённые: по нему видно, когда можно выключать роль.
+var DeprecatedRoleRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "deprecated_role_requests_total",
+	Help:      "Number of authenticated requests made with a deprecated role.",
+}, []string{"role"})
+
+// Operations считает вызовы методов сервиса по операции и результату
+// (success, invalid_input, permission_denied, not_found, conflict, error).
+var Operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: namespace,
+	Name:      "operations_total",
+	Help:      "Number of contract service operations by operation and result.",
+}, []string{"operation", "result"})
+
+// OperationDuration — длительность методов сервиса.
+var OperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: namespace,
+	Name:      "operation_duration_seconds",
+	Help:      "Duration of contract service operations.",
+	Buckets:   prometheus.DefBuckets,
+}, []string{"operation"})
+
+// budgetExceededDesc — число контрактов с total_cost выше budget_total.
+var budgetExceededDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "budget_exceeded_total"),
+	"Number of contracts whose total_cost exceeds budget_total.",
+	nil, nil,
+)
+
+// budgetExceededScrapeTimeout ограничивает запрос к базе при сборе метрик.
+const budgetExceededScrapeTimeout = 5 * time.Second
+
+// budgetExceededCollector считает contract_budget_exceeded_total при каждом
+// сборе метрик, поэтому значение не зависит от фоновых задач.
+type budgetExceededCollector struct {
+	count func(context.Context) (int64, error)
+}
+
+// NewBudgetExceededCollector — коллектор contract_budget_exceeded_total;
+// count вызывается на каждый сбор метрик. Ошибка count отдаётся как
+// невалидная метрика, и сбор завершается ошибкой.
+func NewBudgetExceededCollector(count func(context.Context) (int64, error)) prometheus.Collector {
+	return budgetExceededCollector{count: count}
+}
+
+func (c budgetExceededCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- budgetExceededDesc
+}
+
+func (c budgetExceededCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), budgetExceededScrapeTimeout)
+	defer cancel()
+	count, err := c.count(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(budgetExceededDesc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(budgetExceededDesc, prometheus.GaugeValue, float64(count))
+}
+
+func Register(reg prometheus.Registerer) {
+	reg.MustRegister(
+		UsageInconsistentContracts,
+		UsageRowsMissing,
+		UsageLoadErrors,
+		DeprecatedRoleRequests,
+		Operations,
+		OperationDuration,
+	)
+}
@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestBudgetExceededCollectorCountsOnEveryScrape(t *testing.T) {
+	count := int64(0)
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewBudgetExceededCollector(func(context.Context) (int64, error) {
+		return count, nil
+	}))
+
+	for _, want := range []int64{0, 3} {
+		count = want
+		families, err := reg.Gather()
+		if err != nil {
+			t.Fatalf("gather: %v", err)
+		}
+		if len(families) != 1 || families[0].GetName() != "contract_budget_exceeded_total" {
+			t.Fatalf("families = %v, want contract_budget_exceeded_total", families)
+		}
+		if got := families[0].GetMetric()[0].GetGauge().GetValue(); got != float64(want) {
+			t.Fatalf("contract_budget_exceeded_total = %v, want %d", got, want)
+		}
+	}
+}
+
+func TestBudgetExceededCollectorReportsQueryError(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewBudgetExceededCollector(func(context.Context) (int64, error) {
+		return 0, errors.New("database is unavailable")
+	}))
+	if _, err := reg.Gather(); err == nil {
+		t.Fatalf("gather succeeded, want collector error")
+	}
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,6 +22,21 @@ const (
 	UserRoleDriver          UserRole = "DRIVER"
 )
 
+// UserRoles возвращает все роли, которые понимает сервис.
+func UserRoles() []UserRole {
+	return []UserRole{
+		UserRoleAkimatAdmin,
+		UserRoleAkimatUser,
+		UserRoleKguZkhAdmin,
+		UserRoleKguZkhUser,
+		UserRoleTooAdmin,
+		UserRoleLandfillAdmin,
+		UserRoleLandfillUser,
+		UserRoleContractorAdmin,
+		UserRoleDriver,
+	}
+}
+
 type WorkType string
 
 const (
@@ -28,6 +45,31 @@ const (
 	WorkTypeYard     WorkType = "yard"
 )
 
+// DefaultWorkTypes — типы работ, разрешённые, если список не задан в конфигурации.
+func DefaultWorkTypes() []WorkType {
+	return []WorkType{WorkTypeRoad, WorkTypeSidewalk, WorkTypeYard}
+}
+
+// VolumeUnit — единица объёма, в которой датчик прислал значение.
+type VolumeUnit string
+
+const (
+	VolumeUnitM3     VolumeUnit = "m3"
+	VolumeUnitLiters VolumeUnit = "liters"
+)
+
+func VolumeUnits() []VolumeUnit {
+	return []VolumeUnit{VolumeUnitM3, VolumeUnitLiters}
+}
+
+// ToM3 переводит значение в кубометры.
+func (u VolumeUnit) ToM3(value float64) float64 {
+	if u == VolumeUnitLiters {
+		return value / 1000
+	}
+	return value
+}
+
 type ContractType string
 
 const (
@@ -35,11 +77,17 @@ const (
 	ContractTypeLandfillService   ContractType = "LANDFILL_SERVICE"
 )
 
+func ContractTypes() []ContractType {
+	return []ContractType{ContractTypeContractorService, ContractTypeLandfillService}
+}
+
 type Contract struct {
-	ID              uuid.UUID    `json:"id"`
-	ContractorID    *uuid.UUID   `json:"contractor_id,omitempty"` // Опционально для LANDFILL_SERVICE
-	LandfillID      *uuid.UUID   `json:"landfill_id,omitempty"`   // Для LANDFILL_SERVICE
-	CreatedByOrgID  uuid.UUID    `json:"created_by_org_id"`
+	ID             uuid.UUID  `json:"id"`
+	ContractorID   *uuid.UUID `json:"contractor_id,omitempty"` // Опционально для LANDFILL_SERVICE
+	LandfillID     *uuid.UUID `json:"landfill_id,omitempty"`   // Для LANDFILL_SERVICE
+	CreatedByOrgID uuid.UUID  `json:"created_by_org_id"`
+	// CreatedByUserID — пользователь, создавший контракт; nil у контрактов до появления колонки
+	CreatedByUserID *uuid.UUID   `json:"created_by_user_id,omitempty"`
 	ContractType    ContractType `json:"contract_type"`
 	Name            string       `json:"name"`
 	WorkType        WorkType     `json:"work_type"`
@@ -49,20 +97,167 @@ type Contract struct {
 	StartAt         time.Time    `json:"start_at"`
 	EndAt           time.Time    `json:"end_at"`
 	IsActive        bool         `json:"is_active"`
-	CreatedAt       time.Time    `json:"created_at"`
-	UpdatedAt       *time.Time   `json:"updated_at,omitempty"`
+	ClientReference *string      `json:"client_reference,omitempty"`
+	// IsLocked — контракт заморожен (например, на время сверки): изменения и usage запрещены
+	IsLocked  bool       `json:"locked"`
+	LockedBy  *uuid.UUID `json:"locked_by,omitempty"`
+	LockedAt  *time.Time `json:"locked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	// DeletedAt — момент мягкого удаления; такие контракты видны только с include_deleted
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 
 	// Relations
-	ContractorOrg  *OrganizationLookup `json:"contractor,omitempty" gorm:"-"`
-	LandfillOrg    *OrganizationLookup `json:"landfill,omitempty" gorm:"-"`
-	CreatedByOrg   *OrganizationLookup `json:"created_by_org,omitempty" gorm:"-"`
-	PolygonIDs     []uuid.UUID         `json:"polygon_ids,omitempty" gorm:"-"` // Для LANDFILL_SERVICE
-	Usage          *ContractUsage      `json:"usage,omitempty" gorm:"-"`
-	UIStatus       ContractUIStatus    `json:"ui_status" gorm:"-"`
-	Result         ContractResult      `json:"result" gorm:"-"`
-	PayableAmount  float64             `json:"payable_amount" gorm:"-"`
-	BudgetExceeded bool                `json:"budget_exceeded" gorm:"-"`
-	VolumeProgress float64             `json:"volume_progress" gorm:"-"`
+	ContractorOrg *OrganizationLookup `json:"contractor,omitempty" gorm:"-"`
+	LandfillOrg   *OrganizationLookup `json:"landfill,omitempty" gorm:"-"`
+	CreatedByOrg  *OrganizationLookup `json:"created_by_org,omitempty" gorm:"-"`
+	PolygonIDs    []uuid.UUID         `json:"polygon_ids,omitempty" gorm:"-"` // Для LANDFILL_SERVICE
+	Polygons      []ContractPolygon   `json:"polygons,omitempty" gorm:"-"`    // Для LANDFILL_SERVICE: бюджет и usage по полигонам
+	Usage         *ContractUsage      `json:"usage,omitempty" gorm:"-"`
+	UsageMissing  bool                `json:"usage_missing,omitempty" gorm:"-"` // строка contract_usage не найдена
+	// UsageLoadError — usage или полигоны не прочитаны из-за ошибки БД: суммы неполные
+	UsageLoadError bool             `json:"usage_load_error,omitempty" gorm:"-"`
+	UsageLoadErr   error            `json:"-" gorm:"-"`
+	UIStatus       ContractUIStatus `json:"ui_status" gorm:"-"`
+	// Result и поля ниже считаются из usage; если usage не загружен
+	// (include_usage=false) или не прочитан (usage_load_error), они пустые
+	// (null), а не нули: нулевые суммы выглядели бы как настоящие.
+	Result ContractResult `json:"result,omitempty" gorm:"-"`
+	// ResultTolerance — допуск недобора минимального объёма, с которым вычислен result
+	ResultTolerance float64  `json:"result_tolerance" gorm:"-"`
+	PayableAmount   *float64 `json:"payable_amount" gorm:"-"`
+	BudgetExceeded  *bool    `json:"budget_exceeded" gorm:"-"`
+	VolumeProgress  *float64 `json:"volume_progress" gorm:"-"`
+	HealthScore     *int     `json:"health_score" gorm:"-"`
+	// BudgetExhaustedAt — момент, когда накопленная стоимость достигла budget_total; nil — не достигла
+	BudgetExhaustedAt *time.Time `json:"budget_exhausted_at" gorm:"-"`
+	// EffectiveEndAt — фактическое окончание: исчерпание бюджета, если оно было раньше end_at, иначе end_at
+	EffectiveEndAt *time.Time      `json:"effective_end_at,omitempty" gorm:"-"`
+	Health         *ContractHealth `json:"health,omitempty" gorm:"-"`
+	// Warnings — замечания к условиям, не блокирующие создание (только в ответе create)
+	Warnings []string `json:"warnings,omitempty" gorm:"-"`
+}
+
+// UsageLoaded — usage контракта прочитан полностью: строка contract_usage
+// найдена или достоверно отсутствует (нулевой usage), ошибок чтения нет.
+func (c *Contract) UsageLoaded() bool {
+	return c.UsageLoadErr == nil && (c.Usage != nil || c.UsageMissing)
+}
+
+// ContractHealth — составляющие health_score (каждая 0–100) и доля прошедшего срока (0–1).
+type ContractHealth struct {
+	TimeElapsed     float64 `json:"time_elapsed"`
+	MinimumProgress float64 `json:"minimum_progress_score"`
+	Budget          float64 `json:"budget_score"`
+	Pacing          float64 `json:"pacing_score"`
+}
+
+// ContractPolygon — полигон LANDFILL_SERVICE контракта с выделенной частью бюджета
+// и накопленным по нему usage (по polygon_id рейса).
+type ContractPolygon struct {
+	PolygonID     uuid.UUID `json:"polygon_id"`
+	Budget        *float64  `json:"budget"`
+	TotalVolumeM3 float64   `json:"total_volume_m3"`
+	TotalCost     float64   `json:"total_cost"`
+	Utilization   *float64  `json:"utilization" gorm:"-"`
+}
+
+// ContractAccessRelation — причина, по которой принципал видит контракт.
+type ContractAccessRelation string
+
+const (
+	ContractAccessCreator    ContractAccessRelation = "creator"
+	ContractAccessContractor ContractAccessRelation = "contractor"
+	ContractAccessLandfill   ContractAccessRelation = "landfill"
+	ContractAccessKguScope   ContractAccessRelation = "kgu_scope"
+	ContractAccessAkimat     ContractAccessRelation = "akimat_scope"
+)
+
+// ContractSortField — допустимые поля сортировки списка контрактов.
+type ContractSortField string
+
+const (
+	ContractSortCreatedAt   ContractSortField = "created_at"
+	ContractSortStartAt     ContractSortField = "start_at"
+	ContractSortEndAt       ContractSortField = "end_at"
+	ContractSortName        ContractSortField = "name"
+	ContractSortBudgetTotal ContractSortField = "budget_total"
+)
+
+func ContractSortFields() []ContractSortField {
+	return []ContractSortField{
+		ContractSortCreatedAt,
+		ContractSortStartAt,
+		ContractSortEndAt,
+		ContractSortName,
+		ContractSortBudgetTotal,
+	}
+}
+
+// ParseContractSortField разбирает поле сортировки по allowlist.
+func ParseContractSortField(raw string) (ContractSortField, bool) {
+	value := ContractSortField(strings.ToLower(strings.TrimSpace(raw)))
+	for _, field := range ContractSortFields() {
+		if field == value {
+			return field, true
+		}
+	}
+	return "", false
+}
+
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
+func ParseSortDirection(raw string) (SortDirection, bool) {
+	value := SortDirection(strings.ToLower(strings.TrimSpace(raw)))
+	if value == SortAsc || value == SortDesc {
+		return value, true
+	}
+	return "", false
+}
+
+// LandfillPolygonCoverage — полигон и действующие контракты, которые его покрывают.
+type LandfillPolygonCoverage struct {
+	PolygonID   uuid.UUID   `json:"polygon_id"`
+	ContractIDs []uuid.UUID `json:"contract_ids"`
+}
+
+// ContractPerspective — в какой «роли» организация смотрит на список контрактов.
+type ContractPerspective string
+
+const (
+	ContractPerspectiveAll        ContractPerspective = "all"
+	ContractPerspectiveCreated    ContractPerspective = "created"
+	ContractPerspectiveContractor ContractPerspective = "contractor"
+	ContractPerspectiveLandfill   ContractPerspective = "landfill"
+)
+
+func ContractPerspectives() []ContractPerspective {
+	return []ContractPerspective{
+		ContractPerspectiveAll,
+		ContractPerspectiveCreated,
+		ContractPerspectiveContractor,
+		ContractPerspectiveLandfill,
+	}
+}
+
+// ParseContractPerspective разбирает perspective без учёта регистра и пробелов.
+func ParseContractPerspective(raw string) (ContractPerspective, bool) {
+	value := ContractPerspective(strings.ToLower(strings.TrimSpace(raw)))
+	switch value {
+	case ContractPerspectiveAll, ContractPerspectiveCreated, ContractPerspectiveContractor, ContractPerspectiveLandfill:
+		return value, true
+	}
+	return "", false
+}
+
+type AccessibleContract struct {
+	Contract
+	Relation ContractAccessRelation `json:"relation"`
 }
 
 type ContractUsage struct {
@@ -73,8 +268,142 @@ type ContractUsage struct {
 	UpdatedAt     time.Time `json:"updated_at"`
 }
 
+// AuditAction — тип события в журнале контракта.
+type AuditAction string
+
+const (
+	// AuditActionAutoDeactivated — контракт выключен фоновой задачей после end_at + grace.
+	AuditActionAutoDeactivated AuditAction = "auto_deactivated"
+	AuditActionLocked          AuditAction = "locked"
+	AuditActionUnlocked        AuditAction = "unlocked"
+	// AuditActionBulkDeactivated — контракт выключен массовой операцией по фильтру.
+	AuditActionBulkDeactivated AuditAction = "bulk_deactivated"
+	// AuditActionRestored — контракт восстановлен из снимка.
+	AuditActionRestored AuditAction = "restored"
+	// AuditActionUpdated — изменены условия контракта (PUT /contracts/:id).
+	AuditActionUpdated AuditAction = "updated"
+	// AuditActionDeleted — контракт помечен удалённым (мягкое удаление).
+	AuditActionDeleted AuditAction = "deleted"
+	// AuditActionUndeleted — мягкое удаление снято.
+	AuditActionUndeleted AuditAction = "undeleted"
+)
+
+func AuditActions() []AuditAction {
+	return []AuditAction{
+		AuditActionAutoDeactivated, AuditActionLocked, AuditActionUnlocked, AuditActionBulkDeactivated,
+		AuditActionRestored, AuditActionUpdated, AuditActionDeleted, AuditActionUndeleted,
+	}
+}
+
+// ContractAuditEntry — запись журнала изменений контракта.
+type ContractAuditEntry struct {
+	ID          uuid.UUID       `json:"id"`
+	ContractID  uuid.UUID       `json:"contract_id"`
+	Action      AuditAction     `json:"action"`
+	ActorUserID *uuid.UUID      `json:"actor_user_id"`
+	ActorOrgID  *uuid.UUID      `json:"actor_org_id"`
+	Details     json.RawMessage `json:"details"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// ContractAmendment — изменение одного условия контракта (PUT /contracts/:id).
+// Значения хранятся текстом: числа как есть, время — RFC3339 в UTC.
+type ContractAmendment struct {
+	ID         uuid.UUID `json:"id"`
+	ContractID uuid.UUID `json:"contract_id"`
+	Field      string    `json:"field"`
+	OldValue   string    `json:"old_value"`
+	NewValue   string    `json:"new_value"`
+	ChangedBy  uuid.UUID `json:"changed_by"`
+	ChangedAt  time.Time `json:"changed_at"`
+	Reason     string    `json:"reason"`
+}
+
+// UsageAdjustment — ручная корректировка usage контракта со знаком.
+type UsageAdjustment struct {
+	ID            uuid.UUID `json:"id"`
+	ContractID    uuid.UUID `json:"contract_id"`
+	VolumeDeltaM3 float64   `json:"volume_delta_m3"`
+	CostDelta     float64   `json:"cost_delta"`
+	Reason        string    `json:"reason"`
+	ActorUserID   uuid.UUID `json:"actor_user_id"`
+	ActorOrgID    uuid.UUID `json:"actor_org_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// UsageLedgerEntryKind — источник движения usage.
+type UsageLedgerEntryKind string
+
+const (
+	UsageLedgerEntryTrip       UsageLedgerEntryKind = "trip"
+	UsageLedgerEntryAdjustment UsageLedgerEntryKind = "adjustment"
+)
+
+// UsageLedgerEntry — одно движение usage контракта: рейс или ручная корректировка.
+type UsageLedgerEntry struct {
+	Kind       UsageLedgerEntryKind `json:"kind"`
+	SourceID   uuid.UUID            `json:"source_id"` // trip_id или id корректировки
+	ContractID uuid.UUID            `json:"contract_id"`
+	TicketID   *uuid.UUID           `json:"ticket_id,omitempty"`
+	VolumeM3   float64              `json:"volume_m3"`
+	Cost       float64              `json:"cost"`
+	Reason     *string              `json:"reason,omitempty"`
+	CreatedAt  time.Time            `json:"created_at"`
+}
+
+// TripUsageLogEntry — рейс, учтённый в usage контракта (строка trip_usage_log).
+type TripUsageLogEntry struct {
+	TripID           uuid.UUID `json:"trip_id"`
+	TicketID         uuid.UUID `json:"ticket_id"`
+	RecordedVolumeM3 float64   `json:"recorded_volume_m3"`
+	RecordedCost     float64   `json:"recorded_cost"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// UsageDiscrepancy описывает расхождение contract_usage с суммой trip_usage_log.
+type UsageDiscrepancy struct {
+	ContractID     uuid.UUID `json:"contract_id"`
+	UsageVolumeM3  float64   `json:"usage_volume_m3"`
+	UsageCost      float64   `json:"usage_cost"`
+	LoggedVolumeM3 float64   `json:"logged_volume_m3"`
+	LoggedCost     float64   `json:"logged_cost"`
+}
+
 type TicketStatus string
 
+// Статусы тикетов из таблицы tickets (схема snowops-tickets).
+const (
+	TicketStatusPlanned    TicketStatus = "PLANNED"
+	TicketStatusInProgress TicketStatus = "IN_PROGRESS"
+	TicketStatusCompleted  TicketStatus = "COMPLETED"
+	TicketStatusClosed     TicketStatus = "CLOSED"
+	TicketStatusCancelled  TicketStatus = "CANCELLED"
+)
+
+var ticketStatuses = []TicketStatus{
+	TicketStatusPlanned,
+	TicketStatusInProgress,
+	TicketStatusCompleted,
+	TicketStatusClosed,
+	TicketStatusCancelled,
+}
+
+// TicketStatuses возвращает все известные статусы тикетов.
+func TicketStatuses() []TicketStatus {
+	return append([]TicketStatus(nil), ticketStatuses...)
+}
+
+// ParseTicketStatus разбирает статус без учёта регистра и пробелов.
+func ParseTicketStatus(raw string) (TicketStatus, bool) {
+	value := TicketStatus(strings.ToUpper(strings.TrimSpace(raw)))
+	for _, status := range ticketStatuses {
+		if status == value {
+			return status, true
+		}
+	}
+	return "", false
+}
+
 type ContractTicket struct {
 	ID                uuid.UUID    `json:"id"`
 	CleaningAreaID    uuid.UUID    `json:"cleaning_area_id"`
@@ -87,6 +416,18 @@ type ContractTicket struct {
 	ActiveAssignments int64        `json:"active_assignments"`
 }
 
+// TicketContractCandidate — активный контракт, подходящий тикету по участку и датам.
+// AreaTickets — сколько тикетов этого участка уже привязано к контракту;
+// PolygonTickets — сколько из них выгружалось на полигоны рейсов тикета.
+type TicketContractCandidate struct {
+	ContractID     uuid.UUID `json:"contract_id"`
+	Name           string    `json:"name"`
+	StartAt        time.Time `json:"start_at"`
+	EndAt          time.Time `json:"end_at"`
+	AreaTickets    int64     `json:"area_tickets"`
+	PolygonTickets int64     `json:"polygon_tickets"`
+}
+
 type ContractTrip struct {
 	ID                 uuid.UUID  `json:"id"`
 	TicketID           uuid.UUID  `json:"ticket_id"`
@@ -102,6 +443,11 @@ type ContractTrip struct {
 	Status             string     `json:"status"`
 	VolumeEntry        *float64   `json:"detected_volume_entry,omitempty"`
 	VolumeExit         *float64   `json:"detected_volume_exit,omitempty"`
+
+	// Computed
+	DurationSeconds *int64   `json:"duration_seconds" gorm:"-"`
+	VolumeDeltaM3   *float64 `json:"volume_delta_m3" gorm:"-"`
+	PlateMismatch   bool     `json:"plate_mismatch" gorm:"-"`
 }
 
 type OrganizationLookup struct {
@@ -109,6 +455,16 @@ type OrganizationLookup struct {
 	Name string    `json:"name"`
 }
 
+// ContractFilterOptions — значения для выпадающих фильтров списка контрактов,
+// реально встречающиеся среди доступных пользователю контрактов.
+type ContractFilterOptions struct {
+	Contractors   []OrganizationLookup `json:"contractors"`
+	Landfills     []OrganizationLookup `json:"landfills"`
+	ContractTypes []ContractType       `json:"contract_types"`
+	WorkTypes     []WorkType           `json:"work_types"`
+	Statuses      []ContractUIStatus   `json:"statuses"`
+}
+
 type Principal struct {
 	UserID         uuid.UUID
 	OrganizationID uuid.UUID
@@ -150,6 +506,33 @@ const (
 	ContractUIStatusArchived ContractUIStatus = "ARCHIVED"
 )
 
+func ContractUIStatuses() []ContractUIStatus {
+	return []ContractUIStatus{
+		ContractUIStatusPlanned,
+		ContractUIStatusActive,
+		ContractUIStatusExpired,
+		ContractUIStatusArchived,
+	}
+}
+
+// DeriveUIStatus — статус активного контракта по времени. Период действия —
+// закрытый интервал [start_at, end_at]: в момент start_at контракт уже ACTIVE,
+// в момент end_at ещё ACTIVE. PLANNED — now < start_at, EXPIRED — now > end_at,
+// поэтому любой момент попадает ровно в один статус. SQL-фильтр по статусу
+// (repository.statusCondition) обязан использовать те же границы.
+func DeriveUIStatus(isActive bool, startAt, endAt, now time.Time) ContractUIStatus {
+	switch {
+	case !isActive:
+		return ContractUIStatusArchived
+	case now.Before(startAt):
+		return ContractUIStatusPlanned
+	case now.After(endAt):
+		return ContractUIStatusExpired
+	default:
+		return ContractUIStatusActive
+	}
+}
+
 type ContractResult string
 
 const (
@@ -157,3 +540,7 @@ const (
 	ContractResultSuccess ContractResult = "SUCCESS"
 	ContractResultFail    ContractResult = "FAIL"
 )
+
+func ContractResults() []ContractResult {
+	return []ContractResult{ContractResultNone, ContractResultSuccess, ContractResultFail}
+}
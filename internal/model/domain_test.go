@@ -0,0 +1,48 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeriveUIStatusBoundaries(t *testing.T) {
+	startAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endAt := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		isActive bool
+		now      time.Time
+		want     ContractUIStatus
+	}{
+		{name: "before start", isActive: true, now: startAt.Add(-time.Microsecond), want: ContractUIStatusPlanned},
+		{name: "exactly at start", isActive: true, now: startAt, want: ContractUIStatusActive},
+		{name: "inside period", isActive: true, now: startAt.Add(24 * time.Hour), want: ContractUIStatusActive},
+		{name: "exactly at end", isActive: true, now: endAt, want: ContractUIStatusActive},
+		{name: "after end", isActive: true, now: endAt.Add(time.Microsecond), want: ContractUIStatusExpired},
+		{name: "inactive at start", isActive: false, now: startAt, want: ContractUIStatusArchived},
+		{name: "inactive after end", isActive: false, now: endAt.Add(time.Hour), want: ContractUIStatusArchived},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DeriveUIStatus(tt.isActive, startAt, endAt, tt.now); got != tt.want {
+				t.Fatalf("DeriveUIStatus() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeriveUIStatusZeroLengthPeriod(t *testing.T) {
+	// start_at == end_at: ровно в этот момент контракт ACTIVE, до — PLANNED, после — EXPIRED
+	at := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[time.Time]ContractUIStatus{
+		at.Add(-time.Microsecond): ContractUIStatusPlanned,
+		at:                        ContractUIStatusActive,
+		at.Add(time.Microsecond):  ContractUIStatusExpired,
+	}
+	for now, want := range cases {
+		if got := DeriveUIStatus(true, at, at, now); got != want {
+			t.Fatalf("DeriveUIStatus(now=%s) = %s, want %s", now.Format(time.RFC3339Nano), got, want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package notifier
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// NATS публикует события в JetStream в subject "<prefix>.<type>", например
+// snowops.contract.created. Id события передаётся как Nats-Msg-Id, поэтому
+// поток отбрасывает повторную публикацию того же события. Поток, слушающий
+// "<prefix>.>", создаётся вне сервиса.
+type NATS struct {
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	prefix string
+}
+
+// NewNATS подключается к NATS. Недоступный при старте сервер не мешает
+// запуску: клиент переподключается в фоне, а публикации до этого падают с
+// ошибкой, которую Async только логирует.
+func NewNATS(url, subjectPrefix, clientName string) (*NATS, error) {
+	conn, err := nats.Connect(url,
+		nats.Name(clientName),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+	)
+	if err != nil {
+		return nil, err
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &NATS{conn: conn, js: js, prefix: subjectPrefix}, nil
+}
+
+func (n *NATS) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	subject := event.Type
+	if n.prefix != "" {
+		subject = n.prefix + "." + event.Type
+	}
+	_, err = n.js.Publish(ctx, subject, body, jetstream.WithMsgID(event.ID.String()))
+	return err
+}
+
+// Close отправляет буферизованные публикации и закрывает соединение.
+func (n *NATS) Close() error {
+	return n.conn.Drain()
+}
+
+// Multi доставляет событие каждому получателю по очереди; ошибки объединяются,
+// сбой одного не мешает остальным.
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, event Event) error {
+	var errs []error
+	for _, next := range m {
+		if err := next.Notify(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
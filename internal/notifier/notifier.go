@@ -0,0 +1,136 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// Типы событий, которые сервис отправляет внешним системам.
+const (
+	EventTicketContractAssigned = "ticket.contract_assigned"
+	EventContractCreated        = "contract.created"
+	EventContractUpdated        = "contract.updated"
+	// EventContractArchived — контракт выключен (is_active=false): вручную,
+	// массово или автоматической деактивацией
+	EventContractArchived = "contract.archived"
+	// EventContractBudgetExceeded — total_cost контракта впервые превысил budget_total
+	EventContractBudgetExceeded = "contract.budget_exceeded"
+)
+
+type Event struct {
+	ID         uuid.UUID   `json:"id"`
+	Type       string      `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Notifier доставляет события подписчикам. Notify не должен блокировать
+// запрос надолго: ошибки доставки не откатывают уже выполненное изменение.
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// Noop — уведомления выключены.
+type Noop struct{}
+
+func (Noop) Notify(context.Context, Event) error { return nil }
+
+// SignatureHeader — заголовок подписи webhook: "t=<unix-время>,v1=<hex HMAC-SHA256>".
+const SignatureHeader = "X-Signature"
+
+// Webhook отправляет событие POST-запросом с JSON-телом. С секретом тело
+// подписывается HMAC-SHA256, чтобы получатель мог проверить отправителя.
+type Webhook struct {
+	url    string
+	secret []byte
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewWebhook создаёт webhook; пустой secret — без подписи.
+func NewWebhook(url string, timeout time.Duration, secret string) *Webhook {
+	return &Webhook{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: timeout},
+		now:    time.Now,
+	}
+}
+
+// Sign — подпись тела для SignatureHeader: HMAC-SHA256 секрета от строки
+// "<timestamp>.<body>". Метка времени внутри подписи не даёт переиграть
+// перехваченный запрос позже допустимого окна.
+func Sign(secret []byte, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *Webhook) Notify(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-Type", event.Type)
+	req.Header.Set("X-Event-ID", event.ID.String())
+	if len(w.secret) > 0 {
+		req.Header.Set(SignatureHeader, Sign(w.secret, w.now().Unix(), body))
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Async отправляет события в фоне, чтобы медленный подписчик не задерживал
+// ответ API. Ошибки доставки только логируются.
+type Async struct {
+	next    Notifier
+	timeout time.Duration
+	log     zerolog.Logger
+}
+
+func NewAsync(next Notifier, timeout time.Duration, log zerolog.Logger) *Async {
+	return &Async{next: next, timeout: timeout, log: log}
+}
+
+func (a *Async) Notify(_ context.Context, event Event) error {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+		defer cancel()
+		if err := a.next.Notify(ctx, event); err != nil {
+			a.log.Error().Err(err).
+				Str("event_type", event.Type).
+				Str("event_id", event.ID.String()).
+				Msg("event delivery failed")
+		}
+	}()
+	return nil
+}
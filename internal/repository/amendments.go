@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// contractTerms — условия контракта, которые меняет Update; значения до
+// изменения читаются под блокировкой строки.
+type contractTerms struct {
+	Name            string
+	PricePerM3      float64
+	BudgetTotal     float64
+	MinimalVolumeM3 float64
+	StartAt         time.Time
+	EndAt           time.Time
+	IsActive        bool
+}
+
+// value — значение поля условий в текстовом виде для contract_amendments:
+// числа без потери точности, время — RFC3339 в UTC.
+func (t contractTerms) value(field string) (string, bool) {
+	switch field {
+	case "name":
+		return t.Name, true
+	case "price_per_m3":
+		return strconv.FormatFloat(t.PricePerM3, 'f', -1, 64), true
+	case "budget_total":
+		return strconv.FormatFloat(t.BudgetTotal, 'f', -1, 64), true
+	case "minimal_volume_m3":
+		return strconv.FormatFloat(t.MinimalVolumeM3, 'f', -1, 64), true
+	case "start_at":
+		return t.StartAt.UTC().Format(time.RFC3339Nano), true
+	case "end_at":
+		return t.EndAt.UTC().Format(time.RFC3339Nano), true
+	case "is_active":
+		return strconv.FormatBool(t.IsActive), true
+	}
+	return "", false
+}
+
+// termsOf — условия контракта, которые пишутся в contract_amendments.
+func termsOf(contract model.Contract) contractTerms {
+	return contractTerms{
+		Name:            contract.Name,
+		PricePerM3:      contract.PricePerM3,
+		BudgetTotal:     contract.BudgetTotal,
+		MinimalVolumeM3: contract.MinimalVolumeM3,
+		StartAt:         contract.StartAt,
+		EndAt:           contract.EndAt,
+		IsActive:        contract.IsActive,
+	}
+}
+
+// insertAmendmentsTx пишет по строке contract_amendments на каждое поле,
+// значение которого между before и after (оба прочитаны под блокировкой)
+// действительно изменилось; неизвестные поля пропускаются. Все строки
+// получают одно changed_at.
+func insertAmendmentsTx(tx *gorm.DB, params UpdateContractParams, fields []string, before, after contractTerms) error {
+	for _, field := range fields {
+		oldValue, ok := before.value(field)
+		if !ok {
+			continue
+		}
+		newValue, _ := after.value(field)
+		if newValue == oldValue {
+			continue
+		}
+		if err := tx.Exec(`
+			INSERT INTO contract_amendments (contract_id, field, old_value, new_value, changed_by, changed_at, reason)
+			VALUES (?, ?, ?, ?, ?, NOW(), ?)
+		`, params.ID, field, oldValue, newValue, params.ActorUserID, params.Reason).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListAmendments возвращает изменения условий контракта в хронологическом
+// порядке (старые сверху).
+func (r *ContractRepository) ListAmendments(ctx context.Context, contractID uuid.UUID) ([]model.ContractAmendment, error) {
+	items := []model.ContractAmendment{}
+	err := withRetry(ctx, retryRead, func() error {
+		items = items[:0]
+		return r.db.WithContext(ctx).Raw(`
+			SELECT id, contract_id, field, old_value, new_value, changed_by, changed_at, reason
+			FROM contract_amendments
+			WHERE contract_id = ?
+			ORDER BY changed_at, id
+		`, contractID).Scan(&items).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
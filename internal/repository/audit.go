@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+type AuditFilter struct {
+	ContractID  uuid.UUID
+	Actions     []model.AuditAction
+	ActorUserID *uuid.UUID
+	ActorOrgID  *uuid.UUID
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+	Offset      int
+}
+
+type auditRow struct {
+	ID          uuid.UUID
+	ContractID  uuid.UUID
+	Action      model.AuditAction
+	ActorUserID *uuid.UUID
+	ActorOrgID  *uuid.UUID
+	Details     string
+	CreatedAt   time.Time
+}
+
+// ListAudit возвращает страницу журнала контракта (новые сверху) и общее
+// число записей по фильтру. Фильтрация и пагинация выполняются в SQL.
+func (r *ContractRepository) ListAudit(ctx context.Context, filter AuditFilter) ([]model.ContractAuditEntry, int64, error) {
+	query := r.db.WithContext(ctx).
+		Table("contract_audit_log a").
+		Where("a.contract_id = ?", filter.ContractID)
+	if len(filter.Actions) > 0 {
+		query = query.Where("a.action IN ?", filter.Actions)
+	}
+	if filter.ActorUserID != nil {
+		query = query.Where("a.actor_user_id = ?", *filter.ActorUserID)
+	}
+	if filter.ActorOrgID != nil {
+		query = query.Where("a.actor_org_id = ?", *filter.ActorOrgID)
+	}
+	if filter.From != nil {
+		query = query.Where("a.created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("a.created_at <= ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return []model.ContractAuditEntry{}, 0, nil
+	}
+
+	var rows []auditRow
+	err := query.
+		Select("a.id, a.contract_id, a.action, a.actor_user_id, a.actor_org_id, a.details::text AS details, a.created_at").
+		Order("a.created_at DESC, a.id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	items := make([]model.ContractAuditEntry, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, model.ContractAuditEntry{
+			ID:          row.ID,
+			ContractID:  row.ContractID,
+			Action:      row.Action,
+			ActorUserID: row.ActorUserID,
+			ActorOrgID:  row.ActorOrgID,
+			Details:     json.RawMessage(row.Details),
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+	return items, total, nil
+}
@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,22 +19,79 @@ var (
 	ErrTicketNotLinked     = errors.New("ticket is not linked to any contract")
 	ErrTicketNotFound      = errors.New("ticket not found")
 	ErrTripUsageDuplicate  = errors.New("trip usage already recorded")
+	// ErrClientReferenceExists — контракт с таким client_reference уже создан
+	ErrClientReferenceExists = errors.New("contract with client reference already exists")
+	// ErrIdempotencyKeyUsed — ключ Idempotency-Key уже использован и ещё не истёк
+	ErrIdempotencyKeyUsed = errors.New("idempotency key already used")
+	// ErrUsageWouldBeNegative — корректировка увела бы итоги usage ниже нуля
+	ErrUsageWouldBeNegative = errors.New("usage totals would become negative")
+	// ErrActiveContractLimit — у организации уже максимум активных контрактов
+	ErrActiveContractLimit = errors.New("active contract limit reached for organization")
+	// ErrContractLocked — контракт уже заблокирован
+	ErrContractLocked = errors.New("contract is locked")
+	// ErrBudgetExceeded — рейс увёл бы total_cost выше budget_total (TripUsageParams.RejectOverBudget)
+	ErrBudgetExceeded = errors.New("trip usage would exceed budget_total")
+	// ErrTicketHasUsage — по тикету уже записаны рейсы, перепривязка оставила бы их usage на старом контракте
+	ErrTicketHasUsage = errors.New("ticket has recorded trip usage")
+	// ErrTicketRelinked — тикет перепривязан к другому контракту после того, как рейс нашёл контракт
+	ErrTicketRelinked = errors.New("ticket was relinked to another contract")
 )
 
+// usageLedgerSQL — все движения usage контракта: рейсы и ручные корректировки.
+// contract_usage должна совпадать с их суммой.
+const usageLedgerSQL = `
+	SELECT contract_id, recorded_volume_m3 AS volume_m3, recorded_cost AS cost
+	FROM trip_usage_log
+	UNION ALL
+	SELECT contract_id, volume_delta_m3 AS volume_m3, cost_delta AS cost
+	FROM contract_usage_adjustments
+`
+
 type ContractFilter struct {
+	IDs          []uuid.UUID
 	ContractorID *uuid.UUID
 	LandfillID   *uuid.UUID
 	ContractType *model.ContractType
 	CreatedByOrg *uuid.UUID
-	WorkType     *model.WorkType
-	OnlyActive   bool
-	IncludeUsage bool
-	Status       *model.ContractUIStatus
-	StartFrom    *time.Time
-	StartTo      *time.Time
-	EndFrom      *time.Time
-	EndTo        *time.Time
-	Now          time.Time
+	// CreatedByUser — контракты, созданные пользователем (аудит)
+	CreatedByUser *uuid.UUID
+	// CleaningAreaID — контракты, к которым привязан хотя бы один тикет участка
+	CleaningAreaID *uuid.UUID
+	WorkType       *model.WorkType
+	OnlyActive     bool
+	IncludeUsage   bool
+	Status         *model.ContractUIStatus
+	StartFrom      *time.Time
+	StartTo        *time.Time
+	EndFrom        *time.Time
+	EndTo          *time.Time
+	Now            time.Time
+	// BudgetExceeded — true: usage.total_cost > budget_total, false: в пределах бюджета
+	BudgetExceeded *bool
+	// MinUtilization/MaxUtilization — границы total_cost / budget_total
+	// включительно (1 — 100%); контракты с нулевым бюджетом не проходят фильтр
+	MinUtilization *float64
+	MaxUtilization *float64
+	// SortBy/SortDir — порядок списка; пусто — created_at DESC
+	SortBy  model.ContractSortField
+	SortDir model.SortDirection
+	// Limit — максимум строк; 0 — без ограничения
+	Limit int
+	// After — продолжить список после строки курсора (keyset по колонке
+	// сортировки и id); порядок курсора должен совпадать с SortBy/SortDir
+	After *ContractCursor
+	// IncludeDeleted — вместе с удалёнными (SoftDelete) контрактами
+	IncludeDeleted bool
+}
+
+// ContractCursor — позиция keyset-пагинации списка контрактов: значение
+// колонки сортировки и id последней отданной строки.
+type ContractCursor struct {
+	SortBy  model.ContractSortField
+	SortDir model.SortDirection
+	// Value — time.Time для дат, float64 для budget_total, string для name
+	Value any
+	ID    uuid.UUID
 }
 
 type ContractRepository struct {
@@ -43,12 +103,234 @@ func NewContractRepository(db *gorm.DB) *ContractRepository {
 }
 
 func (r *ContractRepository) List(ctx context.Context, filter ContractFilter) ([]model.Contract, error) {
+	var contracts []model.Contract
+	err := withRetry(ctx, retryRead, func() error {
+		contracts = nil
+		return r.listQuery(ctx, filter).Scan(&contracts).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if filter.IncludeUsage {
+		if err := r.loadUsageAndPolygonsAll(ctx, contracts); err != nil {
+			return nil, err
+		}
+	}
+
+	return contracts, nil
+}
+
+// ListPage — List одной страницы по filter.Limit: читает на строку больше,
+// чтобы узнать, есть ли следующая, и догружает usage и полигоны только для
+// строк самой страницы.
+func (r *ContractRepository) ListPage(ctx context.Context, filter ContractFilter) ([]model.Contract, bool, error) {
+	includeUsage := filter.IncludeUsage
+	filter.IncludeUsage = false
+	filter.Limit++
+	contracts, err := r.List(ctx, filter)
+	if err != nil {
+		return nil, false, err
+	}
+
+	hasMore := len(contracts) >= filter.Limit
+	if hasMore {
+		contracts = contracts[:filter.Limit-1]
+	}
+	if includeUsage {
+		if err := r.loadUsageAndPolygonsAll(ctx, contracts); err != nil {
+			return nil, false, err
+		}
+	}
+	return contracts, hasMore, nil
+}
+
+// activeContractorRow — строка ListActiveForContractor: контракт и usage
+// одним запросом.
+type activeContractorRow struct {
+	model.Contract
+	UsageID            *uuid.UUID
+	UsageTotalVolumeM3 *float64
+	UsageTotalCost     *float64
+	UsageUpdatedAt     *time.Time
+}
+
+// ListActiveForContractor — быстрый путь для самого частого запроса: активные
+// контракты подрядчика с usage. Один JOIN вместо List и отдельного запроса
+// usage; использует idx_contracts_contractor_active_created. Названия
+// организаций не читаются: их подставляет сервис.
+// Результат совпадает с List(ContractorID, OnlyActive, IncludeUsage) с тем же
+// after и limit (0 — без ограничения); порядок — created_at DESC.
+func (r *ContractRepository) ListActiveForContractor(ctx context.Context, contractorID uuid.UUID, after *ContractCursor, limit int) ([]model.Contract, error) {
+	args := []any{contractorID}
+	keyset := ""
+	if after != nil {
+		cond, keysetArgs := contractKeyset(*after)
+		keyset = " AND " + cond
+		args = append(args, keysetArgs...)
+	}
+	query := `
+		SELECT
+			c.id,
+			c.contractor_id,
+			c.landfill_id,
+			c.created_by_org AS created_by_org_id,
+			c.created_by_user AS created_by_user_id,
+			c.contract_type,
+			c.name,
+			c.work_type,
+			c.price_per_m3,
+			c.budget_total,
+			c.minimal_volume_m3,
+			c.start_at,
+			c.end_at,
+			c.is_active,
+			c.client_reference,
+			c.is_locked,
+			c.locked_by,
+			c.locked_at,
+			c.created_at,
+			c.updated_at,
+			c.deleted_at,
+			u.id AS usage_id,
+			u.total_volume_m3 AS usage_total_volume_m3,
+			u.total_cost AS usage_total_cost,
+			u.updated_at AS usage_updated_at
+		FROM contracts c
+		LEFT JOIN contract_usage u ON u.contract_id = c.id
+		WHERE c.contractor_id = ? AND c.is_active = TRUE AND c.deleted_at IS NULL` + keyset + `
+		ORDER BY c.created_at DESC, c.id DESC` + limitClause(limit, &args)
+
+	var rows []activeContractorRow
+	err := withRetry(ctx, retryRead, func() error {
+		rows = nil
+		return r.db.WithContext(ctx).Raw(query, args...).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	contracts := make([]model.Contract, 0, len(rows))
+	for _, row := range rows {
+		contract := row.Contract
+		if row.UsageID != nil {
+			contract.Usage = &model.ContractUsage{
+				ID:            *row.UsageID,
+				ContractID:    contract.ID,
+				TotalVolumeM3: derefFloat(row.UsageTotalVolumeM3),
+				TotalCost:     derefFloat(row.UsageTotalCost),
+			}
+			if row.UsageUpdatedAt != nil {
+				contract.Usage.UpdatedAt = *row.UsageUpdatedAt
+			}
+		} else {
+			contract.UsageMissing = true
+		}
+		contracts = append(contracts, contract)
+	}
+	return contracts, nil
+}
+
+// GetOrganizationNames возвращает названия организаций по id; отсутствующие id пропускаются.
+func (r *ContractRepository) GetOrganizationNames(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	var rows []model.OrganizationLookup
+	err := withRetry(ctx, retryRead, func() error {
+		rows = nil
+		return r.db.WithContext(ctx).
+			Raw(`SELECT id, name FROM organizations WHERE id IN ?`, ids).
+			Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	names := make(map[uuid.UUID]string, len(rows))
+	for _, row := range rows {
+		names[row.ID] = row.Name
+	}
+	return names, nil
+}
+
+func derefFloat(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+// streamChunkSize — число контрактов, читаемых StreamList одним запросом.
+var streamChunkSize = 500
+
+// StreamList читает контракты по фильтру порциями по streamChunkSize строк и
+// передаёт каждую порцию в fn, не загружая весь результат в память. Порция
+// читается keyset-запросом в порядке SortBy/SortDir; курсор закрывается до
+// догрузки usage и полигонов (пакетно на порцию) и до вызова fn, так что
+// поток не держит соединение пула, пока fn пишет ответ или ходит в базу.
+// filter.Limit > 0 ограничивает общее число строк. Остановка — по ошибке из fn.
+func (r *ContractRepository) StreamList(ctx context.Context, filter ContractFilter, fn func([]model.Contract) error) error {
+	includeUsage := filter.IncludeUsage
+	filter.IncludeUsage = false
+	remaining := filter.Limit
+	sortBy, sortDir := NormalizeContractSort(filter.SortBy, filter.SortDir)
+
+	for {
+		filter.Limit = streamChunkSize
+		if remaining > 0 && remaining < streamChunkSize {
+			filter.Limit = remaining
+		}
+		contracts, err := r.List(ctx, filter)
+		if err != nil {
+			return err
+		}
+		if len(contracts) == 0 {
+			return nil
+		}
+		if includeUsage {
+			if err := r.loadUsageAndPolygonsAll(ctx, contracts); err != nil {
+				return err
+			}
+		}
+		if err := fn(contracts); err != nil {
+			return err
+		}
+
+		if len(contracts) < filter.Limit {
+			return nil
+		}
+		if remaining > 0 {
+			if remaining -= len(contracts); remaining == 0 {
+				return nil
+			}
+		}
+		last := contracts[len(contracts)-1]
+		filter.After = &ContractCursor{SortBy: sortBy, SortDir: sortDir, Value: contractSortValue(last, sortBy), ID: last.ID}
+	}
+}
+
+// contractSortValue — значение колонки сортировки контракта для ContractCursor.
+func contractSortValue(contract model.Contract, sortBy model.ContractSortField) any {
+	switch sortBy {
+	case model.ContractSortStartAt:
+		return contract.StartAt
+	case model.ContractSortEndAt:
+		return contract.EndAt
+	case model.ContractSortName:
+		return contract.Name
+	case model.ContractSortBudgetTotal:
+		return contract.BudgetTotal
+	default:
+		return contract.CreatedAt
+	}
+}
+
+func (r *ContractRepository) listQuery(ctx context.Context, filter ContractFilter) *gorm.DB {
 	query := r.db.WithContext(ctx).Table("contracts c").
 		Select(`
 			c.id,
 			c.contractor_id,
 			c.landfill_id,
 			c.created_by_org AS created_by_org_id,
+			c.created_by_user AS created_by_user_id,
 			c.contract_type,
 			c.name,
 			c.work_type,
@@ -58,10 +340,163 @@ func (r *ContractRepository) List(ctx context.Context, filter ContractFilter) ([
 			c.start_at,
 			c.end_at,
 			c.is_active,
+			c.client_reference,
+			c.is_locked,
+			c.locked_by,
+			c.locked_at,
 			c.created_at,
-			NULL::TIMESTAMPTZ AS updated_at
+			c.updated_at,
+			c.deleted_at
 		`)
 
+	query = applyContractFilter(query, filter)
+	if filter.After != nil {
+		cond, args := contractKeyset(*filter.After)
+		query = query.Where(cond, args...)
+	}
+	query = query.Order(contractOrder(filter.SortBy, filter.SortDir))
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	return query
+}
+
+// contractSortColumns — allowlist колонок сортировки; значения попадают в SQL,
+// поэтому только отсюда.
+var contractSortColumns = map[model.ContractSortField]string{
+	model.ContractSortCreatedAt:   "c.created_at",
+	model.ContractSortStartAt:     "c.start_at",
+	model.ContractSortEndAt:       "c.end_at",
+	model.ContractSortName:        "c.name",
+	model.ContractSortBudgetTotal: "c.budget_total",
+}
+
+// NormalizeContractSort приводит сортировку к фактически применяемой:
+// неизвестное или пустое поле — created_at DESC, пустое направление — DESC.
+func NormalizeContractSort(sortBy model.ContractSortField, dir model.SortDirection) (model.ContractSortField, model.SortDirection) {
+	if _, ok := contractSortColumns[sortBy]; !ok {
+		return model.ContractSortCreatedAt, model.SortDesc
+	}
+	if dir != model.SortAsc {
+		dir = model.SortDesc
+	}
+	return sortBy, dir
+}
+
+func contractOrder(sortBy model.ContractSortField, dir model.SortDirection) string {
+	sortBy, dir = NormalizeContractSort(sortBy, dir)
+	direction := "DESC"
+	if dir == model.SortAsc {
+		direction = "ASC"
+	}
+	// id — стабильный порядок при равных значениях и ключ keyset-пагинации
+	return contractSortColumns[sortBy] + " " + direction + ", c.id " + direction
+}
+
+// contractKeyset — условие «строка после курсора» для порядка contractOrder:
+// сравнение пар (колонка, id) в направлении сортировки.
+func contractKeyset(cursor ContractCursor) (string, []any) {
+	sortBy, dir := NormalizeContractSort(cursor.SortBy, cursor.SortDir)
+	op := "<"
+	if dir == model.SortAsc {
+		op = ">"
+	}
+	return "(" + contractSortColumns[sortBy] + ", c.id) " + op + " (?, ?)", []any{cursor.Value, cursor.ID}
+}
+
+// loadUsageAndPolygonsAll догружает usage и полигоны контрактов двумя
+// запросами на весь срез вместо двух на каждый контракт. Без строки
+// contract_usage — Usage nil и UsageMissing, как у loadUsage. Ошибка чтения
+// сохраняется в UsageLoadErr всех контрактов среза, а отмена запроса
+// возвращается как ошибка, чтобы не отдавать страницу, где у всех
+// контрактов "context canceled".
+func (r *ContractRepository) loadUsageAndPolygonsAll(ctx context.Context, contracts []model.Contract) error {
+	if len(contracts) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(contracts))
+	var landfillIDs []uuid.UUID
+	for _, contract := range contracts {
+		ids = append(ids, contract.ID)
+		if contract.ContractType == model.ContractTypeLandfillService {
+			landfillIDs = append(landfillIDs, contract.ID)
+		}
+	}
+
+	usages, err := r.getUsageBatch(ctx, ids)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for i := range contracts {
+		if err != nil {
+			markUsageLoadError(&contracts[i], fmt.Errorf("load usage: %w", err))
+			continue
+		}
+		usage := usages[contracts[i].ID]
+		contracts[i].Usage = usage
+		contracts[i].UsageMissing = usage == nil
+	}
+
+	if len(landfillIDs) == 0 {
+		return nil
+	}
+	polygons, err := r.GetPolygonsBatch(ctx, landfillIDs)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	for i := range contracts {
+		if contracts[i].ContractType != model.ContractTypeLandfillService {
+			continue
+		}
+		if err != nil {
+			markUsageLoadError(&contracts[i], fmt.Errorf("load polygons: %w", err))
+			continue
+		}
+		contracts[i].Polygons = polygons[contracts[i].ID]
+		contracts[i].PolygonIDs = polygonIDsOf(contracts[i].Polygons)
+	}
+	return nil
+}
+
+// loadUsage читает строку contract_usage. Ошибка не прерывает чтение контракта,
+// а сохраняется в UsageLoadErr: решение (500 или флаг в ответе) принимает сервис.
+func (r *ContractRepository) loadUsage(ctx context.Context, contract *model.Contract) {
+	usage, err := r.getUsage(ctx, contract.ID)
+	if err != nil {
+		markUsageLoadError(contract, fmt.Errorf("load usage: %w", err))
+		return
+	}
+	contract.Usage = usage
+	contract.UsageMissing = usage == nil
+}
+
+// loadPolygons загружает полигоны LANDFILL_SERVICE контрактов.
+func (r *ContractRepository) loadPolygons(ctx context.Context, contract *model.Contract) {
+	if contract.ContractType != model.ContractTypeLandfillService {
+		return
+	}
+	polygons, err := r.GetPolygons(ctx, contract.ID)
+	if err != nil {
+		markUsageLoadError(contract, fmt.Errorf("load polygons: %w", err))
+		return
+	}
+	contract.Polygons = polygons
+	contract.PolygonIDs = polygonIDsOf(polygons)
+}
+
+func markUsageLoadError(contract *model.Contract, err error) {
+	contract.UsageLoadError = true
+	contract.UsageLoadErr = errors.Join(contract.UsageLoadErr, err)
+}
+
+// applyContractFilter добавляет условия ContractFilter к запросу по "contracts c".
+func applyContractFilter(query *gorm.DB, filter ContractFilter) *gorm.DB {
+	if !filter.IncludeDeleted {
+		query = query.Where("c.deleted_at IS NULL")
+	}
+	if filter.IDs != nil {
+		query = query.Where("c.id IN ?", filter.IDs)
+	}
 	if filter.ContractorID != nil {
 		query = query.Where("c.contractor_id = ?", *filter.ContractorID)
 	}
@@ -74,6 +509,12 @@ func (r *ContractRepository) List(ctx context.Context, filter ContractFilter) ([
 	if filter.CreatedByOrg != nil {
 		query = query.Where("c.created_by_org = ?", *filter.CreatedByOrg)
 	}
+	if filter.CreatedByUser != nil {
+		query = query.Where("c.created_by_user = ?", *filter.CreatedByUser)
+	}
+	if filter.CleaningAreaID != nil {
+		query = query.Where("EXISTS (SELECT 1 FROM tickets t WHERE t.contract_id = c.id AND t.cleaning_area_id = ?)", *filter.CleaningAreaID)
+	}
 	if filter.WorkType != nil {
 		query = query.Where("c.work_type = ?", string(*filter.WorkType))
 	}
@@ -92,58 +533,77 @@ func (r *ContractRepository) List(ctx context.Context, filter ContractFilter) ([
 	if filter.EndTo != nil {
 		query = query.Where("c.end_at <= ?", *filter.EndTo)
 	}
+	if filter.BudgetExceeded != nil {
+		// то же условие, что и флаг budget_exceeded в decorateContract; нет usage — стоимость 0
+		exceeded := "EXISTS (SELECT 1 FROM contract_usage u WHERE u.contract_id = c.id AND u.total_cost > c.budget_total)"
+		if *filter.BudgetExceeded {
+			query = query.Where(exceeded)
+		} else {
+			query = query.Where("NOT " + exceeded)
+		}
+	}
+	if filter.MinUtilization != nil || filter.MaxUtilization != nil {
+		// нет usage — стоимость 0; нулевой бюджет исключается, а не делится
+		utilization := "COALESCE((SELECT u.total_cost FROM contract_usage u WHERE u.contract_id = c.id), 0) / c.budget_total"
+		query = query.Where("c.budget_total > 0")
+		if filter.MinUtilization != nil {
+			query = query.Where(utilization+" >= ?", *filter.MinUtilization)
+		}
+		if filter.MaxUtilization != nil {
+			query = query.Where(utilization+" <= ?", *filter.MaxUtilization)
+		}
+	}
 	if filter.Status != nil {
 		now := filter.Now
 		if now.IsZero() {
 			now = time.Now()
 		}
-		switch *filter.Status {
-		case model.ContractUIStatusPlanned:
-			query = query.Where("c.is_active = TRUE AND c.start_at > ?", now)
-		case model.ContractUIStatusActive:
-			query = query.Where("c.is_active = TRUE AND c.start_at <= ? AND c.end_at >= ?", now, now)
-		case model.ContractUIStatusExpired:
-			query = query.Where("c.is_active = TRUE AND c.end_at < ?", now)
-		case model.ContractUIStatusArchived:
-			query = query.Where("c.is_active = FALSE")
+		if cond, args, ok := statusCondition(*filter.Status, now); ok {
+			query = query.Where(cond, args...)
 		}
 	}
 
-	query = query.Order("c.created_at DESC")
+	return query
+}
 
-	var contracts []model.Contract
-	if err := query.Scan(&contracts).Error; err != nil {
-		return nil, err
+// statusCondition — SQL-условие для ui_status с теми же границами, что и
+// model.DeriveUIStatus: период [start_at, end_at] включает оба конца.
+func statusCondition(status model.ContractUIStatus, now time.Time) (string, []any, bool) {
+	switch status {
+	case model.ContractUIStatusPlanned:
+		return "c.is_active = TRUE AND c.start_at > ?", []any{now}, true
+	case model.ContractUIStatusActive:
+		return "c.is_active = TRUE AND c.start_at <= ? AND c.end_at >= ?", []any{now, now}, true
+	case model.ContractUIStatusExpired:
+		return "c.is_active = TRUE AND c.end_at < ?", []any{now}, true
+	case model.ContractUIStatusArchived:
+		return "c.is_active = FALSE", nil, true
 	}
+	return "", nil, false
+}
 
-	if filter.IncludeUsage {
-		for i := range contracts {
-			usage, err := r.getUsage(ctx, contracts[i].ID)
-			if err == nil {
-				contracts[i].Usage = usage
-			}
-			// Загружаем polygon_ids для LANDFILL_SERVICE контрактов
-			if contracts[i].ContractType == model.ContractTypeLandfillService {
-				polygonIDs, err := r.GetPolygonIDs(ctx, contracts[i].ID)
-				if err == nil {
-					contracts[i].PolygonIDs = polygonIDs
-				}
-			}
-		}
-	}
+// GetByID читает контракт; удалённый (SoftDelete) — ErrRecordNotFound.
+func (r *ContractRepository) GetByID(ctx context.Context, id uuid.UUID, includeUsage bool) (*model.Contract, error) {
+	return r.getByID(ctx, id, includeUsage, false)
+}
 
-	return contracts, nil
+// GetByIDWithDeleted — GetByID, находящий и удалённые контракты (восстановление,
+// окончательное удаление).
+func (r *ContractRepository) GetByIDWithDeleted(ctx context.Context, id uuid.UUID, includeUsage bool) (*model.Contract, error) {
+	return r.getByID(ctx, id, includeUsage, true)
 }
 
-func (r *ContractRepository) GetByID(ctx context.Context, id uuid.UUID, includeUsage bool) (*model.Contract, error) {
+func (r *ContractRepository) getByID(ctx context.Context, id uuid.UUID, includeUsage, includeDeleted bool) (*model.Contract, error) {
 	var contract model.Contract
-	err := r.db.WithContext(ctx).
-		Raw(`
+	err := withRetry(ctx, retryRead, func() error {
+		return r.db.WithContext(ctx).
+			Raw(`
 			SELECT
 				c.id,
 				c.contractor_id,
 				c.landfill_id,
 				c.created_by_org AS created_by_org_id,
+				c.created_by_user AS created_by_user_id,
 				c.contract_type,
 				c.name,
 				c.work_type,
@@ -153,12 +613,18 @@ func (r *ContractRepository) GetByID(ctx context.Context, id uuid.UUID, includeU
 				c.start_at,
 				c.end_at,
 				c.is_active,
+				c.client_reference,
+				c.is_locked,
+				c.locked_by,
+				c.locked_at,
 				c.created_at,
-				NULL::TIMESTAMPTZ AS updated_at
+				c.updated_at,
+				c.deleted_at
 			FROM contracts c
-			WHERE c.id = ?
+			WHERE c.id = ? AND (? OR c.deleted_at IS NULL)
 			LIMIT 1
-		`, id).Scan(&contract).Error
+		`, id, includeDeleted).Scan(&contract).Error
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -167,27 +633,23 @@ func (r *ContractRepository) GetByID(ctx context.Context, id uuid.UUID, includeU
 	}
 
 	if includeUsage {
-		usage, err := r.getUsage(ctx, contract.ID)
-		if err == nil {
-			contract.Usage = usage
-		}
-	}
-
-	// Загружаем polygon_ids для LANDFILL_SERVICE контрактов
-	if contract.ContractType == model.ContractTypeLandfillService {
-		polygonIDs, err := r.GetPolygonIDs(ctx, contract.ID)
-		if err == nil {
-			contract.PolygonIDs = polygonIDs
-		}
+		r.loadUsage(ctx, &contract)
 	}
+	r.loadPolygons(ctx, &contract)
 
 	return &contract, nil
 }
 
+// GetUsage возвращает строку contract_usage; nil — строки нет.
+func (r *ContractRepository) GetUsage(ctx context.Context, contractID uuid.UUID) (*model.ContractUsage, error) {
+	return r.getUsage(ctx, contractID)
+}
+
 func (r *ContractRepository) getUsage(ctx context.Context, contractID uuid.UUID) (*model.ContractUsage, error) {
 	var usage model.ContractUsage
-	err := r.db.WithContext(ctx).
-		Raw(`
+	err := withRetry(ctx, retryRead, func() error {
+		return r.db.WithContext(ctx).
+			Raw(`
 			SELECT
 				id,
 				contract_id,
@@ -198,186 +660,1095 @@ func (r *ContractRepository) getUsage(ctx context.Context, contractID uuid.UUID)
 			WHERE contract_id = ?
 			LIMIT 1
 		`, contractID).Scan(&usage).Error
+	})
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
 		return nil, err
 	}
+	// Scan не возвращает ErrRecordNotFound — строки нет, если id пустой
+	if usage.ID == uuid.Nil {
+		return nil, nil
+	}
 	return &usage, nil
 }
 
-type CreateContractParams struct {
-	ContractorID    *uuid.UUID
-	LandfillID      *uuid.UUID
-	ContractType    model.ContractType
-	CreatedByOrgID  uuid.UUID
-	Name            string
-	WorkType        model.WorkType
-	PricePerM3      float64
-	BudgetTotal     float64
-	MinimalVolumeM3 float64
-	StartAt         time.Time
-	EndAt           time.Time
-	IsActive        bool
-	PolygonIDs      []uuid.UUID
+// getUsageBatch читает строки contract_usage контрактов одним запросом;
+// контракта без строки в результате нет.
+func (r *ContractRepository) getUsageBatch(ctx context.Context, contractIDs []uuid.UUID) (map[uuid.UUID]*model.ContractUsage, error) {
+	var rows []model.ContractUsage
+	err := withRetry(ctx, retryRead, func() error {
+		rows = nil
+		return r.db.WithContext(ctx).
+			Raw(`
+			SELECT
+				id,
+				contract_id,
+				total_volume_m3,
+				total_cost,
+				updated_at
+			FROM contract_usage
+			WHERE contract_id IN ?
+		`, contractIDs).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	usages := make(map[uuid.UUID]*model.ContractUsage, len(rows))
+	for i := range rows {
+		usages[rows[i].ContractID] = &rows[i]
+	}
+	return usages, nil
 }
 
-func (r *ContractRepository) Create(ctx context.Context, params CreateContractParams) (*model.Contract, error) {
-	var contract model.Contract
-	err := r.db.WithContext(ctx).Raw(`
-		INSERT INTO contracts (
-			contractor_id,
-			landfill_id,
-			contract_type,
-			created_by_org,
-			name,
-			work_type,
-			price_per_m3,
-			budget_total,
-			minimal_volume_m3,
-			start_at,
-			end_at,
-			is_active
-		)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-		RETURNING
-			id,
-			contractor_id,
-			landfill_id,
-			created_by_org AS created_by_org_id,
-			contract_type,
-			name,
-			work_type,
-			price_per_m3,
-			budget_total,
-			minimal_volume_m3,
-			start_at,
-			end_at,
-			is_active,
-			created_at,
-			NULL::TIMESTAMPTZ AS updated_at
-	`, params.ContractorID, params.LandfillID, string(params.ContractType), params.CreatedByOrgID, params.Name, string(params.WorkType),
-		params.PricePerM3, params.BudgetTotal, params.MinimalVolumeM3,
-		params.StartAt, params.EndAt, params.IsActive).Scan(&contract).Error
-	if err != nil {
+// RepairUsage восстанавливает отсутствующую строку contract_usage из суммы
+// движений (рейсы и корректировки). Существующую строку не трогает.
+// Все методы, меняющие usage, вызывают repairUsageTx в своей транзакции.
+func (r *ContractRepository) RepairUsage(ctx context.Context, contractID uuid.UUID) (*model.ContractUsage, error) {
+	if err := repairUsageTx(r.db.WithContext(ctx), contractID); err != nil {
 		return nil, err
 	}
+	return r.getUsage(ctx, contractID)
+}
 
-	// Create initial usage record
-	err = r.db.WithContext(ctx).Exec(`
+func repairUsageTx(tx *gorm.DB, contractID uuid.UUID) error {
+	return tx.Exec(`
 		INSERT INTO contract_usage (contract_id, total_volume_m3, total_cost)
-		VALUES (?, 0, 0)
+		SELECT
+			?,
+			COALESCE(SUM(ledger.volume_m3), 0),
+			COALESCE(SUM(ledger.cost), 0)
+		FROM (`+usageLedgerSQL+`) ledger
+		WHERE ledger.contract_id = ?
 		ON CONFLICT (contract_id) DO NOTHING
-	`, contract.ID).Error
+	`, contractID, contractID).Error
+}
+
+// ListUsageLedger возвращает движения usage контракта в хронологическом порядке;
+// limit > 0 ограничивает число строк (самые ранние).
+func (r *ContractRepository) ListUsageLedger(ctx context.Context, contractID uuid.UUID, limit int) ([]model.UsageLedgerEntry, error) {
+	args := []interface{}{contractID, contractID}
+	return r.queryUsageLedger(ctx, "contract_id = ?", `
+		ORDER BY created_at, source_id`+limitClause(limit, &args), args)
+}
+
+// ListUsageLedgerBatch — ListUsageLedger без ограничения для нескольких
+// контрактов одним запросом. У контракта без движений — пустой срез.
+func (r *ContractRepository) ListUsageLedgerBatch(ctx context.Context, contractIDs []uuid.UUID) (map[uuid.UUID][]model.UsageLedgerEntry, error) {
+	items, err := r.queryUsageLedger(ctx, "contract_id IN ?", `
+		ORDER BY contract_id, created_at, source_id`, []interface{}{contractIDs, contractIDs})
 	if err != nil {
 		return nil, err
 	}
-
-	// Сохраняем polygon_ids для LANDFILL_SERVICE контрактов
-	if params.ContractType == model.ContractTypeLandfillService && len(params.PolygonIDs) > 0 {
-		if err := r.SetPolygonIDs(ctx, contract.ID, params.PolygonIDs); err != nil {
-			return nil, err
-		}
-		contract.PolygonIDs = params.PolygonIDs
+	ledger := make(map[uuid.UUID][]model.UsageLedgerEntry, len(contractIDs))
+	for _, id := range contractIDs {
+		ledger[id] = []model.UsageLedgerEntry{}
 	}
-
-	return &contract, nil
+	for _, item := range items {
+		ledger[item.ContractID] = append(ledger[item.ContractID], item)
+	}
+	return ledger, nil
 }
 
-func (r *ContractRepository) UpdateUsage(ctx context.Context, contractID uuid.UUID, volumeM3, cost float64) error {
-	err := r.db.WithContext(ctx).Exec(`
-		INSERT INTO contract_usage (contract_id, total_volume_m3, total_cost)
-		VALUES (?, ?, ?)
-		ON CONFLICT (contract_id)
-		DO UPDATE SET
-			total_volume_m3 = contract_usage.total_volume_m3 + EXCLUDED.total_volume_m3,
-			total_cost = contract_usage.total_cost + EXCLUDED.total_cost,
+// queryUsageLedger читает рейсы и корректировки по условию condition,
+// применённому к обеим таблицам; tail — сортировка и ограничение.
+func (r *ContractRepository) queryUsageLedger(ctx context.Context, condition, tail string, args []interface{}) ([]model.UsageLedgerEntry, error) {
+	var items []model.UsageLedgerEntry
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT
+			'trip' AS kind,
+			trip_id AS source_id,
+			contract_id,
+			ticket_id,
+			recorded_volume_m3 AS volume_m3,
+			recorded_cost AS cost,
+			NULL AS reason,
+			created_at
+		FROM trip_usage_log
+		WHERE `+condition+`
+		UNION ALL
+		SELECT
+			'adjustment' AS kind,
+			id AS source_id,
+			contract_id,
+			NULL AS ticket_id,
+			volume_delta_m3 AS volume_m3,
+			cost_delta AS cost,
+			reason,
+			created_at
+		FROM contract_usage_adjustments
+		WHERE `+condition+tail, args...).Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ListTripUsageLog возвращает рейсы, учтённые в usage контракта, новые первыми.
+func (r *ContractRepository) ListTripUsageLog(ctx context.Context, contractID uuid.UUID) ([]model.TripUsageLogEntry, error) {
+	var items []model.TripUsageLogEntry
+	err := withRetry(ctx, retryRead, func() error {
+		items = nil
+		return r.db.WithContext(ctx).Raw(`
+			SELECT trip_id, ticket_id, recorded_volume_m3, recorded_cost, created_at
+			FROM trip_usage_log
+			WHERE contract_id = ?
+			ORDER BY created_at DESC, trip_id
+		`, contractID).Scan(&items).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type budgetExhaustionRow struct {
+	ContractID  uuid.UUID
+	ExhaustedAt time.Time
+}
+
+// GetBudgetExhaustedAt возвращает момент, когда накопленная по журналу usage
+// стоимость впервые достигла budget_total. Контракты, не исчерпавшие бюджет,
+// в результат не попадают.
+func (r *ContractRepository) GetBudgetExhaustedAt(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]time.Time, error) {
+	var rows []budgetExhaustionRow
+	err := withRetry(ctx, retryRead, func() error {
+		rows = nil
+		return r.db.WithContext(ctx).Raw(`
+			WITH ledger AS (
+				SELECT contract_id, trip_id AS source_id, recorded_cost AS cost, created_at
+				FROM trip_usage_log
+				WHERE contract_id IN ?
+				UNION ALL
+				SELECT contract_id, id AS source_id, cost_delta AS cost, created_at
+				FROM contract_usage_adjustments
+				WHERE contract_id IN ?
+			), running AS (
+				SELECT
+					contract_id,
+					created_at,
+					SUM(cost) OVER (PARTITION BY contract_id ORDER BY created_at, source_id) AS running_cost
+				FROM ledger
+			)
+			SELECT DISTINCT ON (running.contract_id)
+				running.contract_id,
+				running.created_at AS exhausted_at
+			FROM running
+			JOIN contracts c ON c.id = running.contract_id
+			WHERE running.running_cost >= c.budget_total
+			ORDER BY running.contract_id, running.created_at
+		`, ids, ids).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uuid.UUID]time.Time, len(rows))
+	for _, row := range rows {
+		result[row.ContractID] = row.ExhaustedAt
+	}
+	return result, nil
+}
+
+type usageAsOfRow struct {
+	ContractID    uuid.UUID
+	TotalVolumeM3 float64
+	TotalCost     float64
+}
+
+// GetUsageAsOf пересчитывает usage по журналу (рейсы и корректировки), учитывая
+// только движения с created_at <= asOf. В отличие от contract_usage, это
+// агрегат по журналу на каждый вызов; индексы (contract_id, created_at)
+// ограничивают чтение строками нужных контрактов. Контракты без движений
+// получают нулевой usage.
+func (r *ContractRepository) GetUsageAsOf(ctx context.Context, ids []uuid.UUID, asOf time.Time) (map[uuid.UUID]*model.ContractUsage, error) {
+	result := make(map[uuid.UUID]*model.ContractUsage, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	var rows []usageAsOfRow
+	err := withRetry(ctx, retryRead, func() error {
+		rows = nil
+		return r.db.WithContext(ctx).Raw(`
+			SELECT
+				ledger.contract_id,
+				COALESCE(SUM(ledger.volume_m3), 0) AS total_volume_m3,
+				COALESCE(SUM(ledger.cost), 0) AS total_cost
+			FROM (
+				SELECT contract_id, recorded_volume_m3 AS volume_m3, recorded_cost AS cost
+				FROM trip_usage_log
+				WHERE contract_id IN ? AND created_at <= ?
+				UNION ALL
+				SELECT contract_id, volume_delta_m3 AS volume_m3, cost_delta AS cost
+				FROM contract_usage_adjustments
+				WHERE contract_id IN ? AND created_at <= ?
+			) ledger
+			GROUP BY ledger.contract_id
+		`, ids, asOf, ids, asOf).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		result[id] = &model.ContractUsage{ContractID: id, UpdatedAt: asOf}
+	}
+	for _, row := range rows {
+		if usage, ok := result[row.ContractID]; ok {
+			usage.TotalVolumeM3 = row.TotalVolumeM3
+			usage.TotalCost = row.TotalCost
+		}
+	}
+	return result, nil
+}
+
+// TripUsageStats — агрегаты журнала рейсов контракта (без ручных корректировок).
+type TripUsageStats struct {
+	TripCount      int64
+	AvgVolumeM3    float64
+	AvgCostPerTrip float64
+}
+
+func (r *ContractRepository) GetTripUsageStats(ctx context.Context, contractID uuid.UUID) (*TripUsageStats, error) {
+	var stats TripUsageStats
+	err := withRetry(ctx, retryRead, func() error {
+		return r.db.WithContext(ctx).Raw(`
+			SELECT
+				COUNT(*) AS trip_count,
+				COALESCE(AVG(recorded_volume_m3), 0) AS avg_volume_m3,
+				COALESCE(AVG(recorded_cost), 0) AS avg_cost_per_trip
+			FROM trip_usage_log
+			WHERE contract_id = ?
+		`, contractID).Scan(&stats).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+type UsageAdjustmentParams struct {
+	ContractID    uuid.UUID
+	VolumeDeltaM3 float64
+	CostDelta     float64
+	Reason        string
+	ActorUserID   uuid.UUID
+	ActorOrgID    uuid.UUID
+}
+
+// RecordUsageAdjustment пишет корректировку и применяет её к contract_usage
+// в одной транзакции под блокировкой строки контракта и возвращает изменение
+// usage. Итоги не могут стать отрицательными. Удалённый контракт —
+// ErrRecordNotFound, заблокированный — ErrContractLocked.
+func (r *ContractRepository) RecordUsageAdjustment(ctx context.Context, params UsageAdjustmentParams) (*model.UsageAdjustment, UsageChange, error) {
+	var (
+		adjustment model.UsageAdjustment
+		change     UsageChange
+	)
+	err := withRetry(ctx, retryRollback, func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// порядок блокировок — как у рейсов: сначала строка контракта
+			terms, err := lockContractForUsageTx(tx, params.ContractID)
+			if err != nil {
+				return err
+			}
+			if err := repairUsageTx(tx, params.ContractID); err != nil {
+				return err
+			}
+			change = UsageChange{Cost: params.CostDelta, BudgetTotal: terms.BudgetTotal}
+
+			var usage model.ContractUsage
+			if err := tx.Raw(`
+				SELECT total_volume_m3, total_cost
+				FROM contract_usage
+				WHERE contract_id = ?
+				FOR UPDATE
+			`, params.ContractID).Scan(&usage).Error; err != nil {
+				return err
+			}
+			if usage.TotalVolumeM3+params.VolumeDeltaM3 < 0 || usage.TotalCost+params.CostDelta < 0 {
+				return ErrUsageWouldBeNegative
+			}
+			change.TotalCostBefore = usage.TotalCost
+
+			if err := tx.Raw(`
+				INSERT INTO contract_usage_adjustments (
+					contract_id, volume_delta_m3, cost_delta, reason, actor_user_id, actor_org_id
+				)
+				VALUES (?, ?, ?, ?, ?, ?)
+				RETURNING id, contract_id, volume_delta_m3, cost_delta, reason, actor_user_id, actor_org_id, created_at
+			`, params.ContractID, params.VolumeDeltaM3, params.CostDelta, params.Reason,
+				params.ActorUserID, params.ActorOrgID).Scan(&adjustment).Error; err != nil {
+				return err
+			}
+
+			return tx.Raw(`
+				UPDATE contract_usage
+				SET
+					total_volume_m3 = total_volume_m3 + ?,
+					total_cost = total_cost + ?
+				WHERE contract_id = ?
+				RETURNING total_cost
+			`, params.VolumeDeltaM3, params.CostDelta, params.ContractID).Scan(&change.TotalCostAfter).Error
+		})
+	})
+	if err != nil {
+		return nil, UsageChange{}, err
+	}
+	return &adjustment, change, nil
+}
+
+// GetIDByClientReference возвращает id контракта с данным client_reference.
+func (r *ContractRepository) GetIDByClientReference(ctx context.Context, clientReference string) (uuid.UUID, error) {
+	var id uuid.UUID
+	err := withRetry(ctx, retryRead, func() error {
+		return r.db.WithContext(ctx).
+			Raw(`SELECT id FROM contracts WHERE client_reference = ? LIMIT 1`, clientReference).
+			Scan(&id).Error
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if id == uuid.Nil {
+		return uuid.Nil, gorm.ErrRecordNotFound
+	}
+	return id, nil
+}
+
+type CreateContractParams struct {
+	ContractorID    *uuid.UUID
+	LandfillID      *uuid.UUID
+	ContractType    model.ContractType
+	CreatedByOrgID  uuid.UUID
+	CreatedByUserID uuid.UUID
+	Name            string
+	WorkType        model.WorkType
+	PricePerM3      float64
+	BudgetTotal     float64
+	MinimalVolumeM3 float64
+	StartAt         time.Time
+	EndAt           time.Time
+	IsActive        bool
+	PolygonIDs      []uuid.UUID
+	PolygonBudgets  map[uuid.UUID]float64
+	ClientReference *string
+	// MaxActivePerOrg — лимит активных контрактов организации-создателя (0 — без лимита)
+	MaxActivePerOrg int
+	// IdempotencyKey сохраняется вместе с контрактом и отпечатком тела запроса
+	// IdempotencyHash; ключ, использованный после IdempotencyCutoff, даёт
+	// ErrIdempotencyKeyUsed и откат создания.
+	IdempotencyKey    *string
+	IdempotencyHash   string
+	IdempotencyCutoff time.Time
+}
+
+func (r *ContractRepository) Create(ctx context.Context, params CreateContractParams) (*model.Contract, error) {
+	var contract *model.Contract
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		created, err := createContractTx(tx, params)
+		if err != nil {
+			return err
+		}
+		if params.IdempotencyKey != nil {
+			if err := saveIdempotencyKeyTx(tx, params.CreatedByOrgID, *params.IdempotencyKey, params.IdempotencyHash, created.ID, params.IdempotencyCutoff); err != nil {
+				return err
+			}
+		}
+		// Лимит проверяется после вставки: повтор по client_reference или
+		// Idempotency-Key возвращает уже созданный контракт, даже когда лимит выбран.
+		if params.IsActive && params.MaxActivePerOrg > 0 {
+			if err := checkActiveContractLimit(tx, params.CreatedByOrgID, created.ID, params.MaxActivePerOrg); err != nil {
+				return err
+			}
+		}
+		contract = created
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return contract, nil
+}
+
+// saveIdempotencyKeyTx закрепляет ключ за созданным контрактом. Истёкший ключ
+// перезаписывается; живой — ErrIdempotencyKeyUsed. Параллельный create с тем же
+// ключом ждёт на уникальном индексе и после коммита первого получает ошибку.
+func saveIdempotencyKeyTx(tx *gorm.DB, orgID uuid.UUID, key, requestHash string, contractID uuid.UUID, cutoff time.Time) error {
+	result := tx.Exec(`
+		INSERT INTO contract_idempotency_keys (org_id, idempotency_key, contract_id, request_hash)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (org_id, idempotency_key) DO UPDATE
+		SET contract_id = EXCLUDED.contract_id, request_hash = EXCLUDED.request_hash, created_at = NOW()
+		WHERE contract_idempotency_keys.created_at <= ?
+	`, orgID, key, contractID, requestHash, cutoff)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdempotencyKeyUsed
+	}
+	return nil
+}
+
+// IdempotencyKey — сохранённый Idempotency-Key: созданный с ним контракт и
+// отпечаток тела запроса (nil у ключей, сохранённых до появления отпечатков).
+type IdempotencyKey struct {
+	ContractID  uuid.UUID
+	RequestHash *string
+}
+
+// GetIdempotencyKey возвращает ключ организации, использованный после cutoff.
+func (r *ContractRepository) GetIdempotencyKey(ctx context.Context, orgID uuid.UUID, key string, cutoff time.Time) (*IdempotencyKey, error) {
+	var record IdempotencyKey
+	err := withRetry(ctx, retryRead, func() error {
+		return r.db.WithContext(ctx).Raw(`
+			SELECT contract_id, request_hash
+			FROM contract_idempotency_keys
+			WHERE org_id = ? AND idempotency_key = ? AND created_at > ?
+			LIMIT 1
+		`, orgID, key, cutoff).Scan(&record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if record.ContractID == uuid.Nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &record, nil
+}
+
+// PurgeIdempotencyKeys удаляет ключи, созданные не позже cutoff.
+func (r *ContractRepository) PurgeIdempotencyKeys(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`DELETE FROM contract_idempotency_keys WHERE created_at <= ?`, cutoff)
+	return result.RowsAffected, result.Error
+}
+
+// checkActiveContractLimit считает активные контракты организации, кроме
+// excludeID, под advisory-блокировкой транзакции, чтобы параллельные create не
+// обошли лимит.
+func checkActiveContractLimit(tx *gorm.DB, orgID, excludeID uuid.UUID, limit int) error {
+	if err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext(?))`, "contracts:create:"+orgID.String()).Error; err != nil {
+		return err
+	}
+	var active int64
+	if err := tx.Raw(`
+		SELECT COUNT(*)
+		FROM contracts
+		WHERE created_by_org = ? AND id <> ? AND is_active = TRUE AND deleted_at IS NULL
+	`, orgID, excludeID).Scan(&active).Error; err != nil {
+		return err
+	}
+	if active >= int64(limit) {
+		return ErrActiveContractLimit
+	}
+	return nil
+}
+
+func createContractTx(tx *gorm.DB, params CreateContractParams) (*model.Contract, error) {
+	var contract model.Contract
+	err := tx.Raw(`
+		INSERT INTO contracts (
+			contractor_id,
+			landfill_id,
+			contract_type,
+			created_by_org,
+			created_by_user,
+			name,
+			work_type,
+			price_per_m3,
+			budget_total,
+			minimal_volume_m3,
+			start_at,
+			end_at,
+			is_active,
+			client_reference
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (client_reference) DO NOTHING
+		RETURNING
+			id,
+			contractor_id,
+			landfill_id,
+			created_by_org AS created_by_org_id,
+			created_by_user AS created_by_user_id,
+			contract_type,
+			name,
+			work_type,
+			price_per_m3,
+			budget_total,
+			minimal_volume_m3,
+			start_at,
+			end_at,
+			is_active,
+			client_reference,
+			created_at,
+			updated_at
+	`, params.ContractorID, params.LandfillID, string(params.ContractType), params.CreatedByOrgID, params.CreatedByUserID, params.Name, string(params.WorkType),
+		params.PricePerM3, params.BudgetTotal, params.MinimalVolumeM3,
+		params.StartAt, params.EndAt, params.IsActive, params.ClientReference).Scan(&contract).Error
+	if err != nil {
+		return nil, err
+	}
+	// ON CONFLICT DO NOTHING не возвращает строку — контракт уже создан ранее
+	if contract.ID == uuid.Nil {
+		return nil, ErrClientReferenceExists
+	}
+
+	// Create initial usage record
+	err = tx.Exec(`
+		INSERT INTO contract_usage (contract_id, total_volume_m3, total_cost)
+		VALUES (?, 0, 0)
+		ON CONFLICT (contract_id) DO NOTHING
+	`, contract.ID).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Сохраняем polygon_ids для LANDFILL_SERVICE контрактов
+	if params.ContractType == model.ContractTypeLandfillService && len(params.PolygonIDs) > 0 {
+		if err := setPolygonsTx(tx, contract.ID, params.PolygonIDs, params.PolygonBudgets); err != nil {
+			return nil, err
+		}
+		contract.PolygonIDs = params.PolygonIDs
+		polygons, err := getPolygonsTx(tx, contract.ID)
+		if err != nil {
+			return nil, err
+		}
+		contract.Polygons = polygons
+	}
+
+	return &contract, nil
+}
+
+// AssignTicketContract привязывает тикет к контракту. changed=false — тикет уже
+// был привязан к этому же контракту (идемпотентный повтор).
+func (r *ContractRepository) AssignTicketContract(ctx context.Context, ticketID, contractID uuid.UUID) (changed bool, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing struct {
+			ContractID *uuid.UUID
+		}
+		err := tx.Raw(`SELECT contract_id FROM tickets WHERE id = ? FOR UPDATE`, ticketID).Scan(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTicketNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if existing.ContractID != nil {
+			if *existing.ContractID == contractID {
+				return nil
+			}
+			return ErrTicketAlreadyLinked
+		}
+		result := tx.Exec(`UPDATE tickets SET contract_id = ? WHERE id = ?`, contractID, ticketID)
+		if result.Error != nil {
+			return result.Error
+		}
+		// Raw(...).Scan не возвращает ErrRecordNotFound, отсутствие тикета видно здесь
+		if result.RowsAffected == 0 {
+			return ErrTicketNotFound
+		}
+		changed = true
+		return nil
+	})
+	return changed, err
+}
+
+func (r *ContractRepository) GetContractIDByTicket(ctx context.Context, ticketID uuid.UUID) (uuid.UUID, error) {
+	var contractID uuid.UUID
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT contract_id FROM tickets WHERE id = ?
+	`, ticketID).Scan(&contractID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return uuid.Nil, ErrTicketNotFound
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if contractID == uuid.Nil {
+		return uuid.Nil, ErrTicketNotLinked
+	}
+	return contractID, nil
+}
+
+// TicketRef — поля тикета, нужные для подбора контракта.
+type TicketRef struct {
+	ID             uuid.UUID
+	ContractID     *uuid.UUID
+	CleaningAreaID uuid.UUID
+	PlannedStartAt time.Time
+	PlannedEndAt   time.Time
+}
+
+func (r *ContractRepository) GetTicketRef(ctx context.Context, ticketID uuid.UUID) (*TicketRef, error) {
+	var ticket TicketRef
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT id, contract_id, cleaning_area_id, planned_start_at, planned_end_at
+		FROM tickets
+		WHERE id = ?
+	`, ticketID).Scan(&ticket).Error
+	if err != nil {
+		return nil, err
+	}
+	if ticket.ID == uuid.Nil {
+		return nil, ErrTicketNotFound
+	}
+	return &ticket, nil
+}
+
+// FindContractCandidatesForTicket подбирает активные CONTRACTOR_SERVICE контракты,
+// период которых покрывает плановые даты тикета и к которым уже привязаны другие
+// тикеты того же участка уборки. Выше ранжируются контракты, тикеты которых
+// выгружались на те же полигоны, что и рейсы этого тикета. Лучший кандидат — первый.
+func (r *ContractRepository) FindContractCandidatesForTicket(ctx context.Context, ticket TicketRef) ([]model.TicketContractCandidate, error) {
+	var items []model.TicketContractCandidate
+	err := r.db.WithContext(ctx).Raw(`
+		WITH ticket_polygons AS (
+			SELECT DISTINCT polygon_id
+			FROM trips
+			WHERE ticket_id = ? AND polygon_id IS NOT NULL
+		)
+		SELECT
+			c.id AS contract_id,
+			c.name,
+			c.start_at,
+			c.end_at,
+			COUNT(t.id) AS area_tickets,
+			COUNT(t.id) FILTER (WHERE EXISTS (
+				SELECT 1
+				FROM trips tr
+				JOIN ticket_polygons tp ON tp.polygon_id = tr.polygon_id
+				WHERE tr.ticket_id = t.id
+			)) AS polygon_tickets
+		FROM contracts c
+		JOIN tickets t ON t.contract_id = c.id
+		WHERE t.cleaning_area_id = ?
+			AND t.id <> ?
+			AND c.contract_type = 'CONTRACTOR_SERVICE'
+			AND c.is_active = TRUE
+			AND c.deleted_at IS NULL
+			AND c.start_at <= ?
+			AND c.end_at >= ?
+		GROUP BY c.id, c.name, c.start_at, c.end_at
+		ORDER BY polygon_tickets DESC, area_tickets DESC, c.start_at DESC
+	`, ticket.ID, ticket.CleaningAreaID, ticket.ID, ticket.PlannedStartAt, ticket.PlannedEndAt).Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// RelinkTicketContract перепривязывает тикет к другому контракту (административная правка).
+// Рейсы тикета уже учтены в usage и полигонах старого контракта, поэтому тикет
+// с записями в trip_usage_log не перепривязывается (ErrTicketHasUsage). Проверка
+// идёт под блокировками строк обоих контрактов: рейс, записываемый параллельно,
+// либо уже в журнале, либо увидит новый контракт тикета (ErrTicketRelinked).
+func (r *ContractRepository) RelinkTicketContract(ctx context.Context, ticketID, contractID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ticket struct {
+			ID         uuid.UUID
+			ContractID *uuid.UUID
+		}
+		if err := tx.Raw(`SELECT id, contract_id FROM tickets WHERE id = ? FOR UPDATE`, ticketID).Scan(&ticket).Error; err != nil {
+			return err
+		}
+		if ticket.ID == uuid.Nil {
+			return ErrTicketNotFound
+		}
+		if ticket.ContractID != nil && *ticket.ContractID == contractID {
+			return nil
+		}
+
+		ids := []uuid.UUID{contractID}
+		if ticket.ContractID != nil {
+			ids = append(ids, *ticket.ContractID)
+		}
+		var locked []uuid.UUID
+		if err := tx.Raw(`SELECT id FROM contracts WHERE id IN ? ORDER BY id FOR UPDATE`, ids).Scan(&locked).Error; err != nil {
+			return err
+		}
+
+		var hasUsage bool
+		if err := tx.Raw(`SELECT EXISTS (SELECT 1 FROM trip_usage_log WHERE ticket_id = ?)`, ticketID).Scan(&hasUsage).Error; err != nil {
+			return err
+		}
+		if hasUsage {
+			return ErrTicketHasUsage
+		}
+		return tx.Exec(`UPDATE tickets SET contract_id = ? WHERE id = ?`, contractID, ticketID).Error
+	})
+}
+
+type TripUsageParams struct {
+	TripID     uuid.UUID
+	TicketID   uuid.UUID
+	VolumeM3   float64
+	ContractID uuid.UUID
+	RecordedAt *time.Time // nil — текущее время БД
+	// Исходное значение и единица от датчика (для аудита конвертации)
+	ReportedVolume float64
+	ReportedUnit   model.VolumeUnit
+	// RejectOverBudget — отклонить рейс (ErrBudgetExceeded), если с ним
+	// total_cost превысит budget_total; ровно budget_total допускается
+	RejectOverBudget bool
+}
+
+// UsageChange — изменение total_cost контракта одной записью usage,
+// прочитанное под блокировкой строки контракта.
+type UsageChange struct {
+	// Cost — стоимость записи (рейса или корректировки)
+	Cost        float64
+	BudgetTotal float64
+	// TotalCostBefore/TotalCostAfter — total_cost до и после записи
+	TotalCostBefore float64
+	TotalCostAfter  float64
+}
+
+// ExceededBudget — запись вывела total_cost за budget_total; ровно
+// budget_total превышением не считается.
+func (c UsageChange) ExceededBudget() bool {
+	return c.TotalCostBefore <= c.BudgetTotal && c.TotalCostAfter > c.BudgetTotal
+}
+
+// RecordTripUsage учитывает рейс и возвращает изменение usage контракта;
+// стоимость — по цене контракта на момент записи (см. insertTripUsage).
+func (r *ContractRepository) RecordTripUsage(ctx context.Context, params TripUsageParams) (UsageChange, error) {
+	var change UsageChange
+	// Транзакция целиком повторяется только после гарантированного отката
+	err := withRetry(ctx, retryRollback, func() error {
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			change, err = insertTripUsage(tx, params)
+			return err
+		})
+	})
+	return change, err
+}
+
+// RecordTripUsageBatch записывает рейсы одной транзакцией: либо все, либо ни одного,
+// и возвращает изменение usage каждым рейсом. При ошибке возвращается индекс рейса,
+// на котором транзакция откатилась (-1 — ошибка не связана с конкретным рейсом).
+// Строки контрактов пакета блокируются заранее в порядке id, чтобы пакеты с
+// общими контрактами не взаимоблокировались.
+func (r *ContractRepository) RecordTripUsageBatch(ctx context.Context, items []TripUsageParams) ([]UsageChange, int, error) {
+	changes := make([]UsageChange, len(items))
+	failed := -1
+	err := withRetry(ctx, retryRollback, func() error {
+		failed = -1
+		return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			ids := make([]uuid.UUID, 0, len(items))
+			for _, item := range items {
+				ids = append(ids, item.ContractID)
+			}
+			if err := tx.Exec(`
+				SELECT id FROM contracts WHERE id IN (?) ORDER BY id FOR UPDATE
+			`, ids).Error; err != nil {
+				return err
+			}
+			for i, item := range items {
+				change, err := insertTripUsage(tx, item)
+				if err != nil {
+					failed = i
+					return err
+				}
+				changes[i] = change
+			}
+			return nil
+		})
+	})
+	return changes, failed, err
+}
+
+// Порядок блокировок при изменении usage: сначала строка contracts (FOR UPDATE;
+// в пакете — всех контрактов по возрастанию id), затем contract_usage и
+// contract_polygons. Рейсы, корректировки и Update условий берут одну и ту же
+// блокировку contracts, поэтому смена цены и запись usage по одному контракту
+// выполняются по очереди.
+// Новый код, меняющий usage, должен брать блокировки в том же порядке.
+
+// usageContractTerms — условия контракта, прочитанные под блокировкой строки.
+type usageContractTerms struct {
+	ID          uuid.UUID
+	PricePerM3  float64
+	BudgetTotal float64
+	IsLocked    bool
+}
+
+// lockContractForUsageTx блокирует строку контракта до конца транзакции и
+// читает цену и бюджет. Удалённый контракт — ErrRecordNotFound, заблокированный —
+// ErrContractLocked.
+func lockContractForUsageTx(tx *gorm.DB, contractID uuid.UUID) (usageContractTerms, error) {
+	var terms usageContractTerms
+	if err := tx.Raw(`
+		SELECT id, price_per_m3, budget_total, is_locked
+		FROM contracts
+		WHERE id = ? AND deleted_at IS NULL
+		FOR UPDATE
+	`, contractID).Scan(&terms).Error; err != nil {
+		return terms, err
+	}
+	if terms.ID == uuid.Nil {
+		return terms, gorm.ErrRecordNotFound
+	}
+	if terms.IsLocked {
+		return terms, ErrContractLocked
+	}
+	return terms, nil
+}
+
+// insertTripUsage пишет рейс в журнал, полигон и contract_usage под
+// блокировкой строки контракта. Стоимость считается по price_per_m3,
+// прочитанной под этой блокировкой, поэтому параллельная смена цены не даёт
+// рейсу устаревшую цену. Итоги до и после рейса читаются под той же
+// блокировкой.
+func insertTripUsage(tx *gorm.DB, params TripUsageParams) (UsageChange, error) {
+	terms, err := lockContractForUsageTx(tx, params.ContractID)
+	if err != nil {
+		return UsageChange{}, err
+	}
+	// Контракт тикета найден до блокировки; перепривязка берёт ту же блокировку,
+	// поэтому после неё привязка тикета уже не изменится до конца транзакции.
+	var owner struct {
+		ContractID *uuid.UUID
+	}
+	if err := tx.Raw(`SELECT contract_id FROM tickets WHERE id = ?`, params.TicketID).Scan(&owner).Error; err != nil {
+		return UsageChange{}, err
+	}
+	if owner.ContractID == nil || *owner.ContractID != params.ContractID {
+		return UsageChange{}, ErrTicketRelinked
+	}
+	cost := params.VolumeM3 * terms.PricePerM3
+
+	// До записи рейса в журнал: если строки usage нет, она восстанавливается из
+	// прежних движений, и upsert ниже прибавит только этот рейс.
+	if err := repairUsageTx(tx, params.ContractID); err != nil {
+		return UsageChange{}, err
+	}
+	change := UsageChange{Cost: cost, BudgetTotal: terms.BudgetTotal}
+	if err := tx.Raw(`
+		SELECT total_cost FROM contract_usage WHERE contract_id = ?
+	`, params.ContractID).Scan(&change.TotalCostBefore).Error; err != nil {
+		return UsageChange{}, err
+	}
+	if params.RejectOverBudget {
+		if err := ensureWithinBudgetTx(tx, params.ContractID, cost); err != nil {
+			return UsageChange{}, err
+		}
+	}
+	if err := tx.Exec(`
+		INSERT INTO trip_usage_log (
+			trip_id, ticket_id, contract_id, recorded_volume_m3, recorded_cost,
+			reported_volume, reported_unit, created_at
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, COALESCE(?, NOW()))
+	`, params.TripID, params.TicketID, params.ContractID, params.VolumeM3, cost,
+		params.ReportedVolume, string(params.ReportedUnit), params.RecordedAt).Error; err != nil {
+		if isUniqueViolation(err) {
+			return UsageChange{}, ErrTripUsageDuplicate
+		}
+		return UsageChange{}, err
+	}
+	// Относим usage к полигону рейса, если он входит в контракт (LANDFILL_SERVICE)
+	if err := tx.Exec(`
+		UPDATE contract_polygons cp
+		SET
+			total_volume_m3 = cp.total_volume_m3 + ?,
+			total_cost = cp.total_cost + ?
+		FROM trips tr
+		WHERE tr.id = ?
+			AND cp.contract_id = ?
+			AND cp.polygon_id = tr.polygon_id
+	`, params.VolumeM3, cost, params.TripID, params.ContractID).Error; err != nil {
+		return UsageChange{}, err
+	}
+	if err := tx.Raw(`
+		INSERT INTO contract_usage (contract_id, total_volume_m3, total_cost)
+		VALUES (?, ?, ?)
+		ON CONFLICT (contract_id)
+		DO UPDATE SET
+			total_volume_m3 = contract_usage.total_volume_m3 + EXCLUDED.total_volume_m3,
+			total_cost = contract_usage.total_cost + EXCLUDED.total_cost,
 			updated_at = NOW()
-	`, contractID, volumeM3, cost).Error
-	return err
+		RETURNING total_cost
+	`, params.ContractID, params.VolumeM3, cost).Scan(&change.TotalCostAfter).Error; err != nil {
+		return UsageChange{}, err
+	}
+	return change, nil
 }
 
-func (r *ContractRepository) AssignTicketContract(ctx context.Context, ticketID, contractID uuid.UUID) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var existing struct {
-			ContractID *uuid.UUID
-		}
-		err := tx.Raw(`SELECT contract_id FROM tickets WHERE id = ? FOR UPDATE`, ticketID).Scan(&existing).Error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ErrTicketNotFound
-		}
-		if err != nil {
-			return err
-		}
-		if existing.ContractID != nil {
-			if *existing.ContractID == contractID {
-				return nil
-			}
-			return ErrTicketAlreadyLinked
-		}
-		return tx.Exec(`UPDATE tickets SET contract_id = ? WHERE id = ?`, contractID, ticketID).Error
+// ensureWithinBudgetTx проверяет, что рейс стоимостью cost не выведет
+// total_cost контракта за budget_total. Вызывающий уже держит блокировку
+// строки контракта (lockContractForUsageTx), поэтому параллельные рейсы
+// проверяются по очереди и не могут вместе превысить бюджет. Сравнение — в
+// NUMERIC, с округлением cost так же, как при записи в trip_usage_log.
+func ensureWithinBudgetTx(tx *gorm.DB, contractID uuid.UUID, cost float64) error {
+	// Запрос после блокировки видит usage, записанный предыдущим рейсом
+	var budget struct {
+		BudgetTotal float64
+		NewTotal    float64
+		Exceeded    bool
+	}
+	if err := tx.Raw(`
+		SELECT
+			c.budget_total,
+			COALESCE(u.total_cost, 0) + ROUND(?::numeric, 2) AS new_total,
+			COALESCE(u.total_cost, 0) + ROUND(?::numeric, 2) > c.budget_total AS exceeded
+		FROM contracts c
+		LEFT JOIN contract_usage u ON u.contract_id = c.id
+		WHERE c.id = ?
+	`, cost, cost, contractID).Scan(&budget).Error; err != nil {
+		return err
+	}
+	if budget.Exceeded {
+		return fmt.Errorf("%w (%.2f > %.2f)", ErrBudgetExceeded, budget.NewTotal, budget.BudgetTotal)
+	}
+	return nil
+}
+
+// CountBudgetExceeded — число неудалённых контрактов, у которых total_cost
+// больше budget_total (условие флага budget_exceeded).
+func (r *ContractRepository) CountBudgetExceeded(ctx context.Context) (int64, error) {
+	var count int64
+	err := withRetry(ctx, retryRead, func() error {
+		return r.db.WithContext(ctx).Raw(`
+			SELECT COUNT(*)
+			FROM contracts c
+			JOIN contract_usage u ON u.contract_id = c.id
+			WHERE c.deleted_at IS NULL
+				AND u.total_cost > c.budget_total
+		`).Scan(&count).Error
 	})
+	return count, err
 }
 
-func (r *ContractRepository) GetContractIDByTicket(ctx context.Context, ticketID uuid.UUID) (uuid.UUID, error) {
-	var contractID uuid.UUID
+// ListUsageDiscrepancies возвращает контракты, у которых contract_usage отличается
+// от суммы trip_usage_log больше чем на epsilon по объёму или стоимости.
+func (r *ContractRepository) ListUsageDiscrepancies(ctx context.Context, epsilon float64) ([]model.UsageDiscrepancy, error) {
+	var items []model.UsageDiscrepancy
 	err := r.db.WithContext(ctx).Raw(`
-		SELECT contract_id FROM tickets WHERE id = ?
-	`, ticketID).Scan(&contractID).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return uuid.Nil, ErrTicketNotFound
+		WITH log_agg AS (
+			SELECT
+				contract_id,
+				SUM(volume_m3) AS logged_volume_m3,
+				SUM(cost) AS logged_cost
+			FROM (`+usageLedgerSQL+`) ledger
+			GROUP BY contract_id
+		)
+		SELECT
+			c.id AS contract_id,
+			COALESCE(u.total_volume_m3, 0) AS usage_volume_m3,
+			COALESCE(u.total_cost, 0) AS usage_cost,
+			COALESCE(log_agg.logged_volume_m3, 0) AS logged_volume_m3,
+			COALESCE(log_agg.logged_cost, 0) AS logged_cost
+		FROM contracts c
+		LEFT JOIN contract_usage u ON u.contract_id = c.id
+		LEFT JOIN log_agg ON log_agg.contract_id = c.id
+		WHERE ABS(COALESCE(u.total_volume_m3, 0) - COALESCE(log_agg.logged_volume_m3, 0)) > ?
+			OR ABS(COALESCE(u.total_cost, 0) - COALESCE(log_agg.logged_cost, 0)) > ?
+		ORDER BY c.created_at DESC
+	`, epsilon, epsilon).Scan(&items).Error
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// TicketFilter — выборка тикетов контракта.
+type TicketFilter struct {
+	Status *model.TicketStatus
+	// From/To — границы planned_start_at включительно
+	From *time.Time
+	To   *time.Time
+	// Limit > 0 ограничивает выборку (поздние тикеты первыми); Offset — смещение
+	Limit  int
+	Offset int
+}
+
+// contractTicketConditions — условие WHERE по тикетам t контракта.
+func contractTicketConditions(contractID uuid.UUID, filter TicketFilter) (string, []interface{}) {
+	conditions := "t.contract_id = ?"
+	args := []interface{}{contractID}
+	if filter.Status != nil {
+		conditions += " AND t.status = ?"
+		args = append(args, string(*filter.Status))
 	}
+	if filter.From != nil {
+		conditions += " AND t.planned_start_at >= ?"
+		args = append(args, *filter.From)
+	}
+	if filter.To != nil {
+		conditions += " AND t.planned_start_at <= ?"
+		args = append(args, *filter.To)
+	}
+	return conditions, args
+}
+
+// ListContractTickets возвращает тикеты контракта по фильтру с прогрессом.
+// Фильтр и пагинация выбирают тикеты, а счётчики рейсов и назначений
+// считаются по каждому выбранному тикету целиком.
+func (r *ContractRepository) ListContractTickets(ctx context.Context, contractID uuid.UUID, filter TicketFilter) ([]model.ContractTicket, error) {
+	conditions, args := contractTicketConditions(contractID, filter)
+	pageClause := limitClause(filter.Limit, &args)
+	if filter.Offset > 0 {
+		pageClause += " OFFSET ?"
+		args = append(args, filter.Offset)
+	}
+
+	rows, err := r.queryContractTickets(ctx, conditions, pageClause, args)
 	if err != nil {
-		return uuid.Nil, err
+		return nil, err
 	}
-	if contractID == uuid.Nil {
-		return uuid.Nil, ErrTicketNotLinked
+	items := make([]model.ContractTicket, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row.ContractTicket)
 	}
-	return contractID, nil
+	return items, nil
 }
 
-type TripUsageParams struct {
-	TripID     uuid.UUID
-	TicketID   uuid.UUID
-	VolumeM3   float64
-	ContractID uuid.UUID
+// ListContractTicketsBatch — ListContractTickets без фильтра для нескольких
+// контрактов одним запросом. У контракта без тикетов — пустой срез.
+func (r *ContractRepository) ListContractTicketsBatch(ctx context.Context, contractIDs []uuid.UUID) (map[uuid.UUID][]model.ContractTicket, error) {
+	rows, err := r.queryContractTickets(ctx, "t.contract_id IN ?", "", []interface{}{contractIDs})
+	if err != nil {
+		return nil, err
+	}
+	tickets := make(map[uuid.UUID][]model.ContractTicket, len(contractIDs))
+	for _, id := range contractIDs {
+		tickets[id] = []model.ContractTicket{}
+	}
+	for _, row := range rows {
+		tickets[row.ContractID] = append(tickets[row.ContractID], row.ContractTicket)
+	}
+	return tickets, nil
 }
 
-func (r *ContractRepository) RecordTripUsage(ctx context.Context, params TripUsageParams, pricePerM3 float64) error {
-	cost := params.VolumeM3 * pricePerM3
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		if err := tx.Exec(`
-			INSERT INTO trip_usage_log (trip_id, ticket_id, contract_id, recorded_volume_m3, recorded_cost)
-			VALUES (?, ?, ?, ?, ?)
-		`, params.TripID, params.TicketID, params.ContractID, params.VolumeM3, cost).Error; err != nil {
-			if errors.Is(err, gorm.ErrDuplicatedKey) {
-				return ErrTripUsageDuplicate
-			}
-			return err
-		}
-		return tx.Exec(`
-			INSERT INTO contract_usage (contract_id, total_volume_m3, total_cost)
-			VALUES (?, ?, ?)
-			ON CONFLICT (contract_id)
-			DO UPDATE SET
-				total_volume_m3 = contract_usage.total_volume_m3 + EXCLUDED.total_volume_m3,
-				total_cost = contract_usage.total_cost + EXCLUDED.total_cost,
-				updated_at = NOW()
-		`, params.ContractID, params.VolumeM3, cost).Error
-	})
+type contractTicketRow struct {
+	ContractID uuid.UUID
+	model.ContractTicket
 }
 
-func (r *ContractRepository) ListContractTickets(ctx context.Context, contractID uuid.UUID) ([]model.ContractTicket, error) {
-	var items []model.ContractTicket
+// queryContractTickets выбирает тикеты по условию и странице, затем считает
+// рейсы и назначения по каждому выбранному тикету целиком.
+func (r *ContractRepository) queryContractTickets(ctx context.Context, conditions, pageClause string, args []interface{}) ([]contractTicketRow, error) {
+	var rows []contractTicketRow
 	err := r.db.WithContext(ctx).Raw(`
-		WITH trip_agg AS (
+		WITH page AS (
+			SELECT t.contract_id, t.id, t.cleaning_area_id, t.planned_start_at, t.planned_end_at, t.status
+			FROM tickets t
+			WHERE `+conditions+`
+			ORDER BY t.planned_start_at DESC, t.id DESC
+			`+pageClause+`
+		),
+		trip_agg AS (
 			SELECT
 				ticket_id,
 				COUNT(*) AS trip_count,
 				COALESCE(SUM(COALESCE(detected_volume_entry, 0)), 0) AS total_volume_m3
 			FROM trips
-			WHERE ticket_id IS NOT NULL
+			WHERE ticket_id IN (SELECT id FROM page)
 			GROUP BY ticket_id
 		),
 		assign_agg AS (
@@ -385,10 +1756,11 @@ func (r *ContractRepository) ListContractTickets(ctx context.Context, contractID
 				ticket_id,
 				COUNT(*) AS active_assignments
 			FROM ticket_assignments
-			WHERE is_active = TRUE
+			WHERE is_active = TRUE AND ticket_id IN (SELECT id FROM page)
 			GROUP BY ticket_id
 		)
 		SELECT
+			t.contract_id,
 			t.id,
 			t.cleaning_area_id,
 			ca.name AS cleaning_area_name,
@@ -398,23 +1770,85 @@ func (r *ContractRepository) ListContractTickets(ctx context.Context, contractID
 			COALESCE(trip_agg.trip_count, 0) AS trip_count,
 			COALESCE(trip_agg.total_volume_m3, 0) AS total_volume_m3,
 			COALESCE(assign_agg.active_assignments, 0) AS active_assignments
-		FROM tickets t
+		FROM page t
 		LEFT JOIN cleaning_areas ca ON ca.id = t.cleaning_area_id
 		LEFT JOIN trip_agg ON trip_agg.ticket_id = t.id
 		LEFT JOIN assign_agg ON assign_agg.ticket_id = t.id
-		WHERE t.contract_id = ?
-		ORDER BY t.planned_start_at DESC
-	`, contractID).Scan(&items).Error
+		ORDER BY t.planned_start_at DESC, t.id DESC
+	`, args...).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// CountContractTickets — число тикетов контракта по фильтру без учёта Limit/Offset.
+func (r *ContractRepository) CountContractTickets(ctx context.Context, contractID uuid.UUID, filter TicketFilter) (int64, error) {
+	conditions, args := contractTicketConditions(contractID, filter)
+	var total int64
+	err := r.db.WithContext(ctx).Raw(`SELECT COUNT(*) FROM tickets t WHERE `+conditions, args...).Scan(&total).Error
+	return total, err
+}
+
+type TripFilter struct {
+	Completed *bool
+	// Limit > 0 ограничивает выборку (новые рейсы первыми)
+	Limit int
+}
+
+func (r *ContractRepository) ListContractTrips(ctx context.Context, contractID uuid.UUID, filter TripFilter) ([]model.ContractTrip, error) {
+	conditions := "t.contract_id = ?"
+	args := []interface{}{contractID}
+	if filter.Completed != nil {
+		if *filter.Completed {
+			conditions += " AND tr.exit_at IS NOT NULL"
+		} else {
+			conditions += " AND tr.exit_at IS NULL"
+		}
+	}
+
+	rows, err := r.queryContractTrips(ctx, conditions+`
+		ORDER BY tr.entry_at DESC`+limitClause(filter.Limit, &args), args)
 	if err != nil {
 		return nil, err
 	}
+	items := make([]model.ContractTrip, 0, len(rows))
+	for _, row := range rows {
+		items = append(items, row.ContractTrip)
+	}
 	return items, nil
 }
 
-func (r *ContractRepository) ListContractTrips(ctx context.Context, contractID uuid.UUID) ([]model.ContractTrip, error) {
-	var items []model.ContractTrip
+// ListContractTripsBatch — рейсы по тикетам нескольких контрактов одним
+// запросом, новые первыми. У контракта без рейсов — пустой срез.
+func (r *ContractRepository) ListContractTripsBatch(ctx context.Context, contractIDs []uuid.UUID) (map[uuid.UUID][]model.ContractTrip, error) {
+	rows, err := r.queryContractTrips(ctx, `t.contract_id IN ?
+		ORDER BY t.contract_id, tr.entry_at DESC`, []interface{}{contractIDs})
+	if err != nil {
+		return nil, err
+	}
+	trips := make(map[uuid.UUID][]model.ContractTrip, len(contractIDs))
+	for _, id := range contractIDs {
+		trips[id] = []model.ContractTrip{}
+	}
+	for _, row := range rows {
+		trips[row.ContractID] = append(trips[row.ContractID], row.ContractTrip)
+	}
+	return trips, nil
+}
+
+type contractTripRow struct {
+	ContractID uuid.UUID
+	model.ContractTrip
+}
+
+// queryContractTrips выбирает рейсы тикетов t; tail — условие WHERE с
+// сортировкой и ограничением.
+func (r *ContractRepository) queryContractTrips(ctx context.Context, tail string, args []interface{}) ([]contractTripRow, error) {
+	var rows []contractTripRow
 	err := r.db.WithContext(ctx).Raw(`
 		SELECT
+			t.contract_id,
 			tr.id,
 			tr.ticket_id,
 			tr.ticket_assignment_id,
@@ -431,13 +1865,20 @@ func (r *ContractRepository) ListContractTrips(ctx context.Context, contractID u
 			tr.detected_volume_exit
 		FROM trips tr
 		JOIN tickets t ON t.id = tr.ticket_id
-		WHERE t.contract_id = ?
-		ORDER BY tr.entry_at DESC
-	`, contractID).Scan(&items).Error
+		WHERE `+tail, args...).Scan(&rows).Error
 	if err != nil {
 		return nil, err
 	}
-	return items, nil
+	return rows, nil
+}
+
+// limitClause возвращает " LIMIT ?" и добавляет limit в args; limit <= 0 — без ограничения.
+func limitClause(limit int, args *[]interface{}) string {
+	if limit <= 0 {
+		return ""
+	}
+	*args = append(*args, limit)
+	return " LIMIT ?"
 }
 
 // GetPolygonIDs возвращает список polygon_id для контракта
@@ -456,32 +1897,500 @@ func (r *ContractRepository) GetPolygonIDs(ctx context.Context, contractID uuid.
 	return polygonIDs, nil
 }
 
-// SetPolygonIDs устанавливает список polygon_id для контракта
-func (r *ContractRepository) SetPolygonIDs(ctx context.Context, contractID uuid.UUID, polygonIDs []uuid.UUID) error {
+type StatusSnapshot struct {
+	ContractID uuid.UUID
+	UIStatus   model.ContractUIStatus
+	Result     model.ContractResult
+}
+
+// autoDeactivateLockKey — ключ advisory-блокировки фоновой деактивации, чтобы
+// при нескольких репликах задача выполнялась только в одной.
+const autoDeactivateLockKey = "contracts:auto-deactivate"
+
+// DeactivateExpired выключает активные незаблокированные контракты с end_at
+// раньше cutoff и пишет событие в журнал; заблокированный контракт, как и в
+// BulkDeactivate, не меняется и выключится первым проходом после снятия
+// блокировки. acquired=false — блокировку держит другая реплика.
+func (r *ContractRepository) DeactivateExpired(ctx context.Context, cutoff time.Time) (deactivated []DeactivatedContract, acquired bool, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(`SELECT pg_try_advisory_xact_lock(hashtext(?))`, autoDeactivateLockKey).Scan(&acquired).Error; err != nil {
+			return err
+		}
+		if !acquired {
+			return nil
+		}
+
+		deactivated = nil
+		return tx.Raw(`
+			WITH deactivated AS (
+				UPDATE contracts
+				SET is_active = FALSE
+				WHERE is_active = TRUE AND is_locked = FALSE AND end_at < ? AND deleted_at IS NULL
+				RETURNING id, end_at, created_by_org, contractor_id, landfill_id
+			), logged AS (
+				INSERT INTO contract_audit_log (contract_id, action, details)
+				SELECT id, ?, jsonb_build_object('end_at', end_at, 'cutoff', ?::timestamptz)
+				FROM deactivated
+			)
+			SELECT id, created_by_org AS created_by_org_id, contractor_id, landfill_id
+			FROM deactivated
+		`, cutoff, string(model.AuditActionAutoDeactivated), cutoff).Scan(&deactivated).Error
+	})
+	return deactivated, acquired, err
+}
+
+// DeactivatedContract — контракт, выключенный DeactivateExpired или
+// BulkDeactivate, с организациями для события contract.archived.
+type DeactivatedContract struct {
+	ID             uuid.UUID
+	CreatedByOrgID uuid.UUID
+	ContractorID   *uuid.UUID
+	LandfillID     *uuid.UUID
+}
+
+// BulkDeactivate выключает активные незаблокированные контракты по фильтру и
+// пишет событие в журнал для каждого; всё в одной транзакции.
+func (r *ContractRepository) BulkDeactivate(ctx context.Context, filter ContractFilter, actorUserID, actorOrgID uuid.UUID) ([]DeactivatedContract, error) {
+	var deactivated []DeactivatedContract
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		matched := applyContractFilter(tx.Table("contracts c").Select("c.id"), filter)
+		deactivated = nil
+		return tx.Raw(`
+			WITH deactivated AS (
+				UPDATE contracts
+				SET is_active = FALSE
+				WHERE id IN (?) AND is_active = TRUE AND is_locked = FALSE
+				RETURNING id, end_at, created_by_org, contractor_id, landfill_id
+			), logged AS (
+				INSERT INTO contract_audit_log (contract_id, action, actor_user_id, actor_org_id, details)
+				SELECT id, ?, ?, ?, jsonb_build_object('end_at', end_at)
+				FROM deactivated
+			)
+			SELECT id, created_by_org AS created_by_org_id, contractor_id, landfill_id
+			FROM deactivated
+		`, matched, string(model.AuditActionBulkDeactivated), actorUserID, actorOrgID).Scan(&deactivated).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return deactivated, nil
+}
+
+// SetLock блокирует или снимает блокировку контракта и пишет запись в журнал.
+// Повторная блокировка возвращает ErrContractLocked; снятие идемпотентно.
+func (r *ContractRepository) SetLock(ctx context.Context, id uuid.UUID, locked bool, actorUserID, actorOrgID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var result *gorm.DB
+		action := model.AuditActionLocked
+		if locked {
+			result = tx.Exec(`
+				UPDATE contracts
+				SET is_locked = TRUE, locked_by = ?, locked_at = NOW()
+				WHERE id = ? AND is_locked = FALSE
+			`, actorUserID, id)
+		} else {
+			action = model.AuditActionUnlocked
+			result = tx.Exec(`
+				UPDATE contracts
+				SET is_locked = FALSE, locked_by = NULL, locked_at = NULL
+				WHERE id = ? AND is_locked = TRUE
+			`, id)
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			if locked {
+				return ErrContractLocked
+			}
+			return nil
+		}
+		return tx.Exec(`
+			INSERT INTO contract_audit_log (contract_id, action, actor_user_id, actor_org_id)
+			VALUES (?, ?, ?, ?)
+		`, id, string(action), actorUserID, actorOrgID).Error
+	})
+}
+
+// SaveStatusSnapshots сохраняет вычисленные ui_status/result в колонках contracts
+// одной транзакцией.
+func (r *ContractRepository) SaveStatusSnapshots(ctx context.Context, snapshots []StatusSnapshot, computedAt time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, snapshot := range snapshots {
+			if err := tx.Exec(`
+				UPDATE contracts
+				SET ui_status = ?, result = ?, status_computed_at = ?
+				WHERE id = ?
+			`, string(snapshot.UIStatus), string(snapshot.Result), computedAt, snapshot.ContractID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetFilterOptions возвращает значения для фильтров, встречающиеся среди контрактов,
+// подходящих под filter (обычно — только ограничение видимости по роли).
+func (r *ContractRepository) GetFilterOptions(ctx context.Context, filter ContractFilter) (*model.ContractFilterOptions, error) {
+	base := func() *gorm.DB {
+		return applyContractFilter(r.db.WithContext(ctx).Table("contracts c"), filter)
+	}
+
+	options := model.ContractFilterOptions{
+		Contractors:   []model.OrganizationLookup{},
+		Landfills:     []model.OrganizationLookup{},
+		ContractTypes: []model.ContractType{},
+		WorkTypes:     []model.WorkType{},
+		Statuses:      []model.ContractUIStatus{},
+	}
+
+	if err := base().
+		Select("DISTINCT c.contractor_id AS id, COALESCE(o.name, '') AS name").
+		Joins("LEFT JOIN organizations o ON o.id = c.contractor_id").
+		Where("c.contractor_id IS NOT NULL").
+		Order("name").
+		Scan(&options.Contractors).Error; err != nil {
+		return nil, err
+	}
+
+	if err := base().
+		Select("DISTINCT c.landfill_id AS id, COALESCE(o.name, '') AS name").
+		Joins("LEFT JOIN organizations o ON o.id = c.landfill_id").
+		Where("c.landfill_id IS NOT NULL").
+		Order("name").
+		Scan(&options.Landfills).Error; err != nil {
+		return nil, err
+	}
+
+	if err := base().
+		Select("DISTINCT c.contract_type").
+		Order("c.contract_type").
+		Scan(&options.ContractTypes).Error; err != nil {
+		return nil, err
+	}
+
+	if err := base().
+		Select("DISTINCT c.work_type").
+		Where("c.work_type <> ''").
+		Order("c.work_type").
+		Scan(&options.WorkTypes).Error; err != nil {
+		return nil, err
+	}
+
+	now := filter.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if err := base().
+		// границы как в statusCondition: ACTIVE — start_at <= now <= end_at
+		Select(`DISTINCT CASE
+			WHEN c.is_active = FALSE THEN 'ARCHIVED'
+			WHEN c.start_at > ? THEN 'PLANNED'
+			WHEN c.end_at < ? THEN 'EXPIRED'
+			ELSE 'ACTIVE'
+		END AS status`, now, now).
+		Order("status").
+		Scan(&options.Statuses).Error; err != nil {
+		return nil, err
+	}
+
+	return &options, nil
+}
+
+type UtilizationBucketCount struct {
+	Bucket int
+	Count  int64
+}
+
+// UtilizationDistribution группирует контракты по утилизации бюджета
+// (total_cost / budget_total * 100) с помощью width_bucket по границам bounds
+// (по возрастанию, в процентах). Номер 1 — [bounds[0], bounds[1]), len(bounds) — >= последней границы.
+func (r *ContractRepository) UtilizationDistribution(ctx context.Context, filter ContractFilter, bounds []float64) ([]UtilizationBucketCount, error) {
+	parts := make([]string, 0, len(bounds))
+	for _, bound := range bounds {
+		parts = append(parts, strconv.FormatFloat(bound, 'f', -1, 64))
+	}
+	boundsArray := "{" + strings.Join(parts, ",") + "}"
+
+	query := r.db.WithContext(ctx).Table("contracts c").
+		Select(`
+			width_bucket(COALESCE(u.total_cost, 0) / c.budget_total * 100, ?::numeric[]) AS bucket,
+			COUNT(*) AS count
+		`, boundsArray).
+		Joins("LEFT JOIN contract_usage u ON u.contract_id = c.id").
+		Where("c.budget_total > 0")
+	query = applyContractFilter(query, filter)
+
+	var items []UtilizationBucketCount
+	if err := query.Group("bucket").Order("bucket").Scan(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+type MonthlySpendRow struct {
+	Month           int
+	TripCount       int64
+	AdjustmentCount int64
+	TotalVolumeM3   float64
+	TotalCost       float64
+}
+
+// ContractorMonthlySpend суммирует движения usage неудалённых контрактов
+// подрядчика по месяцам (UTC) в интервале [from, to): рейсы и ручные
+// корректировки (по created_at корректировки), как в usageLedgerSQL, чтобы
+// сумма совпадала с used_amount. Месяцы без движений в результат не попадают.
+func (r *ContractRepository) ContractorMonthlySpend(ctx context.Context, contractorID uuid.UUID, from, to time.Time) ([]MonthlySpendRow, error) {
+	var rows []MonthlySpendRow
+	err := withRetry(ctx, retryRead, func() error {
+		rows = nil
+		return r.db.WithContext(ctx).Raw(`
+			SELECT
+				EXTRACT(MONTH FROM m.created_at AT TIME ZONE 'UTC')::int AS month,
+				COUNT(*) FILTER (WHERE m.is_trip) AS trip_count,
+				COUNT(*) FILTER (WHERE NOT m.is_trip) AS adjustment_count,
+				COALESCE(SUM(m.volume_m3), 0) AS total_volume_m3,
+				COALESCE(SUM(m.cost), 0) AS total_cost
+			FROM (
+				SELECT contract_id, recorded_volume_m3 AS volume_m3, recorded_cost AS cost, created_at, TRUE AS is_trip
+				FROM trip_usage_log
+				UNION ALL
+				SELECT contract_id, volume_delta_m3, cost_delta, created_at, FALSE
+				FROM contract_usage_adjustments
+			) m
+			JOIN contracts c ON c.id = m.contract_id
+			WHERE c.contractor_id = ?
+				AND c.contract_type = ?
+				AND c.deleted_at IS NULL
+				AND m.created_at >= ?
+				AND m.created_at < ?
+			GROUP BY month
+			ORDER BY month
+		`, contractorID, string(model.ContractTypeContractorService), from, to).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+type ContractorPerformanceRow struct {
+	ContractorID   uuid.UUID
+	ContractCount  int64
+	ExpiredCount   int64
+	SuccessCount   int64
+	OnTimeCount    int64
+	AvgUtilization *float64
+}
+
+// ContractorPerformance агрегирует контракты CONTRACTOR_SERVICE по подрядчикам:
+// истёкшие (end_at < now, в том числе уже выключенные), выполнившие минимальный объём с допуском
+// tolerance, выполнившие его до end_at (по накопленной сумме журнала usage) и
+// средняя утилизация бюджета. endFrom/endTo ограничивают контракты по end_at.
+func (r *ContractRepository) ContractorPerformance(ctx context.Context, endFrom, endTo *time.Time, now time.Time, tolerance float64) ([]ContractorPerformanceRow, error) {
+	conditions := []string{"c.contract_type = ?", "c.contractor_id IS NOT NULL", "c.deleted_at IS NULL"}
+	args := []interface{}{now, string(model.ContractTypeContractorService)}
+	if endFrom != nil {
+		conditions = append(conditions, "c.end_at >= ?")
+		args = append(args, *endFrom)
+	}
+	if endTo != nil {
+		conditions = append(conditions, "c.end_at <= ?")
+		args = append(args, *endTo)
+	}
+	args = append(args, tolerance, tolerance)
+
+	var rows []ContractorPerformanceRow
+	err := withRetry(ctx, retryRead, func() error {
+		rows = nil
+		return r.db.WithContext(ctx).Raw(`
+			WITH scoped AS (
+				SELECT
+					c.id,
+					c.contractor_id,
+					c.budget_total,
+					c.minimal_volume_m3,
+					c.end_at,
+					c.end_at < ? AS expired,
+					COALESCE(u.total_volume_m3, 0) AS volume_m3,
+					COALESCE(u.total_cost, 0) AS cost
+				FROM contracts c
+				LEFT JOIN contract_usage u ON u.contract_id = c.id
+				WHERE `+strings.Join(conditions, " AND ")+`
+			),
+			reached AS (
+				SELECT contract_id, MIN(created_at) AS reached_at
+				FROM (
+					SELECT
+						l.contract_id,
+						l.created_at,
+						s.minimal_volume_m3,
+						SUM(l.volume_m3) OVER (PARTITION BY l.contract_id ORDER BY l.created_at) AS running_m3
+					FROM (
+						SELECT contract_id, recorded_volume_m3 AS volume_m3, created_at FROM trip_usage_log
+						UNION ALL
+						SELECT contract_id, volume_delta_m3 AS volume_m3, created_at FROM contract_usage_adjustments
+					) l
+					JOIN scoped s ON s.id = l.contract_id
+				) running
+				WHERE running_m3 >= minimal_volume_m3 * (1 - ?)
+				GROUP BY contract_id
+			)
+			SELECT
+				s.contractor_id,
+				COUNT(*) AS contract_count,
+				COUNT(*) FILTER (WHERE s.expired) AS expired_count,
+				COUNT(*) FILTER (WHERE s.expired AND s.volume_m3 >= s.minimal_volume_m3 * (1 - ?)) AS success_count,
+				COUNT(*) FILTER (WHERE s.expired AND (s.minimal_volume_m3 <= 0 OR r.reached_at <= s.end_at)) AS on_time_count,
+				AVG(s.cost / s.budget_total) FILTER (WHERE s.budget_total > 0) AS avg_utilization
+			FROM scoped s
+			LEFT JOIN reached r ON r.contract_id = s.id
+			GROUP BY s.contractor_id
+		`, args...).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// GetPolygons возвращает полигоны контракта с бюджетом и накопленным usage
+func (r *ContractRepository) GetPolygons(ctx context.Context, contractID uuid.UUID) ([]model.ContractPolygon, error) {
+	return getPolygonsTx(r.db.WithContext(ctx), contractID)
+}
+
+// GetPolygonsBatch — GetPolygons для нескольких контрактов одним запросом.
+// У контракта без полигонов — пустой срез.
+func (r *ContractRepository) GetPolygonsBatch(ctx context.Context, contractIDs []uuid.UUID) (map[uuid.UUID][]model.ContractPolygon, error) {
+	var rows []struct {
+		ContractID uuid.UUID
+		model.ContractPolygon
+	}
+	err := withRetry(ctx, retryRead, func() error {
+		rows = nil
+		return r.db.WithContext(ctx).
+			Raw(`
+			SELECT contract_id, polygon_id, budget, total_volume_m3, total_cost
+			FROM contract_polygons
+			WHERE contract_id IN ?
+			ORDER BY contract_id, polygon_id
+		`, contractIDs).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	polygons := make(map[uuid.UUID][]model.ContractPolygon, len(contractIDs))
+	for _, id := range contractIDs {
+		polygons[id] = []model.ContractPolygon{}
+	}
+	for _, row := range rows {
+		polygons[row.ContractID] = append(polygons[row.ContractID], row.ContractPolygon)
+	}
+	return polygons, nil
+}
+
+func getPolygonsTx(tx *gorm.DB, contractID uuid.UUID) ([]model.ContractPolygon, error) {
+	var polygons []model.ContractPolygon
+	err := tx.
+		Raw(`
+			SELECT polygon_id, budget, total_volume_m3, total_cost
+			FROM contract_polygons
+			WHERE contract_id = ?
+			ORDER BY polygon_id
+		`, contractID).Scan(&polygons).Error
+	if err != nil {
+		return nil, err
+	}
+	return polygons, nil
+}
+
+// ListLandfillPolygonCoverage возвращает полигоны, покрытые действующими на
+// момент now контрактами приёма полигона, со списком покрывающих контрактов.
+func (r *ContractRepository) ListLandfillPolygonCoverage(ctx context.Context, landfillID uuid.UUID, now time.Time) ([]model.LandfillPolygonCoverage, error) {
+	var rows []struct {
+		PolygonID  uuid.UUID
+		ContractID uuid.UUID
+	}
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT cp.polygon_id, c.id AS contract_id
+		FROM contracts c
+		JOIN contract_polygons cp ON cp.contract_id = c.id
+		WHERE c.landfill_id = ?
+			AND c.contract_type = ?
+			AND c.is_active = TRUE
+			AND c.deleted_at IS NULL
+			AND c.start_at <= ?
+			AND c.end_at >= ?
+		ORDER BY cp.polygon_id, c.id
+	`, landfillID, string(model.ContractTypeLandfillService), now, now).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.LandfillPolygonCoverage, 0)
+	for _, row := range rows {
+		if n := len(items); n > 0 && items[n-1].PolygonID == row.PolygonID {
+			items[n-1].ContractIDs = append(items[n-1].ContractIDs, row.ContractID)
+			continue
+		}
+		items = append(items, model.LandfillPolygonCoverage{
+			PolygonID:   row.PolygonID,
+			ContractIDs: []uuid.UUID{row.ContractID},
+		})
+	}
+	return items, nil
+}
+
+func polygonIDsOf(polygons []model.ContractPolygon) []uuid.UUID {
+	if len(polygons) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(polygons))
+	for _, polygon := range polygons {
+		ids = append(ids, polygon.PolygonID)
+	}
+	return ids
+}
+
+// SetPolygons устанавливает список полигонов контракта с необязательным бюджетом по каждому
+func (r *ContractRepository) SetPolygons(ctx context.Context, contractID uuid.UUID, polygonIDs []uuid.UUID, budgets map[uuid.UUID]float64) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return setPolygonsTx(tx, contractID, polygonIDs, budgets)
+	})
+}
+
+func setPolygonsTx(tx *gorm.DB, contractID uuid.UUID, polygonIDs []uuid.UUID, budgets map[uuid.UUID]float64) error {
 	// Удаляем существующие связи
-	if err := r.db.WithContext(ctx).Exec(`
+	if err := tx.Exec(`
 		DELETE FROM contract_polygons
 		WHERE contract_id = ?
 	`, contractID).Error; err != nil {
 		return err
 	}
 
-	// Добавляем новые связи
-	if len(polygonIDs) > 0 {
-		for _, polygonID := range polygonIDs {
-			if err := r.db.WithContext(ctx).Exec(`
-				INSERT INTO contract_polygons (contract_id, polygon_id)
-				VALUES (?, ?)
-				ON CONFLICT (contract_id, polygon_id) DO NOTHING
-			`, contractID, polygonID).Error; err != nil {
-				return err
-			}
+	for _, polygonID := range polygonIDs {
+		var budget *float64
+		if value, ok := budgets[polygonID]; ok {
+			budget = &value
+		}
+		if err := tx.Exec(`
+			INSERT INTO contract_polygons (contract_id, polygon_id, budget)
+			VALUES (?, ?, ?)
+			ON CONFLICT (contract_id, polygon_id) DO NOTHING
+		`, contractID, polygonID, budget).Error; err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// SetPolygonIDs устанавливает список polygon_id для контракта без бюджетов
+func (r *ContractRepository) SetPolygonIDs(ctx context.Context, contractID uuid.UUID, polygonIDs []uuid.UUID) error {
+	return r.SetPolygons(ctx, contractID, polygonIDs, nil)
+}
+
 // Delete deletes a contract by ID
 func (r *ContractRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	result := r.db.WithContext(ctx).
@@ -0,0 +1,715 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/dbtest"
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+func newTestRepository(t *testing.T) (*ContractRepository, *gorm.DB) {
+	t.Helper()
+	database := dbtest.Open(t)
+	return NewContractRepository(database), database
+}
+
+// createTestContract создаёт активный CONTRACTOR_SERVICE контракт (100 за м3,
+// бюджет 100 000, действует сейчас); mutate правит параметры до создания.
+func createTestContract(t testing.TB, r *ContractRepository, database *gorm.DB, mutate func(*CreateContractParams)) *model.Contract {
+	t.Helper()
+	contractorID := dbtest.Organization(t, database, "ТОО Подрядчик")
+	now := time.Now().UTC().Truncate(time.Second)
+	params := CreateContractParams{
+		ContractType:    model.ContractTypeContractorService,
+		ContractorID:    &contractorID,
+		CreatedByOrgID:  dbtest.Organization(t, database, "КГУ ЖКХ"),
+		CreatedByUserID: uuid.New(),
+		Name:            "Уборка дорог",
+		WorkType:        model.WorkTypeRoad,
+		PricePerM3:      100,
+		BudgetTotal:     100000,
+		MinimalVolumeM3: 500,
+		StartAt:         now.Add(-24 * time.Hour),
+		EndAt:           now.Add(30 * 24 * time.Hour),
+		IsActive:        true,
+	}
+	if mutate != nil {
+		mutate(&params)
+	}
+	contract, err := r.Create(context.Background(), params)
+	if err != nil {
+		t.Fatalf("create contract: %v", err)
+	}
+	return contract
+}
+
+func TestListStatusFilterBoundaries(t *testing.T) {
+	ctx := context.Background()
+	r, database := newTestRepository(t)
+	startAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endAt := time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)
+	contract := createTestContract(t, r, database, func(p *CreateContractParams) {
+		p.StartAt = startAt
+		p.EndAt = endAt
+	})
+
+	statuses := []model.ContractUIStatus{
+		model.ContractUIStatusPlanned,
+		model.ContractUIStatusActive,
+		model.ContractUIStatusExpired,
+		model.ContractUIStatusArchived,
+	}
+	// Postgres хранит время с точностью до микросекунды
+	moments := []time.Time{
+		startAt.Add(-time.Microsecond),
+		startAt,
+		endAt,
+		endAt.Add(time.Microsecond),
+	}
+	for _, now := range moments {
+		want := model.DeriveUIStatus(true, startAt, endAt, now)
+		var matched []model.ContractUIStatus
+		for _, status := range statuses {
+			items, err := r.List(ctx, ContractFilter{IDs: []uuid.UUID{contract.ID}, Status: &status, Now: now})
+			if err != nil {
+				t.Fatalf("list status=%s: %v", status, err)
+			}
+			if len(items) > 0 {
+				matched = append(matched, status)
+			}
+		}
+		if len(matched) != 1 || matched[0] != want {
+			t.Fatalf("now=%s: SQL filter matched %v, DeriveUIStatus = %s", now.Format(time.RFC3339Nano), matched, want)
+		}
+	}
+}
+
+// recordTrip записывает рейс объёмом volumeM3 по новому тикету контракта.
+func recordTrip(t *testing.T, r *ContractRepository, database *gorm.DB, contractID uuid.UUID, volumeM3 float64) error {
+	t.Helper()
+	ticketID := dbtest.Ticket(t, database, contractID)
+	tripID := dbtest.Trip(t, database, ticketID, uuid.Nil)
+	_, err := r.RecordTripUsage(context.Background(), TripUsageParams{
+		TripID:         tripID,
+		TicketID:       ticketID,
+		VolumeM3:       volumeM3,
+		ContractID:     contractID,
+		ReportedVolume: volumeM3,
+		ReportedUnit:   model.VolumeUnitM3,
+	})
+	return err
+}
+
+func assertUsage(t *testing.T, r *ContractRepository, contractID uuid.UUID, wantVolume, wantCost float64) {
+	t.Helper()
+	usage, err := r.GetUsage(context.Background(), contractID)
+	if err != nil {
+		t.Fatalf("get usage: %v", err)
+	}
+	if usage == nil {
+		t.Fatalf("contract_usage row is missing")
+	}
+	if usage.TotalVolumeM3 != wantVolume || usage.TotalCost != wantCost {
+		t.Fatalf("usage = %.2f m3 / %.2f, want %.2f m3 / %.2f", usage.TotalVolumeM3, usage.TotalCost, wantVolume, wantCost)
+	}
+}
+
+func TestUsageRowDeletedOutOfBandIsRebuilt(t *testing.T) {
+	ctx := context.Background()
+	r, database := newTestRepository(t)
+	contract := createTestContract(t, r, database, nil)
+
+	if err := recordTrip(t, r, database, contract.ID, 10); err != nil {
+		t.Fatalf("record first trip: %v", err)
+	}
+	dbtest.Exec(t, database, `DELETE FROM contract_usage WHERE contract_id = ?`, contract.ID)
+
+	// строка восстанавливается из журнала, а не заменяется дельтой нового рейса
+	if err := recordTrip(t, r, database, contract.ID, 5); err != nil {
+		t.Fatalf("record trip after delete: %v", err)
+	}
+	assertUsage(t, r, contract.ID, 15, 1500)
+
+	dbtest.Exec(t, database, `DELETE FROM contract_usage WHERE contract_id = ?`, contract.ID)
+	if _, _, err := r.RecordUsageAdjustment(ctx, UsageAdjustmentParams{
+		ContractID:    contract.ID,
+		VolumeDeltaM3: -2,
+		CostDelta:     -200,
+		Reason:        "повторный рейс",
+		ActorUserID:   uuid.New(),
+		ActorOrgID:    contract.CreatedByOrgID,
+	}); err != nil {
+		t.Fatalf("record adjustment after delete: %v", err)
+	}
+	assertUsage(t, r, contract.ID, 13, 1300)
+
+	dbtest.Exec(t, database, `DELETE FROM contract_usage WHERE contract_id = ?`, contract.ID)
+	usage, err := r.RepairUsage(ctx, contract.ID)
+	if err != nil {
+		t.Fatalf("repair usage: %v", err)
+	}
+	if usage == nil || usage.TotalVolumeM3 != 13 || usage.TotalCost != 1300 {
+		t.Fatalf("repaired usage = %+v, want 13 m3 / 1300", usage)
+	}
+}
+
+func TestUsageWritesSerializeWithPriceUpdate(t *testing.T) {
+	ctx := context.Background()
+	r, database := newTestRepository(t)
+	contract := createTestContract(t, r, database, nil)
+
+	// по рейсу на горутину и раунд; тикеты и рейсы готовятся заранее
+	const rounds = 20
+	trips := make([][]TripUsageParams, 2)
+	for g := range trips {
+		for i := 0; i < rounds; i++ {
+			ticketID := dbtest.Ticket(t, database, contract.ID)
+			trips[g] = append(trips[g], TripUsageParams{
+				TripID:         dbtest.Trip(t, database, ticketID, uuid.Nil),
+				TicketID:       ticketID,
+				VolumeM3:       1.5,
+				ContractID:     contract.ID,
+				ReportedVolume: 1.5,
+				ReportedUnit:   model.VolumeUnitM3,
+			})
+		}
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*rounds+1)
+	for g := range trips {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, params := range trips[g] {
+				if _, err := r.RecordTripUsage(ctx, params); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, err := r.Update(ctx, UpdateContractParams{
+			ID: contract.ID,
+			Apply: func(current model.Contract) (model.Contract, []string, error) {
+				current.PricePerM3 = 150
+				return current, []string{"price_per_m3"}, nil
+			},
+			Reason:      "индексация",
+			ActorUserID: uuid.New(),
+			ActorOrgID:  contract.CreatedByOrgID,
+		})
+		if err != nil {
+			errs <- err
+		}
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent write: %v", err)
+	}
+
+	// каждый рейс оценён одной из цен, а итоги сходятся с журналом
+	log, err := r.ListTripUsageLog(ctx, contract.ID)
+	if err != nil {
+		t.Fatalf("list trip usage log: %v", err)
+	}
+	if len(log) != 2*rounds {
+		t.Fatalf("trip_usage_log rows = %d, want %d", len(log), 2*rounds)
+	}
+	var totalCost float64
+	for _, entry := range log {
+		if entry.RecordedCost != 150 && entry.RecordedCost != 225 {
+			t.Fatalf("trip %s cost = %.2f, want 150 (old price) or 225 (new price)", entry.TripID, entry.RecordedCost)
+		}
+		totalCost += entry.RecordedCost
+	}
+	assertUsage(t, r, contract.ID, 1.5*2*rounds, totalCost)
+	discrepancies, err := r.ListUsageDiscrepancies(ctx, 0.001)
+	if err != nil {
+		t.Fatalf("list discrepancies: %v", err)
+	}
+	if len(discrepancies) != 0 {
+		t.Fatalf("usage discrepancies = %+v", discrepancies)
+	}
+}
+
+func TestUsageAdjustmentRespectsContractLock(t *testing.T) {
+	ctx := context.Background()
+	r, database := newTestRepository(t)
+	contract := createTestContract(t, r, database, nil)
+	dbtest.Exec(t, database, `UPDATE contracts SET is_locked = TRUE WHERE id = ?`, contract.ID)
+
+	_, _, err := r.RecordUsageAdjustment(ctx, UsageAdjustmentParams{
+		ContractID:  contract.ID,
+		CostDelta:   100,
+		Reason:      "досчёт",
+		ActorUserID: uuid.New(),
+		ActorOrgID:  contract.CreatedByOrgID,
+	})
+	if !errors.Is(err, ErrContractLocked) {
+		t.Fatalf("adjustment of locked contract: err = %v, want ErrContractLocked", err)
+	}
+	assertUsage(t, r, contract.ID, 0, 0)
+}
+
+func TestListUtilizationBoundaries(t *testing.T) {
+	ctx := context.Background()
+	r, database := newTestRepository(t)
+	// бюджет 100 000 при цене 100: 500 м3 — 50%, 1000 м3 — ровно 100%, 1200 м3 — 120%
+	half := createTestContract(t, r, database, nil)
+	full := createTestContract(t, r, database, nil)
+	over := createTestContract(t, r, database, nil)
+	for _, item := range []struct {
+		id     uuid.UUID
+		volume float64
+	}{{half.ID, 500}, {full.ID, 1000}, {over.ID, 1200}} {
+		if err := recordTrip(t, r, database, item.id, item.volume); err != nil {
+			t.Fatalf("record trip: %v", err)
+		}
+	}
+	// без usage — 0%
+	empty := createTestContract(t, r, database, nil)
+	ids := []uuid.UUID{half.ID, full.ID, over.ID, empty.ID}
+
+	ratio := func(v float64) *float64 { return &v }
+	tests := []struct {
+		name     string
+		min, max *float64
+		want     []uuid.UUID
+	}{
+		{name: ">= 100%", min: ratio(1), want: []uuid.UUID{full.ID, over.ID}},
+		{name: "<= 100%", max: ratio(1), want: []uuid.UUID{half.ID, full.ID, empty.ID}},
+		{name: "exactly 100%", min: ratio(1), max: ratio(1), want: []uuid.UUID{full.ID}},
+		{name: ">= 50%", min: ratio(0.5), want: []uuid.UUID{half.ID, full.ID, over.ID}},
+		{name: "< 50%", max: ratio(0.49), want: []uuid.UUID{empty.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := r.List(ctx, ContractFilter{IDs: ids, MinUtilization: tt.min, MaxUtilization: tt.max})
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			got := make(map[uuid.UUID]bool, len(items))
+			for _, item := range items {
+				got[item.ID] = true
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("matched %d contracts, want %d", len(got), len(tt.want))
+			}
+			for _, id := range tt.want {
+				if !got[id] {
+					t.Fatalf("contract %s is missing", id)
+				}
+			}
+		})
+	}
+}
+
+func TestListSortsByEachColumn(t *testing.T) {
+	ctx := context.Background()
+	r, database := newTestRepository(t)
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fixture := func(name string, startDays, endDays int, budget float64) *model.Contract {
+		return createTestContract(t, r, database, func(p *CreateContractParams) {
+			p.Name = name
+			p.StartAt = base.AddDate(0, 0, startDays)
+			p.EndAt = base.AddDate(0, 0, 90+endDays)
+			p.BudgetTotal = budget
+		})
+	}
+	// создаются по порядку a, b, c — у каждой колонки свой порядок
+	a := fixture("beta", 2, 1, 200000)
+	b := fixture("gamma", 0, 2, 100000)
+	c := fixture("alpha", 1, 0, 300000)
+	ids := []uuid.UUID{a.ID, b.ID, c.ID}
+
+	cursorValue := func(contract model.Contract, sortBy model.ContractSortField) any {
+		switch sortBy {
+		case model.ContractSortStartAt:
+			return contract.StartAt
+		case model.ContractSortEndAt:
+			return contract.EndAt
+		case model.ContractSortName:
+			return contract.Name
+		case model.ContractSortBudgetTotal:
+			return contract.BudgetTotal
+		default:
+			return contract.CreatedAt
+		}
+	}
+
+	tests := []struct {
+		sortBy model.ContractSortField
+		asc    []uuid.UUID
+	}{
+		{sortBy: model.ContractSortCreatedAt, asc: []uuid.UUID{a.ID, b.ID, c.ID}},
+		{sortBy: model.ContractSortStartAt, asc: []uuid.UUID{b.ID, c.ID, a.ID}},
+		{sortBy: model.ContractSortEndAt, asc: []uuid.UUID{c.ID, a.ID, b.ID}},
+		{sortBy: model.ContractSortName, asc: []uuid.UUID{c.ID, a.ID, b.ID}},
+		{sortBy: model.ContractSortBudgetTotal, asc: []uuid.UUID{b.ID, a.ID, c.ID}},
+	}
+	for _, tt := range tests {
+		for _, dir := range []model.SortDirection{model.SortAsc, model.SortDesc} {
+			want := append([]uuid.UUID(nil), tt.asc...)
+			if dir == model.SortDesc {
+				want[0], want[2] = want[2], want[0]
+			}
+			t.Run(string(tt.sortBy)+"_"+string(dir), func(t *testing.T) {
+				items, err := r.List(ctx, ContractFilter{IDs: ids, SortBy: tt.sortBy, SortDir: dir})
+				if err != nil {
+					t.Fatalf("list: %v", err)
+				}
+				if got := contractIDs(items); !equalIDs(got, want) {
+					t.Fatalf("order = %v, want %v", got, want)
+				}
+
+				// постранично по одной строке через keyset-курсор — тот же порядок
+				var paged []uuid.UUID
+				var after *ContractCursor
+				for range want {
+					page, err := r.List(ctx, ContractFilter{IDs: ids, SortBy: tt.sortBy, SortDir: dir, Limit: 1, After: after})
+					if err != nil {
+						t.Fatalf("list page: %v", err)
+					}
+					if len(page) != 1 {
+						t.Fatalf("page has %d rows, want 1", len(page))
+					}
+					paged = append(paged, page[0].ID)
+					after = &ContractCursor{SortBy: tt.sortBy, SortDir: dir, Value: cursorValue(page[0], tt.sortBy), ID: page[0].ID}
+				}
+				if !equalIDs(paged, want) {
+					t.Fatalf("paged order = %v, want %v", paged, want)
+				}
+			})
+		}
+	}
+}
+
+func contractIDs(items []model.Contract) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func equalIDs(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestListContractTicketsFiltersKeepWholeTicketAggregates(t *testing.T) {
+	ctx := context.Background()
+	r, database := newTestRepository(t)
+	contract := createTestContract(t, r, database, nil)
+	day := time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	ticket := func(status model.TicketStatus, plannedStart time.Time, trips int, volume float64, activeAssignments, inactiveAssignments int) uuid.UUID {
+		id := dbtest.Ticket(t, database, contract.ID)
+		dbtest.Exec(t, database, `UPDATE tickets SET status = ?, planned_start_at = ?, planned_end_at = ? WHERE id = ?`,
+			string(status), plannedStart, plannedStart.Add(12*time.Hour), id)
+		for i := 0; i < trips; i++ {
+			tripID := dbtest.Trip(t, database, id, uuid.Nil)
+			dbtest.Exec(t, database, `UPDATE trips SET detected_volume_entry = ? WHERE id = ?`, volume, tripID)
+		}
+		for i := 0; i < activeAssignments+inactiveAssignments; i++ {
+			dbtest.Exec(t, database, `INSERT INTO ticket_assignments (ticket_id, is_active) VALUES (?, ?)`, id, i < activeAssignments)
+		}
+		return id
+	}
+	early := ticket(model.TicketStatusCompleted, day, 3, 10, 1, 1)
+	middle := ticket(model.TicketStatusInProgress, day.AddDate(0, 0, 1), 2, 15, 2, 0)
+	late := ticket(model.TicketStatusCompleted, day.AddDate(0, 0, 2), 4, 5, 0, 2)
+	// тикет другого контракта не попадает в выборку
+	other := createTestContract(t, r, database, nil)
+	dbtest.Ticket(t, database, other.ID)
+
+	type aggregates struct {
+		trips       int64
+		volume      float64
+		assignments int64
+	}
+	whole := map[uuid.UUID]aggregates{
+		early:  {trips: 3, volume: 30, assignments: 1},
+		middle: {trips: 2, volume: 30, assignments: 2},
+		late:   {trips: 4, volume: 20, assignments: 0},
+	}
+
+	completed := model.TicketStatusCompleted
+	from := day.AddDate(0, 0, 1)
+	to := day.AddDate(0, 0, 1)
+	tests := []struct {
+		name      string
+		filter    TicketFilter
+		want      []uuid.UUID
+		wantTotal int64
+	}{
+		{name: "all", filter: TicketFilter{}, want: []uuid.UUID{late, middle, early}, wantTotal: 3},
+		{name: "status", filter: TicketFilter{Status: &completed}, want: []uuid.UUID{late, early}, wantTotal: 2},
+		{name: "from", filter: TicketFilter{From: &from}, want: []uuid.UUID{late, middle}, wantTotal: 2},
+		{name: "to", filter: TicketFilter{To: &to}, want: []uuid.UUID{middle, early}, wantTotal: 2},
+		{name: "status and range", filter: TicketFilter{Status: &completed, From: &from}, want: []uuid.UUID{late}, wantTotal: 1},
+		{name: "limit", filter: TicketFilter{Limit: 2}, want: []uuid.UUID{late, middle}, wantTotal: 3},
+		{name: "offset", filter: TicketFilter{Limit: 2, Offset: 2}, want: []uuid.UUID{early}, wantTotal: 3},
+		{name: "offset past end", filter: TicketFilter{Offset: 5}, want: []uuid.UUID{}, wantTotal: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := r.ListContractTickets(ctx, contract.ID, tt.filter)
+			if err != nil {
+				t.Fatalf("list tickets: %v", err)
+			}
+			got := make([]uuid.UUID, 0, len(items))
+			for _, item := range items {
+				got = append(got, item.ID)
+				want := whole[item.ID]
+				if item.TripCount != want.trips || item.TotalVolumeM3 != want.volume || item.ActiveAssignments != want.assignments {
+					t.Fatalf("ticket %s aggregates = %d trips, %v m3, %d assignments; want %+v",
+						item.ID, item.TripCount, item.TotalVolumeM3, item.ActiveAssignments, want)
+				}
+			}
+			if !equalIDs(got, tt.want) {
+				t.Fatalf("tickets = %v, want %v", got, tt.want)
+			}
+
+			total, err := r.CountContractTickets(ctx, contract.ID, tt.filter)
+			if err != nil {
+				t.Fatalf("count tickets: %v", err)
+			}
+			if total != tt.wantTotal {
+				t.Fatalf("total = %d, want %d", total, tt.wantTotal)
+			}
+		})
+	}
+}
+
+func TestStreamListMoreStreamsThanPoolConnections(t *testing.T) {
+	r, database := newTestRepository(t)
+	var ids []uuid.UUID
+	for i := 0; i < 5; i++ {
+		ids = append(ids, createTestContract(t, r, database, nil).ID)
+	}
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatalf("sql db: %v", err)
+	}
+	const maxOpen = 2
+	sqlDB.SetMaxOpenConns(maxOpen)
+	chunkSize := streamChunkSize
+	streamChunkSize = 2
+	t.Cleanup(func() { streamChunkSize = chunkSize })
+
+	// каждый поток ждёт в fn, пока первую порцию не получат все: если бы
+	// поток держал соединение на время fn, остальные не дождались бы пула
+	const streams = maxOpen * 3
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	var arrived sync.WaitGroup
+	arrived.Add(streams)
+	allArrived := make(chan struct{})
+	go func() {
+		arrived.Wait()
+		close(allArrived)
+	}()
+
+	var wg sync.WaitGroup
+	results := make([][]uuid.UUID, streams)
+	errs := make([]error, streams)
+	for i := 0; i < streams; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			first := true
+			errs[i] = r.StreamList(ctx, ContractFilter{IDs: ids, IncludeUsage: true, SortBy: model.ContractSortCreatedAt, SortDir: model.SortAsc}, func(contracts []model.Contract) error {
+				if first {
+					first = false
+					arrived.Done()
+					select {
+					case <-allArrived:
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				}
+				for _, contract := range contracts {
+					if !contract.UsageLoaded() {
+						return errors.New("usage is not loaded")
+					}
+					results[i] = append(results[i], contract.ID)
+				}
+				return nil
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < streams; i++ {
+		if errs[i] != nil {
+			t.Fatalf("stream %d: %v", i, errs[i])
+		}
+		if !equalIDs(results[i], ids) {
+			t.Fatalf("stream %d read %v, want %v", i, results[i], ids)
+		}
+	}
+}
+
+// seedContractorContracts создаёт active активных и столько же неактивных
+// контрактов подрядчика, а также активный контракт другого подрядчика.
+func seedContractorContracts(tb testing.TB, r *ContractRepository, database *gorm.DB, active int) uuid.UUID {
+	tb.Helper()
+	contractorID := dbtest.Organization(tb, database, "ТОО Подрядчик")
+	for i := 0; i < active*2; i++ {
+		contract := createTestContract(tb, r, database, func(p *CreateContractParams) {
+			p.ContractorID = &contractorID
+			p.IsActive = i%2 == 0
+		})
+		if i%3 == 0 {
+			dbtest.Exec(tb, database, `UPDATE contract_usage SET total_volume_m3 = ?, total_cost = ? WHERE contract_id = ?`, i, i*100, contract.ID)
+		}
+	}
+	createTestContract(tb, r, database, nil)
+	return contractorID
+}
+
+func TestListActiveForContractorMatchesList(t *testing.T) {
+	ctx := context.Background()
+	r, database := newTestRepository(t)
+	contractorID := seedContractorContracts(t, r, database, 5)
+
+	filter := ContractFilter{ContractorID: &contractorID, OnlyActive: true, IncludeUsage: true, Now: time.Now()}
+	want, err := r.List(ctx, filter)
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	got, err := r.ListActiveForContractor(ctx, contractorID, nil, 0)
+	if err != nil {
+		t.Fatalf("list active for contractor: %v", err)
+	}
+	if !equalIDs(contractIDs(got), contractIDs(want)) || len(got) != 5 {
+		t.Fatalf("fast path = %v, List = %v", contractIDs(got), contractIDs(want))
+	}
+	for i := range got {
+		if (got[i].Usage == nil) != (want[i].Usage == nil) ||
+			(got[i].Usage != nil && (got[i].Usage.TotalVolumeM3 != want[i].Usage.TotalVolumeM3 || got[i].Usage.TotalCost != want[i].Usage.TotalCost)) {
+			t.Fatalf("contract %s: usage %+v, List usage %+v", got[i].ID, got[i].Usage, want[i].Usage)
+		}
+	}
+}
+
+// BenchmarkListActiveContractor сравнивает быстрый путь «мои активные
+// контракты» с общим ListPage на странице из 50 строк.
+func BenchmarkListActiveContractor(b *testing.B) {
+	ctx := context.Background()
+	database := dbtest.Open(b)
+	r := NewContractRepository(database)
+	contractorID := seedContractorContracts(b, r, database, 200)
+	const limit = 50
+
+	b.Run("ListActiveForContractor", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := r.ListActiveForContractor(ctx, contractorID, nil, limit+1); err != nil {
+				b.Fatalf("list active for contractor: %v", err)
+			}
+		}
+	})
+	b.Run("ListPage", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_, _, err := r.ListPage(ctx, ContractFilter{
+				ContractorID: &contractorID,
+				OnlyActive:   true,
+				IncludeUsage: true,
+				Now:          time.Now(),
+				Limit:        limit,
+			})
+			if err != nil {
+				b.Fatalf("list page: %v", err)
+			}
+		}
+	})
+}
+
+func TestContractorMonthlySpendSkipsDeletedContracts(t *testing.T) {
+	ctx := context.Background()
+	r, database := newTestRepository(t)
+	contractorID := dbtest.Organization(t, database, "ТОО Подрядчик")
+	forContractor := func(p *CreateContractParams) { p.ContractorID = &contractorID }
+	live := createTestContract(t, r, database, forContractor)
+	deleted := createTestContract(t, r, database, forContractor)
+	if err := recordTrip(t, r, database, live.ID, 10); err != nil {
+		t.Fatalf("record trip: %v", err)
+	}
+	if err := recordTrip(t, r, database, deleted.ID, 30); err != nil {
+		t.Fatalf("record trip: %v", err)
+	}
+	if err := r.SoftDelete(ctx, deleted.ID, uuid.New(), uuid.New()); err != nil {
+		t.Fatalf("soft delete: %v", err)
+	}
+
+	from := time.Now().UTC().AddDate(0, -1, 0)
+	rows, err := r.ContractorMonthlySpend(ctx, contractorID, from, from.AddDate(0, 2, 0))
+	if err != nil {
+		t.Fatalf("monthly spend: %v", err)
+	}
+	var trips int64
+	var volume float64
+	for _, row := range rows {
+		trips += row.TripCount
+		volume += row.TotalVolumeM3
+	}
+	if trips != 1 || volume != 10 {
+		t.Fatalf("spend = %d trips, %v m3; want only the live contract (1 trip, 10 m3)", trips, volume)
+	}
+}
+
+func TestDeactivateExpiredSkipsLockedContracts(t *testing.T) {
+	ctx := context.Background()
+	r, database := newTestRepository(t)
+	expired := func(p *CreateContractParams) {
+		p.StartAt = time.Now().UTC().AddDate(0, -2, 0)
+		p.EndAt = time.Now().UTC().AddDate(0, -1, 0)
+	}
+	unlocked := createTestContract(t, r, database, expired)
+	locked := createTestContract(t, r, database, expired)
+	if err := r.SetLock(ctx, locked.ID, true, uuid.New(), uuid.New()); err != nil {
+		t.Fatalf("lock: %v", err)
+	}
+
+	deactivated, acquired, err := r.DeactivateExpired(ctx, time.Now().UTC())
+	if err != nil || !acquired {
+		t.Fatalf("deactivate expired: acquired=%v, err=%v", acquired, err)
+	}
+	if len(deactivated) != 1 || deactivated[0].ID != unlocked.ID {
+		t.Fatalf("deactivated %+v, want only the unlocked contract", deactivated)
+	}
+	if current, err := r.GetByID(ctx, locked.ID, false); err != nil || !current.IsActive {
+		t.Fatalf("locked contract: is_active=%v, err=%v; want still active", current != nil && current.IsActive, err)
+	}
+
+	// после снятия блокировки следующий проход выключает и его
+	if err := r.SetLock(ctx, locked.ID, false, uuid.New(), uuid.New()); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	deactivated, _, err = r.DeactivateExpired(ctx, time.Now().UTC())
+	if err != nil {
+		t.Fatalf("deactivate expired after unlock: %v", err)
+	}
+	if len(deactivated) != 1 || deactivated[0].ID != locked.ID {
+		t.Fatalf("deactivated %+v after unlock, want the formerly locked contract", deactivated)
+	}
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// GetReadOnlyMode читает флаг режима обслуживания, общий для всех реплик.
+func (r *ContractRepository) GetReadOnlyMode(ctx context.Context) (bool, error) {
+	var enabled bool
+	err := withRetry(ctx, retryRead, func() error {
+		return r.db.WithContext(ctx).Raw(`SELECT read_only FROM maintenance_mode WHERE id`).Scan(&enabled).Error
+	})
+	return enabled, err
+}
+
+// SetReadOnlyMode сохраняет флаг режима обслуживания. changed=false — флаг
+// уже имел это значение. updatedBy — uuid.Nil для включения при старте.
+func (r *ContractRepository) SetReadOnlyMode(ctx context.Context, enabled bool, updatedBy uuid.UUID) (changed bool, err error) {
+	var author *uuid.UUID
+	if updatedBy != uuid.Nil {
+		author = &updatedBy
+	}
+	result := r.db.WithContext(ctx).Exec(`
+		INSERT INTO maintenance_mode (id, read_only, updated_by, updated_at)
+		VALUES (TRUE, ?, ?, NOW())
+		ON CONFLICT (id) DO UPDATE
+		SET read_only = EXCLUDED.read_only, updated_by = EXCLUDED.updated_by, updated_at = NOW()
+		WHERE maintenance_mode.read_only IS DISTINCT FROM EXCLUDED.read_only
+	`, enabled, author)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
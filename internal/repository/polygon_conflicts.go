@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// PolygonCoverage — период, на который действующий контракт приёма занимает полигон.
+type PolygonCoverage struct {
+	PolygonID    uuid.UUID
+	ContractID   uuid.UUID
+	ContractName string
+	StartAt      time.Time
+	EndAt        time.Time
+}
+
+// ListPolygonCoverage возвращает действующие контракты приёма на полигонах
+// polygonIDs, срок которых пересекается с [from, to] (границы включительно,
+// как в ListLandfillPolygonCoverage).
+func (r *ContractRepository) ListPolygonCoverage(ctx context.Context, polygonIDs []uuid.UUID, from, to time.Time) ([]PolygonCoverage, error) {
+	var rows []PolygonCoverage
+	err := withRetry(ctx, retryRead, func() error {
+		rows = nil
+		return r.db.WithContext(ctx).Raw(`
+			SELECT cp.polygon_id, c.id AS contract_id, c.name AS contract_name, c.start_at, c.end_at
+			FROM contracts c
+			JOIN contract_polygons cp ON cp.contract_id = c.id
+			WHERE cp.polygon_id IN ?
+				AND c.contract_type = ?
+				AND c.is_active = TRUE
+				AND c.deleted_at IS NULL
+				AND c.start_at <= ?
+				AND c.end_at >= ?
+			ORDER BY cp.polygon_id, c.start_at, c.id
+		`, polygonIDs, string(model.ContractTypeLandfillService), to, from).Scan(&rows).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
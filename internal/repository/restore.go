@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// ErrContractExists — контракт с id из снимка уже есть, а перезапись не запрошена.
+var ErrContractExists = errors.New("contract already exists")
+
+// RestoreContractParams — данные снимка для восстановления контракта.
+type RestoreContractParams struct {
+	// Contract — условия контракта; uuid.Nil в ID — новый id
+	Contract      model.Contract
+	UsageVolumeM3 float64
+	UsageCost     float64
+	Polygons      []model.ContractPolygon
+	// Ledger — движения usage; nil — журнал не восстанавливается
+	Ledger []model.UsageLedgerEntry
+	// Overwrite — заменить существующий контракт с тем же id
+	Overwrite   bool
+	ActorUserID uuid.UUID
+	ActorOrgID  uuid.UUID
+}
+
+// RestoreContract воссоздаёт контракт, его usage, полигоны и (опционально)
+// журнал usage одной транзакцией и пишет событие restored в журнал контракта.
+// Тикеты и рейсы не трогаются: они принадлежат сервису тикетов.
+func (r *ContractRepository) RestoreContract(ctx context.Context, params RestoreContractParams) (uuid.UUID, error) {
+	contract := params.Contract
+	var id uuid.UUID
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var contractID *uuid.UUID
+		if contract.ID != uuid.Nil {
+			contractID = &contract.ID
+		}
+		conflict := "DO NOTHING"
+		if params.Overwrite {
+			conflict = `DO UPDATE SET
+				contractor_id = EXCLUDED.contractor_id,
+				landfill_id = EXCLUDED.landfill_id,
+				created_by_org = EXCLUDED.created_by_org,
+				created_by_user = EXCLUDED.created_by_user,
+				contract_type = EXCLUDED.contract_type,
+				name = EXCLUDED.name,
+				work_type = EXCLUDED.work_type,
+				price_per_m3 = EXCLUDED.price_per_m3,
+				budget_total = EXCLUDED.budget_total,
+				minimal_volume_m3 = EXCLUDED.minimal_volume_m3,
+				start_at = EXCLUDED.start_at,
+				end_at = EXCLUDED.end_at,
+				is_active = EXCLUDED.is_active,
+				client_reference = EXCLUDED.client_reference,
+				is_locked = EXCLUDED.is_locked,
+				locked_by = EXCLUDED.locked_by,
+				locked_at = EXCLUDED.locked_at,
+				created_at = EXCLUDED.created_at,
+				updated_at = EXCLUDED.updated_at,
+				deleted_at = NULL`
+		}
+		id = uuid.Nil
+		if err := tx.Raw(`
+			INSERT INTO contracts (
+				id, contractor_id, landfill_id, created_by_org, created_by_user, contract_type, name, work_type,
+				price_per_m3, budget_total, minimal_volume_m3, start_at, end_at, is_active,
+				client_reference, is_locked, locked_by, locked_at, created_at, updated_at
+			)
+			VALUES (COALESCE(?, uuid_generate_v4()), ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (id) `+conflict+`
+			RETURNING id
+		`, contractID, contract.ContractorID, contract.LandfillID, contract.CreatedByOrgID, contract.CreatedByUserID,
+			string(contract.ContractType), contract.Name, string(contract.WorkType),
+			contract.PricePerM3, contract.BudgetTotal, contract.MinimalVolumeM3,
+			contract.StartAt, contract.EndAt, contract.IsActive, contract.ClientReference,
+			contract.IsLocked, contract.LockedBy, contract.LockedAt, contract.CreatedAt, contract.UpdatedAt).Scan(&id).Error; err != nil {
+			if isUniqueViolation(err) {
+				return ErrClientReferenceExists
+			}
+			return err
+		}
+		if id == uuid.Nil {
+			return ErrContractExists
+		}
+
+		if err := tx.Exec(`
+			INSERT INTO contract_usage (contract_id, total_volume_m3, total_cost)
+			VALUES (?, ?, ?)
+			ON CONFLICT (contract_id) DO UPDATE SET
+				total_volume_m3 = EXCLUDED.total_volume_m3,
+				total_cost = EXCLUDED.total_cost,
+				updated_at = NOW()
+		`, id, params.UsageVolumeM3, params.UsageCost).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Exec(`DELETE FROM contract_polygons WHERE contract_id = ?`, id).Error; err != nil {
+			return err
+		}
+		for _, polygon := range params.Polygons {
+			if err := tx.Exec(`
+				INSERT INTO contract_polygons (contract_id, polygon_id, budget, total_volume_m3, total_cost)
+				VALUES (?, ?, ?, ?, ?)
+			`, id, polygon.PolygonID, polygon.Budget, polygon.TotalVolumeM3, polygon.TotalCost).Error; err != nil {
+				return err
+			}
+		}
+
+		if params.Ledger != nil {
+			if err := restoreLedgerTx(tx, id, params); err != nil {
+				return err
+			}
+		}
+
+		return tx.Exec(`
+			INSERT INTO contract_audit_log (contract_id, action, actor_user_id, actor_org_id, details)
+			VALUES (?, ?, ?, ?, jsonb_build_object('overwrite', ?::boolean, 'ledger_restored', ?::boolean))
+		`, id, string(model.AuditActionRestored), params.ActorUserID, params.ActorOrgID,
+			params.Overwrite, params.Ledger != nil).Error
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// restoreLedgerTx заменяет журнал usage контракта движениями из снимка.
+// Корректировки записываются от имени восстанавливающего.
+func restoreLedgerTx(tx *gorm.DB, contractID uuid.UUID, params RestoreContractParams) error {
+	if err := tx.Exec(`DELETE FROM trip_usage_log WHERE contract_id = ?`, contractID).Error; err != nil {
+		return err
+	}
+	if err := tx.Exec(`DELETE FROM contract_usage_adjustments WHERE contract_id = ?`, contractID).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range params.Ledger {
+		var err error
+		switch entry.Kind {
+		case model.UsageLedgerEntryTrip:
+			err = tx.Exec(`
+				INSERT INTO trip_usage_log (trip_id, ticket_id, contract_id, recorded_volume_m3, recorded_cost, created_at)
+				VALUES (?, ?, ?, ?, ?, ?)
+			`, entry.SourceID, entry.TicketID, contractID, entry.VolumeM3, entry.Cost, entry.CreatedAt).Error
+			if isUniqueViolation(err) {
+				return ErrTripUsageDuplicate
+			}
+		case model.UsageLedgerEntryAdjustment:
+			reason := ""
+			if entry.Reason != nil {
+				reason = *entry.Reason
+			}
+			err = tx.Exec(`
+				INSERT INTO contract_usage_adjustments (
+					id, contract_id, volume_delta_m3, cost_delta, reason, actor_user_id, actor_org_id, created_at
+				)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			`, entry.SourceID, contractID, entry.VolumeM3, entry.Cost, reason,
+				params.ActorUserID, params.ActorOrgID, entry.CreatedAt).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	retryAttempts = 3
+	retryBackoff  = 50 * time.Millisecond
+)
+
+// retryMode определяет, какие ошибки безопасно повторять.
+type retryMode int
+
+const (
+	// retryRollback — только ошибки, при которых Postgres гарантированно откатил
+	// транзакцию (serialization failure, deadlock). Подходит для записей.
+	retryRollback retryMode = iota
+	// retryRead — дополнительно обрывы соединения; только для чтения, т.к. при
+	// обрыве неизвестно, применилась ли запись.
+	retryRead
+)
+
+// withRetry повторяет fn с линейной задержкой, пока ошибка считается временной.
+func withRetry(ctx context.Context, mode retryMode, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= retryAttempts; attempt++ {
+		err = fn()
+		if err == nil || !isTransient(err, mode) || attempt == retryAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(time.Duration(attempt) * retryBackoff):
+		}
+	}
+	return err
+}
+
+func isTransient(err error, mode retryMode) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", // serialization_failure
+			"40P01": // deadlock_detected
+			return true
+		}
+		// 08xxx — connection_exception, 57P01 — admin_shutdown
+		if mode == retryRead && (len(pgErr.Code) == 5 && pgErr.Code[:2] == "08" || pgErr.Code == "57P01") {
+			return true
+		}
+		return false
+	}
+
+	if mode != retryRead {
+		return false
+	}
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		pgconn.SafeToRetry(err)
+}
+
+// isUniqueViolation — нарушение уникального ограничения (23505). TranslateError
+// в gorm не включён, поэтому gorm.ErrDuplicatedKey сюда не приходит.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
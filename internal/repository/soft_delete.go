@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// ErrContractNotDeleted — восстанавливаемый контракт не удалён
+var ErrContractNotDeleted = errors.New("contract is not deleted")
+
+// SoftDelete помечает контракт удалённым (deleted_at = NOW()) и пишет событие
+// deleted в журнал. Строка, usage, полигоны и тикеты остаются; удалённый
+// контракт пропадает из List и GetByID. Уже удалённый — ErrRecordNotFound.
+func (r *ContractRepository) SoftDelete(ctx context.Context, id, actorUserID, actorOrgID uuid.UUID) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`
+			UPDATE contracts
+			SET deleted_at = NOW()
+			WHERE id = ? AND deleted_at IS NULL
+		`, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Exec(`
+			INSERT INTO contract_audit_log (contract_id, action, actor_user_id, actor_org_id)
+			VALUES (?, ?, ?, ?)
+		`, id, string(model.AuditActionDeleted), actorUserID, actorOrgID).Error
+	})
+}
+
+type UndeleteContractParams struct {
+	ID uuid.UUID
+	// MaxActivePerOrg — лимит активных контрактов, если контракт активен (0 — без проверки)
+	MaxActivePerOrg int
+	ActorUserID     uuid.UUID
+	ActorOrgID      uuid.UUID
+}
+
+// Undelete снимает пометку SoftDelete и пишет событие undeleted в журнал.
+// Активный контракт снова учитывается в лимите организации, поэтому лимит
+// проверяется. Не удалённый контракт — ErrContractNotDeleted.
+func (r *ContractRepository) Undelete(ctx context.Context, params UndeleteContractParams) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current struct {
+			CreatedByOrg uuid.UUID
+			IsActive     bool
+			Deleted      bool
+		}
+		if err := tx.Raw(`
+			SELECT created_by_org, is_active, deleted_at IS NOT NULL AS deleted
+			FROM contracts
+			WHERE id = ?
+			FOR UPDATE
+		`, params.ID).Scan(&current).Error; err != nil {
+			return err
+		}
+		if current.CreatedByOrg == uuid.Nil {
+			return gorm.ErrRecordNotFound
+		}
+		if !current.Deleted {
+			return ErrContractNotDeleted
+		}
+		if current.IsActive && params.MaxActivePerOrg > 0 {
+			if err := checkActiveContractLimit(tx, current.CreatedByOrg, params.ID, params.MaxActivePerOrg); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec(`UPDATE contracts SET deleted_at = NULL WHERE id = ?`, params.ID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			INSERT INTO contract_audit_log (contract_id, action, actor_user_id, actor_org_id)
+			VALUES (?, ?, ?, ?)
+		`, params.ID, string(model.AuditActionUndeleted), params.ActorUserID, params.ActorOrgID).Error
+	})
+}
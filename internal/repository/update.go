@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// UpdateContractParams — изменение условий контракта. Сами новые условия
+// считает Apply по строке, прочитанной под блокировкой.
+type UpdateContractParams struct {
+	ID uuid.UUID
+	// Apply накладывает изменение на текущий контракт (с usage и полигонами) и
+	// проверяет результат; возвращает новые условия и имена изменённых полей.
+	// Ошибка Apply откатывает транзакцию.
+	Apply func(current model.Contract) (model.Contract, []string, error)
+	// Reason — причина изменения (доп. соглашение и т.п.)
+	Reason string
+	// MaxActivePerOrg — лимит активных контрактов при включении (0 — без проверки)
+	MaxActivePerOrg int
+	ActorUserID     uuid.UUID
+	ActorOrgID      uuid.UUID
+}
+
+// Update меняет условия контракта одной транзакцией: блокирует строку
+// контракта, передаёт её в params.Apply и пишет результат с updated_at =
+// NOW(), событие updated в журнал и по строке contract_amendments на
+// изменённое поле. Параллельное изменение или рейс не может прийти между
+// проверкой и записью. Возвращает изменённые поля; пустой список — ничего не
+// записано. Заблокированный контракт — ErrContractLocked.
+//
+// Журнал usage (trip_usage_log) и итоги contract_usage не пересчитываются:
+// новая цена действует только для следующих рейсов.
+func (r *ContractRepository) Update(ctx context.Context, params UpdateContractParams) ([]string, error) {
+	var changed []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var current model.Contract
+		if err := tx.Raw(`
+			SELECT
+				id,
+				contractor_id,
+				landfill_id,
+				created_by_org AS created_by_org_id,
+				created_by_user AS created_by_user_id,
+				contract_type,
+				name,
+				work_type,
+				price_per_m3,
+				budget_total,
+				minimal_volume_m3,
+				start_at,
+				end_at,
+				is_active,
+				client_reference,
+				is_locked,
+				locked_by,
+				locked_at,
+				created_at,
+				updated_at
+			FROM contracts
+			WHERE id = ? AND deleted_at IS NULL
+			FOR UPDATE
+		`, params.ID).Scan(&current).Error; err != nil {
+			return err
+		}
+		if current.ID == uuid.Nil {
+			return gorm.ErrRecordNotFound
+		}
+		if current.IsLocked {
+			return ErrContractLocked
+		}
+		// Рейсы берут ту же блокировку контракта, поэтому usage ниже
+		// не изменится до конца транзакции.
+		if err := repairUsageTx(tx, params.ID); err != nil {
+			return err
+		}
+		var usage model.ContractUsage
+		if err := tx.Raw(`
+			SELECT id, contract_id, total_volume_m3, total_cost, updated_at
+			FROM contract_usage
+			WHERE contract_id = ?
+		`, params.ID).Scan(&usage).Error; err != nil {
+			return err
+		}
+		current.Usage = &usage
+		polygons, err := getPolygonsTx(tx, params.ID)
+		if err != nil {
+			return err
+		}
+		current.Polygons = polygons
+
+		updated, fields, err := params.Apply(current)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		if updated.IsActive && !current.IsActive && params.MaxActivePerOrg > 0 {
+			if err := checkActiveContractLimit(tx, current.CreatedByOrgID, params.ID, params.MaxActivePerOrg); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec(`
+			UPDATE contracts
+			SET name = ?,
+				price_per_m3 = ?,
+				budget_total = ?,
+				minimal_volume_m3 = ?,
+				start_at = ?,
+				end_at = ?,
+				is_active = ?,
+				updated_at = NOW()
+			WHERE id = ?
+		`, updated.Name, updated.PricePerM3, updated.BudgetTotal, updated.MinimalVolumeM3,
+			updated.StartAt, updated.EndAt, updated.IsActive, params.ID).Error; err != nil {
+			return err
+		}
+		if err := insertAmendmentsTx(tx, params, fields, termsOf(current), termsOf(updated)); err != nil {
+			return err
+		}
+
+		details, err := json.Marshal(map[string]interface{}{"changed": fields, "reason": params.Reason})
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec(`
+			INSERT INTO contract_audit_log (contract_id, action, actor_user_id, actor_org_id, details)
+			VALUES (?, ?, ?, ?, ?::jsonb)
+		`, params.ID, string(model.AuditActionUpdated), params.ActorUserID, params.ActorOrgID, string(details)).Error; err != nil {
+			return err
+		}
+		changed = fields
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return changed, nil
+}
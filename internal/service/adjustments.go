@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+type RecordUsageAdjustmentInput struct {
+	VolumeDeltaM3 float64
+	CostDelta     float64
+	Reason        string
+}
+
+// RecordUsageAdjustment применяет ручную корректировку usage (например, по
+// итогам урегулирования спора). Доступно организации-создателю контракта.
+func (s *ContractService) RecordUsageAdjustment(ctx context.Context, principal model.Principal, contractID uuid.UUID, input RecordUsageAdjustmentInput) (_ *model.UsageAdjustment, err error) {
+	defer observeOperation("record_usage_adjustment", time.Now(), &err)
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		return nil, ErrInvalidInput
+	}
+	if math.IsNaN(input.VolumeDeltaM3) || math.IsInf(input.VolumeDeltaM3, 0) ||
+		math.IsNaN(input.CostDelta) || math.IsInf(input.CostDelta, 0) {
+		return nil, ErrInvalidInput
+	}
+	if input.VolumeDeltaM3 == 0 && input.CostDelta == 0 {
+		return nil, ErrInvalidInput
+	}
+
+	contract, err := s.contracts.GetByID(ctx, contractID, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureWriteAccess(principal, contract); err != nil {
+		return nil, err
+	}
+	if err := ensureNotLocked(contract); err != nil {
+		return nil, err
+	}
+
+	adjustment, change, err := s.contracts.RecordUsageAdjustment(ctx, repository.UsageAdjustmentParams{
+		ContractID:    contract.ID,
+		VolumeDeltaM3: input.VolumeDeltaM3,
+		CostDelta:     input.CostDelta,
+		Reason:        reason,
+		ActorUserID:   principal.UserID,
+		ActorOrgID:    principal.OrganizationID,
+	})
+	switch {
+	case errors.Is(err, repository.ErrUsageWouldBeNegative):
+		return nil, ErrConflict
+	case errors.Is(err, repository.ErrContractLocked):
+		return nil, ErrContractLocked
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, err
+	}
+	s.publishUsage(ctx, contract.ID)
+	s.notifyIfBudgetExceeded(ctx, principal, contract, change)
+	return adjustment, nil
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// ListContractAmendments возвращает историю изменений условий контракта,
+// старые сверху. Только КГУ и акимат.
+func (s *ContractService) ListContractAmendments(ctx context.Context, principal model.Principal, contractID uuid.UUID) ([]model.ContractAmendment, error) {
+	if !principal.IsKgu() && !principal.IsAkimat() {
+		return nil, ErrPermissionDenied
+	}
+
+	contract, err := s.contracts.GetByID(ctx, contractID, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureReadAccess(principal, contract); err != nil {
+		return nil, err
+	}
+
+	return s.contracts.ListAmendments(ctx, contract.ID)
+}
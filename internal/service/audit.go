@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 500
+)
+
+type ListAuditInput struct {
+	Actions     []model.AuditAction
+	ActorUserID *uuid.UUID
+	ActorOrgID  *uuid.UUID
+	From        *time.Time
+	To          *time.Time
+	Limit       int
+	Offset      int
+}
+
+type AuditPage struct {
+	Items  []model.ContractAuditEntry
+	Total  int64
+	Limit  int
+	Offset int
+}
+
+// ListContractAudit возвращает страницу журнала контракта с фильтрами.
+func (s *ContractService) ListContractAudit(ctx context.Context, principal model.Principal, contractID uuid.UUID, input ListAuditInput) (*AuditPage, error) {
+	for _, action := range input.Actions {
+		if !containsValue(model.AuditActions(), action) {
+			return nil, fmt.Errorf("%w: unknown audit action %q", ErrInvalidInput, action)
+		}
+	}
+	if input.From != nil && input.To != nil && input.From.After(*input.To) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidInput)
+	}
+	if input.Limit <= 0 {
+		input.Limit = DefaultPageLimit
+	}
+	if input.Limit > MaxPageLimit || input.Offset < 0 {
+		return nil, fmt.Errorf("%w: limit must be at most %d and offset non-negative", ErrInvalidInput, MaxPageLimit)
+	}
+
+	contract, err := s.contracts.GetByID(ctx, contractID, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureReadAccess(principal, contract); err != nil {
+		return nil, err
+	}
+
+	items, total, err := s.contracts.ListAudit(ctx, repository.AuditFilter{
+		ContractID:  contractID,
+		Actions:     input.Actions,
+		ActorUserID: input.ActorUserID,
+		ActorOrgID:  input.ActorOrgID,
+		From:        input.From,
+		To:          input.To,
+		Limit:       input.Limit,
+		Offset:      input.Offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditPage{
+		Items:  items,
+		Total:  total,
+		Limit:  input.Limit,
+		Offset: input.Offset,
+	}, nil
+}
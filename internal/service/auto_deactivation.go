@@ -0,0 +1,27 @@
+package service
+
+import (
+	"context"
+)
+
+// DeactivateExpired выключает контракты, закончившиеся раньше чем grace period
+// назад: они переходят в ARCHIVED и пропадают из активных списков. Вызывается
+// фоновой задачей; безопасна при нескольких репликах. В режиме обслуживания
+// ничего не меняет.
+func (s *ContractService) DeactivateExpired(ctx context.Context) (int, error) {
+	if s.ReadOnly(ctx) {
+		s.log.Debug().Msg("auto deactivation skipped: read-only mode")
+		return 0, nil
+	}
+	cutoff := s.now().Add(-s.cfg.AutoDeactivateGrace)
+	deactivated, acquired, err := s.contracts.DeactivateExpired(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	if !acquired {
+		s.log.Debug().Msg("auto deactivation skipped: lock held by another instance")
+		return 0, nil
+	}
+	s.notifyDeactivated(ctx, deactivated, nil)
+	return len(deactivated), nil
+}
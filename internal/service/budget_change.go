@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+type BudgetChangePreview struct {
+	ContractID       uuid.UUID `json:"contract_id"`
+	CurrentBudget    float64   `json:"current_budget_total"`
+	NewBudget        float64   `json:"new_budget_total"`
+	CurrentTotalCost float64   `json:"current_total_cost"`
+	BudgetRemaining  float64   `json:"budget_remaining"`
+	Overage          float64   `json:"overage"`
+	// Allowed — изменение пройдёт проверки update; иначе Reason — почему нет
+	Allowed  bool     `json:"allowed"`
+	Reason   string   `json:"reason,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// PreviewBudgetChange показывает, чем обернётся новый budget_total контракта:
+// остаток или перерасход при текущем usage и пройдёт ли изменение проверки
+// update, включая право принципала на изменение. Ничего не записывает.
+// КГУ-создатель или акимат.
+func (s *ContractService) PreviewBudgetChange(ctx context.Context, principal model.Principal, contractID uuid.UUID, newBudget float64) (*BudgetChangePreview, error) {
+	if newBudget <= 0 || math.IsInf(newBudget, 0) || math.IsNaN(newBudget) {
+		return nil, ErrInvalidInput
+	}
+
+	contract, err := s.contracts.GetByID(ctx, contractID, true)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Акимат видит превью, но менять контракт не может: это отражается в
+	// allowed, а не ошибкой.
+	writeErr := ensureWriteAccess(principal, contract)
+	if writeErr != nil && !principal.IsAkimat() {
+		return nil, writeErr
+	}
+	if err := s.ensureUsage(ctx, contract); err != nil {
+		return nil, err
+	}
+	// Остаток по нулевому usage вместо реального ввёл бы в заблуждение —
+	// превью не отдаём и в режиме FlagUsageLoadErrors.
+	if contract.UsageLoadErr != nil {
+		return nil, fmt.Errorf("contract %s: %w", contract.ID, contract.UsageLoadErr)
+	}
+
+	currentCost := 0.0
+	if contract.Usage != nil {
+		currentCost = contract.Usage.TotalCost
+	}
+	preview := &BudgetChangePreview{
+		ContractID:       contract.ID,
+		CurrentBudget:    contract.BudgetTotal,
+		NewBudget:        newBudget,
+		CurrentTotalCost: currentCost,
+		BudgetRemaining:  math.Max(newBudget-currentCost, 0),
+		Overage:          math.Max(currentCost-newBudget, 0),
+		Allowed:          true,
+	}
+
+	warnings, err := s.validateBudgetChange(contract, newBudget)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidInput) && !errors.Is(err, ErrConflict) {
+			return nil, err
+		}
+		preview.Allowed = false
+		preview.Reason = err.Error()
+	}
+	// Update отказал бы раньше любых проверок бюджета
+	if writeErr != nil {
+		preview.Allowed = false
+		preview.Reason = writeErr.Error()
+	}
+	preview.Warnings = warnings
+	return preview, nil
+}
+
+// validateBudgetChange — проверки нового budget_total при изменении условий
+// контракта: контракт не заблокирован, бюджет не ниже уже начисленной
+// стоимости, вмещает бюджеты полигонов и согласуется с minimal_volume_m3.
+// Usage контракта должен быть загружен.
+func (s *ContractService) validateBudgetChange(contract *model.Contract, newBudget float64) ([]string, error) {
+	if newBudget <= 0 {
+		return nil, fieldError("budget_total", "budget_total must be positive")
+	}
+	if err := ensureNotLocked(contract); err != nil {
+		return nil, err
+	}
+	if contract.Usage != nil && newBudget < contract.Usage.TotalCost {
+		return nil, fieldError("budget_total", "budget_total is below accrued cost (%.2f < %.2f)",
+			newBudget, contract.Usage.TotalCost)
+	}
+
+	polygonBudgets := 0.0
+	for _, polygon := range contract.Polygons {
+		if polygon.Budget != nil {
+			polygonBudgets += *polygon.Budget
+		}
+	}
+	if polygonBudgets > newBudget {
+		return nil, fieldError("budget_total", "polygon budgets exceed budget_total (%.2f > %.2f)",
+			polygonBudgets, newBudget)
+	}
+
+	return checkMinimalVolume(contract.MinimalVolumeM3, contract.PricePerM3, newBudget, s.cfg.MinimalVolumeBudgetFactor)
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/dbtest"
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+func TestPreviewBudgetChangeReflectsWriteAccess(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	creator := kguPrincipal(t, database)
+	contract := createContract(t, s, creator, contractorInput(t, database))
+
+	akimat := model.Principal{
+		UserID:         uuid.New(),
+		OrganizationID: dbtest.Organization(t, database, "Акимат"),
+		Role:           model.UserRoleAkimatAdmin,
+	}
+
+	preview, err := s.PreviewBudgetChange(ctx, creator, contract.ID, 200000)
+	if err != nil {
+		t.Fatalf("creator preview: %v", err)
+	}
+	if !preview.Allowed || preview.Reason != "" {
+		t.Fatalf("creator preview: allowed = %v, reason = %q, want allowed", preview.Allowed, preview.Reason)
+	}
+
+	preview, err = s.PreviewBudgetChange(ctx, akimat, contract.ID, 200000)
+	if err != nil {
+		t.Fatalf("akimat preview: %v", err)
+	}
+	if preview.Allowed || preview.Reason != ErrPermissionDenied.Error() {
+		t.Fatalf("akimat preview: allowed = %v, reason = %q, want denied", preview.Allowed, preview.Reason)
+	}
+	if preview.NewBudget != 200000 || preview.BudgetRemaining != 200000 {
+		t.Fatalf("akimat preview numbers = %+v", preview)
+	}
+}
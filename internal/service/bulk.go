@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+type BulkDeactivateInput struct {
+	ContractorID *uuid.UUID
+	// Status — по умолчанию EXPIRED; ARCHIVED недопустим (уже выключены).
+	Status  *model.ContractUIStatus
+	EndFrom *time.Time
+	EndTo   *time.Time
+}
+
+type BulkDeactivateResult struct {
+	Deactivated int `json:"deactivated"`
+}
+
+// BulkDeactivate выключает (is_active=false) все контракты по фильтру одной
+// транзакцией: КГУ — только созданные своей организацией, акимат — любые.
+// Заблокированные контракты пропускаются.
+func (s *ContractService) BulkDeactivate(ctx context.Context, principal model.Principal, input BulkDeactivateInput) (*BulkDeactivateResult, error) {
+	if !principal.IsKgu() && !principal.IsAkimat() {
+		return nil, ErrPermissionDenied
+	}
+
+	status := model.ContractUIStatusExpired
+	if input.Status != nil {
+		status = *input.Status
+	}
+	if status == model.ContractUIStatusArchived {
+		return nil, ErrInvalidInput
+	}
+	if input.EndFrom != nil && input.EndTo != nil && input.EndFrom.After(*input.EndTo) {
+		return nil, ErrInvalidInput
+	}
+
+	filter := repository.ContractFilter{
+		ContractorID: input.ContractorID,
+		Status:       &status,
+		EndFrom:      input.EndFrom,
+		EndTo:        input.EndTo,
+		Now:          s.now(),
+	}
+	if !principal.IsAkimat() {
+		applyWriteScope(principal, &filter)
+	}
+
+	deactivated, err := s.contracts.BulkDeactivate(ctx, filter, principal.UserID, principal.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyDeactivated(ctx, deactivated, &principal)
+	return &BulkDeactivateResult{Deactivated: len(deactivated)}, nil
+}
+
+// MaxTripUsageBatch ограничивает число рейсов в одном пакете RecordTripUsageBatch.
+const MaxTripUsageBatch = 500
+
+// RecordTripUsageBatch записывает usage по пакету рейсов и возвращает ошибку
+// каждого элемента (nil — записан). atomic=true — всё или ничего: при любой
+// ошибке ничего не записывается, остальные элементы получают ErrBatchAborted;
+// иначе каждый рейс записывается независимо (best-effort).
+func (s *ContractService) RecordTripUsageBatch(ctx context.Context, principal model.Principal, inputs []RecordTripUsageInput, atomic bool) (_ []error, err error) {
+	defer observeOperation("record_trip_usage_batch", time.Now(), &err)
+	if !(principal.IsKgu() || principal.IsAkimat()) {
+		return nil, ErrPermissionDenied
+	}
+	if len(inputs) == 0 || len(inputs) > MaxTripUsageBatch {
+		return nil, ErrInvalidInput
+	}
+
+	results := make([]error, len(inputs))
+	if !atomic {
+		for i, input := range inputs {
+			results[i] = s.RecordTripUsage(ctx, principal, input)
+		}
+		return results, nil
+	}
+
+	items := make([]repository.TripUsageParams, len(inputs))
+	byID := make(map[uuid.UUID]*model.Contract)
+	failed := false
+	for i, input := range inputs {
+		params, contract, err := s.prepareTripUsage(ctx, principal, input)
+		if err != nil {
+			results[i] = err
+			failed = true
+			continue
+		}
+		items[i] = params
+		byID[contract.ID] = contract
+	}
+	if !failed {
+		changes, index, err := s.contracts.RecordTripUsageBatch(ctx, items)
+		switch {
+		case err == nil:
+			// события — по контракту: итоги его рейсов в пакете растут
+			// монотонно, и бюджет может пересечь только один из них
+			combined := make(map[uuid.UUID]repository.UsageChange)
+			for i, item := range items {
+				change, ok := combined[item.ContractID]
+				if !ok {
+					combined[item.ContractID] = changes[i]
+					continue
+				}
+				change.Cost += changes[i].Cost
+				change.TotalCostAfter = changes[i].TotalCostAfter
+				combined[item.ContractID] = change
+			}
+			for contractID, change := range combined {
+				s.publishUsage(ctx, contractID)
+				s.notifyIfBudgetExceeded(ctx, principal, byID[contractID], change)
+			}
+			return results, nil
+		case index < 0:
+			return nil, err
+		default:
+			results[index] = tripUsageError(err)
+		}
+	}
+	for i := range results {
+		if results[i] == nil {
+			results[i] = ErrBatchAborted
+		}
+	}
+	return results, nil
+}
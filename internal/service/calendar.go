@@ -0,0 +1,126 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// BusinessCalendar — рабочие дни для прогнозов: выходные дни недели и
+// праздники не считаются прошедшим сроком. nil — календарные дни.
+type BusinessCalendar struct {
+	weekend  map[time.Weekday]bool
+	holidays map[string]struct{} // YYYY-MM-DD в location
+	location *time.Location
+}
+
+// NewBusinessCalendar разбирает выходные (sun..sat), праздники (YYYY-MM-DD) и
+// часовой пояс (IANA, пусто — UTC). Без выходных и праздников возвращает nil.
+func NewBusinessCalendar(weekendDays, holidays []string, timezone string) (*BusinessCalendar, error) {
+	if len(weekendDays) == 0 && len(holidays) == 0 {
+		return nil, nil
+	}
+
+	location := time.UTC
+	if timezone != "" {
+		loaded, err := time.LoadLocation(timezone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid business calendar timezone %q: %w", timezone, err)
+		}
+		location = loaded
+	}
+
+	calendar := &BusinessCalendar{
+		weekend:  make(map[time.Weekday]bool, len(weekendDays)),
+		holidays: make(map[string]struct{}, len(holidays)),
+		location: location,
+	}
+	for _, raw := range weekendDays {
+		day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(raw))]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekend day %q: expected sun..sat", raw)
+		}
+		calendar.weekend[day] = true
+	}
+	if len(calendar.weekend) == len(weekdayNames) {
+		return nil, fmt.Errorf("business calendar has no working days")
+	}
+	for _, raw := range holidays {
+		day, err := time.ParseInLocation(time.DateOnly, strings.TrimSpace(raw), location)
+		if err != nil {
+			return nil, fmt.Errorf("invalid holiday %q: expected YYYY-MM-DD", raw)
+		}
+		calendar.holidays[day.Format(time.DateOnly)] = struct{}{}
+	}
+	return calendar, nil
+}
+
+func (c *BusinessCalendar) isWorkingDay(day time.Time) bool {
+	if c.weekend[day.Weekday()] {
+		return false
+	}
+	_, holiday := c.holidays[day.Format(time.DateOnly)]
+	return !holiday
+}
+
+// workingDuration — время в [from, to), приходящееся на рабочие дни;
+// без календаря — весь интервал.
+func (c *BusinessCalendar) workingDuration(from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+	if c == nil {
+		return to.Sub(from)
+	}
+
+	var total time.Duration
+	from, to = from.In(c.location), to.In(c.location)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, c.location)
+	for day.Before(to) {
+		next := day.AddDate(0, 0, 1)
+		if c.isWorkingDay(day) {
+			start, end := day, next
+			if from.After(start) {
+				start = from
+			}
+			if to.Before(end) {
+				end = to
+			}
+			total += end.Sub(start)
+		}
+		day = next
+	}
+	return total
+}
+
+// elapsedFraction — доля срока [start, end], прошедшая к now, в рабочем времени
+// календаря; [0, 1]. Если в сроке нет рабочих дней — по календарному времени.
+func (c *BusinessCalendar) elapsedFraction(start, end, now time.Time) float64 {
+	if now.After(end) {
+		now = end
+	}
+	total := c.workingDuration(start, end)
+	if total <= 0 && c != nil {
+		c = nil
+		total = c.workingDuration(start, end)
+	}
+	if total <= 0 {
+		return 0
+	}
+	return clamp(float64(c.workingDuration(start, now))/float64(total), 0, 1)
+}
+
+// workingDays — число рабочих дней (дробное) в [from, to).
+func (c *BusinessCalendar) workingDays(from, to time.Time) float64 {
+	return c.workingDuration(from, to).Hours() / 24
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// minTripsForCapacityEstimate — меньше рейсов в журнале не дают надёжного среднего.
+const minTripsForCapacityEstimate = 3
+
+type CapacityEstimate struct {
+	ContractID      uuid.UUID `json:"contract_id"`
+	TripCount       int64     `json:"trip_count"`
+	AvgTripVolumeM3 *float64  `json:"avg_trip_volume_m3"`
+	AvgTripCost     *float64  `json:"avg_trip_cost"`
+	BudgetRemaining float64   `json:"budget_remaining"`
+	VolumeToMinimum float64   `json:"volume_to_minimum_m3"`
+	TripsToBudget   *int64    `json:"trips_to_budget_exhaustion"`
+	TripsToMinimum  *int64    `json:"trips_to_minimal_volume"`
+	// Дни срока — рабочие, если настроен бизнес-календарь (business_days)
+	BusinessDays  bool    `json:"business_days"`
+	DaysElapsed   float64 `json:"days_elapsed"`
+	DaysRemaining float64 `json:"days_remaining"`
+	// BurnRatePerDay — средний расход в день прошедшего срока; nil — срок не начался
+	BurnRatePerDay         *float64 `json:"burn_rate_per_day"`
+	DaysToBudgetExhaustion *float64 `json:"days_to_budget_exhaustion"`
+}
+
+// EstimateCapacity оценивает, сколько ещё рейсов средним объёмом поместится в
+// остаток бюджета и сколько нужно до minimal_volume_m3. При недостатке истории
+// (меньше minTripsForCapacityEstimate рейсов) оценки равны nil.
+func (s *ContractService) EstimateCapacity(ctx context.Context, principal model.Principal, contractID uuid.UUID) (*CapacityEstimate, error) {
+	contract, err := s.contracts.GetByID(ctx, contractID, true)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureReadAccess(principal, contract); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUsage(ctx, contract); err != nil {
+		return nil, err
+	}
+
+	stats, err := s.contracts.GetTripUsageStats(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+
+	usageVolume, usageCost := 0.0, 0.0
+	if contract.Usage != nil {
+		usageVolume = contract.Usage.TotalVolumeM3
+		usageCost = contract.Usage.TotalCost
+	}
+
+	estimate := &CapacityEstimate{
+		ContractID:      contract.ID,
+		TripCount:       stats.TripCount,
+		BudgetRemaining: math.Max(contract.BudgetTotal-usageCost, 0),
+		VolumeToMinimum: math.Max(contract.MinimalVolumeM3-usageVolume, 0),
+	}
+	s.estimateBurnRate(estimate, contract, usageCost)
+	if stats.TripCount < minTripsForCapacityEstimate || stats.AvgVolumeM3 <= 0 {
+		return estimate, nil
+	}
+
+	avgVolume, avgCost := stats.AvgVolumeM3, stats.AvgCostPerTrip
+	estimate.AvgTripVolumeM3 = &avgVolume
+	estimate.AvgTripCost = &avgCost
+
+	// рейсы, целиком укладывающиеся в остаток бюджета
+	if avgCost > 0 {
+		trips := int64(math.Floor(estimate.BudgetRemaining / avgCost))
+		estimate.TripsToBudget = &trips
+	}
+	// рейсы, нужные для достижения минимального объёма (последний может быть неполным)
+	trips := int64(math.Ceil(estimate.VolumeToMinimum / avgVolume))
+	estimate.TripsToMinimum = &trips
+
+	return estimate, nil
+}
+
+// estimateBurnRate считает расход в день прошедшего срока и через сколько дней
+// при таком темпе закончится бюджет.
+func (s *ContractService) estimateBurnRate(estimate *CapacityEstimate, contract *model.Contract, usageCost float64) {
+	calendar := s.cfg.Calendar
+	now := s.now()
+	elapsedTo, remainingFrom := now, now
+	if elapsedTo.After(contract.EndAt) {
+		elapsedTo = contract.EndAt
+	}
+	if remainingFrom.Before(contract.StartAt) {
+		remainingFrom = contract.StartAt
+	}
+
+	estimate.BusinessDays = calendar != nil
+	estimate.DaysElapsed = calendar.workingDays(contract.StartAt, elapsedTo)
+	estimate.DaysRemaining = calendar.workingDays(remainingFrom, contract.EndAt)
+	if estimate.DaysElapsed <= 0 {
+		return
+	}
+
+	burnRate := usageCost / estimate.DaysElapsed
+	estimate.BurnRatePerDay = &burnRate
+	if burnRate > 0 {
+		days := estimate.BudgetRemaining / burnRate
+		estimate.DaysToBudgetExhaustion = &days
+	}
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nurpe/snowops-contract/internal/metrics"
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// usageConsistencyEpsilon — допустимое расхождение (копейки/сотые м3 из-за округления NUMERIC).
+const usageConsistencyEpsilon = 0.01
+
+type UsageConsistencyReport struct {
+	InconsistentCount int                      `json:"inconsistent_count"`
+	Contracts         []model.UsageDiscrepancy `json:"contracts"`
+}
+
+// CheckUsageConsistency сверяет contract_usage с суммой trip_usage_log и
+// корректировок (только акимат).
+func (s *ContractService) CheckUsageConsistency(ctx context.Context, principal model.Principal) (*UsageConsistencyReport, error) {
+	if !principal.IsAkimat() {
+		return nil, ErrPermissionDenied
+	}
+	return s.RefreshUsageConsistency(ctx)
+}
+
+// RefreshUsageConsistency выполняет сверку без проверки прав и обновляет метрику;
+// используется фоновой проверкой.
+func (s *ContractService) RefreshUsageConsistency(ctx context.Context) (*UsageConsistencyReport, error) {
+	items, err := s.contracts.ListUsageDiscrepancies(ctx, usageConsistencyEpsilon)
+	if err != nil {
+		return nil, err
+	}
+	if items == nil {
+		items = []model.UsageDiscrepancy{}
+	}
+
+	metrics.UsageInconsistentContracts.Set(float64(len(items)))
+
+	return &UsageConsistencyReport{
+		InconsistentCount: len(items),
+		Contracts:         items,
+	}, nil
+}
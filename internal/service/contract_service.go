@@ -2,28 +2,114 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
 	"gorm.io/gorm"
 
+	"github.com/nurpe/snowops-contract/internal/metrics"
 	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/notifier"
 	"github.com/nurpe/snowops-contract/internal/repository"
 )
 
+type Config struct {
+	// StrictUsage включает логирование и автоматическое восстановление
+	// отсутствующих строк contract_usage при чтении контрактов.
+	StrictUsage bool
+	// WorkTypes — допустимые типы работ; пустой список — model.DefaultWorkTypes().
+	WorkTypes []model.WorkType
+	// MaxActivePerOrg — лимит активных контрактов на организацию (0 — без лимита).
+	MaxActivePerOrg int
+	// SnapshotMaxItems — лимит строк каждой коллекции снимка; 0 — DefaultSnapshotMaxItems.
+	SnapshotMaxItems int
+	// Calendar — рабочие дни для time_elapsed и прогнозов; nil — календарные дни.
+	Calendar *BusinessCalendar
+	// FlagUsageLoadErrors — при ошибке чтения usage отдавать контракт с
+	// usage_load_error вместо ошибки запроса.
+	FlagUsageLoadErrors bool
+	// AutoDeactivateGrace — сколько ждать после end_at до автоматической деактивации.
+	AutoDeactivateGrace time.Duration
+	// ListPresets — фильтры/сортировка списка по умолчанию для ролей.
+	ListPresets ListPresets
+	// ResultTolerance — доля минимального объёма, недобор в пределах которой
+	// всё ещё считается SUCCESS.
+	ResultTolerance float64
+	// OrgCacheTTL/OrgCacheSize — кэш названий организаций; TTL 0 — без кэша.
+	OrgCacheTTL  time.Duration
+	OrgCacheSize int
+	// MinimalVolumeBudgetFactor — допустимое превышение budget_total стоимостью
+	// минимального объёма; 0 — без жёсткого ограничения.
+	MinimalVolumeBudgetFactor float64
+	// IdempotencyKeyTTL — сколько Idempotency-Key возвращает ранее созданный контракт.
+	IdempotencyKeyTTL time.Duration
+	// RejectOverBudget — не записывать рейс, с которым total_cost превысит
+	// budget_total (ErrConflict); KGU_ZKH_ADMIN может записать принудительно.
+	RejectOverBudget bool
+}
+
 type ContractService struct {
 	contracts *repository.ContractRepository
+	cfg       Config
+	workTypes map[model.WorkType]struct{}
+	notifier  notifier.Notifier
+	log       zerolog.Logger
 	now       func() time.Time
+	readOnly  atomic.Bool   // последнее прочитанное из БД состояние режима обслуживания
+	orgNames  *orgNameCache // nil — кэш выключен
+	// usageUpdates — подписчики на изменения usage (SSE)
+	usageUpdates *usageBroker
 }
 
-func NewContractService(contracts *repository.ContractRepository) *ContractService {
-	return &ContractService{
+func NewContractService(contracts *repository.ContractRepository, events notifier.Notifier, cfg Config, log zerolog.Logger) *ContractService {
+	if events == nil {
+		events = notifier.Noop{}
+	}
+	if len(cfg.WorkTypes) == 0 {
+		cfg.WorkTypes = model.DefaultWorkTypes()
+	}
+	if cfg.IdempotencyKeyTTL <= 0 {
+		cfg.IdempotencyKeyTTL = 24 * time.Hour
+	}
+	workTypes := make(map[model.WorkType]struct{}, len(cfg.WorkTypes))
+	for _, workType := range cfg.WorkTypes {
+		workTypes[workType] = struct{}{}
+	}
+
+	service := &ContractService{
 		contracts: contracts,
+		cfg:       cfg,
+		workTypes: workTypes,
+		notifier:  events,
+		log:       log,
 		now:       time.Now,
+
+		usageUpdates: newUsageBroker(),
+	}
+	if cfg.OrgCacheTTL > 0 && cfg.OrgCacheSize > 0 {
+		service.orgNames = newOrgNameCache(cfg.OrgCacheTTL, cfg.OrgCacheSize)
 	}
+	return service
+}
+
+// IsAllowedWorkType проверяет тип работ по настроенному списку.
+func (s *ContractService) IsAllowedWorkType(workType model.WorkType) bool {
+	_, ok := s.workTypes[workType]
+	return ok
+}
+
+// WorkTypes возвращает настроенный список типов работ.
+func (s *ContractService) WorkTypes() []model.WorkType {
+	return append([]model.WorkType(nil), s.cfg.WorkTypes...)
 }
 
 type ListContractsInput struct {
@@ -31,35 +117,182 @@ type ListContractsInput struct {
 	LandfillID   *uuid.UUID
 	ContractType *model.ContractType
 	WorkType     *model.WorkType
-	OnlyActive   bool
-	Status       *model.ContractUIStatus
-	StartFrom    *time.Time
-	StartTo      *time.Time
-	EndFrom      *time.Time
-	EndTo        *time.Time
+	// CreatedByUser — контракты, созданные пользователем; только КГУ и акимат
+	CreatedByUser *uuid.UUID
+	OnlyActive    bool
+	IncludeUsage  bool
+	Status        *model.ContractUIStatus
+	StartFrom     *time.Time
+	StartTo       *time.Time
+	EndFrom       *time.Time
+	EndTo         *time.Time
+	// WritableOnly оставляет только контракты, которые принципал может изменять
+	WritableOnly bool
+	// Perspective уточняет скоуп для организаций с несколькими ролями;
+	// пустое значение — самый широкий доступный скоуп.
+	Perspective model.ContractPerspective
+	// BudgetExceeded — nil: без фильтра
+	BudgetExceeded *bool
+	// MinUtilization/MaxUtilization — границы total_cost / budget_total (1 — 100%);
+	// nil — без границы
+	MinUtilization *float64
+	MaxUtilization *float64
+	SortBy         model.ContractSortField
+	SortDir        model.SortDirection
+	// UsePreset — клиент не передал фильтров и сортировки, можно применить
+	// пресет роли из конфигурации.
+	UsePreset bool
+	// AsOf — момент, на который считаются status, ui_status и usage (по журналу);
+	// nil — текущее время и живой contract_usage.
+	AsOf *time.Time
+	// Limit — размер страницы; 0 — DefaultContractPageLimit
+	Limit int
+	// Cursor — next_cursor предыдущей страницы; пусто — первая страница
+	Cursor string
+	// IncludeDeleted — вместе с удалёнными контрактами; только КГУ и акимат
+	IncludeDeleted bool
 }
 
-func (s *ContractService) List(ctx context.Context, principal model.Principal, input ListContractsInput) ([]model.Contract, error) {
+// List отдаёт страницу контрактов и курсор следующей; пустой курсор — страница
+// последняя. Usage догружается только для строк страницы.
+func (s *ContractService) List(ctx context.Context, principal model.Principal, input ListContractsInput) (_ []model.Contract, _ string, err error) {
+	defer observeOperation("list", time.Now(), &err)
+	input = s.withListPreset(principal, input)
+	filter, err := s.listFilter(principal, input)
+	if err != nil {
+		return nil, "", err
+	}
+
+	limit := input.Limit
+	if limit == 0 {
+		limit = DefaultContractPageLimit
+	}
+	if limit < 0 || limit > MaxContractPageLimit {
+		return nil, "", fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidInput, MaxContractPageLimit)
+	}
+	sortBy, sortDir := repository.NormalizeContractSort(filter.SortBy, filter.SortDir)
+	if input.Cursor != "" {
+		filter.After, err = decodeContractCursor(input.Cursor, sortBy, sortDir)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	filter.Limit = limit
+
+	var (
+		contracts []model.Contract
+		hasMore   bool
+	)
+	if isContractorActiveList(principal, input) {
+		// usage приходит тем же JOIN; +1 строка показывает, что есть следующая страница
+		contracts, err = s.contracts.ListActiveForContractor(ctx, principal.OrganizationID, filter.After, limit+1)
+		if hasMore = len(contracts) > limit; hasMore {
+			contracts = contracts[:limit]
+		}
+	} else {
+		contracts, hasMore, err = s.contracts.ListPage(ctx, filter)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if hasMore {
+		nextCursor = encodeContractCursor(contracts[limit-1], sortBy, sortDir)
+	}
+
+	if input.AsOf != nil {
+		if err := s.applyUsageAsOf(ctx, contracts, *input.AsOf); err != nil {
+			return nil, "", err
+		}
+	}
+
+	for i := range contracts {
+		if err := s.ensureUsage(ctx, &contracts[i]); err != nil {
+			return nil, "", err
+		}
+		s.decorateContractAt(&contracts[i], filter.Now)
+	}
+	s.enrichContracts(ctx, contracts)
+
+	return contracts, nextCursor, nil
+}
+
+// isContractorActiveList — запрос «мои активные контракты» подрядчика без
+// дополнительных фильтров; обслуживается ListActiveForContractor. Проверка
+// обратная: разрешённые поля обнуляются, и любое другое заданное поле,
+// в том числе добавленное позже, уводит запрос на общий путь List.
+func isContractorActiveList(principal model.Principal, input ListContractsInput) bool {
+	if !principal.IsContractor() || !input.OnlyActive || !input.IncludeUsage {
+		return false
+	}
+	switch input.Perspective {
+	case model.ContractPerspectiveAll, model.ContractPerspectiveContractor:
+		input.Perspective = ""
+	}
+	input.OnlyActive = false
+	input.IncludeUsage = false
+	input.UsePreset = false
+	input.Limit = 0
+	input.Cursor = ""
+	return input == ListContractsInput{}
+}
+
+// StreamList — потоковый вариант List: контракты читаются порциями, каждая
+// порция декорируется целиком, затем контракты по одному передаются в fn.
+func (s *ContractService) StreamList(ctx context.Context, principal model.Principal, input ListContractsInput, fn func(model.Contract) error) error {
+	input = s.withListPreset(principal, input)
+	filter, err := s.listFilter(principal, input)
+	if err != nil {
+		return err
+	}
+
+	return s.contracts.StreamList(ctx, filter, func(contracts []model.Contract) error {
+		if input.AsOf != nil {
+			if err := s.applyUsageAsOf(ctx, contracts, *input.AsOf); err != nil {
+				return err
+			}
+		}
+		for i := range contracts {
+			if err := s.ensureUsage(ctx, &contracts[i]); err != nil {
+				return err
+			}
+			s.decorateContractAt(&contracts[i], filter.Now)
+		}
+		s.enrichContracts(ctx, contracts)
+		for _, contract := range contracts {
+			if err := fn(contract); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *ContractService) listFilter(principal model.Principal, input ListContractsInput) (repository.ContractFilter, error) {
 	filter := repository.ContractFilter{
-		OnlyActive:   input.OnlyActive && input.Status == nil,
-		IncludeUsage: true,
-		Status:       input.Status,
-		StartFrom:    input.StartFrom,
-		StartTo:      input.StartTo,
-		EndFrom:      input.EndFrom,
-		EndTo:        input.EndTo,
-		Now:          s.now(),
+		OnlyActive:     input.OnlyActive && input.Status == nil,
+		IncludeUsage:   input.IncludeUsage,
+		Status:         input.Status,
+		StartFrom:      input.StartFrom,
+		StartTo:        input.StartTo,
+		EndFrom:        input.EndFrom,
+		EndTo:          input.EndTo,
+		Now:            s.now(),
+		SortBy:         input.SortBy,
+		SortDir:        input.SortDir,
+		BudgetExceeded: input.BudgetExceeded,
+		MinUtilization: input.MinUtilization,
+		MaxUtilization: input.MaxUtilization,
+	}
+	if input.AsOf != nil {
+		filter.Now = *input.AsOf
+	}
+	if input.MinUtilization != nil && input.MaxUtilization != nil && *input.MinUtilization > *input.MaxUtilization {
+		return repository.ContractFilter{}, fmt.Errorf("%w: utilization_gte must not exceed utilization_lte", ErrInvalidInput)
 	}
 
-	switch {
-	case principal.IsContractor():
-		filter.ContractorID = &principal.OrganizationID
-	case principal.IsLandfill():
-		// LANDFILL видит только свои контракты приёма
-		filter.LandfillID = &principal.OrganizationID
-		filterContractType := model.ContractTypeLandfillService
-		filter.ContractType = &filterContractType
-	case principal.IsKgu(), principal.IsAkimat():
+	if principal.IsKgu() || principal.IsAkimat() {
 		if input.ContractorID != nil {
 			filter.ContractorID = input.ContractorID
 		}
@@ -69,27 +302,49 @@ func (s *ContractService) List(ctx context.Context, principal model.Principal, i
 		if input.ContractType != nil {
 			filter.ContractType = input.ContractType
 		}
-	default:
-		return nil, ErrPermissionDenied
+		filter.CreatedByUser = input.CreatedByUser
+		filter.IncludeDeleted = input.IncludeDeleted
+	} else if input.CreatedByUser != nil || input.IncludeDeleted {
+		return repository.ContractFilter{}, ErrPermissionDenied
+	}
+	if err := applyReadScope(principal, &filter); err != nil {
+		return repository.ContractFilter{}, err
+	}
+	if err := applyPerspective(principal, input.Perspective, &filter); err != nil {
+		return repository.ContractFilter{}, err
+	}
+	if input.WritableOnly {
+		applyWriteScope(principal, &filter)
 	}
 
 	if input.WorkType != nil {
 		filter.WorkType = input.WorkType
 	}
 
-	contracts, err := s.contracts.List(ctx, filter)
-	if err != nil {
+	return filter, nil
+}
+
+// GetFilterOptions возвращает значения фильтров, встречающиеся среди доступных контрактов.
+func (s *ContractService) GetFilterOptions(ctx context.Context, principal model.Principal) (*model.ContractFilterOptions, error) {
+	filter := repository.ContractFilter{Now: s.now()}
+	if err := applyReadScope(principal, &filter); err != nil {
 		return nil, err
 	}
+	return s.contracts.GetFilterOptions(ctx, filter)
+}
 
-	for i := range contracts {
-		s.decorateContract(&contracts[i])
-	}
+func (s *ContractService) Get(ctx context.Context, principal model.Principal, id uuid.UUID) (_ *model.Contract, err error) {
+	defer observeOperation("get", time.Now(), &err)
+	return s.get(ctx, principal, id, nil)
+}
 
-	return contracts, nil
+// GetAsOf — контракт на момент asOf: ui_status и usage восстанавливаются по
+// журналу движений до этой даты, без учёта более поздней активности.
+func (s *ContractService) GetAsOf(ctx context.Context, principal model.Principal, id uuid.UUID, asOf time.Time) (*model.Contract, error) {
+	return s.get(ctx, principal, id, &asOf)
 }
 
-func (s *ContractService) Get(ctx context.Context, principal model.Principal, id uuid.UUID) (*model.Contract, error) {
+func (s *ContractService) get(ctx context.Context, principal model.Principal, id uuid.UUID, asOf *time.Time) (*model.Contract, error) {
 	contract, err := s.contracts.GetByID(ctx, id, true)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, ErrNotFound
@@ -102,8 +357,80 @@ func (s *ContractService) Get(ctx context.Context, principal model.Principal, id
 		return nil, err
 	}
 
-	s.decorateContract(contract)
-	return contract, nil
+	batch := []model.Contract{*contract}
+	now := s.now()
+	if asOf != nil {
+		if err := s.applyUsageAsOf(ctx, batch, *asOf); err != nil {
+			return nil, err
+		}
+		now = *asOf
+	}
+	if err := s.ensureUsage(ctx, &batch[0]); err != nil {
+		return nil, err
+	}
+	s.decorateContractAt(&batch[0], now)
+	s.enrichContracts(ctx, batch)
+	return &batch[0], nil
+}
+
+// MaxBatchGetIDs ограничивает число id в одном запросе BatchGet.
+const MaxBatchGetIDs = 100
+
+type BatchGetResult struct {
+	Contracts  []model.Contract `json:"contracts"`
+	MissingIDs []uuid.UUID      `json:"missing_ids"`
+}
+
+// BatchGet возвращает контракты по списку id. Контракты, которых нет или которые
+// недоступны принципалу, не различаются и попадают в MissingIDs.
+func (s *ContractService) BatchGet(ctx context.Context, principal model.Principal, ids []uuid.UUID) (*BatchGetResult, error) {
+	if len(ids) == 0 || len(ids) > MaxBatchGetIDs {
+		return nil, ErrInvalidInput
+	}
+
+	unique := make([]uuid.UUID, 0, len(ids))
+	seen := make(map[uuid.UUID]struct{}, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+
+	contracts, err := s.contracts.List(ctx, repository.ContractFilter{
+		IDs:          unique,
+		IncludeUsage: true,
+		Now:          s.now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &BatchGetResult{
+		Contracts:  make([]model.Contract, 0, len(contracts)),
+		MissingIDs: []uuid.UUID{},
+	}
+	found := make(map[uuid.UUID]struct{}, len(contracts))
+	for i := range contracts {
+		if err := s.ensureReadAccess(principal, &contracts[i]); err != nil {
+			continue
+		}
+		if err := s.ensureUsage(ctx, &contracts[i]); err != nil {
+			return nil, err
+		}
+		s.decorateContract(&contracts[i])
+		result.Contracts = append(result.Contracts, contracts[i])
+		found[contracts[i].ID] = struct{}{}
+	}
+	s.enrichContracts(ctx, result.Contracts)
+	for _, id := range unique {
+		if _, ok := found[id]; !ok {
+			result.MissingIDs = append(result.MissingIDs, id)
+		}
+	}
+
+	return result, nil
 }
 
 type CreateContractInput struct {
@@ -111,6 +438,7 @@ type CreateContractInput struct {
 	ContractorID    *uuid.UUID
 	LandfillID      *uuid.UUID
 	PolygonIDs      []uuid.UUID
+	PolygonBudgets  map[uuid.UUID]float64
 	Name            string
 	WorkType        model.WorkType
 	PricePerM3      float64
@@ -119,53 +447,43 @@ type CreateContractInput struct {
 	StartAt         time.Time
 	EndAt           time.Time
 	IsActive        *bool
+	// ClientReference — естественный ключ клиента; повторный create с тем же
+	// значением возвращает уже созданный контракт.
+	ClientReference *string
+	// IdempotencyKey — непрозрачный ключ запроса (заголовок Idempotency-Key);
+	// повтор в пределах IdempotencyKeyTTL возвращает уже созданный контракт.
+	IdempotencyKey *string
 }
 
-func (s *ContractService) Create(ctx context.Context, principal model.Principal, input CreateContractInput) (*model.Contract, error) {
+// Create создаёт контракт. created=false, если контракт с тем же
+// client_reference уже существовал и был возвращён вместо нового.
+func (s *ContractService) Create(ctx context.Context, principal model.Principal, input CreateContractInput) (_ *model.Contract, _ bool, err error) {
+	defer observeOperation("create", time.Now(), &err)
 	if !principal.IsKgu() {
-		return nil, ErrPermissionDenied
+		return nil, false, ErrPermissionDenied
 	}
 
-	if strings.TrimSpace(input.Name) == "" {
-		return nil, ErrInvalidInput
-	}
-	if input.PricePerM3 <= 0 {
-		return nil, ErrInvalidInput
-	}
-	if input.BudgetTotal <= 0 {
-		return nil, ErrInvalidInput
-	}
-	if input.MinimalVolumeM3 <= 0 {
-		return nil, ErrInvalidInput
-	}
-	if !input.EndAt.After(input.StartAt) {
-		return nil, ErrInvalidInput
-	}
-
-	// Валидация типа контракта
-	if input.ContractType != model.ContractTypeContractorService && input.ContractType != model.ContractTypeLandfillService {
-		return nil, ErrInvalidInput
-	}
-
-	// Валидация в зависимости от типа контракта
-	if input.ContractType == model.ContractTypeContractorService {
-		if input.ContractorID == nil {
-			return nil, ErrInvalidInput
+	idempotencyCutoff := s.now().Add(-s.cfg.IdempotencyKeyTTL)
+	var fingerprint string
+	if input.IdempotencyKey != nil {
+		fingerprint, err = createFingerprint(input)
+		if err != nil {
+			return nil, false, err
 		}
-		if input.WorkType != model.WorkTypeRoad &&
-			input.WorkType != model.WorkTypeSidewalk &&
-			input.WorkType != model.WorkTypeYard {
-			return nil, ErrInvalidInput
+		existing, err := s.getByIdempotencyKey(ctx, principal, *input.IdempotencyKey, fingerprint, idempotencyCutoff)
+		if err == nil {
+			return existing, false, nil
 		}
-	} else if input.ContractType == model.ContractTypeLandfillService {
-		if input.LandfillID == nil {
-			return nil, ErrInvalidInput
-		}
-		if len(input.PolygonIDs) == 0 {
-			return nil, ErrInvalidInput
+		if !errors.Is(err, ErrNotFound) {
+			return nil, false, err
 		}
 	}
 
+	warnings, err := s.validateCreate(input)
+	if err != nil {
+		return nil, false, err
+	}
+
 	isActive := true
 	if input.IsActive != nil {
 		isActive = *input.IsActive
@@ -176,7 +494,9 @@ func (s *ContractService) Create(ctx context.Context, principal model.Principal,
 		ContractorID:    input.ContractorID,
 		LandfillID:      input.LandfillID,
 		PolygonIDs:      input.PolygonIDs,
+		PolygonBudgets:  input.PolygonBudgets,
 		CreatedByOrgID:  principal.OrganizationID,
+		CreatedByUserID: principal.UserID,
 		Name:            strings.TrimSpace(input.Name),
 		WorkType:        input.WorkType,
 		PricePerM3:      input.PricePerM3,
@@ -185,21 +505,307 @@ func (s *ContractService) Create(ctx context.Context, principal model.Principal,
 		StartAt:         input.StartAt,
 		EndAt:           input.EndAt,
 		IsActive:        isActive,
+		ClientReference: input.ClientReference,
+		MaxActivePerOrg: s.cfg.MaxActivePerOrg,
+
+		IdempotencyKey:    input.IdempotencyKey,
+		IdempotencyHash:   fingerprint,
+		IdempotencyCutoff: idempotencyCutoff,
 	}
 
 	contract, err := s.contracts.Create(ctx, params)
+	if errors.Is(err, repository.ErrActiveContractLimit) {
+		return nil, false, ErrConflict
+	}
+	if errors.Is(err, repository.ErrIdempotencyKeyUsed) {
+		// параллельный запрос с тем же ключом успел создать контракт
+		existing, err := s.getByIdempotencyKey(ctx, principal, *input.IdempotencyKey, fingerprint, idempotencyCutoff)
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, false, nil
+	}
+	if errors.Is(err, repository.ErrClientReferenceExists) {
+		existing, err := s.getByClientReference(ctx, principal, *input.ClientReference)
+		if err != nil {
+			return nil, false, err
+		}
+		return existing, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+
+	s.notifyContract(ctx, notifier.EventContractCreated, contract.ID, newContractEvent(contract, &principal))
+
+	s.decorateContract(contract)
+	contract.Warnings = warnings
+	return contract, true, nil
+}
+
+// validateCreate проверяет поля нового контракта и возвращает все ошибки
+// сразу одной ValidationError, а также предупреждения checkMinimalVolume.
+func (s *ContractService) validateCreate(input CreateContractInput) ([]string, error) {
+	validation := &ValidationError{}
+	validateTerms(validation, input.Name, input.PricePerM3, input.BudgetTotal, input.MinimalVolumeM3, input.StartAt, input.EndAt)
+
+	// Валидация в зависимости от типа контракта
+	switch input.ContractType {
+	case model.ContractTypeContractorService:
+		if input.ContractorID == nil {
+			validation.add("contractor_id", "contractor_id is required for CONTRACTOR_SERVICE")
+		}
+		if !s.IsAllowedWorkType(input.WorkType) {
+			validation.add("work_type", "invalid work_type")
+		}
+	case model.ContractTypeLandfillService:
+		if input.LandfillID == nil {
+			validation.add("landfill_id", "landfill_id is required for LANDFILL_SERVICE")
+		}
+		if len(input.PolygonIDs) == 0 {
+			validation.add("polygon_ids", "polygon_ids is required for LANDFILL_SERVICE")
+		} else if err := validation.merge(validatePolygonIDs(input.PolygonIDs)); err != nil {
+			return nil, err
+		}
+		if input.BudgetTotal > 0 {
+			if err := validation.merge(validatePolygonBudgets(input.PolygonIDs, input.PolygonBudgets, input.BudgetTotal)); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		validation.add("contract_type", "invalid contract_type")
+	}
+
+	// соотношение минимального объёма и бюджета имеет смысл только для корректных чисел
+	if input.PricePerM3 <= 0 || input.BudgetTotal <= 0 || input.MinimalVolumeM3 <= 0 {
+		return nil, validation.errOrNil()
+	}
+	warnings, err := checkMinimalVolume(input.MinimalVolumeM3, input.PricePerM3, input.BudgetTotal, s.cfg.MinimalVolumeBudgetFactor)
+	if err := validation.merge(err); err != nil {
+		return nil, err
+	}
+	if err := validation.errOrNil(); err != nil {
+		return nil, err
+	}
+	return warnings, nil
+}
+
+// validateTerms добавляет ошибки условий контракта, общих для create и update.
+func validateTerms(validation *ValidationError, name string, pricePerM3, budgetTotal, minimalVolumeM3 float64, startAt, endAt time.Time) {
+	if strings.TrimSpace(name) == "" {
+		validation.add("name", "name is required")
+	}
+	if pricePerM3 <= 0 {
+		validation.add("price_per_m3", "price_per_m3 must be positive")
+	}
+	if budgetTotal <= 0 {
+		validation.add("budget_total", "budget_total must be positive")
+	}
+	if minimalVolumeM3 <= 0 {
+		validation.add("minimal_volume_m3", "minimal_volume_m3 must be positive")
+	}
+	if !endAt.After(startAt) {
+		validation.add("end_at", "end_at must be after start_at")
+	}
+}
+
+// checkMinimalVolume сверяет стоимость минимального объёма с бюджетом: если
+// minimal_volume_m3 * price_per_m3 больше budget_total — минимум недостижим
+// (предупреждение), больше budget_total * factor — скорее всего ошибка ввода.
+func checkMinimalVolume(minimalVolume, pricePerM3, budgetTotal, factor float64) ([]string, error) {
+	impliedCost := minimalVolume * pricePerM3
+	if impliedCost <= budgetTotal {
+		return nil, nil
+	}
+	if factor > 0 && impliedCost > budgetTotal*factor {
+		return nil, fieldError("minimal_volume_m3", "minimal_volume_m3 * price_per_m3 (%.2f) exceeds budget_total (%.2f) more than %gx",
+			impliedCost, budgetTotal, factor)
+	}
+	return []string{fmt.Sprintf("minimal_volume_m3 is unreachable within budget_total: minimal_volume_m3 * price_per_m3 = %.2f > %.2f",
+		impliedCost, budgetTotal)}, nil
+}
+
+// getByClientReference возвращает ранее созданный контракт для повторного create.
+// Чужой client_reference не раскрываем — это конфликт.
+func (s *ContractService) getByClientReference(ctx context.Context, principal model.Principal, clientReference string) (*model.Contract, error) {
+	id, err := s.contracts.GetIDByClientReference(ctx, clientReference)
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
 		return nil, err
 	}
+	contract, err := s.contracts.GetByID(ctx, id, true)
+	if err != nil {
+		return nil, err
+	}
+	if contract.CreatedByOrgID != principal.OrganizationID {
+		return nil, ErrConflict
+	}
+	if err := s.ensureUsage(ctx, contract); err != nil {
+		return nil, err
+	}
+	s.decorateContract(contract)
+	return contract, nil
+}
 
+// getByIdempotencyKey возвращает контракт, созданный организацией с этим ключом
+// после cutoff; ErrNotFound — ключ не использовался или истёк,
+// ErrIdempotencyKeyMismatch — ключ использован с другим телом запроса.
+func (s *ContractService) getByIdempotencyKey(ctx context.Context, principal model.Principal, key, fingerprint string, cutoff time.Time) (*model.Contract, error) {
+	record, err := s.contracts.GetIdempotencyKey(ctx, principal.OrganizationID, key, cutoff)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if record.RequestHash != nil && *record.RequestHash != fingerprint {
+		return nil, ErrIdempotencyKeyMismatch
+	}
+	contract, err := s.contracts.GetByID(ctx, record.ContractID, true)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if err := s.ensureUsage(ctx, contract); err != nil {
+		return nil, err
+	}
 	s.decorateContract(contract)
 	return contract, nil
 }
 
+// createFingerprint — SHA-256 полей запроса create без самого ключа; по нему
+// повтор с тем же Idempotency-Key отличается от другого запроса с этим ключом.
+func createFingerprint(input CreateContractInput) (string, error) {
+	input.IdempotencyKey = nil
+	body, err := json.Marshal(input)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// PurgeExpiredIdempotencyKeys удаляет истёкшие ключи Idempotency-Key (фоновая задача).
+func (s *ContractService) PurgeExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	if s.ReadOnly(ctx) {
+		s.log.Debug().Msg("idempotency key cleanup skipped: read-only mode")
+		return 0, nil
+	}
+	return s.contracts.PurgeIdempotencyKeys(ctx, s.now().Add(-s.cfg.IdempotencyKeyTTL))
+}
+
+// validatePolygonIDs отклоняет nil UUID и повторы в наборе полигонов; общая
+// проверка для всех путей, задающих полигоны контракта.
+func validatePolygonIDs(polygonIDs []uuid.UUID) error {
+	seen := make(map[uuid.UUID]struct{}, len(polygonIDs))
+	for _, id := range polygonIDs {
+		if id == uuid.Nil {
+			return fieldError("polygon_ids", "polygon_ids contains nil uuid")
+		}
+		if _, ok := seen[id]; ok {
+			return fieldError("polygon_ids", "duplicate polygon_id %s", id)
+		}
+		seen[id] = struct{}{}
+	}
+	return nil
+}
+
+// validatePolygonBudgets проверяет, что бюджеты заданы только для полигонов контракта,
+// положительны и в сумме не превышают budget_total.
+func validatePolygonBudgets(polygonIDs []uuid.UUID, budgets map[uuid.UUID]float64, budgetTotal float64) error {
+	if len(budgets) == 0 {
+		return nil
+	}
+
+	polygons := make(map[uuid.UUID]struct{}, len(polygonIDs))
+	for _, id := range polygonIDs {
+		polygons[id] = struct{}{}
+	}
+
+	sum := 0.0
+	for polygonID, budget := range budgets {
+		if _, ok := polygons[polygonID]; !ok {
+			return fieldError("per_polygon_budget", "per_polygon_budget has polygon %s outside polygon_ids", polygonID)
+		}
+		if budget <= 0 {
+			return fieldError("per_polygon_budget", "per_polygon_budget for polygon %s must be positive", polygonID)
+		}
+		sum += budget
+	}
+	if sum > budgetTotal {
+		return fieldError("per_polygon_budget", "polygon budgets exceed budget_total (%.2f > %.2f)", sum, budgetTotal)
+	}
+	return nil
+}
+
+// ensureUsage проверяет, что usage контракта прочитан. Ошибка чтения usage или
+// полигонов логируется; при FlagUsageLoadErrors контракт отдаётся с
+// usage_load_error, иначе запрос завершается ошибкой — неполные суммы хуже 500.
+// Отсутствующая строка contract_usage восстанавливается в StrictUsage.
+func (s *ContractService) ensureUsage(ctx context.Context, contract *model.Contract) error {
+	if contract.UsageLoadErr != nil {
+		metrics.UsageLoadErrors.Inc()
+		s.log.Error().Err(contract.UsageLoadErr).Str("contract_id", contract.ID.String()).Msg("failed to load contract usage")
+		if !s.cfg.FlagUsageLoadErrors {
+			return fmt.Errorf("contract %s: %w", contract.ID, contract.UsageLoadErr)
+		}
+		return nil
+	}
+	if !contract.UsageMissing {
+		return nil
+	}
+
+	metrics.UsageRowsMissing.Inc()
+	if !s.cfg.StrictUsage {
+		return nil
+	}
+
+	s.log.Warn().Str("contract_id", contract.ID.String()).Msg("contract_usage row missing, repairing")
+	usage, err := s.contracts.RepairUsage(ctx, contract.ID)
+	if err != nil {
+		s.log.Error().Err(err).Str("contract_id", contract.ID.String()).Msg("failed to repair contract_usage")
+		return nil
+	}
+	if usage != nil {
+		contract.Usage = usage
+		contract.UsageMissing = false
+	}
+	return nil
+}
+
 func (s *ContractService) decorateContract(contract *model.Contract) {
-	now := s.now()
+	s.decorateContractAt(contract, s.now())
+}
+
+// decorateContractAt считает вычисляемые поля на момент now (для as_of — исторический).
+// Поля, зависящие от usage, считаются только при загруженном usage.
+func (s *ContractService) decorateContractAt(contract *model.Contract, now time.Time) {
 	status := deriveUIStatus(contract, now)
 	contract.UIStatus = status
+	contract.ResultTolerance = s.cfg.ResultTolerance
+
+	for i := range contract.Polygons {
+		polygon := &contract.Polygons[i]
+		polygon.Utilization = nil
+		if polygon.Budget != nil && *polygon.Budget > 0 {
+			utilization := polygon.TotalCost / *polygon.Budget
+			polygon.Utilization = &utilization
+		}
+	}
+
+	contract.Result = ""
+	contract.PayableAmount = nil
+	contract.BudgetExceeded = nil
+	contract.VolumeProgress = nil
+	contract.HealthScore = nil
+	contract.Health = nil
+	if !contract.UsageLoaded() {
+		return
+	}
 
 	usageVolume := 0.0
 	usageCost := 0.0
@@ -208,18 +814,24 @@ func (s *ContractService) decorateContract(contract *model.Contract) {
 		usageCost = contract.Usage.TotalCost
 	}
 
+	progress := 0.0
 	if contract.MinimalVolumeM3 > 0 {
-		contract.VolumeProgress = usageVolume / contract.MinimalVolumeM3
+		progress = usageVolume / contract.MinimalVolumeM3
 	}
+	contract.VolumeProgress = &progress
 
-	contract.PayableAmount = math.Min(usageCost, contract.BudgetTotal)
-	if usageCost > contract.BudgetTotal {
-		contract.BudgetExceeded = true
-	}
+	score, health := computeHealth(contract, usageVolume, usageCost, now, s.cfg.Calendar)
+	contract.HealthScore = &score
+	contract.Health = &health
+
+	payable := computePayable(usageCost, contract.BudgetTotal).PayableAmount
+	contract.PayableAmount = &payable
+	exceeded := usageCost > contract.BudgetTotal
+	contract.BudgetExceeded = &exceeded
 
 	switch status {
 	case model.ContractUIStatusExpired:
-		if usageVolume >= contract.MinimalVolumeM3 {
+		if meetsMinimalVolume(usageVolume, contract.MinimalVolumeM3, s.cfg.ResultTolerance) {
 			contract.Result = model.ContractResultSuccess
 		} else {
 			contract.Result = model.ContractResultFail
@@ -229,17 +841,14 @@ func (s *ContractService) decorateContract(contract *model.Contract) {
 	}
 }
 
+// meetsMinimalVolume — выполнен ли минимальный объём с учётом допуска:
+// при tolerance = 0.02 достаточно 98% от minimal_volume_m3.
+func meetsMinimalVolume(volume, minimal, tolerance float64) bool {
+	return volume >= minimal*(1-tolerance)
+}
+
 func deriveUIStatus(contract *model.Contract, now time.Time) model.ContractUIStatus {
-	if !contract.IsActive {
-		return model.ContractUIStatusArchived
-	}
-	if now.Before(contract.StartAt) {
-		return model.ContractUIStatusPlanned
-	}
-	if now.After(contract.EndAt) {
-		return model.ContractUIStatusExpired
-	}
-	return model.ContractUIStatusActive
+	return model.DeriveUIStatus(contract.IsActive, contract.StartAt, contract.EndAt, now)
 }
 
 type AssignTicketContractInput struct {
@@ -247,7 +856,8 @@ type AssignTicketContractInput struct {
 	ContractID uuid.UUID
 }
 
-func (s *ContractService) AssignTicketContract(ctx context.Context, principal model.Principal, input AssignTicketContractInput) error {
+func (s *ContractService) AssignTicketContract(ctx context.Context, principal model.Principal, input AssignTicketContractInput) (err error) {
+	defer observeOperation("assign_ticket_contract", time.Now(), &err)
 	if !principal.IsKgu() {
 		return ErrPermissionDenied
 	}
@@ -258,12 +868,18 @@ func (s *ContractService) AssignTicketContract(ctx context.Context, principal mo
 	if err != nil {
 		return err
 	}
-	if contract.CreatedByOrgID != principal.OrganizationID {
-		return ErrPermissionDenied
+	if err := ensureWriteAccess(principal, contract); err != nil {
+		return err
+	}
+	if err := ensureNotLocked(contract); err != nil {
+		return err
 	}
-	err = s.contracts.AssignTicketContract(ctx, input.TicketID, input.ContractID)
+	changed, err := s.contracts.AssignTicketContract(ctx, input.TicketID, input.ContractID)
 	switch {
 	case err == nil:
+		if changed {
+			s.notifyTicketContractAssigned(ctx, principal, input.TicketID, input.ContractID)
+		}
 		return nil
 	case errors.Is(err, repository.ErrTicketAlreadyLinked):
 		return ErrConflict
@@ -277,57 +893,198 @@ func (s *ContractService) AssignTicketContract(ctx context.Context, principal mo
 type RecordTripUsageInput struct {
 	TripID   uuid.UUID
 	TicketID uuid.UUID
+	// VolumeM3 — объём в единицах Unit (по умолчанию м3)
 	VolumeM3 float64
+	Unit     model.VolumeUnit
+	// RecordedAt задаёт время записи при повторной загрузке исторических рейсов.
+	RecordedAt *time.Time
+	// ForceOverBudget — записать рейс и сверх budget_total при RejectOverBudget;
+	// только KGU_ZKH_ADMIN.
+	ForceOverBudget bool
 }
 
-func (s *ContractService) RecordTripUsage(ctx context.Context, principal model.Principal, input RecordTripUsageInput) error {
+func (s *ContractService) RecordTripUsage(ctx context.Context, principal model.Principal, input RecordTripUsageInput) (err error) {
+	defer observeOperation("record_trip_usage", time.Now(), &err)
 	if !(principal.IsKgu() || principal.IsAkimat()) {
 		return ErrPermissionDenied
 	}
+	params, contract, err := s.prepareTripUsage(ctx, principal, input)
+	if err != nil {
+		return err
+	}
+
+	change, err := s.contracts.RecordTripUsage(ctx, params)
+	if err != nil {
+		return tripUsageError(err)
+	}
+	s.publishUsage(ctx, params.ContractID)
+	s.notifyIfBudgetExceeded(ctx, principal, contract, change)
+	return nil
+}
+
+// tripUsageError переводит ошибку записи рейса в ошибку сервиса.
+func tripUsageError(err error) error {
+	switch {
+	case errors.Is(err, repository.ErrTripUsageDuplicate):
+		return ErrConflict
+	case errors.Is(err, repository.ErrBudgetExceeded), errors.Is(err, repository.ErrTicketRelinked):
+		return fmt.Errorf("%w: %v", ErrConflict, err)
+	case errors.Is(err, repository.ErrContractLocked):
+		return ErrContractLocked
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	default:
+		return err
+	}
+}
+
+// minTripVolumeM3 — наименьший объём рейса, представимый в trip_usage_log (NUMERIC(10,2)).
+const minTripVolumeM3 = 0.01
+
+// prepareTripUsage проверяет рейс и находит его контракт. Цену для стоимости
+// репозиторий перечитывает под блокировкой строки контракта.
+func (s *ContractService) prepareTripUsage(ctx context.Context, principal model.Principal, input RecordTripUsageInput) (repository.TripUsageParams, *model.Contract, error) {
+	var params repository.TripUsageParams
+	if input.ForceOverBudget && principal.Role != model.UserRoleKguZkhAdmin {
+		return params, nil, ErrPermissionDenied
+	}
 	if input.VolumeM3 <= 0 {
-		return ErrInvalidInput
+		return params, nil, ErrInvalidInput
+	}
+	unit := input.Unit
+	if unit == "" {
+		unit = model.VolumeUnitM3
+	}
+	if unit != model.VolumeUnitM3 && unit != model.VolumeUnitLiters {
+		return params, nil, ErrInvalidInput
+	}
+	// trip_usage_log хранит объём с точностью до 0.01 м3; стоимость считается
+	// от того же округлённого значения, что попадёт в журнал
+	volumeM3 := math.Round(unit.ToM3(input.VolumeM3)*100) / 100
+	if volumeM3 < minTripVolumeM3 {
+		return params, nil, fieldError("detected_volume_m3", "detected_volume_m3 must be at least 0.01 m3")
 	}
 
 	contractID, err := s.contracts.GetContractIDByTicket(ctx, input.TicketID)
 	if err != nil {
 		switch {
 		case errors.Is(err, repository.ErrTicketNotFound):
-			return ErrNotFound
+			return params, nil, ErrNotFound
 		case errors.Is(err, repository.ErrTicketNotLinked):
-			return ErrInvalidInput
+			return params, nil, ErrInvalidInput
 		default:
-			return err
+			return params, nil, err
 		}
 	}
 
 	contract, err := s.contracts.GetByID(ctx, contractID, false)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return ErrNotFound
+		return params, nil, ErrNotFound
 	}
 	if err != nil {
-		return err
+		return params, nil, err
+	}
+	if err := ensureNotLocked(contract); err != nil {
+		return params, nil, err
 	}
 
-	params := repository.TripUsageParams{
-		TripID:     input.TripID,
-		TicketID:   input.TicketID,
-		VolumeM3:   input.VolumeM3,
-		ContractID: contractID,
+	if input.RecordedAt != nil {
+		recordedAt := *input.RecordedAt
+		if recordedAt.Before(contract.StartAt) || recordedAt.After(contract.EndAt) || recordedAt.After(s.now()) {
+			return params, nil, ErrInvalidInput
+		}
 	}
 
-	err = s.contracts.RecordTripUsage(ctx, params, contract.PricePerM3)
+	params = repository.TripUsageParams{
+		TripID:         input.TripID,
+		TicketID:       input.TicketID,
+		VolumeM3:       volumeM3,
+		ContractID:     contractID,
+		RecordedAt:     input.RecordedAt,
+		ReportedVolume: input.VolumeM3,
+		ReportedUnit:   unit,
+		// принудительная запись сверх бюджета не проверяет его совсем
+		RejectOverBudget: s.cfg.RejectOverBudget && !input.ForceOverBudget,
+	}
+
+	return params, contract, nil
+}
+
+type ListContractTicketsInput struct {
+	Status *model.TicketStatus
+	// From/To — границы planned_start_at включительно
+	From *time.Time
+	To   *time.Time
+	// Limit — размер страницы, 0 — все тикеты
+	Limit  int
+	Offset int
+}
+
+type TicketPage struct {
+	Items  []model.ContractTicket
+	Total  int64
+	Limit  int
+	Offset int
+}
+
+// ListContractTickets возвращает страницу тикетов контракта с фильтрами;
+// счётчики рейсов и назначений — по тикету целиком.
+func (s *ContractService) ListContractTickets(ctx context.Context, principal model.Principal, contractID uuid.UUID, input ListContractTicketsInput) (*TicketPage, error) {
+	if input.From != nil && input.To != nil && input.From.After(*input.To) {
+		return nil, fmt.Errorf("%w: from must not be after to", ErrInvalidInput)
+	}
+	// без limit список не ограничивается, как и до появления пагинации
+	if input.Limit < 0 || input.Limit > MaxPageLimit || input.Offset < 0 {
+		return nil, fmt.Errorf("%w: limit must be at most %d and offset non-negative", ErrInvalidInput, MaxPageLimit)
+	}
+
+	contract, err := s.contracts.GetByID(ctx, contractID, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
-		switch {
-		case errors.Is(err, repository.ErrTripUsageDuplicate):
-			return ErrConflict
-		default:
-			return err
+		return nil, err
+	}
+	if err := s.ensureReadAccess(principal, contract); err != nil {
+		return nil, err
+	}
+
+	filter := repository.TicketFilter{
+		Status: input.Status,
+		From:   input.From,
+		To:     input.To,
+		Limit:  input.Limit,
+		Offset: input.Offset,
+	}
+	total, err := s.contracts.CountContractTickets(ctx, contractID, filter)
+	if err != nil {
+		return nil, err
+	}
+	items := []model.ContractTicket{}
+	if total > int64(input.Offset) {
+		if items, err = s.contracts.ListContractTickets(ctx, contractID, filter); err != nil {
+			return nil, err
 		}
 	}
-	return nil
+
+	return &TicketPage{
+		Items:  emptyIfNil(items),
+		Total:  total,
+		Limit:  input.Limit,
+		Offset: input.Offset,
+	}, nil
+}
+
+type ListContractTripsInput struct {
+	// Completed filters trips by presence of exit_at: true — finished trips,
+	// false — trips still open (vehicle on site or exit not detected).
+	Completed *bool
+	// PlateMismatch — true оставляет только рейсы, где распознанный номер
+	// отличается от номера техники (только КГУ, акимат и подрядчик контракта).
+	PlateMismatch bool
 }
 
-func (s *ContractService) ListContractTickets(ctx context.Context, principal model.Principal, contractID uuid.UUID) ([]model.ContractTicket, error) {
+func (s *ContractService) ListContractTrips(ctx context.Context, principal model.Principal, contractID uuid.UUID, input ListContractTripsInput) ([]model.ContractTrip, error) {
 	contract, err := s.contracts.GetByID(ctx, contractID, false)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, ErrNotFound
@@ -338,10 +1095,33 @@ func (s *ContractService) ListContractTickets(ctx context.Context, principal mod
 	if err := s.ensureReadAccess(principal, contract); err != nil {
 		return nil, err
 	}
-	return s.contracts.ListContractTickets(ctx, contractID)
+	if input.PlateMismatch && !principal.IsKgu() && !principal.IsAkimat() && !principal.IsContractor() {
+		return nil, ErrPermissionDenied
+	}
+	trips, err := s.contracts.ListContractTrips(ctx, contractID, repository.TripFilter{
+		Completed: input.Completed,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// фильтр по номеру — в Go, чтобы нормализация совпадала с plate_mismatch в ответе
+	filtered := trips[:0]
+	for i := range trips {
+		decorateTrip(&trips[i])
+		if input.PlateMismatch && !trips[i].PlateMismatch {
+			continue
+		}
+		filtered = append(filtered, trips[i])
+	}
+
+	return filtered, nil
 }
 
-func (s *ContractService) ListContractTrips(ctx context.Context, principal model.Principal, contractID uuid.UUID) ([]model.ContractTrip, error) {
+// ListUsageLog возвращает рейсы, из которых сложился usage контракта, новые
+// первыми — для сверки счетов подрядчика по рейсам. Доступ — как у
+// ListContractTrips. Ручные корректировки сюда не входят.
+func (s *ContractService) ListUsageLog(ctx context.Context, principal model.Principal, contractID uuid.UUID) ([]model.TripUsageLogEntry, error) {
 	contract, err := s.contracts.GetByID(ctx, contractID, false)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, ErrNotFound
@@ -352,31 +1132,99 @@ func (s *ContractService) ListContractTrips(ctx context.Context, principal model
 	if err := s.ensureReadAccess(principal, contract); err != nil {
 		return nil, err
 	}
-	return s.contracts.ListContractTrips(ctx, contractID)
+
+	items, err := s.contracts.ListTripUsageLog(ctx, contractID)
+	if err != nil {
+		return nil, err
+	}
+	return emptyIfNil(items), nil
 }
 
-func (s *ContractService) ensureReadAccess(principal model.Principal, contract *model.Contract) error {
-	switch {
-	case principal.IsContractor():
-		if contract.ContractType != model.ContractTypeContractorService {
-			return ErrPermissionDenied
-		}
-		if contract.ContractorID == nil || *contract.ContractorID != principal.OrganizationID {
-			return ErrPermissionDenied
+// decorateTrip fills computed fields; each stays nil unless both sides are known.
+func decorateTrip(trip *model.ContractTrip) {
+	trip.DurationSeconds = nil
+	if trip.ExitAt != nil {
+		duration := int64(trip.ExitAt.Sub(trip.EntryAt).Seconds())
+		trip.DurationSeconds = &duration
+	}
+
+	trip.VolumeDeltaM3 = nil
+	if trip.VolumeEntry != nil && trip.VolumeExit != nil {
+		delta := *trip.VolumeEntry - *trip.VolumeExit
+		trip.VolumeDeltaM3 = &delta
+	}
+
+	trip.PlateMismatch = isPlateMismatch(trip)
+}
+
+// ListByCleaningArea возвращает контракты, к которым привязаны тикеты участка уборки.
+func (s *ContractService) ListByCleaningArea(ctx context.Context, principal model.Principal, cleaningAreaID uuid.UUID) ([]model.Contract, error) {
+	filter := repository.ContractFilter{
+		CleaningAreaID: &cleaningAreaID,
+		IncludeUsage:   true,
+		Now:            s.now(),
+	}
+	if err := applyReadScope(principal, &filter); err != nil {
+		return nil, err
+	}
+
+	contracts, err := s.contracts.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range contracts {
+		if err := s.ensureUsage(ctx, &contracts[i]); err != nil {
+			return nil, err
 		}
-	case principal.IsLandfill():
-		if contract.ContractType != model.ContractTypeLandfillService {
-			return ErrPermissionDenied
+		s.decorateContract(&contracts[i])
+	}
+
+	return contracts, nil
+}
+
+// ListLandfillPolygons возвращает покрытие полигонов действующими контрактами
+// полигона приёма: помогает увидеть пробелы и пересечения.
+func (s *ContractService) ListLandfillPolygons(ctx context.Context, principal model.Principal, landfillID uuid.UUID) ([]model.LandfillPolygonCoverage, error) {
+	if err := ensureLandfillAccess(principal, landfillID); err != nil {
+		return nil, err
+	}
+	return s.contracts.ListLandfillPolygonCoverage(ctx, landfillID, s.now())
+}
+
+// ListAccessible возвращает все контракты, которые принципал может читать,
+// с указанием причины доступа.
+func (s *ContractService) ListAccessible(ctx context.Context, principal model.Principal) ([]model.AccessibleContract, error) {
+	filter := repository.ContractFilter{
+		IncludeUsage: true,
+		Now:          s.now(),
+	}
+	if err := applyReadScope(principal, &filter); err != nil {
+		return nil, err
+	}
+
+	contracts, err := s.contracts.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]model.AccessibleContract, 0, len(contracts))
+	for i := range contracts {
+		relation, ok := accessRelation(principal, &contracts[i])
+		if !ok {
+			continue
 		}
-		if contract.LandfillID == nil || *contract.LandfillID != principal.OrganizationID {
-			return ErrPermissionDenied
+		if err := s.ensureUsage(ctx, &contracts[i]); err != nil {
+			return nil, err
 		}
-	case principal.IsKgu(), principal.IsAkimat():
-		// allowed
-	default:
-		return ErrPermissionDenied
+		s.decorateContract(&contracts[i])
+		items = append(items, model.AccessibleContract{
+			Contract: contracts[i],
+			Relation: relation,
+		})
 	}
-	return nil
+
+	return items, nil
 }
 
 type DeletionInfo struct {
@@ -399,8 +1247,8 @@ func (s *ContractService) GetDeletionInfo(ctx context.Context, principal model.P
 	}
 
 	// Verify contract was created by the principal's organization
-	if contract.CreatedByOrgID != principal.OrganizationID {
-		return nil, ErrPermissionDenied
+	if err := ensureWriteAccess(principal, contract); err != nil {
+		return nil, err
 	}
 
 	// Get dependency information
@@ -415,13 +1263,26 @@ func (s *ContractService) GetDeletionInfo(ctx context.Context, principal model.P
 	}, nil
 }
 
-func (s *ContractService) Delete(ctx context.Context, principal model.Principal, id uuid.UUID, force bool) error {
+// Delete по умолчанию удаляет контракт мягко: ставит deleted_at, строка и
+// связанные данные остаются, Restore возвращает контракт. Контракт с тикетами
+// удаляется только с force. Физическое удаление (purge) — только вместе с
+// force и вместе с тикетами.
+func (s *ContractService) Delete(ctx context.Context, principal model.Principal, id uuid.UUID, force, purge bool) (err error) {
+	defer observeOperation("delete", time.Now(), &err)
 	if !principal.IsKgu() {
 		return ErrPermissionDenied
 	}
+	if purge && !force {
+		return fmt.Errorf("%w: purge requires force", ErrInvalidInput)
+	}
 
-	// Check if contract exists
-	contract, err := s.contracts.GetByID(ctx, id, false)
+	// Check if contract exists; purge also cleans up soft-deleted contracts
+	var contract *model.Contract
+	if purge {
+		contract, err = s.contracts.GetByIDWithDeleted(ctx, id, false)
+	} else {
+		contract, err = s.contracts.GetByID(ctx, id, false)
+	}
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return ErrNotFound
 	}
@@ -430,8 +1291,8 @@ func (s *ContractService) Delete(ctx context.Context, principal model.Principal,
 	}
 
 	// Verify contract was created by the principal's organization
-	if contract.CreatedByOrgID != principal.OrganizationID {
-		return ErrPermissionDenied
+	if err := ensureWriteAccess(principal, contract); err != nil {
+		return err
 	}
 
 	// If force=false, check for related tickets
@@ -445,12 +1306,18 @@ func (s *ContractService) Delete(ctx context.Context, principal model.Principal,
 		}
 	}
 
-	// If force=true, delete tickets first (cascades to assignments and appeals)
-	// trips.ticket_id will be set to NULL automatically via ON DELETE SET NULL
-	if force {
-		if err := s.contracts.DeleteTicketsByContractID(ctx, id); err != nil {
-			return err
+	if !purge {
+		err := s.contracts.SoftDelete(ctx, id, principal.UserID, principal.OrganizationID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
 		}
+		return err
+	}
+
+	// Purge deletes tickets first (cascades to assignments and appeals)
+	// trips.ticket_id will be set to NULL automatically via ON DELETE SET NULL
+	if err := s.contracts.DeleteTicketsByContractID(ctx, id); err != nil {
+		return err
 	}
 
 	// Delete contract (cascades to polygons, usage, trip_usage_log)
@@ -463,3 +1330,41 @@ func (s *ContractService) Delete(ctx context.Context, principal model.Principal,
 
 	return nil
 }
+
+// Restore снимает мягкое удаление контракта. Права — как у Delete; активный
+// контракт снова учитывается в лимите активных контрактов организации.
+func (s *ContractService) Restore(ctx context.Context, principal model.Principal, id uuid.UUID) (_ *model.Contract, err error) {
+	defer observeOperation("restore", time.Now(), &err)
+	if !principal.IsKgu() {
+		return nil, ErrPermissionDenied
+	}
+
+	contract, err := s.contracts.GetByIDWithDeleted(ctx, id, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureWriteAccess(principal, contract); err != nil {
+		return nil, err
+	}
+
+	err = s.contracts.Undelete(ctx, repository.UndeleteContractParams{
+		ID:              id,
+		MaxActivePerOrg: s.cfg.MaxActivePerOrg,
+		ActorUserID:     principal.UserID,
+		ActorOrgID:      principal.OrganizationID,
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, ErrNotFound
+	case errors.Is(err, repository.ErrContractNotDeleted),
+		errors.Is(err, repository.ErrActiveContractLimit):
+		return nil, fmt.Errorf("%w: %v", ErrConflict, err)
+	case err != nil:
+		return nil, err
+	}
+
+	return s.get(ctx, principal, id, nil)
+}
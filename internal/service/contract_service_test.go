@@ -0,0 +1,804 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/dbtest"
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/notifier"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+// recordingNotifier запоминает отправленные события вместо доставки.
+type recordingNotifier struct {
+	mu     sync.Mutex
+	events []notifier.Event
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, event notifier.Event) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.events = append(n.events, event)
+	return nil
+}
+
+func (n *recordingNotifier) ofType(eventType string) []notifier.Event {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var items []notifier.Event
+	for _, event := range n.events {
+		if event.Type == eventType {
+			items = append(items, event)
+		}
+	}
+	return items
+}
+
+// newTestService — сервис над отдельной тестовой базой (см. dbtest).
+func newTestService(t *testing.T, cfg Config) (*ContractService, *gorm.DB, *recordingNotifier) {
+	t.Helper()
+	database := dbtest.Open(t)
+	events := &recordingNotifier{}
+	return NewContractService(repository.NewContractRepository(database), events, cfg, zerolog.Nop()), database, events
+}
+
+func kguPrincipal(t *testing.T, database *gorm.DB) model.Principal {
+	t.Helper()
+	return model.Principal{
+		UserID:         uuid.New(),
+		OrganizationID: dbtest.Organization(t, database, "КГУ ЖКХ"),
+		Role:           model.UserRoleKguZkhAdmin,
+	}
+}
+
+// contractorInput — корректный CONTRACTOR_SERVICE контракт, действующий сейчас:
+// 100 за м3, бюджет 100 000, минимум 500 м3.
+func contractorInput(t *testing.T, database *gorm.DB) CreateContractInput {
+	t.Helper()
+	contractorID := dbtest.Organization(t, database, "ТОО Подрядчик")
+	now := time.Now().UTC().Truncate(time.Second)
+	return CreateContractInput{
+		ContractType:    model.ContractTypeContractorService,
+		ContractorID:    &contractorID,
+		Name:            "Уборка дорог",
+		WorkType:        model.WorkTypeRoad,
+		PricePerM3:      100,
+		BudgetTotal:     100000,
+		MinimalVolumeM3: 500,
+		StartAt:         now.Add(-24 * time.Hour),
+		EndAt:           now.Add(30 * 24 * time.Hour),
+	}
+}
+
+func createContract(t *testing.T, s *ContractService, principal model.Principal, input CreateContractInput) *model.Contract {
+	t.Helper()
+	contract, created, err := s.Create(context.Background(), principal, input)
+	if err != nil {
+		t.Fatalf("create contract: %v", err)
+	}
+	if !created {
+		t.Fatalf("create contract: existing contract returned")
+	}
+	return contract
+}
+
+func TestAssignTicketContractNotifiesOnlyOnChange(t *testing.T) {
+	ctx := context.Background()
+	s, database, events := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+	ticketID := dbtest.Ticket(t, database, uuid.Nil)
+	input := AssignTicketContractInput{TicketID: ticketID, ContractID: contract.ID}
+
+	if err := s.AssignTicketContract(ctx, principal, input); err != nil {
+		t.Fatalf("assign: %v", err)
+	}
+	assigned := events.ofType(notifier.EventTicketContractAssigned)
+	if len(assigned) != 1 {
+		t.Fatalf("events after assign = %d, want 1", len(assigned))
+	}
+	data, ok := assigned[0].Data.(TicketContractAssignedEvent)
+	if !ok {
+		t.Fatalf("event data = %T, want TicketContractAssignedEvent", assigned[0].Data)
+	}
+	if data.TicketID != ticketID || data.ContractID != contract.ID || data.ActorUserID != principal.UserID {
+		t.Fatalf("event data = %+v", data)
+	}
+
+	// повторная привязка к тому же контракту — no-op без события
+	if err := s.AssignTicketContract(ctx, principal, input); err != nil {
+		t.Fatalf("repeated assign: %v", err)
+	}
+	if n := len(events.ofType(notifier.EventTicketContractAssigned)); n != 1 {
+		t.Fatalf("events after no-op assign = %d, want 1", n)
+	}
+}
+
+func TestAssignTicketContractConflictDoesNotNotify(t *testing.T) {
+	ctx := context.Background()
+	s, database, events := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	first := createContract(t, s, principal, contractorInput(t, database))
+	second := createContract(t, s, principal, contractorInput(t, database))
+	ticketID := dbtest.Ticket(t, database, first.ID)
+
+	err := s.AssignTicketContract(ctx, principal, AssignTicketContractInput{TicketID: ticketID, ContractID: second.ID})
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("assign to another contract: err = %v, want ErrConflict", err)
+	}
+	if n := len(events.ofType(notifier.EventTicketContractAssigned)); n != 0 {
+		t.Fatalf("events after rejected assign = %d, want 0", n)
+	}
+}
+
+// recordTrip записывает рейс объёмом volumeM3 по новому тикету контракта.
+func recordTrip(t *testing.T, s *ContractService, database *gorm.DB, principal model.Principal, contractID uuid.UUID, volumeM3 float64) error {
+	t.Helper()
+	ticketID := dbtest.Ticket(t, database, contractID)
+	return s.RecordTripUsage(context.Background(), principal, RecordTripUsageInput{
+		TripID:   dbtest.Trip(t, database, ticketID, uuid.Nil),
+		TicketID: ticketID,
+		VolumeM3: volumeM3,
+	})
+}
+
+// landfillInput — корректный LANDFILL_SERVICE контракт с одним полигоном.
+func landfillInput(t *testing.T, database *gorm.DB) CreateContractInput {
+	t.Helper()
+	input := contractorInput(t, database)
+	landfillID := dbtest.Organization(t, database, "ТОО Полигон")
+	input.ContractType = model.ContractTypeLandfillService
+	input.ContractorID = nil
+	input.WorkType = ""
+	input.LandfillID = &landfillID
+	input.PolygonIDs = []uuid.UUID{uuid.New()}
+	return input
+}
+
+func TestCreateContractTypes(t *testing.T) {
+	s, database, _ := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+
+	contractor := createContract(t, s, principal, contractorInput(t, database))
+	if contractor.ContractType != model.ContractTypeContractorService || contractor.ContractorID == nil {
+		t.Fatalf("contractor contract = type %s, contractor_id %v", contractor.ContractType, contractor.ContractorID)
+	}
+	if contractor.LandfillID != nil || len(contractor.Polygons) != 0 {
+		t.Fatalf("contractor contract has landfill %v and %d polygons", contractor.LandfillID, len(contractor.Polygons))
+	}
+
+	input := landfillInput(t, database)
+	landfill := createContract(t, s, principal, input)
+	if landfill.ContractType != model.ContractTypeLandfillService || landfill.ContractorID != nil {
+		t.Fatalf("landfill contract = type %s, contractor_id %v", landfill.ContractType, landfill.ContractorID)
+	}
+	if landfill.LandfillID == nil || *landfill.LandfillID != *input.LandfillID {
+		t.Fatalf("landfill_id = %v, want %s", landfill.LandfillID, *input.LandfillID)
+	}
+	if len(landfill.Polygons) != 1 || landfill.Polygons[0].PolygonID != input.PolygonIDs[0] {
+		t.Fatalf("polygons = %+v, want %s", landfill.Polygons, input.PolygonIDs[0])
+	}
+}
+
+// fieldNames — поля ошибки валидации в порядке появления.
+func fieldNames(t *testing.T, err error) []string {
+	t.Helper()
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		t.Fatalf("err = %v, want *ValidationError", err)
+	}
+	names := make([]string, 0, len(validation.Fields))
+	for _, field := range validation.Fields {
+		names = append(names, field.Field)
+	}
+	return names
+}
+
+func TestValidateCreateContractTypeRequirements(t *testing.T) {
+	s := NewContractService(nil, nil, Config{}, zerolog.Nop())
+	contractorID := uuid.New()
+	landfillID := uuid.New()
+	now := time.Now()
+	base := CreateContractInput{
+		Name:            "Уборка",
+		PricePerM3:      100,
+		BudgetTotal:     100000,
+		MinimalVolumeM3: 500,
+		StartAt:         now,
+		EndAt:           now.Add(24 * time.Hour),
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*CreateContractInput)
+		want   []string
+	}{
+		{
+			name: "contractor service",
+			mutate: func(in *CreateContractInput) {
+				in.ContractType = model.ContractTypeContractorService
+				in.ContractorID = &contractorID
+				in.WorkType = model.WorkTypeRoad
+			},
+		},
+		{
+			name: "contractor service without contractor",
+			mutate: func(in *CreateContractInput) {
+				in.ContractType = model.ContractTypeContractorService
+				in.WorkType = model.WorkTypeRoad
+			},
+			want: []string{"contractor_id"},
+		},
+		{
+			name: "contractor service with unknown work type",
+			mutate: func(in *CreateContractInput) {
+				in.ContractType = model.ContractTypeContractorService
+				in.ContractorID = &contractorID
+				in.WorkType = "snow_melting"
+			},
+			want: []string{"work_type"},
+		},
+		{
+			name: "landfill service",
+			mutate: func(in *CreateContractInput) {
+				in.ContractType = model.ContractTypeLandfillService
+				in.LandfillID = &landfillID
+				in.PolygonIDs = []uuid.UUID{uuid.New()}
+			},
+		},
+		{
+			name: "landfill service without landfill",
+			mutate: func(in *CreateContractInput) {
+				in.ContractType = model.ContractTypeLandfillService
+				in.PolygonIDs = []uuid.UUID{uuid.New()}
+			},
+			want: []string{"landfill_id"},
+		},
+		{
+			name: "landfill service without polygons",
+			mutate: func(in *CreateContractInput) {
+				in.ContractType = model.ContractTypeLandfillService
+				in.LandfillID = &landfillID
+			},
+			want: []string{"polygon_ids"},
+		},
+		{
+			name: "landfill service with nil polygon",
+			mutate: func(in *CreateContractInput) {
+				in.ContractType = model.ContractTypeLandfillService
+				in.LandfillID = &landfillID
+				in.PolygonIDs = []uuid.UUID{uuid.Nil}
+			},
+			want: []string{"polygon_ids"},
+		},
+		{
+			name: "unknown contract type",
+			mutate: func(in *CreateContractInput) {
+				in.ContractType = "SNOW_MELTING"
+			},
+			want: []string{"contract_type"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := base
+			tt.mutate(&input)
+			_, err := s.validateCreate(input)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("validateCreate: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidInput) {
+				t.Fatalf("err = %v, want ErrInvalidInput", err)
+			}
+			if got := fieldNames(t, err); !slices.Equal(got, tt.want) {
+				t.Fatalf("fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListScopesEachRole(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	creator := kguPrincipal(t, database)
+	contractorIn := contractorInput(t, database)
+	contractor := createContract(t, s, creator, contractorIn)
+	landfillIn := landfillInput(t, database)
+	landfill := createContract(t, s, creator, landfillIn)
+	// контракты других подрядчика и полигона
+	foreignContractor := createContract(t, s, creator, contractorInput(t, database))
+	foreignLandfill := createContract(t, s, creator, landfillInput(t, database))
+	all := []uuid.UUID{contractor.ID, landfill.ID, foreignContractor.ID, foreignLandfill.ID}
+
+	tests := []struct {
+		role  model.UserRole
+		orgID uuid.UUID
+		want  []uuid.UUID
+	}{
+		{role: model.UserRoleKguZkhAdmin, orgID: creator.OrganizationID, want: all},
+		{role: model.UserRoleKguZkhUser, orgID: uuid.New(), want: all},
+		{role: model.UserRoleAkimatAdmin, orgID: uuid.New(), want: all},
+		{role: model.UserRoleAkimatUser, orgID: uuid.New(), want: all},
+		{role: model.UserRoleContractorAdmin, orgID: *contractorIn.ContractorID, want: []uuid.UUID{contractor.ID}},
+		{role: model.UserRoleLandfillAdmin, orgID: *landfillIn.LandfillID, want: []uuid.UUID{landfill.ID}},
+		{role: model.UserRoleLandfillUser, orgID: *landfillIn.LandfillID, want: []uuid.UUID{landfill.ID}},
+		{role: model.UserRoleTooAdmin, orgID: *landfillIn.LandfillID, want: []uuid.UUID{landfill.ID}},
+		{role: model.UserRoleDriver, orgID: *contractorIn.ContractorID},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			principal := model.Principal{UserID: uuid.New(), OrganizationID: tt.orgID, Role: tt.role}
+			items, _, err := s.List(ctx, principal, ListContractsInput{})
+			if tt.want == nil {
+				if !errors.Is(err, ErrPermissionDenied) {
+					t.Fatalf("err = %v, want ErrPermissionDenied", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			got := make([]uuid.UUID, 0, len(items))
+			for _, item := range items {
+				got = append(got, item.ID)
+			}
+			if !sameIDs(got, tt.want) {
+				t.Fatalf("visible = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListLandfillScopeIgnoresForeignFilters(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	creator := kguPrincipal(t, database)
+	own := landfillInput(t, database)
+	ownContract := createContract(t, s, creator, own)
+	foreign := landfillInput(t, database)
+	createContract(t, s, creator, foreign)
+	principal := model.Principal{UserID: uuid.New(), OrganizationID: *own.LandfillID, Role: model.UserRoleLandfillUser}
+
+	// фильтр по чужому полигону не расширяет скоуп LANDFILL
+	items, _, err := s.List(ctx, principal, ListContractsInput{LandfillID: foreign.LandfillID})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != ownContract.ID {
+		t.Fatalf("visible = %d contracts, want only own %s", len(items), ownContract.ID)
+	}
+
+	contractType := model.ContractTypeContractorService
+	items, _, err = s.List(ctx, principal, ListContractsInput{ContractType: &contractType})
+	if err != nil {
+		t.Fatalf("list by contract type: %v", err)
+	}
+	if len(items) != 1 || items[0].ID != ownContract.ID {
+		t.Fatalf("visible by contract type = %d contracts, want only own %s", len(items), ownContract.ID)
+	}
+}
+
+func TestListFiltersForKgu(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	contractorIn := contractorInput(t, database)
+	contractor := createContract(t, s, principal, contractorIn)
+	landfillIn := landfillInput(t, database)
+	landfill := createContract(t, s, principal, landfillIn)
+
+	contractType := model.ContractTypeLandfillService
+	tests := []struct {
+		name  string
+		input ListContractsInput
+		want  []uuid.UUID
+	}{
+		{name: "contractor_id", input: ListContractsInput{ContractorID: contractorIn.ContractorID}, want: []uuid.UUID{contractor.ID}},
+		{name: "landfill_id", input: ListContractsInput{LandfillID: landfillIn.LandfillID}, want: []uuid.UUID{landfill.ID}},
+		{name: "contract_type", input: ListContractsInput{ContractType: &contractType}, want: []uuid.UUID{landfill.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, _, err := s.List(ctx, principal, tt.input)
+			if err != nil {
+				t.Fatalf("list: %v", err)
+			}
+			got := make([]uuid.UUID, 0, len(items))
+			for _, item := range items {
+				got = append(got, item.ID)
+			}
+			if !sameIDs(got, tt.want) {
+				t.Fatalf("visible = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// sameIDs сравнивает наборы id без учёта порядка.
+func sameIDs(got, want []uuid.UUID) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	seen := make(map[uuid.UUID]bool, len(got))
+	for _, id := range got {
+		seen[id] = true
+	}
+	for _, id := range want {
+		if !seen[id] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestListFilterRoleScope(t *testing.T) {
+	s := NewContractService(nil, nil, Config{}, zerolog.Nop())
+	orgID := uuid.New()
+	requested := uuid.New()
+	landfillType := model.ContractTypeLandfillService
+	input := ListContractsInput{ContractorID: &requested, LandfillID: &requested}
+
+	tests := []struct {
+		role         model.UserRole
+		contractorID *uuid.UUID
+		landfillID   *uuid.UUID
+		contractType *model.ContractType
+		err          error
+	}{
+		{role: model.UserRoleKguZkhAdmin, contractorID: &requested, landfillID: &requested},
+		{role: model.UserRoleKguZkhUser, contractorID: &requested, landfillID: &requested},
+		{role: model.UserRoleAkimatAdmin, contractorID: &requested, landfillID: &requested},
+		{role: model.UserRoleAkimatUser, contractorID: &requested, landfillID: &requested},
+		{role: model.UserRoleContractorAdmin, contractorID: &orgID},
+		{role: model.UserRoleLandfillAdmin, landfillID: &orgID, contractType: &landfillType},
+		{role: model.UserRoleLandfillUser, landfillID: &orgID, contractType: &landfillType},
+		{role: model.UserRoleTooAdmin, landfillID: &orgID, contractType: &landfillType},
+		{role: model.UserRoleDriver, err: ErrPermissionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			principal := model.Principal{UserID: uuid.New(), OrganizationID: orgID, Role: tt.role}
+			filter, err := s.listFilter(principal, input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if !equalPtr(filter.ContractorID, tt.contractorID) || !equalPtr(filter.LandfillID, tt.landfillID) || !equalPtr(filter.ContractType, tt.contractType) {
+				t.Fatalf("filter contractor=%v landfill=%v type=%v, want %v %v %v",
+					filter.ContractorID, filter.LandfillID, filter.ContractType, tt.contractorID, tt.landfillID, tt.contractType)
+			}
+		})
+	}
+}
+
+func equalPtr[T comparable](a, b *T) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func TestRecordTripUsageRejectOverBudgetBoundary(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{RejectOverBudget: true})
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+
+	if err := recordTrip(t, s, database, principal, contract.ID, 999.99); err != nil {
+		t.Fatalf("record trip below budget: %v", err)
+	}
+	// 0.01 м3 по 100 доводит total_cost ровно до budget_total — это ещё в бюджете
+	if err := recordTrip(t, s, database, principal, contract.ID, 0.01); err != nil {
+		t.Fatalf("record trip landing exactly on budget_total: %v", err)
+	}
+	usage, err := s.contracts.GetUsage(ctx, contract.ID)
+	if err != nil {
+		t.Fatalf("get usage: %v", err)
+	}
+	if usage.TotalCost != contract.BudgetTotal {
+		t.Fatalf("total_cost = %.2f, want exactly %.2f", usage.TotalCost, contract.BudgetTotal)
+	}
+
+	err = recordTrip(t, s, database, principal, contract.ID, 0.01)
+	if !errors.Is(err, ErrConflict) {
+		t.Fatalf("trip past budget_total: err = %v, want ErrConflict", err)
+	}
+
+	// принудительная запись — только KGU_ZKH_ADMIN
+	ticketID := dbtest.Ticket(t, database, contract.ID)
+	input := RecordTripUsageInput{
+		TripID:          dbtest.Trip(t, database, ticketID, uuid.Nil),
+		TicketID:        ticketID,
+		VolumeM3:        0.01,
+		ForceOverBudget: true,
+	}
+	user := principal
+	user.Role = model.UserRoleKguZkhUser
+	if err := s.RecordTripUsage(ctx, user, input); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("forced trip by KGU_ZKH_USER: err = %v, want ErrPermissionDenied", err)
+	}
+	if err := s.RecordTripUsage(ctx, principal, input); err != nil {
+		t.Fatalf("forced trip by KGU_ZKH_ADMIN: %v", err)
+	}
+	usage, err = s.contracts.GetUsage(ctx, contract.ID)
+	if err != nil {
+		t.Fatalf("get usage: %v", err)
+	}
+	if usage.TotalCost != 100001 {
+		t.Fatalf("total_cost after forced trip = %.2f, want 100001", usage.TotalCost)
+	}
+}
+
+func TestRecordTripUsageConcurrentTripsCannotBothPassBudget(t *testing.T) {
+	s, database, _ := newTestService(t, Config{RejectOverBudget: true})
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+	if err := recordTrip(t, s, database, principal, contract.ID, 999); err != nil {
+		t.Fatalf("record trip: %v", err)
+	}
+
+	// остаток 100 — хватает ровно на один из двух рейсов по 1 м3
+	inputs := make([]RecordTripUsageInput, 2)
+	for i := range inputs {
+		ticketID := dbtest.Ticket(t, database, contract.ID)
+		inputs[i] = RecordTripUsageInput{TripID: dbtest.Trip(t, database, ticketID, uuid.Nil), TicketID: ticketID, VolumeM3: 1}
+	}
+	errs := make([]error, len(inputs))
+	var wg sync.WaitGroup
+	for i := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.RecordTripUsage(context.Background(), principal, inputs[i])
+		}()
+	}
+	wg.Wait()
+
+	recorded, rejected := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			recorded++
+		case errors.Is(err, ErrConflict):
+			rejected++
+		default:
+			t.Fatalf("record trip: %v", err)
+		}
+	}
+	if recorded != 1 || rejected != 1 {
+		t.Fatalf("recorded %d, rejected %d; want one of each", recorded, rejected)
+	}
+}
+
+func TestListContractTicketsWithoutLimitIsUnbounded(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+	// больше прежнего размера страницы по умолчанию (50)
+	const tickets = 60
+	dbtest.Exec(t, database, `INSERT INTO tickets (contract_id, planned_start_at)
+		SELECT ?, NOW() - n * INTERVAL '1 hour' FROM generate_series(1, ?) AS n`, contract.ID, tickets)
+
+	page, err := s.ListContractTickets(ctx, principal, contract.ID, ListContractTicketsInput{})
+	if err != nil {
+		t.Fatalf("list tickets: %v", err)
+	}
+	if len(page.Items) != tickets || page.Total != tickets || page.Limit != 0 {
+		t.Fatalf("page = %d items, total %d, limit %d; want %d items, total %d, limit 0",
+			len(page.Items), page.Total, page.Limit, tickets, tickets)
+	}
+
+	page, err = s.ListContractTickets(ctx, principal, contract.ID, ListContractTicketsInput{Limit: 10, Offset: 55})
+	if err != nil {
+		t.Fatalf("list tickets page: %v", err)
+	}
+	if len(page.Items) != 5 || page.Total != tickets {
+		t.Fatalf("page = %d items, total %d; want 5 items, total %d", len(page.Items), page.Total, tickets)
+	}
+}
+
+func TestListContractTicketsRejectsInvalidInput(t *testing.T) {
+	s := NewContractService(nil, nil, Config{}, zerolog.Nop())
+	from := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, -1)
+	tests := []struct {
+		name  string
+		input ListContractTicketsInput
+	}{
+		{name: "from after to", input: ListContractTicketsInput{From: &from, To: &to}},
+		{name: "limit over max", input: ListContractTicketsInput{Limit: MaxPageLimit + 1}},
+		{name: "negative limit", input: ListContractTicketsInput{Limit: -1}},
+		{name: "negative offset", input: ListContractTicketsInput{Offset: -1}},
+	}
+	for _, tt := range tests {
+		_, err := s.ListContractTickets(context.Background(), model.Principal{}, uuid.New(), tt.input)
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("%s: err = %v, want ErrInvalidInput", tt.name, err)
+		}
+	}
+}
+
+func TestIsContractorActiveListFallsBackOnAnyOtherField(t *testing.T) {
+	contractor := model.Principal{OrganizationID: uuid.New(), Role: model.UserRoleContractorAdmin}
+	base := ListContractsInput{OnlyActive: true, IncludeUsage: true}
+
+	fast := []ListContractsInput{
+		base,
+		{OnlyActive: true, IncludeUsage: true, Limit: 10, Cursor: "cursor", UsePreset: true},
+		{OnlyActive: true, IncludeUsage: true, Perspective: model.ContractPerspectiveAll},
+		{OnlyActive: true, IncludeUsage: true, Perspective: model.ContractPerspectiveContractor},
+	}
+	for _, input := range fast {
+		if !isContractorActiveList(contractor, input) {
+			t.Fatalf("%+v: want fast path", input)
+		}
+	}
+	if isContractorActiveList(model.Principal{OrganizationID: uuid.New(), Role: model.UserRoleKguZkhAdmin}, base) {
+		t.Fatalf("kgu principal: want generic path")
+	}
+	if isContractorActiveList(contractor, ListContractsInput{OnlyActive: true}) {
+		t.Fatalf("without usage: want generic path")
+	}
+	landfill := base
+	landfill.Perspective = model.ContractPerspectiveLandfill
+	if isContractorActiveList(contractor, landfill) {
+		t.Fatalf("landfill perspective: want generic path")
+	}
+
+	// любое другое поле, в том числе добавленное позже, уводит на общий путь
+	allowed := map[string]bool{"OnlyActive": true, "IncludeUsage": true, "Perspective": true, "UsePreset": true, "Limit": true, "Cursor": true}
+	typ := reflect.TypeOf(base)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if allowed[field.Name] {
+			continue
+		}
+		input := base
+		value := reflect.ValueOf(&input).Elem().Field(i)
+		switch value.Kind() {
+		case reflect.Bool:
+			value.SetBool(true)
+		case reflect.String:
+			value.SetString("x")
+		case reflect.Int:
+			value.SetInt(1)
+		case reflect.Pointer:
+			value.Set(reflect.New(value.Type().Elem()))
+		default:
+			t.Fatalf("field %s: unsupported kind %s", field.Name, value.Kind())
+		}
+		if isContractorActiveList(contractor, input) {
+			t.Fatalf("field %s set: want generic path", field.Name)
+		}
+	}
+}
+
+func TestValidatePolygonBudgets(t *testing.T) {
+	a, b, foreign := uuid.New(), uuid.New(), uuid.New()
+	polygons := []uuid.UUID{a, b}
+	tests := []struct {
+		name    string
+		budgets map[uuid.UUID]float64
+		wantErr bool
+	}{
+		{name: "no budgets", budgets: nil},
+		{name: "part of polygons", budgets: map[uuid.UUID]float64{a: 40000}},
+		{name: "sum equals budget_total", budgets: map[uuid.UUID]float64{a: 60000, b: 40000}},
+		{name: "polygon outside contract", budgets: map[uuid.UUID]float64{a: 10000, foreign: 10000}, wantErr: true},
+		{name: "sum above budget_total", budgets: map[uuid.UUID]float64{a: 60000, b: 40000.01}, wantErr: true},
+		{name: "zero budget", budgets: map[uuid.UUID]float64{a: 0}, wantErr: true},
+		{name: "negative budget", budgets: map[uuid.UUID]float64{b: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		err := validatePolygonBudgets(polygons, tt.budgets, 100000)
+		if !tt.wantErr {
+			if err != nil {
+				t.Fatalf("%s: err = %v", tt.name, err)
+			}
+			continue
+		}
+		if names := fieldNames(t, err); !slices.Equal(names, []string{"per_polygon_budget"}) {
+			t.Fatalf("%s: fields = %v, want per_polygon_budget", tt.name, names)
+		}
+	}
+
+	// через validateCreate ошибка приходит полем per_polygon_budget
+	s := NewContractService(nil, nil, Config{}, zerolog.Nop())
+	landfillID := uuid.New()
+	_, err := s.validateCreate(CreateContractInput{
+		ContractType:    model.ContractTypeLandfillService,
+		LandfillID:      &landfillID,
+		PolygonIDs:      polygons,
+		PolygonBudgets:  map[uuid.UUID]float64{foreign: 1000},
+		Name:            "Приём снега",
+		PricePerM3:      100,
+		BudgetTotal:     100000,
+		MinimalVolumeM3: 500,
+		StartAt:         time.Now(),
+		EndAt:           time.Now().AddDate(0, 1, 0),
+	})
+	if names := fieldNames(t, err); !slices.Equal(names, []string{"per_polygon_budget"}) {
+		t.Fatalf("validateCreate fields = %v, want per_polygon_budget", names)
+	}
+}
+
+func TestRecordTripUsageAttributesUsageToTripPolygon(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	budgeted, unbudgeted, other := uuid.New(), uuid.New(), uuid.New()
+	input := landfillInput(t, database)
+	input.PolygonIDs = []uuid.UUID{budgeted, unbudgeted}
+	input.PolygonBudgets = map[uuid.UUID]float64{budgeted: 30000}
+	contract := createContract(t, s, principal, input)
+
+	trips := []struct {
+		polygon uuid.UUID
+		volume  float64
+	}{
+		{budgeted, 100},
+		{budgeted, 50},
+		{unbudgeted, 20},
+		// полигон не из контракта и рейс без полигона идут только в общий usage
+		{other, 10},
+		{uuid.Nil, 5},
+	}
+	for _, trip := range trips {
+		ticketID := dbtest.Ticket(t, database, contract.ID)
+		err := s.RecordTripUsage(ctx, principal, RecordTripUsageInput{
+			TripID:   dbtest.Trip(t, database, ticketID, trip.polygon),
+			TicketID: ticketID,
+			VolumeM3: trip.volume,
+		})
+		if err != nil {
+			t.Fatalf("record trip on polygon %s: %v", trip.polygon, err)
+		}
+	}
+
+	got, err := s.Get(ctx, principal, contract.ID)
+	if err != nil {
+		t.Fatalf("get contract: %v", err)
+	}
+	if got.Usage == nil || got.Usage.TotalVolumeM3 != 185 || got.Usage.TotalCost != 18500 {
+		t.Fatalf("contract usage = %+v, want 185 m3 / 18500", got.Usage)
+	}
+	want := map[uuid.UUID]struct {
+		volume, cost float64
+		utilization  *float64
+	}{
+		budgeted:   {volume: 150, cost: 15000, utilization: ptrTo(0.5)},
+		unbudgeted: {volume: 20, cost: 2000},
+	}
+	if len(got.Polygons) != len(want) {
+		t.Fatalf("polygons = %+v, want %d", got.Polygons, len(want))
+	}
+	for _, polygon := range got.Polygons {
+		w, ok := want[polygon.PolygonID]
+		if !ok {
+			t.Fatalf("unexpected polygon %s", polygon.PolygonID)
+		}
+		if polygon.TotalVolumeM3 != w.volume || polygon.TotalCost != w.cost || !equalPtr(polygon.Utilization, w.utilization) {
+			t.Fatalf("polygon %s = %+v, want %+v", polygon.PolygonID, polygon, w)
+		}
+	}
+}
+
+func ptrTo[T any](value T) *T {
+	return &value
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+type CostPreview struct {
+	ContractID        uuid.UUID `json:"contract_id"`
+	VolumeM3          float64   `json:"volume_m3"`
+	PricePerM3        float64   `json:"price_per_m3"`
+	Cost              float64   `json:"cost"`
+	CurrentTotalCost  float64   `json:"current_total_cost"`
+	ResultingTotal    float64   `json:"resulting_total_cost"`
+	BudgetTotal       float64   `json:"budget_total"`
+	BudgetRemaining   float64   `json:"budget_remaining"`
+	WouldExceedBudget bool      `json:"would_exceed_budget"`
+	// PricingTiers — как сложилась cost: объём и цена по каждой ступени тарифа
+	PricingTiers []PriceTier `json:"pricing_tiers"`
+}
+
+// PriceTier — ступень тарифа: объём в пределах [from_m3, to_m3) по price_per_m3.
+// to_m3 = null — ступень без верхней границы.
+type PriceTier struct {
+	FromM3     float64  `json:"from_m3"`
+	ToM3       *float64 `json:"to_m3"`
+	PricePerM3 float64  `json:"price_per_m3"`
+	VolumeM3   float64  `json:"volume_m3"`
+	Cost       float64  `json:"cost"`
+}
+
+// PreviewCost считает, во что обойдётся volumeM3 по контракту, ничего не записывая.
+func (s *ContractService) PreviewCost(ctx context.Context, principal model.Principal, contractID uuid.UUID, volumeM3 float64) (*CostPreview, error) {
+	if volumeM3 <= 0 {
+		return nil, ErrInvalidInput
+	}
+
+	contract, err := s.contracts.GetByID(ctx, contractID, true)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureReadAccess(principal, contract); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUsage(ctx, contract); err != nil {
+		return nil, err
+	}
+
+	currentCost := 0.0
+	if contract.Usage != nil {
+		currentCost = contract.Usage.TotalCost
+	}
+
+	cost := calculateCost(contract.PricePerM3, volumeM3)
+	resulting := currentCost + cost
+
+	return &CostPreview{
+		ContractID:        contract.ID,
+		VolumeM3:          volumeM3,
+		PricePerM3:        contract.PricePerM3,
+		Cost:              cost,
+		CurrentTotalCost:  currentCost,
+		ResultingTotal:    resulting,
+		BudgetTotal:       contract.BudgetTotal,
+		BudgetRemaining:   math.Max(contract.BudgetTotal-resulting, 0),
+		WouldExceedBudget: resulting > contract.BudgetTotal,
+		PricingTiers:      pricingTiers(contract.PricePerM3, volumeM3),
+	}, nil
+}
+
+// calculateCost — стоимость объёма по плоской цене; совпадает с расчётом
+// в repository.RecordTripUsage (там — по цене, прочитанной под блокировкой).
+func calculateCost(pricePerM3, volumeM3 float64) float64 {
+	return volumeM3 * pricePerM3
+}
+
+// pricingTiers раскладывает стоимость объёма по ступеням тарифа; сумма cost
+// ступеней равна calculateCost. Цена контракта плоская — ступень одна.
+func pricingTiers(pricePerM3, volumeM3 float64) []PriceTier {
+	return []PriceTier{{
+		FromM3:     0,
+		PricePerM3: pricePerM3,
+		VolumeM3:   volumeM3,
+		Cost:       calculateCost(pricePerM3, volumeM3),
+	}}
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+const (
+	// DefaultContractPageLimit — размер страницы GET /contracts по умолчанию.
+	DefaultContractPageLimit = 50
+	// MaxContractPageLimit — наибольший допустимый limit GET /contracts.
+	MaxContractPageLimit = 200
+)
+
+// contractCursorToken — содержимое курсора; клиенту он отдаётся непрозрачной
+// base64url-строкой.
+type contractCursorToken struct {
+	SortBy  model.ContractSortField `json:"s"`
+	SortDir model.SortDirection     `json:"d"`
+	Value   string                  `json:"v"`
+	ID      uuid.UUID               `json:"id"`
+}
+
+// encodeContractCursor — курсор на строку после contract при заданном порядке.
+func encodeContractCursor(contract model.Contract, sortBy model.ContractSortField, dir model.SortDirection) string {
+	token := contractCursorToken{SortBy: sortBy, SortDir: dir, ID: contract.ID}
+	switch sortBy {
+	case model.ContractSortStartAt:
+		token.Value = contract.StartAt.UTC().Format(time.RFC3339Nano)
+	case model.ContractSortEndAt:
+		token.Value = contract.EndAt.UTC().Format(time.RFC3339Nano)
+	case model.ContractSortName:
+		token.Value = contract.Name
+	case model.ContractSortBudgetTotal:
+		token.Value = strconv.FormatFloat(contract.BudgetTotal, 'f', -1, 64)
+	default:
+		token.Value = contract.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	raw, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeContractCursor разбирает курсор и проверяет, что он выдан для того же
+// порядка списка: курсор другой сортировки указал бы на случайную позицию.
+func decodeContractCursor(cursor string, sortBy model.ContractSortField, dir model.SortDirection) (*repository.ContractCursor, error) {
+	invalid := fmt.Errorf("%w: invalid cursor", ErrInvalidInput)
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+	var token contractCursorToken
+	if err := json.Unmarshal(raw, &token); err != nil || token.ID == uuid.Nil {
+		return nil, invalid
+	}
+	if token.SortBy != sortBy || token.SortDir != dir {
+		return nil, fmt.Errorf("%w: cursor does not match sort order", ErrInvalidInput)
+	}
+
+	result := &repository.ContractCursor{SortBy: sortBy, SortDir: dir, ID: token.ID}
+	switch sortBy {
+	case model.ContractSortName:
+		result.Value = token.Value
+	case model.ContractSortBudgetTotal:
+		value, err := strconv.ParseFloat(token.Value, 64)
+		if err != nil {
+			return nil, invalid
+		}
+		result.Value = value
+	default:
+		value, err := time.Parse(time.RFC3339Nano, token.Value)
+		if err != nil {
+			return nil, invalid
+		}
+		result.Value = value
+	}
+	return result, nil
+}
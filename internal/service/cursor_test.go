@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+func TestContractCursorRoundTripsEachSortColumn(t *testing.T) {
+	contract := model.Contract{
+		ID:          uuid.New(),
+		Name:        "Уборка дорог",
+		BudgetTotal: 1234567.5,
+		StartAt:     time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndAt:       time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC),
+		CreatedAt:   time.Date(2023, 12, 20, 10, 30, 0, 123456000, time.UTC),
+	}
+	want := map[model.ContractSortField]any{
+		model.ContractSortCreatedAt:   contract.CreatedAt,
+		model.ContractSortStartAt:     contract.StartAt,
+		model.ContractSortEndAt:       contract.EndAt,
+		model.ContractSortName:        contract.Name,
+		model.ContractSortBudgetTotal: contract.BudgetTotal,
+	}
+	for _, sortBy := range model.ContractSortFields() {
+		for _, dir := range []model.SortDirection{model.SortAsc, model.SortDesc} {
+			cursor, err := decodeContractCursor(encodeContractCursor(contract, sortBy, dir), sortBy, dir)
+			if err != nil {
+				t.Fatalf("%s %s: decode: %v", sortBy, dir, err)
+			}
+			if cursor.SortBy != sortBy || cursor.SortDir != dir || cursor.ID != contract.ID {
+				t.Fatalf("%s %s: cursor = %+v", sortBy, dir, cursor)
+			}
+			switch value := cursor.Value.(type) {
+			case time.Time:
+				if !value.Equal(want[sortBy].(time.Time)) {
+					t.Fatalf("%s %s: value = %v, want %v", sortBy, dir, value, want[sortBy])
+				}
+			default:
+				if value != want[sortBy] {
+					t.Fatalf("%s %s: value = %v, want %v", sortBy, dir, value, want[sortBy])
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeContractCursorRejectsOtherSortOrder(t *testing.T) {
+	contract := model.Contract{ID: uuid.New(), Name: "Уборка дорог", CreatedAt: time.Now()}
+	cursor := encodeContractCursor(contract, model.ContractSortName, model.SortAsc)
+
+	tests := []struct {
+		name   string
+		sortBy model.ContractSortField
+		dir    model.SortDirection
+	}{
+		{name: "other column", sortBy: model.ContractSortBudgetTotal, dir: model.SortAsc},
+		{name: "other direction", sortBy: model.ContractSortName, dir: model.SortDesc},
+		{name: "default order", sortBy: model.ContractSortCreatedAt, dir: model.SortDesc},
+	}
+	for _, tt := range tests {
+		_, err := decodeContractCursor(cursor, tt.sortBy, tt.dir)
+		if !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("%s: err = %v, want ErrInvalidInput", tt.name, err)
+		}
+	}
+
+	for _, raw := range []string{"not-base64!", "e30"} {
+		if _, err := decodeContractCursor(raw, model.ContractSortName, model.SortAsc); !errors.Is(err, ErrInvalidInput) {
+			t.Fatalf("cursor %q: err = %v, want ErrInvalidInput", raw, err)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+// ExportEntry — один файл выгрузки данных подрядчика.
+type ExportEntry struct {
+	Name string
+	Data interface{}
+}
+
+// EnsureContractorExportAccess — выгрузку получает сам подрядчик или акимат.
+func EnsureContractorExportAccess(principal model.Principal, contractorID uuid.UUID) error {
+	switch {
+	case principal.IsAkimat():
+		return nil
+	case principal.IsContractor() && principal.OrganizationID == contractorID:
+		return nil
+	}
+	return ErrPermissionDenied
+}
+
+// ExportContractorData отдаёт всё, что хранится о подрядчике: контракты с usage,
+// тикеты, рейсы и движения usage по каждому контракту. Контракты читаются
+// порциями StreamList; тикеты, рейсы и журнал порции загружаются тремя
+// запросами на всю порцию, так что в памяти не собирается вся выгрузка.
+func (s *ContractService) ExportContractorData(ctx context.Context, principal model.Principal, contractorID uuid.UUID, fn func(ExportEntry) error) error {
+	if err := EnsureContractorExportAccess(principal, contractorID); err != nil {
+		return err
+	}
+
+	contractType := model.ContractTypeContractorService
+	return s.contracts.StreamList(ctx, repository.ContractFilter{
+		ContractorID: &contractorID,
+		ContractType: &contractType,
+		IncludeUsage: true,
+		Now:          s.now(),
+	}, func(contracts []model.Contract) error {
+		return s.exportContractorChunk(ctx, contracts, fn)
+	})
+}
+
+// exportContractorChunk отдаёт файлы порции контрактов: сначала contract.json
+// каждого, затем тикеты, рейсы и журнал, загруженные пакетно.
+func (s *ContractService) exportContractorChunk(ctx context.Context, contracts []model.Contract, fn func(ExportEntry) error) error {
+	ids := make([]uuid.UUID, 0, len(contracts))
+	for i := range contracts {
+		if err := s.ensureUsage(ctx, &contracts[i]); err != nil {
+			return err
+		}
+		s.decorateContract(&contracts[i])
+		ids = append(ids, contracts[i].ID)
+	}
+	for _, contract := range contracts {
+		if err := fn(ExportEntry{Name: contractExportPath(contract.ID, "contract.json"), Data: contract}); err != nil {
+			return err
+		}
+	}
+
+	tickets, err := s.contracts.ListContractTicketsBatch(ctx, ids)
+	if err != nil {
+		return err
+	}
+	trips, err := s.contracts.ListContractTripsBatch(ctx, ids)
+	if err != nil {
+		return err
+	}
+	ledger, err := s.contracts.ListUsageLedgerBatch(ctx, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if err := fn(ExportEntry{Name: contractExportPath(id, "tickets.json"), Data: tickets[id]}); err != nil {
+			return err
+		}
+		contractTrips := trips[id]
+		for i := range contractTrips {
+			decorateTrip(&contractTrips[i])
+		}
+		if err := fn(ExportEntry{Name: contractExportPath(id, "trips.json"), Data: contractTrips}); err != nil {
+			return err
+		}
+		if err := fn(ExportEntry{Name: contractExportPath(id, "usage_ledger.json"), Data: ledger[id]}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func contractExportPath(contractID uuid.UUID, file string) string {
+	return fmt.Sprintf("contracts/%s/%s", contractID, file)
+}
+
+// emptyIfNil сериализует пустую выборку как [] вместо null.
+func emptyIfNil[T any](items []T) []T {
+	if items == nil {
+		return []T{}
+	}
+	return items
+}
@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+func TestExportContractorDataGroupsRowsByContract(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	kgu := kguPrincipal(t, database)
+
+	first := createContract(t, s, kgu, contractorInput(t, database))
+	secondInput := contractorInput(t, database)
+	secondInput.ContractorID = first.ContractorID
+	second := createContract(t, s, kgu, secondInput)
+	// контракт другого подрядчика в выгрузку не попадает
+	other := createContract(t, s, kgu, contractorInput(t, database))
+
+	trips := map[uuid.UUID]int{first.ID: 2, second.ID: 1, other.ID: 1}
+	for contractID, count := range trips {
+		for i := 0; i < count; i++ {
+			if err := recordTrip(t, s, database, kgu, contractID, 10); err != nil {
+				t.Fatalf("record trip: %v", err)
+			}
+		}
+	}
+
+	principal := model.Principal{UserID: uuid.New(), OrganizationID: *first.ContractorID, Role: model.UserRoleContractorAdmin}
+	entries := map[string]interface{}{}
+	err := s.ExportContractorData(ctx, principal, *first.ContractorID, func(entry ExportEntry) error {
+		if _, ok := entries[entry.Name]; ok {
+			t.Fatalf("entry %s written twice", entry.Name)
+		}
+		entries[entry.Name] = entry.Data
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("export: %v", err)
+	}
+
+	if len(entries) != 8 {
+		t.Fatalf("got %d entries, want 8 (4 files for 2 contracts)", len(entries))
+	}
+	for _, id := range []uuid.UUID{first.ID, second.ID} {
+		contract, ok := entries[contractExportPath(id, "contract.json")].(model.Contract)
+		if !ok || contract.Usage == nil || contract.Usage.TotalVolumeM3 != float64(trips[id]*10) {
+			t.Fatalf("contract %s: contract.json = %+v", id, entries[contractExportPath(id, "contract.json")])
+		}
+		if got := len(entries[contractExportPath(id, "tickets.json")].([]model.ContractTicket)); got != trips[id] {
+			t.Fatalf("contract %s: %d tickets, want %d", id, got, trips[id])
+		}
+		if got := len(entries[contractExportPath(id, "trips.json")].([]model.ContractTrip)); got != trips[id] {
+			t.Fatalf("contract %s: %d trips, want %d", id, got, trips[id])
+		}
+		ledger := entries[contractExportPath(id, "usage_ledger.json")].([]model.UsageLedgerEntry)
+		if len(ledger) != trips[id] {
+			t.Fatalf("contract %s: %d ledger entries, want %d", id, len(ledger), trips[id])
+		}
+		for _, entry := range ledger {
+			if entry.ContractID != id {
+				t.Fatalf("contract %s: ledger entry of contract %s", id, entry.ContractID)
+			}
+		}
+	}
+}
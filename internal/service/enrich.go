@@ -0,0 +1,79 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// enrichContracts дополняет уже декорированные контракты данными, которые
+// выгоднее загружать одним запросом на всю выборку.
+func (s *ContractService) enrichContracts(ctx context.Context, contracts []model.Contract) {
+	s.attachOrganizations(ctx, contracts)
+	s.attachBudgetExhaustion(ctx, contracts)
+}
+
+// attachBudgetExhaustion заполняет budget_exhausted_at и effective_end_at.
+// Журнал читается только для контрактов, у которых usage уже достиг бюджета.
+func (s *ContractService) attachBudgetExhaustion(ctx context.Context, contracts []model.Contract) {
+	var ids []uuid.UUID
+	for i := range contracts {
+		contract := &contracts[i]
+		endAt := contract.EndAt
+		contract.EffectiveEndAt = &endAt
+		contract.BudgetExhaustedAt = nil
+		if contract.Usage != nil && contract.BudgetTotal > 0 && contract.Usage.TotalCost >= contract.BudgetTotal {
+			ids = append(ids, contract.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	exhausted, err := s.contracts.GetBudgetExhaustedAt(ctx, ids)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("failed to load budget exhaustion time")
+		return
+	}
+	for i := range contracts {
+		contract := &contracts[i]
+		at, ok := exhausted[contract.ID]
+		if !ok {
+			continue
+		}
+		contract.BudgetExhaustedAt = &at
+		if at.Before(contract.EndAt) {
+			effective := at
+			contract.EffectiveEndAt = &effective
+		}
+	}
+}
+
+// applyUsageAsOf заменяет текущий usage контрактов восстановленным по журналу
+// на момент asOf. Контракты без include_usage не трогаются.
+func (s *ContractService) applyUsageAsOf(ctx context.Context, contracts []model.Contract, asOf time.Time) error {
+	ids := make([]uuid.UUID, 0, len(contracts))
+	for i := range contracts {
+		if contracts[i].Usage != nil || contracts[i].UsageMissing {
+			ids = append(ids, contracts[i].ID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	usage, err := s.contracts.GetUsageAsOf(ctx, ids, asOf)
+	if err != nil {
+		return err
+	}
+	for i := range contracts {
+		if value, ok := usage[contracts[i].ID]; ok {
+			contracts[i].Usage = value
+			contracts[i].UsageMissing = false
+		}
+	}
+	return nil
+}
@@ -1,11 +1,72 @@
 package service
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
 
 var (
 	ErrNotFound         = errors.New("not found")
 	ErrPermissionDenied = errors.New("permission denied")
 	ErrInvalidInput     = errors.New("invalid input")
 	ErrConflict         = errors.New("conflict")
+	// ErrIdempotencyKeyMismatch — Idempotency-Key повторён с другим телом запроса.
+	ErrIdempotencyKeyMismatch = errors.New("idempotency key already used with a different request body")
+	// ErrBatchAborted — элемент атомарного пакета не применён из-за ошибки в другом элементе.
+	ErrBatchAborted = errors.New("batch aborted")
 )
 
+// FieldError — ошибка проверки одного поля запроса.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError — ошибки полей запроса, собранные все сразу, а не до первой.
+// errors.Is(err, ErrInvalidInput) для неё истинно; текст — "invalid input: "
+// и сообщения полей через "; ".
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		messages = append(messages, field.Message)
+	}
+	return ErrInvalidInput.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidInput
+}
+
+func (e *ValidationError) add(field, format string, args ...any) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// merge добавляет ошибки полей из err; другую ошибку возвращает как есть.
+func (e *ValidationError) merge(err error) error {
+	var validation *ValidationError
+	if !errors.As(err, &validation) {
+		return err
+	}
+	e.Fields = append(e.Fields, validation.Fields...)
+	return nil
+}
+
+// errOrNil — nil, если ошибок полей нет.
+func (e *ValidationError) errOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+	return e
+}
+
+// fieldError — ValidationError с одним полем.
+func fieldError(field, format string, args ...any) error {
+	validation := &ValidationError{}
+	validation.add(field, format, args...)
+	return validation
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+func TestValidateCreateReportsAllFieldErrors(t *testing.T) {
+	s := NewContractService(nil, nil, Config{}, zerolog.Nop())
+	now := time.Now()
+	_, err := s.validateCreate(CreateContractInput{
+		ContractType:    model.ContractTypeContractorService,
+		Name:            "  ",
+		WorkType:        model.WorkTypeRoad,
+		PricePerM3:      0,
+		BudgetTotal:     -1,
+		MinimalVolumeM3: 0,
+		StartAt:         now,
+		EndAt:           now.Add(-time.Hour),
+	})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("err = %v, want ErrInvalidInput", err)
+	}
+	want := []string{"name", "price_per_m3", "budget_total", "minimal_volume_m3", "end_at", "contractor_id"}
+	if got := fieldNames(t, err); !slices.Equal(got, want) {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestValidateContractUpdateReportsAllFieldErrors(t *testing.T) {
+	s := NewContractService(nil, nil, Config{}, zerolog.Nop())
+	now := time.Now()
+	contractorID := uuid.New()
+	updated := model.Contract{
+		ContractType:    model.ContractTypeContractorService,
+		ContractorID:    &contractorID,
+		Name:            "",
+		PricePerM3:      -5,
+		BudgetTotal:     100000,
+		MinimalVolumeM3: 500,
+		StartAt:         now,
+		EndAt:           now,
+	}
+	_, err := s.validateContractUpdate(&updated, []string{"name", "price_per_m3", "end_at"})
+	want := []string{"name", "price_per_m3", "end_at"}
+	if got := fieldNames(t, err); !slices.Equal(got, want) {
+		t.Fatalf("fields = %v, want %v", got, want)
+	}
+}
+
+func TestValidationErrorWrapsInvalidInput(t *testing.T) {
+	validation := &ValidationError{}
+	validation.add("name", "name is required")
+	validation.add("end_at", "end_at must be after start_at")
+	err := validation.errOrNil()
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("errors.Is(err, ErrInvalidInput) = false for %v", err)
+	}
+	if want := "invalid input: name is required; end_at must be after start_at"; err.Error() != want {
+		t.Fatalf("Error() = %q, want %q", err.Error(), want)
+	}
+	if (&ValidationError{}).errOrNil() != nil {
+		t.Fatalf("empty ValidationError is not nil")
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/notifier"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+type TicketContractAssignedEvent struct {
+	TicketID    uuid.UUID `json:"ticket_id"`
+	ContractID  uuid.UUID `json:"contract_id"`
+	ActorUserID uuid.UUID `json:"actor_user_id"`
+	ActorOrgID  uuid.UUID `json:"actor_org_id"`
+}
+
+// notifyTicketContractAssigned сообщает о фактической смене привязки тикета.
+// Изменение уже зафиксировано, поэтому ошибка доставки только логируется.
+func (s *ContractService) notifyTicketContractAssigned(ctx context.Context, principal model.Principal, ticketID, contractID uuid.UUID) {
+	err := s.notifier.Notify(ctx, notifier.Event{
+		ID:         uuid.New(),
+		Type:       notifier.EventTicketContractAssigned,
+		OccurredAt: s.now(),
+		Data: TicketContractAssignedEvent{
+			TicketID:    ticketID,
+			ContractID:  contractID,
+			ActorUserID: principal.UserID,
+			ActorOrgID:  principal.OrganizationID,
+		},
+	})
+	if err != nil {
+		s.log.Error().Err(err).
+			Str("ticket_id", ticketID.String()).
+			Str("contract_id", contractID.String()).
+			Msg("ticket contract assignment notification failed")
+	}
+}
+
+// ContractEvent — данные событий contract.created, contract.updated и
+// contract.archived: контракт и организации, которых он касается.
+type ContractEvent struct {
+	ContractID     uuid.UUID  `json:"contract_id"`
+	CreatedByOrgID uuid.UUID  `json:"created_by_org_id"`
+	ContractorID   *uuid.UUID `json:"contractor_id,omitempty"`
+	LandfillID     *uuid.UUID `json:"landfill_id,omitempty"`
+	// ActorUserID/ActorOrgID — нет у изменений фоновых задач (автоматическая деактивация)
+	ActorUserID *uuid.UUID `json:"actor_user_id,omitempty"`
+	ActorOrgID  *uuid.UUID `json:"actor_org_id,omitempty"`
+	// ChangedFields — изменённые поля (contract.updated)
+	ChangedFields []string `json:"changed_fields,omitempty"`
+}
+
+// ContractBudgetExceededEvent — данные contract.budget_exceeded.
+type ContractBudgetExceededEvent struct {
+	ContractEvent
+	BudgetTotal float64 `json:"budget_total"`
+	TotalCost   float64 `json:"total_cost"`
+}
+
+func newContractEvent(contract *model.Contract, principal *model.Principal) ContractEvent {
+	event := ContractEvent{
+		ContractID:     contract.ID,
+		CreatedByOrgID: contract.CreatedByOrgID,
+		ContractorID:   contract.ContractorID,
+		LandfillID:     contract.LandfillID,
+	}
+	if principal != nil {
+		event.ActorUserID = &principal.UserID
+		event.ActorOrgID = &principal.OrganizationID
+	}
+	return event
+}
+
+// notifyContract отправляет событие контракта. Изменение уже зафиксировано,
+// поэтому ошибка доставки только логируется.
+func (s *ContractService) notifyContract(ctx context.Context, eventType string, contractID uuid.UUID, data interface{}) {
+	err := s.notifier.Notify(ctx, notifier.Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		OccurredAt: s.now(),
+		Data:       data,
+	})
+	if err != nil {
+		s.log.Error().Err(err).
+			Str("event_type", eventType).
+			Str("contract_id", contractID.String()).
+			Msg("contract event notification failed")
+	}
+}
+
+// notifyDeactivated отправляет contract.archived по каждому выключенному контракту.
+func (s *ContractService) notifyDeactivated(ctx context.Context, deactivated []repository.DeactivatedContract, principal *model.Principal) {
+	for _, item := range deactivated {
+		event := newContractEvent(&model.Contract{
+			ID:             item.ID,
+			CreatedByOrgID: item.CreatedByOrgID,
+			ContractorID:   item.ContractorID,
+			LandfillID:     item.LandfillID,
+		}, principal)
+		s.notifyContract(ctx, notifier.EventContractArchived, item.ID, event)
+	}
+}
+
+// notifyIfBudgetExceeded отправляет contract.budget_exceeded, если запись
+// usage вывела total_cost за budget_total. Решение принимается по итогам,
+// прочитанным под блокировкой контракта в той же транзакции, поэтому из
+// параллельных записей событие отправит только та, что пересекла бюджет.
+func (s *ContractService) notifyIfBudgetExceeded(ctx context.Context, principal model.Principal, contract *model.Contract, change repository.UsageChange) {
+	if !change.ExceededBudget() {
+		return
+	}
+	s.notifyContract(ctx, notifier.EventContractBudgetExceeded, contract.ID, ContractBudgetExceededEvent{
+		ContractEvent: newContractEvent(contract, &principal),
+		BudgetTotal:   change.BudgetTotal,
+		TotalCost:     change.TotalCostAfter,
+	})
+}
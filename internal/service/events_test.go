@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+
+	"github.com/nurpe/snowops-contract/internal/dbtest"
+	"github.com/nurpe/snowops-contract/internal/notifier"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+// failingNotifier не доставляет ни одного события.
+type failingNotifier struct{}
+
+func (failingNotifier) Notify(context.Context, notifier.Event) error {
+	return errors.New("broker is unavailable")
+}
+
+func TestContractLifecycleEvents(t *testing.T) {
+	ctx := context.Background()
+	s, database, events := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	input := contractorInput(t, database)
+	contract := createContract(t, s, principal, input)
+
+	created := events.ofType(notifier.EventContractCreated)
+	if len(created) != 1 {
+		t.Fatalf("contract.created events = %d, want 1", len(created))
+	}
+	data, ok := created[0].Data.(ContractEvent)
+	if !ok {
+		t.Fatalf("contract.created data = %T, want ContractEvent", created[0].Data)
+	}
+	if data.ContractID != contract.ID || data.CreatedByOrgID != principal.OrganizationID ||
+		data.ContractorID == nil || *data.ContractorID != *input.ContractorID ||
+		data.ActorUserID == nil || *data.ActorUserID != principal.UserID {
+		t.Fatalf("contract.created data = %+v", data)
+	}
+	if created[0].OccurredAt.IsZero() {
+		t.Fatalf("contract.created has no occurred_at")
+	}
+
+	name := "Уборка дворов"
+	if _, err := s.Update(ctx, principal, contract.ID, UpdateContractInput{Name: &name, Reason: "уточнение"}); err != nil {
+		t.Fatalf("update name: %v", err)
+	}
+	updated := events.ofType(notifier.EventContractUpdated)
+	if len(updated) != 1 || !slices.Equal(updated[0].Data.(ContractEvent).ChangedFields, []string{"name"}) {
+		t.Fatalf("contract.updated events = %+v, want one with changed [name]", updated)
+	}
+	if n := len(events.ofType(notifier.EventContractArchived)); n != 0 {
+		t.Fatalf("contract.archived after rename = %d, want 0", n)
+	}
+
+	inactive := false
+	if _, err := s.Update(ctx, principal, contract.ID, UpdateContractInput{IsActive: &inactive, Reason: "расторжение"}); err != nil {
+		t.Fatalf("deactivate: %v", err)
+	}
+	if n := len(events.ofType(notifier.EventContractUpdated)); n != 2 {
+		t.Fatalf("contract.updated events = %d, want 2", n)
+	}
+	archived := events.ofType(notifier.EventContractArchived)
+	if len(archived) != 1 || archived[0].Data.(ContractEvent).ContractID != contract.ID {
+		t.Fatalf("contract.archived events = %+v, want one for %s", archived, contract.ID)
+	}
+
+	// повтор того же изменения ничего не пишет и событий не шлёт
+	if _, err := s.Update(ctx, principal, contract.ID, UpdateContractInput{IsActive: &inactive, Reason: "расторжение"}); err != nil {
+		t.Fatalf("repeated deactivate: %v", err)
+	}
+	if n := len(events.ofType(notifier.EventContractUpdated)); n != 2 {
+		t.Fatalf("contract.updated after no-op update = %d, want 2", n)
+	}
+}
+
+func TestBudgetExceededEventFiresOnlyWhenCrossing(t *testing.T) {
+	s, database, events := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+
+	// ровно budget_total — ещё не превышение
+	if err := recordTrip(t, s, database, principal, contract.ID, 1000); err != nil {
+		t.Fatalf("record trip: %v", err)
+	}
+	if n := len(events.ofType(notifier.EventContractBudgetExceeded)); n != 0 {
+		t.Fatalf("budget_exceeded at exactly budget_total = %d, want 0", n)
+	}
+
+	if err := recordTrip(t, s, database, principal, contract.ID, 1); err != nil {
+		t.Fatalf("record trip past budget: %v", err)
+	}
+	exceeded := events.ofType(notifier.EventContractBudgetExceeded)
+	if len(exceeded) != 1 {
+		t.Fatalf("budget_exceeded events = %d, want 1", len(exceeded))
+	}
+	data, ok := exceeded[0].Data.(ContractBudgetExceededEvent)
+	if !ok {
+		t.Fatalf("budget_exceeded data = %T, want ContractBudgetExceededEvent", exceeded[0].Data)
+	}
+	if data.ContractID != contract.ID || data.BudgetTotal != 100000 || data.TotalCost != 100100 {
+		t.Fatalf("budget_exceeded data = %+v, want 100100 over 100000", data)
+	}
+
+	// контракт уже за бюджетом — следующие рейсы событие не повторяют
+	if err := recordTrip(t, s, database, principal, contract.ID, 1); err != nil {
+		t.Fatalf("record another trip: %v", err)
+	}
+	if n := len(events.ofType(notifier.EventContractBudgetExceeded)); n != 1 {
+		t.Fatalf("budget_exceeded events after another trip = %d, want 1", n)
+	}
+}
+
+func TestBudgetExceededEventFromConcurrentTripsFiresOnce(t *testing.T) {
+	s, database, events := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+
+	// каждый рейс по 600 м3 укладывается в бюджет, оба вместе — нет
+	inputs := make([]RecordTripUsageInput, 2)
+	for i := range inputs {
+		ticketID := dbtest.Ticket(t, database, contract.ID)
+		inputs[i] = RecordTripUsageInput{TripID: dbtest.Trip(t, database, ticketID, uuid.Nil), TicketID: ticketID, VolumeM3: 600}
+	}
+	var wg sync.WaitGroup
+	errs := make([]error, len(inputs))
+	for i := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.RecordTripUsage(context.Background(), principal, inputs[i])
+		}()
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			t.Fatalf("record trip: %v", err)
+		}
+	}
+	if n := len(events.ofType(notifier.EventContractBudgetExceeded)); n != 1 {
+		t.Fatalf("budget_exceeded events = %d, want 1", n)
+	}
+}
+
+func TestBudgetExceededEventFromBatchAndAdjustment(t *testing.T) {
+	ctx := context.Background()
+	s, database, events := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+
+	batchContract := createContract(t, s, principal, contractorInput(t, database))
+	inputs := make([]RecordTripUsageInput, 2)
+	for i := range inputs {
+		ticketID := dbtest.Ticket(t, database, batchContract.ID)
+		inputs[i] = RecordTripUsageInput{TripID: dbtest.Trip(t, database, ticketID, uuid.Nil), TicketID: ticketID, VolumeM3: 600}
+	}
+	results, err := s.RecordTripUsageBatch(ctx, principal, inputs, true)
+	if err != nil {
+		t.Fatalf("record batch: %v", err)
+	}
+	for i, err := range results {
+		if err != nil {
+			t.Fatalf("batch item %d: %v", i, err)
+		}
+	}
+	exceeded := events.ofType(notifier.EventContractBudgetExceeded)
+	if len(exceeded) != 1 || exceeded[0].Data.(ContractBudgetExceededEvent).TotalCost != 120000 {
+		t.Fatalf("budget_exceeded after batch = %+v, want one at 120000", exceeded)
+	}
+
+	adjusted := createContract(t, s, principal, contractorInput(t, database))
+	if _, err := s.RecordUsageAdjustment(ctx, principal, adjusted.ID, RecordUsageAdjustmentInput{
+		VolumeDeltaM3: 1000,
+		CostDelta:     100000.01,
+		Reason:        "досчёт по акту",
+	}); err != nil {
+		t.Fatalf("record adjustment: %v", err)
+	}
+	exceeded = events.ofType(notifier.EventContractBudgetExceeded)
+	if len(exceeded) != 2 || exceeded[1].Data.(ContractBudgetExceededEvent).ContractID != adjusted.ID {
+		t.Fatalf("budget_exceeded after adjustment = %+v, want second for %s", exceeded, adjusted.ID)
+	}
+}
+
+func TestFailedNotificationDoesNotFailOperation(t *testing.T) {
+	database := dbtest.Open(t)
+	s := NewContractService(repository.NewContractRepository(database), failingNotifier{}, Config{}, zerolog.Nop())
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+	if err := recordTrip(t, s, database, principal, contract.ID, 1001); err != nil {
+		t.Fatalf("record trip past budget: %v", err)
+	}
+}
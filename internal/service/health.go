@@ -0,0 +1,58 @@
+package service
+
+import (
+	"math"
+	"time"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// Веса составляющих health_score; в сумме 1.
+const (
+	healthWeightMinimum = 0.5
+	healthWeightBudget  = 0.3
+	healthWeightPacing  = 0.2
+)
+
+// computeHealth считает health_score контракта (0–100) на момент now:
+//
+//   - time_elapsed = (now - start_at) / (end_at - start_at), ограничено [0, 1];
+//     с календарём — по рабочему времени, без выходных и праздников;
+//   - minimum_progress_score = 100 * min(1, прогноз объёма / minimal_volume_m3),
+//     прогноз = объём / time_elapsed (до старта — 100);
+//   - budget_score = 100 * (1 - min(1, 2 * перерасход / budget_total)),
+//     т.е. перерасход на 50% бюджета обнуляет составляющую;
+//   - pacing_score = 100 * (1 - min(1, max(0, доля потраченного бюджета - time_elapsed))),
+//     штраф за опережение расходов относительно прошедшего срока;
+//   - health_score = round(0.5 * minimum + 0.3 * budget + 0.2 * pacing).
+func computeHealth(contract *model.Contract, volumeM3, cost float64, now time.Time, calendar *BusinessCalendar) (int, model.ContractHealth) {
+	var health model.ContractHealth
+
+	health.TimeElapsed = calendar.elapsedFraction(contract.StartAt, contract.EndAt, now)
+
+	health.MinimumProgress = 100
+	if health.TimeElapsed > 0 && contract.MinimalVolumeM3 > 0 {
+		projected := volumeM3 / health.TimeElapsed
+		health.MinimumProgress = 100 * math.Min(1, projected/contract.MinimalVolumeM3)
+	}
+
+	health.Budget = 100
+	health.Pacing = 100
+	if contract.BudgetTotal > 0 {
+		overrun := math.Max(0, cost-contract.BudgetTotal) / contract.BudgetTotal
+		health.Budget = 100 * (1 - math.Min(1, 2*overrun))
+
+		spent := cost / contract.BudgetTotal
+		health.Pacing = 100 * (1 - clamp(spent-health.TimeElapsed, 0, 1))
+	}
+
+	score := healthWeightMinimum*health.MinimumProgress +
+		healthWeightBudget*health.Budget +
+		healthWeightPacing*health.Pacing
+
+	return int(math.Round(score)), health
+}
+
+func clamp(value, low, high float64) float64 {
+	return math.Max(low, math.Min(high, value))
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+func TestComputeHealth(t *testing.T) {
+	day := func(month time.Month, d int) time.Time { return time.Date(2024, month, d, 0, 0, 0, 0, time.UTC) }
+	// 10 дней, минимум 1000 м3, бюджет 100 000
+	start, end := day(time.January, 1), day(time.January, 11)
+	middle := day(time.January, 6)
+	after := day(time.February, 1)
+
+	// рабочие дни пн–пт: с пн 1 по пн 15 января — 10 рабочих дней, к субботе 6-го прошло 5
+	weekdays, err := NewBusinessCalendar([]string{"sat", "sun"}, nil, "")
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+	withHoliday, err := NewBusinessCalendar([]string{"sat", "sun"}, []string{"2024-01-03"}, "")
+	if err != nil {
+		t.Fatalf("calendar: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		end          time.Time // пусто — 11 января
+		now          time.Time
+		volume, cost float64
+		calendar     *BusinessCalendar
+		wantScore    int
+		want         model.ContractHealth
+	}{
+		{name: "before start", now: time.Date(2023, time.December, 25, 0, 0, 0, 0, time.UTC),
+			wantScore: 100, want: model.ContractHealth{TimeElapsed: 0, MinimumProgress: 100, Budget: 100, Pacing: 100}},
+		{name: "after end below minimum", now: after, volume: 800, cost: 80000,
+			wantScore: 90, want: model.ContractHealth{TimeElapsed: 1, MinimumProgress: 80, Budget: 100, Pacing: 100}},
+		{name: "on pace", now: middle, volume: 500, cost: 50000,
+			wantScore: 100, want: model.ContractHealth{TimeElapsed: 0.5, MinimumProgress: 100, Budget: 100, Pacing: 100}},
+		{name: "ahead of pace", now: middle, volume: 600, cost: 60000,
+			wantScore: 98, want: model.ContractHealth{TimeElapsed: 0.5, MinimumProgress: 100, Budget: 100, Pacing: 90}},
+		{name: "behind pace", now: middle, volume: 250, cost: 25000,
+			wantScore: 75, want: model.ContractHealth{TimeElapsed: 0.5, MinimumProgress: 50, Budget: 100, Pacing: 100}},
+		{name: "budget overrun", now: after, volume: 1200, cost: 120000,
+			wantScore: 84, want: model.ContractHealth{TimeElapsed: 1, MinimumProgress: 100, Budget: 60, Pacing: 80}},
+
+		// границы составляющих
+		{name: "budget spent exactly", now: after, volume: 1000, cost: 100000,
+			wantScore: 100, want: model.ContractHealth{TimeElapsed: 1, MinimumProgress: 100, Budget: 100, Pacing: 100}},
+		{name: "overrun of half the budget zeroes budget score", now: after, volume: 1500, cost: 150000,
+			wantScore: 60, want: model.ContractHealth{TimeElapsed: 1, MinimumProgress: 100, Budget: 0, Pacing: 50}},
+		{name: "overrun beyond half stays at zero", now: after, volume: 3000, cost: 300000,
+			wantScore: 50, want: model.ContractHealth{TimeElapsed: 1, MinimumProgress: 100, Budget: 0, Pacing: 0}},
+		{name: "whole budget spent at start zeroes pacing", now: start, volume: 1000, cost: 100000,
+			wantScore: 80, want: model.ContractHealth{TimeElapsed: 0, MinimumProgress: 100, Budget: 100, Pacing: 0}},
+
+		// рабочий календарь: к субботе прошла половина рабочего времени, а не 5/14 срока
+		{name: "business calendar on pace", end: day(time.January, 15), now: middle, volume: 500, cost: 50000, calendar: weekdays,
+			wantScore: 100, want: model.ContractHealth{TimeElapsed: 0.5, MinimumProgress: 100, Budget: 100, Pacing: 100}},
+		{name: "same contract without calendar", end: day(time.January, 15), now: middle, volume: 500, cost: 50000,
+			wantScore: 97, want: model.ContractHealth{TimeElapsed: 5.0 / 14, MinimumProgress: 100, Budget: 100, Pacing: 100 * (1 - (0.5 - 5.0/14))}},
+		{name: "business calendar with holiday", end: day(time.January, 15), now: middle, volume: 500, cost: 50000, calendar: withHoliday,
+			wantScore: 99, want: model.ContractHealth{TimeElapsed: 4.0 / 9, MinimumProgress: 100, Budget: 100, Pacing: 100 * (1 - (0.5 - 4.0/9))}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contract := model.Contract{StartAt: start, EndAt: end, MinimalVolumeM3: 1000, BudgetTotal: 100000, PricePerM3: 100}
+			if !tt.end.IsZero() {
+				contract.EndAt = tt.end
+			}
+			score, health := computeHealth(&contract, tt.volume, tt.cost, tt.now, tt.calendar)
+			if score != tt.wantScore {
+				t.Fatalf("score = %d, want %d (%+v)", score, tt.wantScore, health)
+			}
+			for _, c := range []struct {
+				name      string
+				got, want float64
+			}{
+				{"time_elapsed", health.TimeElapsed, tt.want.TimeElapsed},
+				{"minimum_progress_score", health.MinimumProgress, tt.want.MinimumProgress},
+				{"budget_score", health.Budget, tt.want.Budget},
+				{"pacing_score", health.Pacing, tt.want.Pacing},
+			} {
+				if math.Abs(c.got-c.want) > 1e-9 {
+					t.Fatalf("%s = %v, want %v", c.name, c.got, c.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// ListPreset — фильтры и сортировка списка контрактов по умолчанию для роли.
+// Применяется, только если клиент не передал ни одного фильтра или сортировки.
+type ListPreset struct {
+	OnlyActive   *bool                     `json:"only_active"`
+	Status       *model.ContractUIStatus   `json:"status"`
+	ContractType *model.ContractType       `json:"contract_type"`
+	Perspective  model.ContractPerspective `json:"perspective"`
+	SortBy       model.ContractSortField   `json:"sort_by"`
+	SortDir      model.SortDirection       `json:"sort_dir"`
+}
+
+type ListPresets map[model.UserRole]ListPreset
+
+// LoadListPresets читает пресеты из JSON-файла вида {"ROLE": {...}} и проверяет значения.
+func LoadListPresets(path string) (ListPresets, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var presets ListPresets
+	if err := json.Unmarshal(raw, &presets); err != nil {
+		return nil, fmt.Errorf("parse list presets: %w", err)
+	}
+	for role, preset := range presets {
+		if err := validateListPreset(role, preset); err != nil {
+			return nil, err
+		}
+	}
+	return presets, nil
+}
+
+func validateListPreset(role model.UserRole, preset ListPreset) error {
+	if !containsValue(model.UserRoles(), role) {
+		return fmt.Errorf("list presets: unknown role %q", role)
+	}
+	if preset.Status != nil && !containsValue(model.ContractUIStatuses(), *preset.Status) {
+		return fmt.Errorf("list presets: %s: invalid status %q", role, *preset.Status)
+	}
+	if preset.ContractType != nil && !containsValue(model.ContractTypes(), *preset.ContractType) {
+		return fmt.Errorf("list presets: %s: invalid contract_type %q", role, *preset.ContractType)
+	}
+	if preset.Perspective != "" && !containsValue(model.ContractPerspectives(), preset.Perspective) {
+		return fmt.Errorf("list presets: %s: invalid perspective %q", role, preset.Perspective)
+	}
+	if preset.SortBy != "" && !containsValue(model.ContractSortFields(), preset.SortBy) {
+		return fmt.Errorf("list presets: %s: invalid sort_by %q", role, preset.SortBy)
+	}
+	if preset.SortDir != "" && preset.SortDir != model.SortAsc && preset.SortDir != model.SortDesc {
+		return fmt.Errorf("list presets: %s: invalid sort_dir %q", role, preset.SortDir)
+	}
+	return nil
+}
+
+func containsValue[T comparable](values []T, value T) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// withListPreset подставляет пресет роли в запрос без явных фильтров и сортировки.
+func (s *ContractService) withListPreset(principal model.Principal, input ListContractsInput) ListContractsInput {
+	if !input.UsePreset {
+		return input
+	}
+	preset, ok := s.cfg.ListPresets[principal.Role]
+	if !ok {
+		return input
+	}
+
+	if preset.OnlyActive != nil {
+		input.OnlyActive = *preset.OnlyActive
+	}
+	input.Status = preset.Status
+	input.ContractType = preset.ContractType
+	input.Perspective = preset.Perspective
+	input.SortBy = preset.SortBy
+	input.SortDir = preset.SortDir
+	return input
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+// ErrContractLocked — изменение заблокированного контракта (409).
+var ErrContractLocked = fmt.Errorf("%w: contract is locked", ErrConflict)
+
+// ensureNotLocked запрещает изменения условий, usage и привязку тикетов к
+// заблокированному контракту. Чтение не ограничивается.
+func ensureNotLocked(contract *model.Contract) error {
+	if contract.IsLocked {
+		return ErrContractLocked
+	}
+	return nil
+}
+
+// SetContractLock блокирует или разблокирует контракт (КГУ-создатель или акимат).
+func (s *ContractService) SetContractLock(ctx context.Context, principal model.Principal, contractID uuid.UUID, locked bool) (*model.Contract, error) {
+	contract, err := s.contracts.GetByID(ctx, contractID, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !principal.IsAkimat() {
+		if err := ensureWriteAccess(principal, contract); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.contracts.SetLock(ctx, contractID, locked, principal.UserID, principal.OrganizationID)
+	if errors.Is(err, repository.ErrContractLocked) {
+		return nil, ErrContractLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, principal, contractID)
+}
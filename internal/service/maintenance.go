@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// ReadOnly сообщает, включён ли режим обслуживания: чтение работает,
+// изменяющие запросы и фоновые задачи, меняющие данные, отклоняются. Флаг
+// хранится в БД и общий для всех реплик; если прочитать его не удалось,
+// используется последнее прочитанное значение.
+func (s *ContractService) ReadOnly(ctx context.Context) bool {
+	enabled, err := s.contracts.GetReadOnlyMode(ctx)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("failed to read maintenance mode, using last known state")
+		return s.readOnly.Load()
+	}
+	s.readOnly.Store(enabled)
+	return enabled
+}
+
+// SetReadOnly переключает режим обслуживания без перезапуска (только акимат).
+func (s *ContractService) SetReadOnly(ctx context.Context, principal model.Principal, enabled bool) error {
+	if !principal.IsAkimat() {
+		return ErrPermissionDenied
+	}
+	changed, err := s.contracts.SetReadOnlyMode(ctx, enabled, principal.UserID)
+	if err != nil {
+		return err
+	}
+	s.readOnly.Store(enabled)
+	if changed {
+		s.log.Warn().
+			Bool("read_only", enabled).
+			Str("user_id", principal.UserID.String()).
+			Msg("read-only mode switched")
+	}
+	return nil
+}
+
+// EnableReadOnly включает режим обслуживания при старте с READ_ONLY_MODE=true.
+// Выключается он только через API: рестарт без флага режим не снимает.
+func (s *ContractService) EnableReadOnly(ctx context.Context) error {
+	changed, err := s.contracts.SetReadOnlyMode(ctx, true, uuid.Nil)
+	if err != nil {
+		return err
+	}
+	s.readOnly.Store(true)
+	if changed {
+		s.log.Warn().Bool("read_only", true).Msg("read-only mode enabled on startup")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nurpe/snowops-contract/internal/metrics"
+)
+
+// observeOperation учитывает вызов метода сервиса в contract_operations_total
+// и contract_operation_duration_seconds. Вызывается через defer с указателем
+// на именованную ошибку метода: defer observeOperation("create", time.Now(), &err).
+func observeOperation(operation string, start time.Time, err *error) {
+	metrics.OperationDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	metrics.Operations.WithLabelValues(operation, operationResult(*err)).Inc()
+}
+
+// operationResult — метка result: success или класс ошибки сервиса.
+func operationResult(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrInvalidInput):
+		return "invalid_input"
+	case errors.Is(err, ErrPermissionDenied):
+		return "permission_denied"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrConflict):
+		return "conflict"
+	default:
+		return "error"
+	}
+}
+
+// CountBudgetExceeded — число неудалённых контрактов с total_cost выше
+// budget_total; источник contract_budget_exceeded_total.
+func (s *ContractService) CountBudgetExceeded(ctx context.Context) (int64, error) {
+	return s.contracts.CountBudgetExceeded(ctx)
+}
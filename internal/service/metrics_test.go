@@ -0,0 +1,76 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/nurpe/snowops-contract/internal/metrics"
+)
+
+// gatheredValue — значение метрики name с метками labels из registry (0 — нет такой).
+func gatheredValue(t *testing.T, reg *prometheus.Registry, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatalf("gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if want, ok := labels[label.GetName()]; ok && want != label.GetValue() {
+					continue metrics
+				}
+			}
+			switch {
+			case metric.GetCounter() != nil:
+				return metric.GetCounter().GetValue()
+			case metric.GetGauge() != nil:
+				return metric.GetGauge().GetValue()
+			case metric.GetHistogram() != nil:
+				return float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+	return 0
+}
+
+func TestMetricsAfterCreateAndRecordTripUsage(t *testing.T) {
+	s, database, _ := newTestService(t, Config{})
+	reg := prometheus.NewRegistry()
+	metrics.Register(reg)
+	reg.MustRegister(metrics.NewBudgetExceededCollector(s.CountBudgetExceeded))
+
+	create := map[string]string{"operation": "create", "result": "success"}
+	record := map[string]string{"operation": "record_trip_usage", "result": "success"}
+	createdBefore := gatheredValue(t, reg, "contract_operations_total", create)
+	recordedBefore := gatheredValue(t, reg, "contract_operations_total", record)
+	createDurations := gatheredValue(t, reg, "contract_operation_duration_seconds", map[string]string{"operation": "create"})
+
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+	if got := gatheredValue(t, reg, "contract_budget_exceeded_total", nil); got != 0 {
+		t.Fatalf("contract_budget_exceeded_total after create = %v, want 0", got)
+	}
+	// 1001 м3 по 100 — на 100 больше бюджета 100 000
+	if err := recordTrip(t, s, database, principal, contract.ID, 1001); err != nil {
+		t.Fatalf("record trip: %v", err)
+	}
+
+	if got := gatheredValue(t, reg, "contract_operations_total", create) - createdBefore; got != 1 {
+		t.Fatalf("create successes = %v, want 1", got)
+	}
+	if got := gatheredValue(t, reg, "contract_operations_total", record) - recordedBefore; got != 1 {
+		t.Fatalf("record_trip_usage successes = %v, want 1", got)
+	}
+	if got := gatheredValue(t, reg, "contract_operation_duration_seconds", map[string]string{"operation": "create"}) - createDurations; got != 1 {
+		t.Fatalf("create duration samples = %v, want 1", got)
+	}
+	if got := gatheredValue(t, reg, "contract_budget_exceeded_total", nil); got != 1 {
+		t.Fatalf("contract_budget_exceeded_total = %v, want 1", got)
+	}
+}
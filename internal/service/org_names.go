@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+type orgNameEntry struct {
+	name      string
+	expiresAt time.Time
+}
+
+// orgNameCache — TTL-кэш id→название организации для заполнения contractor,
+// landfill и created_by_org. Безопасен для конкурентного использования.
+type orgNameCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	maxSize int
+	entries map[uuid.UUID]orgNameEntry
+}
+
+func newOrgNameCache(ttl time.Duration, maxSize int) *orgNameCache {
+	return &orgNameCache{
+		ttl:     ttl,
+		maxSize: maxSize,
+		entries: make(map[uuid.UUID]orgNameEntry),
+	}
+}
+
+// lookup возвращает найденные имена и id, которых нет в кэше или срок которых истёк.
+func (c *orgNameCache) lookup(ids []uuid.UUID, now time.Time) (map[uuid.UUID]string, []uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	names := make(map[uuid.UUID]string, len(ids))
+	var missing []uuid.UUID
+	for _, id := range ids {
+		entry, ok := c.entries[id]
+		if !ok || !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+			missing = append(missing, id)
+			continue
+		}
+		names[id] = entry.name
+	}
+	return names, missing
+}
+
+func (c *orgNameCache) store(names map[uuid.UUID]string, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for id, name := range names {
+		if _, ok := c.entries[id]; !ok && len(c.entries) >= c.maxSize {
+			c.evict(now)
+			if len(c.entries) >= c.maxSize {
+				return
+			}
+		}
+		c.entries[id] = orgNameEntry{name: name, expiresAt: now.Add(c.ttl)}
+	}
+}
+
+// evict удаляет истёкшие записи, а если их нет — запись с ближайшим истечением.
+func (c *orgNameCache) evict(now time.Time) {
+	var oldestID uuid.UUID
+	var oldest time.Time
+	for id, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(c.entries, id)
+			continue
+		}
+		if oldest.IsZero() || entry.expiresAt.Before(oldest) {
+			oldestID, oldest = id, entry.expiresAt
+		}
+	}
+	if len(c.entries) >= c.maxSize && !oldest.IsZero() {
+		delete(c.entries, oldestID)
+	}
+}
+
+// attachOrganizations заполняет contractor/landfill/created_by_org названиями
+// организаций. Названия справочные: ошибка загрузки только логируется.
+func (s *ContractService) attachOrganizations(ctx context.Context, contracts []model.Contract) {
+	seen := make(map[uuid.UUID]struct{})
+	var ids []uuid.UUID
+	add := func(id *uuid.UUID) {
+		if id == nil {
+			return
+		}
+		if _, ok := seen[*id]; !ok {
+			seen[*id] = struct{}{}
+			ids = append(ids, *id)
+		}
+	}
+	for i := range contracts {
+		add(contracts[i].ContractorID)
+		add(contracts[i].LandfillID)
+		add(&contracts[i].CreatedByOrgID)
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	names, err := s.organizationNames(ctx, ids)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("failed to load organization names")
+		return
+	}
+
+	lookup := func(id *uuid.UUID) *model.OrganizationLookup {
+		if id == nil {
+			return nil
+		}
+		name, ok := names[*id]
+		if !ok {
+			return nil
+		}
+		return &model.OrganizationLookup{ID: *id, Name: name}
+	}
+	for i := range contracts {
+		contracts[i].ContractorOrg = lookup(contracts[i].ContractorID)
+		contracts[i].LandfillOrg = lookup(contracts[i].LandfillID)
+		contracts[i].CreatedByOrg = lookup(&contracts[i].CreatedByOrgID)
+	}
+}
+
+func (s *ContractService) organizationNames(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]string, error) {
+	if s.orgNames == nil {
+		return s.contracts.GetOrganizationNames(ctx, ids)
+	}
+
+	now := s.now()
+	names, missing := s.orgNames.lookup(ids, now)
+	if len(missing) == 0 {
+		return names, nil
+	}
+	loaded, err := s.contracts.GetOrganizationNames(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	s.orgNames.store(loaded, now)
+	for id, name := range loaded {
+		names[id] = name
+	}
+	return names, nil
+}
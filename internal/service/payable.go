@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// Коды строк расшифровки суммы к оплате.
+const (
+	PayableItemTotalCost         = "total_cost"
+	PayableItemOverageNotPayable = "overage_not_payable"
+)
+
+// PayableLineItem — слагаемое суммы к оплате; вычитаемые строки отрицательны.
+type PayableLineItem struct {
+	Code   string  `json:"code"`
+	Amount float64 `json:"amount"`
+}
+
+type PayableBreakdown struct {
+	ContractID    uuid.UUID         `json:"contract_id"`
+	BudgetCap     float64           `json:"budget_cap"`
+	Items         []PayableLineItem `json:"items"`
+	PayableAmount float64           `json:"payable_amount"`
+	// PricingTiers — стоимость накопленного объёма по ступеням текущего тарифа;
+	// от total_cost отличается на ручные корректировки и рейсы по прежней цене
+	PricingTiers []PriceTier `json:"pricing_tiers,omitempty"`
+}
+
+// GetPayableBreakdown расшифровывает payable_amount контракта по строкам.
+func (s *ContractService) GetPayableBreakdown(ctx context.Context, principal model.Principal, id uuid.UUID) (*PayableBreakdown, error) {
+	contract, err := s.contracts.GetByID(ctx, id, true)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureReadAccess(principal, contract); err != nil {
+		return nil, err
+	}
+	if err := s.ensureUsage(ctx, contract); err != nil {
+		return nil, err
+	}
+
+	usageCost, usageVolume := 0.0, 0.0
+	if contract.Usage != nil {
+		usageCost = contract.Usage.TotalCost
+		usageVolume = contract.Usage.TotalVolumeM3
+	}
+
+	breakdown := computePayable(usageCost, contract.BudgetTotal)
+	breakdown.ContractID = contract.ID
+	breakdown.PricingTiers = pricingTiers(contract.PricePerM3, usageVolume)
+	return &breakdown, nil
+}
+
+// computePayable — единственное место расчёта суммы к оплате: стоимость
+// выполненного объёма, ограниченная бюджетом. Используется и в decorateContract.
+func computePayable(usageCost, budgetTotal float64) PayableBreakdown {
+	items := []PayableLineItem{
+		{Code: PayableItemTotalCost, Amount: usageCost},
+	}
+
+	payable := usageCost
+	if overage := usageCost - budgetTotal; overage > 0 {
+		items = append(items, PayableLineItem{Code: PayableItemOverageNotPayable, Amount: -overage})
+		payable = budgetTotal
+	}
+
+	return PayableBreakdown{
+		BudgetCap:     budgetTotal,
+		Items:         items,
+		PayableAmount: math.Max(payable, 0),
+	}
+}
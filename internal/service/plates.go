@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// plateLookalikes — кириллические буквы, которые на казахстанских номерах
+// пишутся латиницей и путаются при ручном вводе и распознавании.
+var plateLookalikes = map[rune]rune{
+	'А': 'A',
+	'В': 'B',
+	'Е': 'E',
+	'К': 'K',
+	'М': 'M',
+	'Н': 'H',
+	'О': 'O',
+	'Р': 'P',
+	'С': 'C',
+	'Т': 'T',
+	'У': 'Y',
+	'Х': 'X',
+}
+
+// normalizePlate приводит госномер к виду для сравнения: без пробелов и дефисов,
+// в верхнем регистре, кириллические двойники заменены латиницей.
+// Единственная точка нормализации для фильтра plate_mismatch и сводки.
+func normalizePlate(raw string) string {
+	var b strings.Builder
+	b.Grow(len(raw))
+	for _, r := range raw {
+		if unicode.IsSpace(r) || r == '-' {
+			continue
+		}
+		r = unicode.ToUpper(r)
+		if latin, ok := plateLookalikes[r]; ok {
+			r = latin
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isPlateMismatch — распознанный номер отличается от номера техники.
+// Если одна из сторон неизвестна, расхождением это не считается.
+func isPlateMismatch(trip *model.ContractTrip) bool {
+	if trip.VehiclePlateNumber == nil || trip.DetectedPlate == nil {
+		return false
+	}
+	registered := normalizePlate(*trip.VehiclePlateNumber)
+	detected := normalizePlate(*trip.DetectedPlate)
+	if registered == "" || detected == "" {
+		return false
+	}
+	return registered != detected
+}
+
+type PlateMismatchSummary struct {
+	ContractID uuid.UUID            `json:"contract_id"`
+	Count      int                  `json:"count"`
+	Trips      []model.ContractTrip `json:"trips"`
+}
+
+// GetPlateMismatchSummary — рейсы контракта с расхождением номеров и их число.
+// Использует тот же отбор, что и plate_mismatch=true в списке рейсов.
+func (s *ContractService) GetPlateMismatchSummary(ctx context.Context, principal model.Principal, contractID uuid.UUID) (*PlateMismatchSummary, error) {
+	trips, err := s.ListContractTrips(ctx, principal, contractID, ListContractTripsInput{PlateMismatch: true})
+	if err != nil {
+		return nil, err
+	}
+	return &PlateMismatchSummary{
+		ContractID: contractID,
+		Count:      len(trips),
+		Trips:      emptyIfNil(trips),
+	}, nil
+}
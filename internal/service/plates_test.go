@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/dbtest"
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+func TestNormalizePlate(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "123ABC02", want: "123ABC02"},
+		{raw: " 123 abc 02 ", want: "123ABC02"},
+		{raw: "123-ABC-02", want: "123ABC02"},
+		// кириллические А, В, С вместо латинских
+		{raw: "123АВС02", want: "123ABC02"},
+		{raw: "123авс02", want: "123ABC02"},
+		{raw: "о777ко", want: "O777KO"},
+		// буквы без латинского двойника не меняются
+		{raw: "123ЖЗ02", want: "123ЖЗ02"},
+		{raw: "  ", want: ""},
+	}
+	for _, tt := range tests {
+		if got := normalizePlate(tt.raw); got != tt.want {
+			t.Errorf("normalizePlate(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestIsPlateMismatch(t *testing.T) {
+	plate := func(value string) *string { return &value }
+	tests := []struct {
+		name       string
+		registered *string
+		detected   *string
+		want       bool
+	}{
+		{name: "same plate", registered: plate("123ABC02"), detected: plate("123ABC02"), want: false},
+		{name: "cyrillic lookalikes and spaces", registered: plate("123ABC02"), detected: plate("123 АВС 02"), want: false},
+		{name: "different plate", registered: plate("123ABC02"), detected: plate("124ABC02"), want: true},
+		{name: "registered unknown", registered: nil, detected: plate("123ABC02"), want: false},
+		{name: "detected unknown", registered: plate("123ABC02"), detected: nil, want: false},
+		{name: "detected empty", registered: plate("123ABC02"), detected: plate(" "), want: false},
+	}
+	for _, tt := range tests {
+		trip := &model.ContractTrip{VehiclePlateNumber: tt.registered, DetectedPlate: tt.detected}
+		if got := isPlateMismatch(trip); got != tt.want {
+			t.Errorf("%s: isPlateMismatch() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPlateMismatchSummaryMatchesTripFilter(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+	ticketID := dbtest.Ticket(t, database, contract.ID)
+
+	plates := []struct{ registered, detected string }{
+		{"123ABC02", "123ABC02"},
+		{"123ABC02", "123 АВС 02"}, // кириллица — тот же номер
+		{"123ABC02", "777XYZ02"},
+		{"555KKK01", "555ККК01"},
+		{"555KKK01", "556KKK01"},
+	}
+	for _, p := range plates {
+		dbtest.Exec(t, database, `
+			INSERT INTO trips (id, ticket_id, vehicle_plate_number, detected_plate_number)
+			VALUES (?, ?, ?, ?)
+		`, uuid.New(), ticketID, p.registered, p.detected)
+	}
+
+	summary, err := s.GetPlateMismatchSummary(ctx, principal, contract.ID)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if summary.Count != 2 || len(summary.Trips) != 2 {
+		t.Fatalf("summary count = %d (%d trips), want 2", summary.Count, len(summary.Trips))
+	}
+	filtered, err := s.ListContractTrips(ctx, principal, contract.ID, ListContractTripsInput{PlateMismatch: true})
+	if err != nil {
+		t.Fatalf("list trips: %v", err)
+	}
+	if len(filtered) != summary.Count {
+		t.Fatalf("plate_mismatch filter returned %d trips, summary counted %d", len(filtered), summary.Count)
+	}
+}
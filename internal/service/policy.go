@@ -0,0 +1,147 @@
+package service
+
+import (
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+// Правила доступа к контрактам. Проверки по одному контракту (ensure*) и
+// ограничения списков (apply*) должны оставаться согласованными.
+
+// applyReadScope ограничивает фильтр контрактами, видимыми принципалу.
+// Зеркалит ensureReadAccess для запросов списком.
+func applyReadScope(principal model.Principal, filter *repository.ContractFilter) error {
+	switch {
+	case principal.IsContractor():
+		filter.ContractorID = &principal.OrganizationID
+	case principal.IsLandfill():
+		// LANDFILL видит только свои контракты приёма
+		filter.LandfillID = &principal.OrganizationID
+		filterContractType := model.ContractTypeLandfillService
+		filter.ContractType = &filterContractType
+	case principal.IsKgu(), principal.IsAkimat():
+		// allowed
+	default:
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// applyPerspective сужает уже ограниченный applyReadScope фильтр до одной
+// роли организации. Для CONTRACTOR/LANDFILL допустима только их собственная
+// перспектива (или all, что то же самое).
+func applyPerspective(principal model.Principal, perspective model.ContractPerspective, filter *repository.ContractFilter) error {
+	if perspective == "" || perspective == model.ContractPerspectiveAll {
+		return nil
+	}
+
+	switch {
+	case principal.IsContractor():
+		if perspective != model.ContractPerspectiveContractor {
+			return ErrPermissionDenied
+		}
+		return nil
+	case principal.IsLandfill():
+		if perspective != model.ContractPerspectiveLandfill {
+			return ErrPermissionDenied
+		}
+		return nil
+	}
+
+	switch perspective {
+	case model.ContractPerspectiveCreated:
+		filter.CreatedByOrg = &principal.OrganizationID
+	case model.ContractPerspectiveContractor:
+		contractType := model.ContractTypeContractorService
+		filter.ContractorID = &principal.OrganizationID
+		filter.ContractType = &contractType
+	case model.ContractPerspectiveLandfill:
+		contractType := model.ContractTypeLandfillService
+		filter.LandfillID = &principal.OrganizationID
+		filter.ContractType = &contractType
+	default:
+		return ErrInvalidInput
+	}
+	return nil
+}
+
+// accessRelation объясняет, почему принципал видит контракт; порядок проверок
+// задаёт приоритет, если причин несколько. Согласован с ensureReadAccess.
+func accessRelation(principal model.Principal, contract *model.Contract) (model.ContractAccessRelation, bool) {
+	if principal.IsKgu() && contract.CreatedByOrgID == principal.OrganizationID {
+		return model.ContractAccessCreator, true
+	}
+	switch {
+	case principal.IsContractor():
+		if contract.ContractType == model.ContractTypeContractorService &&
+			contract.ContractorID != nil && *contract.ContractorID == principal.OrganizationID {
+			return model.ContractAccessContractor, true
+		}
+	case principal.IsLandfill():
+		if contract.ContractType == model.ContractTypeLandfillService &&
+			contract.LandfillID != nil && *contract.LandfillID == principal.OrganizationID {
+			return model.ContractAccessLandfill, true
+		}
+	case principal.IsKgu():
+		return model.ContractAccessKguScope, true
+	case principal.IsAkimat():
+		return model.ContractAccessAkimat, true
+	}
+	return "", false
+}
+
+func (s *ContractService) ensureReadAccess(principal model.Principal, contract *model.Contract) error {
+	switch {
+	case principal.IsContractor():
+		if contract.ContractType != model.ContractTypeContractorService {
+			return ErrPermissionDenied
+		}
+		if contract.ContractorID == nil || *contract.ContractorID != principal.OrganizationID {
+			return ErrPermissionDenied
+		}
+	case principal.IsLandfill():
+		if contract.ContractType != model.ContractTypeLandfillService {
+			return ErrPermissionDenied
+		}
+		if contract.LandfillID == nil || *contract.LandfillID != principal.OrganizationID {
+			return ErrPermissionDenied
+		}
+	case principal.IsKgu(), principal.IsAkimat():
+		// allowed
+	default:
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// ensureWriteAccess — изменять контракт может только КГУ, создавшее его.
+func ensureWriteAccess(principal model.Principal, contract *model.Contract) error {
+	if !principal.IsKgu() || contract.CreatedByOrgID != principal.OrganizationID {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// applyWriteScope ограничивает фильтр контрактами, которые принципал может
+// изменять. Зеркалит ensureWriteAccess для запросов списком.
+func applyWriteScope(principal model.Principal, filter *repository.ContractFilter) {
+	if principal.IsKgu() {
+		filter.CreatedByOrg = &principal.OrganizationID
+		return
+	}
+	// Пустой (не nil) список id даёт пустую выборку: c.id IN (NULL)
+	filter.IDs = []uuid.UUID{}
+}
+
+// ensureLandfillAccess — данные полигона приёма видят сам полигон, КГУ и акимат.
+func ensureLandfillAccess(principal model.Principal, landfillID uuid.UUID) error {
+	switch {
+	case principal.IsKgu(), principal.IsAkimat():
+		return nil
+	case principal.IsLandfill() && principal.OrganizationID == landfillID:
+		return nil
+	}
+	return ErrPermissionDenied
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// MaxPolygonConflictItems — предел назначений в одной проверке конфликтов.
+const MaxPolygonConflictItems = 500
+
+// PolygonAssignment — предполагаемое назначение полигона контракту приёма на срок.
+type PolygonAssignment struct {
+	PolygonID uuid.UUID
+	StartAt   time.Time
+	EndAt     time.Time
+}
+
+type PolygonConflictContract struct {
+	ContractID uuid.UUID `json:"contract_id"`
+	Name       string    `json:"name"`
+	StartAt    time.Time `json:"start_at"`
+	EndAt      time.Time `json:"end_at"`
+}
+
+type PolygonConflictItem struct {
+	Index     int       `json:"index"`
+	PolygonID uuid.UUID `json:"polygon_id"`
+	StartAt   time.Time `json:"start_at"`
+	EndAt     time.Time `json:"end_at"`
+	// ContractConflicts — действующие контракты приёма с тем же полигоном
+	ContractConflicts []PolygonConflictContract `json:"contract_conflicts"`
+	// ItemConflicts — индексы других назначений запроса с тем же полигоном
+	ItemConflicts []int `json:"item_conflicts"`
+	Conflicting   bool  `json:"conflicting"`
+}
+
+type PolygonConflictReport struct {
+	HasConflicts bool                  `json:"has_conflicts"`
+	Items        []PolygonConflictItem `json:"items"`
+}
+
+// CheckPolygonConflicts проверяет план назначений полигонов до создания
+// контрактов: пересечения сроков с действующими контрактами приёма на тех же
+// полигонах и назначений плана между собой. Ничего не записывает. КГУ и акимат.
+func (s *ContractService) CheckPolygonConflicts(ctx context.Context, principal model.Principal, assignments []PolygonAssignment) (*PolygonConflictReport, error) {
+	if !principal.IsKgu() && !principal.IsAkimat() {
+		return nil, ErrPermissionDenied
+	}
+	if len(assignments) == 0 || len(assignments) > MaxPolygonConflictItems {
+		return nil, fmt.Errorf("%w: items must contain 1 to %d entries", ErrInvalidInput, MaxPolygonConflictItems)
+	}
+
+	polygonIDs := make([]uuid.UUID, 0, len(assignments))
+	seen := make(map[uuid.UUID]struct{}, len(assignments))
+	from, to := assignments[0].StartAt, assignments[0].EndAt
+	for i, assignment := range assignments {
+		if assignment.PolygonID == uuid.Nil {
+			return nil, fmt.Errorf("%w: items[%d].polygon_id is nil uuid", ErrInvalidInput, i)
+		}
+		if !assignment.EndAt.After(assignment.StartAt) {
+			return nil, fmt.Errorf("%w: items[%d] end_at must be after start_at", ErrInvalidInput, i)
+		}
+		if _, ok := seen[assignment.PolygonID]; !ok {
+			seen[assignment.PolygonID] = struct{}{}
+			polygonIDs = append(polygonIDs, assignment.PolygonID)
+		}
+		if assignment.StartAt.Before(from) {
+			from = assignment.StartAt
+		}
+		if assignment.EndAt.After(to) {
+			to = assignment.EndAt
+		}
+	}
+
+	coverage, err := s.contracts.ListPolygonCoverage(ctx, polygonIDs, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &PolygonConflictReport{Items: make([]PolygonConflictItem, len(assignments))}
+	for i, assignment := range assignments {
+		item := PolygonConflictItem{
+			Index:             i,
+			PolygonID:         assignment.PolygonID,
+			StartAt:           assignment.StartAt,
+			EndAt:             assignment.EndAt,
+			ContractConflicts: []PolygonConflictContract{},
+			ItemConflicts:     []int{},
+		}
+		for _, existing := range coverage {
+			if existing.PolygonID == assignment.PolygonID &&
+				periodsOverlap(existing.StartAt, existing.EndAt, assignment.StartAt, assignment.EndAt) {
+				item.ContractConflicts = append(item.ContractConflicts, PolygonConflictContract{
+					ContractID: existing.ContractID,
+					Name:       existing.ContractName,
+					StartAt:    existing.StartAt,
+					EndAt:      existing.EndAt,
+				})
+			}
+		}
+		for j, other := range assignments {
+			if j != i && other.PolygonID == assignment.PolygonID &&
+				periodsOverlap(other.StartAt, other.EndAt, assignment.StartAt, assignment.EndAt) {
+				item.ItemConflicts = append(item.ItemConflicts, j)
+			}
+		}
+		item.Conflicting = len(item.ContractConflicts) > 0 || len(item.ItemConflicts) > 0
+		report.HasConflicts = report.HasConflicts || item.Conflicting
+		report.Items[i] = item
+	}
+	return report, nil
+}
+
+// periodsOverlap — сроки [aStart, aEnd] и [bStart, bEnd] пересекаются; границы
+// включительно, как и статус ACTIVE контракта.
+func periodsOverlap(aStart, aEnd, bStart, bEnd time.Time) bool {
+	return !aStart.After(bEnd) && !bStart.After(aEnd)
+}
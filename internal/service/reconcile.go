@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+type ReconcileTicketContractResult struct {
+	TicketID           uuid.UUID                       `json:"ticket_id"`
+	CurrentContractID  *uuid.UUID                      `json:"current_contract_id"`
+	ProposedContractID *uuid.UUID                      `json:"proposed_contract_id"`
+	Candidates         []model.TicketContractCandidate `json:"candidates"`
+	Changed            bool                            `json:"changed"`
+	Applied            bool                            `json:"applied"`
+}
+
+// ReconcileTicketContract предлагает контракт для тикета по участку уборки и плановым
+// датам; при apply=true перепривязывает тикет к предложенному контракту.
+func (s *ContractService) ReconcileTicketContract(ctx context.Context, principal model.Principal, ticketID uuid.UUID, apply bool) (*ReconcileTicketContractResult, error) {
+	if !(principal.IsKgu() || principal.IsAkimat()) {
+		return nil, ErrPermissionDenied
+	}
+
+	ticket, err := s.contracts.GetTicketRef(ctx, ticketID)
+	if errors.Is(err, repository.ErrTicketNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := s.contracts.FindContractCandidatesForTicket(ctx, *ticket)
+	if err != nil {
+		return nil, err
+	}
+	if candidates == nil {
+		candidates = []model.TicketContractCandidate{}
+	}
+
+	result := &ReconcileTicketContractResult{
+		TicketID:          ticket.ID,
+		CurrentContractID: ticket.ContractID,
+		Candidates:        candidates,
+	}
+	if len(candidates) == 0 {
+		return result, nil
+	}
+
+	proposed := candidates[0].ContractID
+	result.ProposedContractID = &proposed
+	result.Changed = ticket.ContractID == nil || *ticket.ContractID != proposed
+
+	if !apply || !result.Changed {
+		return result, nil
+	}
+
+	contract, err := s.contracts.GetByID(ctx, proposed, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if principal.IsKgu() {
+		if err := ensureWriteAccess(principal, contract); err != nil {
+			return nil, err
+		}
+	}
+	if err := ensureNotLocked(contract); err != nil {
+		return nil, err
+	}
+
+	if err := s.contracts.RelinkTicketContract(ctx, ticket.ID, proposed); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrTicketNotFound):
+			return nil, ErrNotFound
+		case errors.Is(err, repository.ErrTicketHasUsage):
+			return nil, fmt.Errorf("%w: %v", ErrConflict, err)
+		}
+		return nil, err
+	}
+	result.Applied = true
+	s.notifyTicketContractAssigned(ctx, principal, ticket.ID, proposed)
+
+	return result, nil
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+// DefaultUtilizationBuckets — верхние границы корзин гистограммы утилизации, %.
+var DefaultUtilizationBuckets = []float64{25, 50, 75, 100}
+
+const maxUtilizationBuckets = 20
+
+type UtilizationBucket struct {
+	FromPercent float64  `json:"from_percent"`
+	ToPercent   *float64 `json:"to_percent"` // nil — без верхней границы
+	Count       int64    `json:"count"`
+}
+
+type UtilizationDistribution struct {
+	Buckets []UtilizationBucket `json:"buckets"`
+	Total   int64               `json:"total"`
+}
+
+// GetUtilizationDistribution считает, сколько доступных контрактов попадает в каждую
+// корзину утилизации бюджета. upperBounds — возрастающие границы в процентах;
+// последняя корзина открыта сверху (перерасход).
+func (s *ContractService) GetUtilizationDistribution(ctx context.Context, principal model.Principal, upperBounds []float64) (*UtilizationDistribution, error) {
+	if len(upperBounds) == 0 {
+		upperBounds = DefaultUtilizationBuckets
+	}
+	if len(upperBounds) > maxUtilizationBuckets {
+		return nil, ErrInvalidInput
+	}
+	for i, bound := range upperBounds {
+		if bound <= 0 || (i > 0 && bound <= upperBounds[i-1]) {
+			return nil, ErrInvalidInput
+		}
+	}
+
+	filter := repository.ContractFilter{Now: s.now()}
+	if err := applyReadScope(principal, &filter); err != nil {
+		return nil, err
+	}
+
+	bounds := append([]float64{0}, upperBounds...)
+	counts, err := s.contracts.UtilizationDistribution(ctx, filter, bounds)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UtilizationDistribution{
+		Buckets: make([]UtilizationBucket, len(bounds)),
+	}
+	for i := range bounds {
+		result.Buckets[i].FromPercent = bounds[i]
+		if i+1 < len(bounds) {
+			to := bounds[i+1]
+			result.Buckets[i].ToPercent = &to
+		}
+	}
+	for _, item := range counts {
+		// width_bucket нумерует корзины с 1; 0 (ниже нуля) невозможен при неотрицательной стоимости
+		index := item.Bucket - 1
+		if index < 0 {
+			index = 0
+		}
+		result.Buckets[index].Count += item.Count
+		result.Total += item.Count
+	}
+
+	return result, nil
+}
+
+type RecomputeStatusesInput struct {
+	ContractorID *uuid.UUID
+	LandfillID   *uuid.UUID
+	ContractType *model.ContractType
+}
+
+type RecomputeStatusesResult struct {
+	Updated         int       `json:"updated"`
+	ComputedAt      time.Time `json:"computed_at"`
+	ResultTolerance float64   `json:"result_tolerance"`
+}
+
+// RecomputeStatuses вычисляет ui_status/result для контрактов по фильтру и
+// сохраняет снимок в таблицу contracts для быстрых отчётов (только акимат).
+func (s *ContractService) RecomputeStatuses(ctx context.Context, principal model.Principal, input RecomputeStatusesInput) (*RecomputeStatusesResult, error) {
+	if !principal.IsAkimat() {
+		return nil, ErrPermissionDenied
+	}
+
+	now := s.now()
+	contracts, err := s.contracts.List(ctx, repository.ContractFilter{
+		ContractorID: input.ContractorID,
+		LandfillID:   input.LandfillID,
+		ContractType: input.ContractType,
+		IncludeUsage: true,
+		Now:          now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshots := make([]repository.StatusSnapshot, 0, len(contracts))
+	for i := range contracts {
+		// Снимок статусов по неполному usage недопустим независимо от режима
+		if contracts[i].UsageLoadErr != nil {
+			return nil, fmt.Errorf("contract %s: %w", contracts[i].ID, contracts[i].UsageLoadErr)
+		}
+		s.decorateContract(&contracts[i])
+		snapshots = append(snapshots, repository.StatusSnapshot{
+			ContractID: contracts[i].ID,
+			UIStatus:   contracts[i].UIStatus,
+			Result:     contracts[i].Result,
+		})
+	}
+
+	if err := s.contracts.SaveStatusSnapshots(ctx, snapshots, now); err != nil {
+		return nil, err
+	}
+
+	return &RecomputeStatusesResult{
+		Updated:         len(snapshots),
+		ComputedAt:      now,
+		ResultTolerance: s.cfg.ResultTolerance,
+	}, nil
+}
+
+type MonthlySpend struct {
+	Month           int     `json:"month"`
+	TripCount       int64   `json:"trip_count"`
+	AdjustmentCount int64   `json:"adjustment_count"`
+	TotalVolumeM3   float64 `json:"total_volume_m3"`
+	TotalCost       float64 `json:"total_cost"`
+}
+
+type ContractorMonthlySpend struct {
+	ContractorID  uuid.UUID      `json:"contractor_id"`
+	Year          int            `json:"year"`
+	Months        []MonthlySpend `json:"months"`
+	TotalVolumeM3 float64        `json:"total_volume_m3"`
+	TotalCost     float64        `json:"total_cost"`
+}
+
+// ensureContractorReportAccess — отчёты по подрядчику видят он сам, КГУ и акимат.
+func ensureContractorReportAccess(principal model.Principal, contractorID uuid.UUID) error {
+	switch {
+	case principal.IsKgu(), principal.IsAkimat():
+		return nil
+	case principal.IsContractor() && principal.OrganizationID == contractorID:
+		return nil
+	}
+	return ErrPermissionDenied
+}
+
+// GetContractorMonthlySpend возвращает расход подрядчика по месяцам года по
+// журналу рейсов и ручным корректировкам. Все 12 месяцев присутствуют,
+// месяцы без движений — с нулями. Границы месяцев — UTC.
+func (s *ContractService) GetContractorMonthlySpend(ctx context.Context, principal model.Principal, contractorID uuid.UUID, year int) (*ContractorMonthlySpend, error) {
+	if err := ensureContractorReportAccess(principal, contractorID); err != nil {
+		return nil, err
+	}
+	if year < 2000 || year > 2100 {
+		return nil, ErrInvalidInput
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := s.contracts.ContractorMonthlySpend(ctx, contractorID, from, from.AddDate(1, 0, 0))
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ContractorMonthlySpend{
+		ContractorID: contractorID,
+		Year:         year,
+		Months:       make([]MonthlySpend, 12),
+	}
+	for i := range result.Months {
+		result.Months[i].Month = i + 1
+	}
+	for _, row := range rows {
+		if row.Month < 1 || row.Month > 12 {
+			continue
+		}
+		result.Months[row.Month-1] = MonthlySpend{
+			Month:           row.Month,
+			TripCount:       row.TripCount,
+			AdjustmentCount: row.AdjustmentCount,
+			TotalVolumeM3:   row.TotalVolumeM3,
+			TotalCost:       row.TotalCost,
+		}
+		result.TotalVolumeM3 += row.TotalVolumeM3
+		result.TotalCost += row.TotalCost
+	}
+	return result, nil
+}
+
+// Поля, по которым сортируется рейтинг подрядчиков (по убыванию).
+const (
+	PerformanceSortSuccessRate    = "success_rate"
+	PerformanceSortOnTimeRate     = "on_time_rate"
+	PerformanceSortAvgUtilization = "avg_utilization"
+)
+
+type ContractorPerformanceInput struct {
+	EndFrom *time.Time
+	EndTo   *time.Time
+	// SortBy — по умолчанию success_rate
+	SortBy string
+}
+
+type ContractorPerformance struct {
+	Rank         int                       `json:"rank"`
+	ContractorID uuid.UUID                 `json:"contractor_id"`
+	Contractor   *model.OrganizationLookup `json:"contractor,omitempty"`
+	Contracts    int64                     `json:"contracts"`
+	Expired      int64                     `json:"expired"`
+	Succeeded    int64                     `json:"succeeded"`
+	OnTime       int64                     `json:"on_time"`
+	// Доли истёкших контрактов; nil — истёкших нет
+	SuccessRate    *float64 `json:"success_rate"`
+	OnTimeRate     *float64 `json:"on_time_rate"`
+	AvgUtilization *float64 `json:"avg_utilization"`
+}
+
+type ContractorPerformanceReport struct {
+	SortBy          string                  `json:"sort_by"`
+	ResultTolerance float64                 `json:"result_tolerance"`
+	Contractors     []ContractorPerformance `json:"contractors"`
+}
+
+// GetContractorPerformance строит рейтинг подрядчиков по контрактам CONTRACTOR_SERVICE:
+// доля истёкших контрактов, выполнивших минимальный объём (с RESULT_TOLERANCE),
+// доля выполнивших его до end_at и средняя утилизация бюджета. Только КГУ и акимат.
+func (s *ContractService) GetContractorPerformance(ctx context.Context, principal model.Principal, input ContractorPerformanceInput) (*ContractorPerformanceReport, error) {
+	if !principal.IsKgu() && !principal.IsAkimat() {
+		return nil, ErrPermissionDenied
+	}
+	if input.EndFrom != nil && input.EndTo != nil && input.EndFrom.After(*input.EndTo) {
+		return nil, ErrInvalidInput
+	}
+	sortBy := input.SortBy
+	if sortBy == "" {
+		sortBy = PerformanceSortSuccessRate
+	}
+	if sortBy != PerformanceSortSuccessRate && sortBy != PerformanceSortOnTimeRate && sortBy != PerformanceSortAvgUtilization {
+		return nil, ErrInvalidInput
+	}
+	metric := func(item ContractorPerformance) *float64 {
+		switch sortBy {
+		case PerformanceSortOnTimeRate:
+			return item.OnTimeRate
+		case PerformanceSortAvgUtilization:
+			return item.AvgUtilization
+		default:
+			return item.SuccessRate
+		}
+	}
+
+	rows, err := s.contracts.ContractorPerformance(ctx, input.EndFrom, input.EndTo, s.now(), s.cfg.ResultTolerance)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]ContractorPerformance, 0, len(rows))
+	ids := make([]uuid.UUID, 0, len(rows))
+	for _, row := range rows {
+		item := ContractorPerformance{
+			ContractorID:   row.ContractorID,
+			Contracts:      row.ContractCount,
+			Expired:        row.ExpiredCount,
+			Succeeded:      row.SuccessCount,
+			OnTime:         row.OnTimeCount,
+			AvgUtilization: row.AvgUtilization,
+		}
+		if row.ExpiredCount > 0 {
+			successRate := float64(row.SuccessCount) / float64(row.ExpiredCount)
+			onTimeRate := float64(row.OnTimeCount) / float64(row.ExpiredCount)
+			item.SuccessRate = &successRate
+			item.OnTimeRate = &onTimeRate
+		}
+		items = append(items, item)
+		ids = append(ids, row.ContractorID)
+	}
+
+	// По убыванию метрики; подрядчики без значения — в конце
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := metric(items[i]), metric(items[j])
+		switch {
+		case a == nil || b == nil:
+			if (a == nil) != (b == nil) {
+				return b == nil
+			}
+		case *a != *b:
+			return *a > *b
+		}
+		if items[i].Expired != items[j].Expired {
+			return items[i].Expired > items[j].Expired
+		}
+		return items[i].ContractorID.String() < items[j].ContractorID.String()
+	})
+
+	names, err := s.organizationNames(ctx, ids)
+	if err != nil {
+		s.log.Warn().Err(err).Msg("failed to load organization names")
+	}
+	for i := range items {
+		items[i].Rank = i + 1
+		if name, ok := names[items[i].ContractorID]; ok {
+			items[i].Contractor = &model.OrganizationLookup{ID: items[i].ContractorID, Name: name}
+		}
+	}
+
+	return &ContractorPerformanceReport{
+		SortBy:          sortBy,
+		ResultTolerance: s.cfg.ResultTolerance,
+		Contractors:     items,
+	}, nil
+}
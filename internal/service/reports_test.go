@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+func TestGetContractorMonthlySpend(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	kgu := kguPrincipal(t, database)
+	contract := createContract(t, s, kgu, contractorInput(t, database))
+	for _, volume := range []float64{10, 20} {
+		if err := recordTrip(t, s, database, kgu, contract.ID, volume); err != nil {
+			t.Fatalf("record trip: %v", err)
+		}
+	}
+	_, err := s.RecordUsageAdjustment(ctx, kgu, contract.ID, RecordUsageAdjustmentInput{VolumeDeltaM3: -5, CostDelta: -500, Reason: "спор"})
+	if err != nil {
+		t.Fatalf("record adjustment: %v", err)
+	}
+
+	contractor := model.Principal{UserID: uuid.New(), OrganizationID: *contract.ContractorID, Role: model.UserRoleContractorAdmin}
+	now := time.Now().UTC()
+	spend, err := s.GetContractorMonthlySpend(ctx, contractor, *contract.ContractorID, now.Year())
+	if err != nil {
+		t.Fatalf("monthly spend: %v", err)
+	}
+	if len(spend.Months) != 12 {
+		t.Fatalf("got %d months, want 12", len(spend.Months))
+	}
+	for i, month := range spend.Months {
+		if month.Month != i+1 {
+			t.Fatalf("months[%d].month = %d", i, month.Month)
+		}
+		want := MonthlySpend{Month: i + 1}
+		if month.Month == int(now.Month()) {
+			// корректировка входит в месяц, как и в used_amount
+			want = MonthlySpend{Month: i + 1, TripCount: 2, AdjustmentCount: 1, TotalVolumeM3: 25, TotalCost: 2500}
+		}
+		if month != want {
+			t.Fatalf("month %d = %+v, want %+v", month.Month, month, want)
+		}
+	}
+	usage, err := s.contracts.GetUsage(ctx, contract.ID)
+	if err != nil {
+		t.Fatalf("get usage: %v", err)
+	}
+	if spend.TotalCost != usage.TotalCost || spend.TotalVolumeM3 != usage.TotalVolumeM3 {
+		t.Fatalf("spend total = %v m3 / %v, usage = %v m3 / %v", spend.TotalVolumeM3, spend.TotalCost, usage.TotalVolumeM3, usage.TotalCost)
+	}
+
+	// год без движений — 12 нулевых месяцев
+	empty, err := s.GetContractorMonthlySpend(ctx, contractor, *contract.ContractorID, now.Year()-1)
+	if err != nil {
+		t.Fatalf("monthly spend of empty year: %v", err)
+	}
+	if len(empty.Months) != 12 || empty.TotalCost != 0 || empty.TotalVolumeM3 != 0 {
+		t.Fatalf("empty year = %+v", empty)
+	}
+	for i, month := range empty.Months {
+		if month != (MonthlySpend{Month: i + 1}) {
+			t.Fatalf("empty year month %d = %+v", i+1, month)
+		}
+	}
+
+	if _, err := s.GetContractorMonthlySpend(ctx, contractor, *contract.ContractorID, 1999); !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("year 1999: err = %v, want ErrInvalidInput", err)
+	}
+	stranger := model.Principal{UserID: uuid.New(), OrganizationID: uuid.New(), Role: model.UserRoleContractorAdmin}
+	if _, err := s.GetContractorMonthlySpend(ctx, stranger, *contract.ContractorID, now.Year()); !errors.Is(err, ErrPermissionDenied) {
+		t.Fatalf("other contractor: err = %v, want ErrPermissionDenied", err)
+	}
+}
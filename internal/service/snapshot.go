@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+// DefaultSnapshotMaxItems — лимит строк каждой связанной коллекции снимка по умолчанию.
+const DefaultSnapshotMaxItems = 10000
+
+// SnapshotTruncation — какие коллекции снимка обрезаны по лимиту.
+type SnapshotTruncation struct {
+	Tickets     bool `json:"tickets"`
+	Trips       bool `json:"trips"`
+	UsageLedger bool `json:"usage_ledger"`
+}
+
+// ContractSnapshot — полный JSON-снимок контракта для архива перед изменением
+// или удалением: контракт с usage и полигонами, тикеты, рейсы и журнал usage.
+type ContractSnapshot struct {
+	SnapshotAt  time.Time                `json:"snapshot_at"`
+	Contract    *model.Contract          `json:"contract"`
+	Tickets     []model.ContractTicket   `json:"tickets"`
+	Trips       []model.ContractTrip     `json:"trips"`
+	UsageLedger []model.UsageLedgerEntry `json:"usage_ledger"`
+	// MaxItems — лимит строк каждой коллекции; Truncated — какие из них обрезаны
+	MaxItems  int                `json:"max_items"`
+	Truncated SnapshotTruncation `json:"truncated"`
+}
+
+// GetSnapshot собирает снимок контракта с теми же правами, что и чтение
+// контракта. Тикеты и рейсы — новые первыми, журнал — хронологически; каждая
+// коллекция ограничена SnapshotMaxItems строками.
+func (s *ContractService) GetSnapshot(ctx context.Context, principal model.Principal, id uuid.UUID) (*ContractSnapshot, error) {
+	contract, err := s.get(ctx, principal, id, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	maxItems := s.cfg.SnapshotMaxItems
+	if maxItems <= 0 {
+		maxItems = DefaultSnapshotMaxItems
+	}
+	snapshot := &ContractSnapshot{
+		SnapshotAt: s.now(),
+		Contract:   contract,
+		MaxItems:   maxItems,
+	}
+
+	// +1 строка показывает, что коллекция длиннее лимита
+	tickets, err := s.contracts.ListContractTickets(ctx, id, repository.TicketFilter{Limit: maxItems + 1})
+	if err != nil {
+		return nil, err
+	}
+	snapshot.Tickets, snapshot.Truncated.Tickets = truncate(emptyIfNil(tickets), maxItems)
+
+	trips, err := s.contracts.ListContractTrips(ctx, id, repository.TripFilter{Limit: maxItems + 1})
+	if err != nil {
+		return nil, err
+	}
+	for i := range trips {
+		decorateTrip(&trips[i])
+	}
+	snapshot.Trips, snapshot.Truncated.Trips = truncate(emptyIfNil(trips), maxItems)
+
+	ledger, err := s.contracts.ListUsageLedger(ctx, id, maxItems+1)
+	if err != nil {
+		return nil, err
+	}
+	snapshot.UsageLedger, snapshot.Truncated.UsageLedger = truncate(emptyIfNil(ledger), maxItems)
+
+	return snapshot, nil
+}
+
+func truncate[T any](items []T, limit int) ([]T, bool) {
+	if len(items) > limit {
+		return items[:limit], true
+	}
+	return items, false
+}
+
+type RestoreSnapshotInput struct {
+	Snapshot ContractSnapshot
+	// Overwrite — заменить существующий контракт с тем же id
+	Overwrite bool
+	// RestoreLedger — восстановить и журнал usage (рейсы и корректировки)
+	RestoreLedger bool
+}
+
+// RestoreSnapshot воссоздаёт контракт из снимка GetSnapshot одной транзакцией:
+// условия, итоги usage, полигоны и по запросу журнал usage. Id из снимка
+// сохраняется (без него — новый); существующий контракт перезаписывается
+// только с Overwrite. Только акимат.
+func (s *ContractService) RestoreSnapshot(ctx context.Context, principal model.Principal, input RestoreSnapshotInput) (*model.Contract, error) {
+	if !principal.IsAkimat() {
+		return nil, ErrPermissionDenied
+	}
+	if err := validateSnapshot(input.Snapshot, input.RestoreLedger); err != nil {
+		return nil, err
+	}
+
+	contract := *input.Snapshot.Contract
+	params := repository.RestoreContractParams{
+		Contract:    contract,
+		Polygons:    contract.Polygons,
+		Overwrite:   input.Overwrite,
+		ActorUserID: principal.UserID,
+		ActorOrgID:  principal.OrganizationID,
+	}
+	if contract.Usage != nil {
+		params.UsageVolumeM3 = contract.Usage.TotalVolumeM3
+		params.UsageCost = contract.Usage.TotalCost
+	}
+	if input.RestoreLedger {
+		params.Ledger = emptyIfNil(input.Snapshot.UsageLedger)
+	}
+
+	id, err := s.contracts.RestoreContract(ctx, params)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrContractExists),
+			errors.Is(err, repository.ErrClientReferenceExists),
+			errors.Is(err, repository.ErrTripUsageDuplicate):
+			return nil, fmt.Errorf("%w: %v", ErrConflict, err)
+		default:
+			return nil, err
+		}
+	}
+	s.publishUsage(ctx, id)
+	return s.get(ctx, principal, id, nil)
+}
+
+// validateSnapshot проверяет, что снимок целый и условия контракта допустимы.
+func validateSnapshot(snapshot ContractSnapshot, restoreLedger bool) error {
+	contract := snapshot.Contract
+	if contract == nil {
+		return fmt.Errorf("%w: snapshot has no contract", ErrInvalidInput)
+	}
+	switch {
+	case strings.TrimSpace(contract.Name) == "":
+		return fmt.Errorf("%w: contract name is required", ErrInvalidInput)
+	case contract.CreatedByOrgID == uuid.Nil:
+		return fmt.Errorf("%w: contract created_by_org_id is required", ErrInvalidInput)
+	case contract.WorkType == "":
+		return fmt.Errorf("%w: contract work_type is required", ErrInvalidInput)
+	case contract.PricePerM3 <= 0, contract.BudgetTotal <= 0, contract.MinimalVolumeM3 <= 0:
+		return fmt.Errorf("%w: contract price, budget and minimal volume must be positive", ErrInvalidInput)
+	case !contract.EndAt.After(contract.StartAt):
+		return fmt.Errorf("%w: contract end_at must be after start_at", ErrInvalidInput)
+	}
+
+	switch contract.ContractType {
+	case model.ContractTypeContractorService:
+		if contract.ContractorID == nil || len(contract.Polygons) > 0 {
+			return fmt.Errorf("%w: CONTRACTOR_SERVICE requires contractor_id and no polygons", ErrInvalidInput)
+		}
+	case model.ContractTypeLandfillService:
+		if contract.LandfillID == nil || len(contract.Polygons) == 0 {
+			return fmt.Errorf("%w: LANDFILL_SERVICE requires landfill_id and polygons", ErrInvalidInput)
+		}
+		if err := validatePolygonIDs(polygonIDsOf(contract.Polygons)); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: invalid contract_type", ErrInvalidInput)
+	}
+
+	if !restoreLedger {
+		return nil
+	}
+	if snapshot.Truncated.UsageLedger {
+		return fmt.Errorf("%w: snapshot usage ledger is truncated", ErrInvalidInput)
+	}
+	for i, entry := range snapshot.UsageLedger {
+		if entry.SourceID == uuid.Nil || entry.CreatedAt.IsZero() {
+			return fmt.Errorf("%w: usage_ledger[%d] is incomplete", ErrInvalidInput, i)
+		}
+		switch entry.Kind {
+		case model.UsageLedgerEntryTrip:
+			if entry.TicketID == nil || entry.VolumeM3 <= 0 {
+				return fmt.Errorf("%w: usage_ledger[%d] trip needs ticket_id and positive volume", ErrInvalidInput, i)
+			}
+		case model.UsageLedgerEntryAdjustment:
+			if entry.VolumeM3 == 0 && entry.Cost == 0 {
+				return fmt.Errorf("%w: usage_ledger[%d] adjustment is empty", ErrInvalidInput, i)
+			}
+		default:
+			return fmt.Errorf("%w: usage_ledger[%d] has invalid kind", ErrInvalidInput, i)
+		}
+	}
+	return nil
+}
+
+func polygonIDsOf(polygons []model.ContractPolygon) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(polygons))
+	for _, polygon := range polygons {
+		ids = append(ids, polygon.PolygonID)
+	}
+	return ids
+}
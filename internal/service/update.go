@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+	"github.com/nurpe/snowops-contract/internal/notifier"
+	"github.com/nurpe/snowops-contract/internal/repository"
+)
+
+// UpdateContractInput — изменяемые условия контракта; nil — поле не меняется.
+type UpdateContractInput struct {
+	Name            *string
+	PricePerM3      *float64
+	BudgetTotal     *float64
+	MinimalVolumeM3 *float64
+	StartAt         *time.Time
+	EndAt           *time.Time
+	IsActive        *bool
+	// Reason — причина изменения, обязательна; попадает в contract_amendments
+	Reason string
+}
+
+// Update меняет условия контракта. Только КГУ-создатель; заблокированный
+// контракт не меняется. Каждое изменённое поле записывается в
+// contract_amendments с причиной и автором. Новая цена не пересчитывает уже
+// учтённые рейсы: trip_usage_log и contract_usage остаются как есть. Ответ —
+// контракт с обновлённым updated_at и предупреждениями, как у Create.
+func (s *ContractService) Update(ctx context.Context, principal model.Principal, id uuid.UUID, input UpdateContractInput) (_ *model.Contract, err error) {
+	defer observeOperation("update", time.Now(), &err)
+	if (input == UpdateContractInput{Reason: input.Reason}) {
+		return nil, fmt.Errorf("%w: no fields to update", ErrInvalidInput)
+	}
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		return nil, fieldError("reason", "reason is required")
+	}
+
+	contract, err := s.contracts.GetByID(ctx, id, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureWriteAccess(principal, contract); err != nil {
+		return nil, err
+	}
+	if err := ensureNotLocked(contract); err != nil {
+		return nil, err
+	}
+
+	// Слияние и проверки повторяются под блокировкой строки контракта:
+	// параллельное изменение или рейс между чтением выше и записью иначе
+	// остались бы непроверенными или были бы перезаписаны устаревшей копией.
+	var (
+		updated  model.Contract
+		warnings []string
+	)
+	changed, err := s.contracts.Update(ctx, repository.UpdateContractParams{
+		ID: id,
+		Apply: func(current model.Contract) (model.Contract, []string, error) {
+			var (
+				changed []string
+				err     error
+			)
+			updated, changed = applyContractUpdate(current, input)
+			if len(changed) == 0 {
+				return updated, nil, nil
+			}
+			warnings, err = s.validateContractUpdate(&updated, changed)
+			return updated, changed, err
+		},
+		Reason:          reason,
+		MaxActivePerOrg: s.cfg.MaxActivePerOrg,
+		ActorUserID:     principal.UserID,
+		ActorOrgID:      principal.OrganizationID,
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, ErrNotFound
+	case errors.Is(err, repository.ErrContractLocked):
+		return nil, ErrContractLocked
+	case errors.Is(err, repository.ErrActiveContractLimit):
+		return nil, fmt.Errorf("%w: %v", ErrConflict, err)
+	case err != nil:
+		return nil, err
+	}
+	if len(changed) == 0 {
+		// повтор того же PUT ничего не пишет
+		return s.get(ctx, principal, id, nil)
+	}
+
+	event := newContractEvent(&updated, &principal)
+	event.ChangedFields = changed
+	s.notifyContract(ctx, notifier.EventContractUpdated, id, event)
+	if slices.Contains(changed, "is_active") && !updated.IsActive {
+		s.notifyContract(ctx, notifier.EventContractArchived, id, newContractEvent(&updated, &principal))
+	}
+
+	result, err := s.get(ctx, principal, id, nil)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	return result, nil
+}
+
+// validateContractUpdate проверяет новые условия контракта. Бюджет ниже
+// начисленного проверяется, только если его меняют: контракт с перерасходом
+// можно переименовать. Usage контракта должен быть загружен.
+func (s *ContractService) validateContractUpdate(updated *model.Contract, changed []string) ([]string, error) {
+	validation := &ValidationError{}
+	validateTerms(validation, updated.Name, updated.PricePerM3, updated.BudgetTotal, updated.MinimalVolumeM3, updated.StartAt, updated.EndAt)
+	if err := validation.errOrNil(); err != nil {
+		return nil, err
+	}
+	switch {
+	case slices.Contains(changed, "budget_total"):
+		return s.validateBudgetChange(updated, updated.BudgetTotal)
+	case slices.Contains(changed, "price_per_m3") || slices.Contains(changed, "minimal_volume_m3"):
+		return checkMinimalVolume(updated.MinimalVolumeM3, updated.PricePerM3, updated.BudgetTotal, s.cfg.MinimalVolumeBudgetFactor)
+	}
+	return nil, nil
+}
+
+// applyContractUpdate накладывает заданные поля на копию контракта и
+// возвращает имена полей, значение которых действительно изменилось.
+func applyContractUpdate(contract model.Contract, input UpdateContractInput) (model.Contract, []string) {
+	var changed []string
+	if input.Name != nil {
+		if name := strings.TrimSpace(*input.Name); name != contract.Name {
+			contract.Name = name
+			changed = append(changed, "name")
+		}
+	}
+	if input.PricePerM3 != nil && *input.PricePerM3 != contract.PricePerM3 {
+		contract.PricePerM3 = *input.PricePerM3
+		changed = append(changed, "price_per_m3")
+	}
+	if input.BudgetTotal != nil && *input.BudgetTotal != contract.BudgetTotal {
+		contract.BudgetTotal = *input.BudgetTotal
+		changed = append(changed, "budget_total")
+	}
+	if input.MinimalVolumeM3 != nil && *input.MinimalVolumeM3 != contract.MinimalVolumeM3 {
+		contract.MinimalVolumeM3 = *input.MinimalVolumeM3
+		changed = append(changed, "minimal_volume_m3")
+	}
+	if input.StartAt != nil && !input.StartAt.Equal(contract.StartAt) {
+		contract.StartAt = *input.StartAt
+		changed = append(changed, "start_at")
+	}
+	if input.EndAt != nil && !input.EndAt.Equal(contract.EndAt) {
+		contract.EndAt = *input.EndAt
+		changed = append(changed, "end_at")
+	}
+	if input.IsActive != nil && *input.IsActive != contract.IsActive {
+		contract.IsActive = *input.IsActive
+		changed = append(changed, "is_active")
+	}
+	return contract, changed
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestUpdatePriceLeavesRecordedUsageUntouched(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+	if err := recordTrip(t, s, database, principal, contract.ID, 10); err != nil {
+		t.Fatalf("record trip: %v", err)
+	}
+
+	price := 150.0
+	updated, err := s.Update(ctx, principal, contract.ID, UpdateContractInput{PricePerM3: &price, Reason: "доп. соглашение №1"})
+	if err != nil {
+		t.Fatalf("update price: %v", err)
+	}
+	if updated.PricePerM3 != 150 {
+		t.Fatalf("price_per_m3 = %.2f, want 150", updated.PricePerM3)
+	}
+	if updated.Usage == nil || updated.Usage.TotalVolumeM3 != 10 || updated.Usage.TotalCost != 1000 {
+		t.Fatalf("usage after price change = %+v, want 10 m3 / 1000", updated.Usage)
+	}
+	log, err := s.contracts.ListTripUsageLog(ctx, contract.ID)
+	if err != nil {
+		t.Fatalf("list trip usage log: %v", err)
+	}
+	if len(log) != 1 || log[0].RecordedCost != 1000 {
+		t.Fatalf("trip_usage_log = %+v, want one trip at 1000", log)
+	}
+
+	// следующий рейс идёт по новой цене
+	if err := recordTrip(t, s, database, principal, contract.ID, 10); err != nil {
+		t.Fatalf("record trip after price change: %v", err)
+	}
+	usage, err := s.contracts.GetUsage(ctx, contract.ID)
+	if err != nil {
+		t.Fatalf("get usage: %v", err)
+	}
+	if usage.TotalVolumeM3 != 20 || usage.TotalCost != 2500 {
+		t.Fatalf("usage = %+v, want 20 m3 / 2500", usage)
+	}
+}
+
+func TestUpdateRejectsBudgetBelowAccruedCost(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	input := contractorInput(t, database)
+	input.MinimalVolumeM3 = 1
+	contract := createContract(t, s, principal, input)
+	if err := recordTrip(t, s, database, principal, contract.ID, 20); err != nil {
+		t.Fatalf("record trip: %v", err)
+	}
+
+	budget := 1500.0
+	_, err := s.Update(ctx, principal, contract.ID, UpdateContractInput{BudgetTotal: &budget, Reason: "сокращение"})
+	if !errors.Is(err, ErrInvalidInput) {
+		t.Fatalf("budget below accrued cost: err = %v, want ErrInvalidInput", err)
+	}
+	current, err := s.contracts.GetByID(ctx, contract.ID, false)
+	if err != nil {
+		t.Fatalf("get contract: %v", err)
+	}
+	if current.BudgetTotal != input.BudgetTotal || current.UpdatedAt != nil {
+		t.Fatalf("rejected update was written: budget_total = %.2f, updated_at = %v", current.BudgetTotal, current.UpdatedAt)
+	}
+}
+
+func TestConcurrentUpdatesOfDifferentFieldsKeepBoth(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("Уборка дорог %d", i)
+		price := 100.0 + float64(i+1)
+		var wg sync.WaitGroup
+		errs := make([]error, 2)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_, errs[0] = s.Update(ctx, principal, contract.ID, UpdateContractInput{Name: &name, Reason: "переименование"})
+		}()
+		go func() {
+			defer wg.Done()
+			_, errs[1] = s.Update(ctx, principal, contract.ID, UpdateContractInput{PricePerM3: &price, Reason: "индексация"})
+		}()
+		wg.Wait()
+		for _, err := range errs {
+			if err != nil {
+				t.Fatalf("round %d: update: %v", i, err)
+			}
+		}
+
+		// ни одно изменение не затёрто устаревшей копией другого
+		current, err := s.contracts.GetByID(ctx, contract.ID, false)
+		if err != nil {
+			t.Fatalf("get contract: %v", err)
+		}
+		if current.Name != name || current.PricePerM3 != price {
+			t.Fatalf("round %d: name = %q, price_per_m3 = %.2f, want %q and %.2f", i, current.Name, current.PricePerM3, name, price)
+		}
+	}
+}
+
+func TestUpdateWritesAmendmentPerChangedField(t *testing.T) {
+	ctx := context.Background()
+	s, database, _ := newTestService(t, Config{})
+	principal := kguPrincipal(t, database)
+	contract := createContract(t, s, principal, contractorInput(t, database))
+
+	name := "Уборка дорог и тротуаров"
+	price := 120.5
+	same := contract.BudgetTotal
+	if _, err := s.Update(ctx, principal, contract.ID, UpdateContractInput{
+		Name:        &name,
+		PricePerM3:  &price,
+		BudgetTotal: &same,
+		Reason:      "доп. соглашение №2",
+	}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	amendments, err := s.ListContractAmendments(ctx, principal, contract.ID)
+	if err != nil {
+		t.Fatalf("list amendments: %v", err)
+	}
+	// неизменённый budget_total строки не даёт
+	if len(amendments) != 2 {
+		t.Fatalf("amendments = %+v, want 2 rows", amendments)
+	}
+	want := map[string][2]string{
+		"name":         {contract.Name, name},
+		"price_per_m3": {"100", "120.5"},
+	}
+	for _, amendment := range amendments {
+		values, ok := want[amendment.Field]
+		if !ok {
+			t.Fatalf("unexpected amendment for %s", amendment.Field)
+		}
+		if amendment.OldValue != values[0] || amendment.NewValue != values[1] {
+			t.Fatalf("%s: %q -> %q, want %q -> %q", amendment.Field, amendment.OldValue, amendment.NewValue, values[0], values[1])
+		}
+		if amendment.ChangedBy != principal.UserID || amendment.Reason != "доп. соглашение №2" {
+			t.Fatalf("%s: changed_by %s, reason %q", amendment.Field, amendment.ChangedBy, amendment.Reason)
+		}
+		if !amendment.ChangedAt.Equal(amendments[0].ChangedAt) {
+			t.Fatalf("amendments of one update have different changed_at")
+		}
+		delete(want, amendment.Field)
+	}
+
+	// повтор того же изменения истории не добавляет
+	if _, err := s.Update(ctx, principal, contract.ID, UpdateContractInput{Name: &name, PricePerM3: &price, Reason: "повтор"}); err != nil {
+		t.Fatalf("repeated update: %v", err)
+	}
+	amendments, err = s.ListContractAmendments(ctx, principal, contract.ID)
+	if err != nil {
+		t.Fatalf("list amendments: %v", err)
+	}
+	if len(amendments) != 2 {
+		t.Fatalf("amendments after repeated update = %d, want 2", len(amendments))
+	}
+}
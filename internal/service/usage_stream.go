@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/nurpe/snowops-contract/internal/model"
+)
+
+// usageBroker — in-process pub/sub изменений usage по контракту. Подписчик
+// получает только последнее значение: если он не успел прочитать предыдущее,
+// оно заменяется новым. Событие видят подписчики той же реплики, что записала usage.
+type usageBroker struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan model.ContractUsage]struct{}
+}
+
+func newUsageBroker() *usageBroker {
+	return &usageBroker{subscribers: make(map[uuid.UUID]map[chan model.ContractUsage]struct{})}
+}
+
+func (b *usageBroker) subscribe(contractID uuid.UUID) (chan model.ContractUsage, func()) {
+	ch := make(chan model.ContractUsage, 1)
+
+	b.mu.Lock()
+	if b.subscribers[contractID] == nil {
+		b.subscribers[contractID] = make(map[chan model.ContractUsage]struct{})
+	}
+	b.subscribers[contractID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers[contractID], ch)
+			if len(b.subscribers[contractID]) == 0 {
+				delete(b.subscribers, contractID)
+			}
+		})
+	}
+	return ch, cancel
+}
+
+func (b *usageBroker) publish(usage model.ContractUsage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[usage.ContractID] {
+		select {
+		case <-ch:
+		default:
+		}
+		ch <- usage
+	}
+}
+
+func (b *usageBroker) hasSubscribers(contractID uuid.UUID) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subscribers[contractID]) > 0
+}
+
+// UsageSubscription — текущий usage контракта и канал его последующих изменений.
+// Cancel нужно вызвать при отключении клиента.
+type UsageSubscription struct {
+	Current *model.ContractUsage
+	Updates <-chan model.ContractUsage
+	Cancel  func()
+}
+
+// SubscribeUsage подписывает на изменения usage контракта (доступ — как на чтение).
+func (s *ContractService) SubscribeUsage(ctx context.Context, principal model.Principal, contractID uuid.UUID) (*UsageSubscription, error) {
+	contract, err := s.contracts.GetByID(ctx, contractID, false)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.ensureReadAccess(principal, contract); err != nil {
+		return nil, err
+	}
+
+	// подписка до чтения текущего значения: изменение между ними не потеряется
+	updates, cancel := s.usageUpdates.subscribe(contractID)
+	current, err := s.contracts.GetUsage(ctx, contractID)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &UsageSubscription{Current: current, Updates: updates, Cancel: cancel}, nil
+}
+
+// publishUsage рассылает свежий usage подписчикам после записи. Запись уже
+// зафиксирована, поэтому ошибка чтения только логируется.
+func (s *ContractService) publishUsage(ctx context.Context, contractID uuid.UUID) {
+	if !s.usageUpdates.hasSubscribers(contractID) {
+		return
+	}
+	usage, err := s.contracts.GetUsage(ctx, contractID)
+	if err != nil {
+		s.log.Warn().Err(err).Str("contract_id", contractID.String()).Msg("failed to load usage for stream")
+		return
+	}
+	if usage != nil {
+		s.usageUpdates.publish(*usage)
+	}
+}
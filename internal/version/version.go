@@ -0,0 +1,11 @@
+package version
+
+// Значения подставляются при сборке:
+//
+//	go build -ldflags "-X github.com/nurpe/snowops-contract/internal/version.Version=1.2.0 -X github.com/nurpe/snowops-contract/internal/version.Commit=$(git rev-parse --short HEAD)"
+var (
+	Version = "dev"
+	Commit  = "unknown"
+)
+
+const ServiceName = "contract-service"